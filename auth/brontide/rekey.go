@@ -0,0 +1,44 @@
+package brontide
+
+import (
+	"crypto/sha256"
+	"errors"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+// ErrUnreadData is returned when the receive key is changed while a decrypted message is still being read
+var ErrUnreadData = errors.New("message data left unread")
+
+// rekeyInfo is used as the info of the HKDF invocation deriving keys after a rekey
+var rekeyInfo = []byte("astral rekey")
+
+// rekey replaces the key and the salt of the cipherState with ones derived from the current key and a new
+// shared secret, and resets the nonce. Both parties mix the same secret into matching cipher states, so
+// that they stay in sync.
+func (c *cipherState) rekey(secret []byte) {
+	var nextKey [32]byte
+
+	h := hkdf.New(sha256.New, append(c.secretKey[:], secret...), c.salt[:], rekeyInfo)
+	h.Read(c.salt[:])
+	h.Read(nextKey[:])
+
+	c.InitializeKey(nextKey)
+}
+
+// RekeySend mixes a new shared secret into the key used to encrypt messages. All messages written after
+// the call are encrypted with the new key.
+func (c *Conn) RekeySend(secret []byte) {
+	c.noise.sendCipher.rekey(secret)
+}
+
+// RekeyRecv mixes a new shared secret into the key used to decrypt messages. All messages read after
+// the call are decrypted with the new key. It fails if data of the last message has not been read yet,
+// because it would mean that the remote party switched keys at a different point in the stream.
+func (c *Conn) RekeyRecv(secret []byte) error {
+	if c.readBuf.Len() > 0 {
+		return ErrUnreadData
+	}
+	c.noise.recvCipher.rekey(secret)
+	return nil
+}
@@ -4,6 +4,8 @@ import (
 	"github.com/cryptopunkscc/astrald/auth/brontide"
 	"github.com/cryptopunkscc/astrald/auth/id"
 	"github.com/cryptopunkscc/astrald/net"
+	"sync"
+	"time"
 )
 
 var _ net.SecureConn = &NoiseConn{}
@@ -12,14 +14,36 @@ var _ net.SecureConn = &NoiseConn{}
 type NoiseConn struct {
 	conn     net.Conn
 	brontide *brontide.Conn
+	rmu      sync.Mutex
+	wmu      sync.Mutex
+	rekey    rekeyState
+}
+
+func newNoiseConn(conn net.Conn, bConn *brontide.Conn) *NoiseConn {
+	var c = &NoiseConn{
+		conn:     conn,
+		brontide: bConn,
+	}
+	c.rekey.keyAt = time.Now()
+	return c
 }
 
 func (conn *NoiseConn) Read(p []byte) (n int, err error) {
-	return conn.brontide.Read(p)
+	conn.rmu.Lock()
+	defer conn.rmu.Unlock()
+
+	n, err = conn.brontide.Read(p)
+	conn.rekey.bytes.Add(uint64(n))
+	return
 }
 
 func (conn *NoiseConn) Write(p []byte) (n int, err error) {
-	return conn.brontide.Write(p)
+	conn.wmu.Lock()
+	defer conn.wmu.Unlock()
+
+	n, err = conn.brontide.Write(p)
+	conn.rekey.bytes.Add(uint64(n))
+	return
 }
 
 func (conn *NoiseConn) Close() error {
@@ -22,6 +22,11 @@ func HandshakeInbound(ctx context.Context, conn net.Conn, localID id.Identity) (
 		}
 	}()
 
+	// the noise handshake only supports secp256k1 keys
+	if localID.Algorithm() != id.AlgorithmSecp256k1 {
+		return nil, id.ErrUnsupportedAlgorithm
+	}
+
 	bConn, err := brontide.PassiveHandshake(conn, localID.PrivateKey())
 	select {
 	case err := <-errCh:
@@ -32,10 +37,7 @@ func HandshakeInbound(ctx context.Context, conn net.Conn, localID id.Identity) (
 		return nil, err
 	}
 
-	return &NoiseConn{
-		conn:     conn,
-		brontide: bConn,
-	}, nil
+	return newNoiseConn(conn, bConn), nil
 }
 
 // HandshakeOutbound performs a handshake as the active party.
@@ -52,6 +54,11 @@ func HandshakeOutbound(ctx context.Context, conn net.Conn, expectedRemoteID id.I
 		case <-done:
 		}
 	}()
+	// the noise handshake only supports secp256k1 keys
+	if localID.Algorithm() != id.AlgorithmSecp256k1 || expectedRemoteID.Algorithm() != id.AlgorithmSecp256k1 {
+		return nil, id.ErrUnsupportedAlgorithm
+	}
+
 	c, err := brontide.ActiveHandshake(conn, localID.PrivateKey(), expectedRemoteID.PublicKey())
 	select {
 	case err := <-errCh:
@@ -62,8 +69,5 @@ func HandshakeOutbound(ctx context.Context, conn net.Conn, expectedRemoteID id.I
 		return nil, err
 	}
 
-	return &NoiseConn{
-		conn:     conn,
-		brontide: c,
-	}, nil
+	return newNoiseConn(conn, c), nil
 }
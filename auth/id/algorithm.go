@@ -0,0 +1,57 @@
+package id
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+)
+
+// Algorithm is a signature algorithm used by an identity
+type Algorithm int
+
+const (
+	AlgorithmSecp256k1 Algorithm = iota
+	AlgorithmEd25519
+)
+
+// ed25519Tag is the first byte of serialized Ed25519 public keys. Compressed secp256k1 keys always start with
+// 0x02 or 0x03, so keys of both algorithms can be told apart while having the same length.
+const ed25519Tag = 0xed
+
+var ErrUnsupportedAlgorithm = errors.New("unsupported algorithm")
+var ErrNoPrivateKey = errors.New("private key missing")
+
+func (a Algorithm) String() string {
+	switch a {
+	case AlgorithmSecp256k1:
+		return "secp256k1"
+	case AlgorithmEd25519:
+		return "ed25519"
+	}
+	return "unknown"
+}
+
+// Sign signs the hash with the identity's private key
+func (id Identity) Sign(hash []byte) ([]byte, error) {
+	switch {
+	case id.privateKey != nil:
+		return ecdsa.SignASN1(rand.Reader, id.privateKey.ToECDSA(), hash)
+	case id.edSeed != nil:
+		return ed25519.Sign(ed25519.NewKeyFromSeed(id.edSeed[:]), hash), nil
+	}
+	return nil, ErrNoPrivateKey
+}
+
+// Verify returns true if sig is a valid signature of the hash made by the identity
+func (id Identity) Verify(hash []byte, sig []byte) bool {
+	switch id.Algorithm() {
+	case AlgorithmSecp256k1:
+		if pub := id.PublicKey(); pub != nil {
+			return ecdsa.VerifyASN1(pub.ToECDSA(), hash, sig)
+		}
+	case AlgorithmEd25519:
+		return ed25519.Verify(id.edPublicKey(), hash, sig)
+	}
+	return false
+}
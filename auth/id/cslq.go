@@ -1,7 +1,6 @@
 package id
 
 import (
-	"github.com/btcsuite/btcd/btcec/v2"
 	"github.com/cryptopunkscc/astrald/cslq"
 )
 
@@ -27,9 +26,9 @@ func (id *Identity) UnmarshalCSLQ(dec *cslq.Decoder) error {
 func (id Identity) MarshalCSLQ(enc *cslq.Encoder) error {
 	var serialized []byte
 	if id.IsZero() {
-		serialized = make([]byte, btcec.PubKeyBytesLenCompressed)
+		serialized = make([]byte, PublicKeySize)
 	} else {
-		serialized = id.PublicKey().SerializeCompressed()
+		serialized = id.PublicKeyBytes()
 	}
 	return enc.Encodef(cslqPattern, serialized)
 }
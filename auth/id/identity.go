@@ -1,21 +1,29 @@
 package id
 
 import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
 	"encoding/hex"
 	"errors"
 	"github.com/btcsuite/btcd/btcec/v2"
 )
 
-// Identity is an eliptic-curve-based identity
+// Identity is a public-key-based identity. It uses secp256k1 keys by default, but can also use Ed25519 keys.
 type Identity struct {
 	privateKey *btcec.PrivateKey
 	publicKey  *btcec.PublicKey
+	edSeed     *[ed25519.SeedSize]byte
+	edPublic   *[ed25519.PublicKeySize]byte
 }
 
 var Anyone = Identity{}
 
 var ErrInvalidKeyLength = errors.New("invalid key length")
 
+// PublicKeySize is the length of a serialized public key of any algorithm
+const PublicKeySize = btcec.PubKeyBytesLenCompressed
+
 // GenerateIdentity returns a new Identity
 func GenerateIdentity() (Identity, error) {
 	var err error
@@ -29,6 +37,16 @@ func GenerateIdentity() (Identity, error) {
 	return id, nil
 }
 
+// GenerateEd25519Identity returns a new Identity that uses an Ed25519 key
+func GenerateEd25519Identity() (Identity, error) {
+	var seed [ed25519.SeedSize]byte
+	if _, err := rand.Read(seed[:]); err != nil {
+		return Identity{}, err
+	}
+
+	return ParseEd25519PrivateKey(seed[:])
+}
+
 func PublicKey(key *btcec.PublicKey) Identity {
 	return Identity{publicKey: key}
 }
@@ -43,12 +61,38 @@ func ParsePrivateKey(data []byte) (Identity, error) {
 	}, nil
 }
 
+// ParseEd25519PrivateKey returns an identity of an Ed25519 private key seed
+func ParseEd25519PrivateKey(seed []byte) (Identity, error) {
+	if len(seed) != ed25519.SeedSize {
+		return Identity{}, ErrInvalidKeyLength
+	}
+
+	var id = Identity{
+		edSeed:   &[ed25519.SeedSize]byte{},
+		edPublic: &[ed25519.PublicKeySize]byte{},
+	}
+	copy(id.edSeed[:], seed)
+	copy(id.edPublic[:], ed25519.NewKeyFromSeed(seed).Public().(ed25519.PublicKey))
+
+	return id, nil
+}
+
+// ParsePublicKey parses a serialized public key. The algorithm is determined by the first byte of the key.
 func ParsePublicKey(pkData []byte) (Identity, error) {
 	// All zeroes are valid zero identity
 	if isAllNull(pkData) {
 		return Identity{}, nil
 	}
 
+	if len(pkData) > 0 && pkData[0] == ed25519Tag {
+		if len(pkData) != PublicKeySize {
+			return Identity{}, ErrInvalidKeyLength
+		}
+		var id = Identity{edPublic: &[ed25519.PublicKeySize]byte{}}
+		copy(id.edPublic[:], pkData[1:])
+		return id, nil
+	}
+
 	key, err := btcec.ParsePubKey(pkData)
 	if err != nil {
 		return Identity{}, err
@@ -84,10 +128,19 @@ func ParsePublicKeyHex(hexKey string) (Identity, error) {
 func (id Identity) Public() Identity {
 	return Identity{
 		publicKey: id.PublicKey(),
+		edPublic:  id.edPublic,
+	}
+}
+
+// Algorithm returns the signature algorithm used by the identity
+func (id Identity) Algorithm() Algorithm {
+	if id.edPublic != nil {
+		return AlgorithmEd25519
 	}
+	return AlgorithmSecp256k1
 }
 
-// PublicKey returns identity's public key
+// PublicKey returns identity's secp256k1 public key or nil if the identity uses a different algorithm
 func (id Identity) PublicKey() *btcec.PublicKey {
 	if id.privateKey != nil {
 		return id.privateKey.PubKey()
@@ -95,19 +148,48 @@ func (id Identity) PublicKey() *btcec.PublicKey {
 	return id.publicKey
 }
 
+// PublicKeyBytes returns the serialized public key of the identity. Keys of all algorithms are PublicKeySize
+// bytes long.
+func (id Identity) PublicKeyBytes() []byte {
+	switch {
+	case id.edPublic != nil:
+		return append([]byte{ed25519Tag}, id.edPublic[:]...)
+	case id.PublicKey() != nil:
+		return id.PublicKey().SerializeCompressed()
+	}
+	return nil
+}
+
 // PublicKeyHex returns a serialized, compressed, hex-encoded public key
 func (id Identity) PublicKeyHex() string {
-	if id.PublicKey() == nil {
+	var b = id.PublicKeyBytes()
+	if b == nil {
 		return ""
 	}
-	return hex.EncodeToString(id.PublicKey().SerializeCompressed())
+	return hex.EncodeToString(b)
 }
 
-// PrivateKey returns identity's private key
+// PrivateKey returns identity's secp256k1 private key or nil if the identity uses a different algorithm
 func (id Identity) PrivateKey() *btcec.PrivateKey {
 	return id.privateKey
 }
 
+// HasPrivateKey returns true if the identity holds a private key of any algorithm
+func (id Identity) HasPrivateKey() bool {
+	return id.privateKey != nil || id.edSeed != nil
+}
+
+// PrivateKeyBytes returns the serialized private key of the identity or nil if it doesn't hold one
+func (id Identity) PrivateKeyBytes() []byte {
+	switch {
+	case id.privateKey != nil:
+		return id.privateKey.Serialize()
+	case id.edSeed != nil:
+		return bytes.Clone(id.edSeed[:])
+	}
+	return nil
+}
+
 // IsEqual checks if the public key is the same as the other identity's or if both are zero
 func (id Identity) IsEqual(other Identity) bool {
 	if id.IsZero() {
@@ -116,11 +198,11 @@ func (id Identity) IsEqual(other Identity) bool {
 	if other.IsZero() {
 		return false
 	}
-	return id.PublicKey().IsEqual(other.PublicKey())
+	return bytes.Equal(id.PublicKeyBytes(), other.PublicKeyBytes())
 }
 
 func (id Identity) IsZero() bool {
-	return (id.privateKey == nil) && (id.publicKey == nil)
+	return (id.privateKey == nil) && (id.publicKey == nil) && (id.edPublic == nil)
 }
 
 // String returns a string representation of this identtity
@@ -132,3 +214,10 @@ func (id Identity) Fingerprint() string {
 	hex := id.PublicKeyHex()
 	return hex[0:8] + ":" + hex[len(hex)-8:]
 }
+
+func (id Identity) edPublicKey() ed25519.PublicKey {
+	if id.edPublic == nil {
+		return nil
+	}
+	return id.edPublic[:]
+}
@@ -0,0 +1,100 @@
+package id
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"github.com/cryptopunkscc/astrald/cslq"
+	"testing"
+)
+
+func TestAlgorithms(t *testing.T) {
+	secp, err := GenerateIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ed, err := GenerateEd25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var hash = sha256.Sum256([]byte("hello"))
+
+	for _, identity := range []Identity{secp, ed} {
+		var name = identity.Algorithm().String()
+
+		if len(identity.PublicKeyBytes()) != PublicKeySize {
+			t.Fatalf("%s: public key has %d bytes", name, len(identity.PublicKeyBytes()))
+		}
+
+		// hex
+		parsed, err := ParsePublicKeyHex(identity.PublicKeyHex())
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !parsed.IsEqual(identity) || parsed.Algorithm() != identity.Algorithm() {
+			t.Fatalf("%s: hex roundtrip failed", name)
+		}
+
+		// cslq
+		var buf = &bytes.Buffer{}
+		if err := cslq.Encode(buf, "v", identity); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if buf.Len() != PublicKeySize {
+			t.Fatalf("%s: encoded %d bytes", name, buf.Len())
+		}
+		var decoded Identity
+		if err := cslq.Decode(buf, "v", &decoded); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !decoded.IsEqual(identity) {
+			t.Fatalf("%s: cslq roundtrip failed", name)
+		}
+
+		// json
+		j, err := json.Marshal(identity)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		var unmarshaled Identity
+		if err := json.Unmarshal(j, &unmarshaled); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !unmarshaled.IsEqual(identity) {
+			t.Fatalf("%s: json roundtrip failed", name)
+		}
+
+		// private key
+		var restored Identity
+		if identity.Algorithm() == AlgorithmEd25519 {
+			restored, err = ParseEd25519PrivateKey(identity.PrivateKeyBytes())
+		} else {
+			restored, err = ParsePrivateKey(identity.PrivateKeyBytes())
+		}
+		if err != nil || !restored.IsEqual(identity) {
+			t.Fatalf("%s: private key roundtrip failed: %v", name, err)
+		}
+
+		// signatures
+		sig, err := identity.Sign(hash[:])
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !parsed.Verify(hash[:], sig) {
+			t.Fatalf("%s: valid signature rejected", name)
+		}
+		if _, err := parsed.Sign(hash[:]); err != ErrNoPrivateKey {
+			t.Fatalf("%s: signed without a private key", name)
+		}
+	}
+
+	if secp.IsEqual(ed) {
+		t.Fatal("identities of different algorithms are equal")
+	}
+
+	sig, _ := secp.Sign(hash[:])
+	if ed.Verify(hash[:], sig) {
+		t.Fatal("ed25519 identity accepted a secp256k1 signature")
+	}
+}
@@ -0,0 +1,162 @@
+package obfs
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"github.com/cryptopunkscc/astrald/net"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/hkdf"
+	"io"
+	mrand "math/rand"
+	"sync"
+)
+
+const (
+	maxPayload  = 16 * 1024
+	maxFramePad = 256
+	headerSize  = 4 // payload length and padding length
+	sealedSize  = headerSize + chacha20poly1305.Overhead
+)
+
+var _ net.Conn = &Conn{}
+
+// Conn is an obfuscated connection. Data is sent in frames, each made of an encrypted header with
+// the lengths of the payload and the padding, followed by the encrypted payload and padding.
+type Conn struct {
+	net.Conn
+	r io.Reader
+
+	rmu    sync.Mutex
+	rAEAD  cipher.AEAD
+	rNonce uint64
+	rbuf   []byte
+
+	wmu    sync.Mutex
+	wAEAD  cipher.AEAD
+	wNonce uint64
+}
+
+func newConn(conn net.Conn, key Key, nonceC []byte, nonceS []byte, rest []byte, client bool) (*Conn, error) {
+	var salt = append(append([]byte{}, nonceC...), nonceS...)
+	var keys = make([]byte, 2*chacha20poly1305.KeySize)
+
+	if _, err := io.ReadFull(hkdf.New(sha256.New, key[:], salt, []byte("astral obfs")), keys); err != nil {
+		return nil, err
+	}
+
+	c2s, err := chacha20poly1305.New(keys[:chacha20poly1305.KeySize])
+	if err != nil {
+		return nil, err
+	}
+	s2c, err := chacha20poly1305.New(keys[chacha20poly1305.KeySize:])
+	if err != nil {
+		return nil, err
+	}
+
+	var c = &Conn{
+		Conn: conn,
+		r:    io.MultiReader(bytes.NewReader(rest), conn),
+	}
+
+	if client {
+		c.wAEAD, c.rAEAD = c2s, s2c
+	} else {
+		c.wAEAD, c.rAEAD = s2c, c2s
+	}
+
+	return c, nil
+}
+
+func (conn *Conn) Read(p []byte) (int, error) {
+	conn.rmu.Lock()
+	defer conn.rmu.Unlock()
+
+	for len(conn.rbuf) == 0 {
+		if err := conn.readFrame(); err != nil {
+			return 0, err
+		}
+	}
+
+	var n = copy(p, conn.rbuf)
+	conn.rbuf = conn.rbuf[n:]
+
+	return n, nil
+}
+
+func (conn *Conn) Write(p []byte) (n int, err error) {
+	conn.wmu.Lock()
+	defer conn.wmu.Unlock()
+
+	for len(p) > 0 {
+		var size = min(len(p), maxPayload)
+		if err = conn.writeFrame(p[:size]); err != nil {
+			return
+		}
+		n += size
+		p = p[size:]
+	}
+
+	return
+}
+
+func (conn *Conn) readFrame() error {
+	var header = make([]byte, sealedSize)
+	if _, err := io.ReadFull(conn.r, header); err != nil {
+		return err
+	}
+
+	header, err := conn.rAEAD.Open(header[:0], conn.nextNonce(&conn.rNonce), header, nil)
+	if err != nil {
+		return errors.New("invalid frame header")
+	}
+
+	var payloadLen = int(binary.BigEndian.Uint16(header[0:2]))
+	var padLen = int(binary.BigEndian.Uint16(header[2:4]))
+	if payloadLen > maxPayload || padLen > maxFramePad {
+		return errors.New("invalid frame length")
+	}
+
+	var body = make([]byte, payloadLen+padLen+chacha20poly1305.Overhead)
+	if _, err := io.ReadFull(conn.r, body); err != nil {
+		return err
+	}
+
+	body, err = conn.rAEAD.Open(body[:0], conn.nextNonce(&conn.rNonce), body, nil)
+	if err != nil {
+		return errors.New("invalid frame")
+	}
+
+	conn.rbuf = body[:payloadLen]
+
+	return nil
+}
+
+func (conn *Conn) writeFrame(payload []byte) error {
+	var padLen = mrand.Intn(maxFramePad + 1)
+
+	var header = make([]byte, headerSize, sealedSize)
+	binary.BigEndian.PutUint16(header[0:2], uint16(len(payload)))
+	binary.BigEndian.PutUint16(header[2:4], uint16(padLen))
+
+	// padding is encrypted along with the payload, so it can be left zeroed
+	var body = make([]byte, len(payload)+padLen, len(payload)+padLen+chacha20poly1305.Overhead)
+	copy(body, payload)
+
+	var frame = make([]byte, 0, sealedSize+cap(body))
+	frame = conn.wAEAD.Seal(frame, conn.nextNonce(&conn.wNonce), header, nil)
+	frame = conn.wAEAD.Seal(frame, conn.nextNonce(&conn.wNonce), body, nil)
+
+	_, err := conn.Conn.Write(frame)
+	return err
+}
+
+// nextNonce returns the AEAD nonce for the counter and increments the counter
+func (conn *Conn) nextNonce(counter *uint64) []byte {
+	var nonce = make([]byte, chacha20poly1305.NonceSize)
+	binary.BigEndian.PutUint64(nonce[chacha20poly1305.NonceSize-8:], *counter)
+	*counter++
+	return nonce
+}
@@ -0,0 +1,74 @@
+package obfs
+
+import (
+	"errors"
+	"github.com/cryptopunkscc/astrald/net"
+	"strings"
+)
+
+// NetworkSuffix marks endpoints of obfuscated connections. An obfuscated endpoint of a network is
+// written as the network with the suffix and the address of the network prefixed with the key, for
+// example: tcp+obfs <key>@192.168.1.2:1791
+const NetworkSuffix = "+obfs"
+
+var _ net.Endpoint = &Endpoint{}
+
+// Endpoint wraps an endpoint of another network, so that connections to it are obfuscated
+type Endpoint struct {
+	endpoint net.Endpoint
+	key      Key
+}
+
+func NewEndpoint(endpoint net.Endpoint, key Key) *Endpoint {
+	return &Endpoint{endpoint: endpoint, key: key}
+}
+
+func (e *Endpoint) Network() string {
+	return e.endpoint.Network() + NetworkSuffix
+}
+
+func (e *Endpoint) String() string {
+	return e.key.String() + "@" + e.endpoint.String()
+}
+
+func (e *Endpoint) Pack() []byte {
+	return append(append([]byte{}, e.key[:]...), e.endpoint.Pack()...)
+}
+
+// Unwrap returns the endpoint of the underlying network
+func (e *Endpoint) Unwrap() net.Endpoint {
+	return e.endpoint
+}
+
+func (e *Endpoint) Key() Key {
+	return e.key
+}
+
+// SplitNetwork returns the underlying network of an obfuscated network
+func SplitNetwork(network string) (string, bool) {
+	if !strings.HasSuffix(network, NetworkSuffix) {
+		return "", false
+	}
+	return strings.TrimSuffix(network, NetworkSuffix), true
+}
+
+// SplitAddress returns the key and the underlying address of an obfuscated address
+func SplitAddress(address string) (key Key, inner string, err error) {
+	k, inner, found := strings.Cut(address, "@")
+	if !found {
+		return key, "", errors.New("missing key")
+	}
+
+	key, err = ParseKey(k)
+	return
+}
+
+// SplitPacked returns the key and the underlying packed endpoint of a packed obfuscated endpoint
+func SplitPacked(data []byte) (key Key, inner []byte, err error) {
+	if len(data) < KeySize {
+		return key, nil, errors.New("data too short")
+	}
+
+	copy(key[:], data[:KeySize])
+	return key, data[KeySize:], nil
+}
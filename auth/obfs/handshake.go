@@ -0,0 +1,265 @@
+package obfs
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"github.com/cryptopunkscc/astrald/net"
+	"io"
+	mrand "math/rand"
+	"time"
+)
+
+const (
+	nonceSize   = 32
+	markSize    = 16
+	macSize     = 16
+	maxHandPad  = 1024
+	maxHandSize = nonceSize + maxHandPad + markSize + macSize
+)
+
+// epochs in which a handshake is valid are hours, to limit how long nonces have to be remembered
+const epochDuration = time.Hour
+
+// handshakeTimeout limits the time of the server handshake, which otherwise waits for more data
+// from clients that never send the mark
+var handshakeTimeout = 30 * time.Second
+
+// failed handshakes are held open for a random time up to maxStall
+var maxStall = 10 * time.Second
+
+var ErrHandshakeFailed = errors.New("obfs handshake failed")
+var ErrPlainConn = errors.New("plain connection")
+
+var replays = newReplayFilter(3 * epochDuration)
+
+// Open runs the handshake as the client and returns the obfuscated connection
+func Open(ctx context.Context, conn net.Conn, key Key) (*Conn, error) {
+	var stop = closeOnDone(ctx, conn)
+	defer stop()
+
+	nonceC, err := newNonce()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := conn.Write(buildHandshake(key, "client", nonceC, nil, epoch(time.Now()))); err != nil {
+		return nil, err
+	}
+
+	nonceS, rest, err := readHandshake(conn, key, "server", nonceC, func(mac []byte, macOf func(int64) []byte) bool {
+		return hmac.Equal(mac, macOf(0))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return newConn(conn, key, nonceC, nonceS, rest, true)
+}
+
+// Accept detects whether the connection is obfuscated. Plain connections are returned as they are,
+// obfuscated connections go through the server handshake. Failed handshakes are not answered, the
+// connection is held open for a random time and closed.
+func Accept(ctx context.Context, conn net.Conn, key Key) (net.Conn, error) {
+	return acceptConn(ctx, conn, key, true)
+}
+
+// AcceptObfuscated runs the server handshake like Accept, but treats plain connections like failed
+// handshakes, so that they get no response.
+func AcceptObfuscated(ctx context.Context, conn net.Conn, key Key) (net.Conn, error) {
+	return acceptConn(ctx, conn, key, false)
+}
+
+func acceptConn(ctx context.Context, conn net.Conn, key Key, allowPlain bool) (net.Conn, error) {
+	ctx, cancel := context.WithTimeout(ctx, handshakeTimeout)
+	defer cancel()
+
+	var stop = closeOnDone(ctx, conn)
+	defer stop()
+
+	var prefix = make([]byte, plainPrefixSize)
+	if _, err := io.ReadFull(conn, prefix); err != nil {
+		return nil, err
+	}
+
+	if isPlain(prefix) {
+		if !allowPlain {
+			stall(conn)
+			return nil, ErrPlainConn
+		}
+		return &prefixedConn{Conn: conn, prefix: prefix}, nil
+	}
+
+	var now = epoch(time.Now())
+
+	nonceC, rest, err := readHandshake(&prefixedConn{Conn: conn, prefix: prefix}, key, "client", nil, func(mac []byte, macOf func(int64) []byte) bool {
+		for _, e := range []int64{now - 1, now, now + 1} {
+			if hmac.Equal(mac, macOf(e)) {
+				return true
+			}
+		}
+		return false
+	})
+	if err == nil && !replays.add(nonceC) {
+		err = ErrHandshakeFailed
+	}
+	if err != nil {
+		stall(conn)
+		return nil, err
+	}
+
+	nonceS, err := newNonce()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := conn.Write(buildHandshake(key, "server", nonceS, nonceC, 0)); err != nil {
+		return nil, err
+	}
+
+	return newConn(conn, key, nonceC, nonceS, rest, false)
+}
+
+// buildHandshake returns a handshake message - the nonce, random padding, the mark and the MAC. The
+// server binds its MAC to the client's nonce, the client binds it to the current epoch.
+func buildHandshake(key Key, role string, nonce []byte, peerNonce []byte, epoch int64) []byte {
+	var pad = make([]byte, mrand.Intn(maxHandPad+1))
+	rand.Read(pad)
+
+	var msg = make([]byte, 0, maxHandSize)
+	msg = append(msg, nonce...)
+	msg = append(msg, pad...)
+	msg = append(msg, mark(key, role, nonce)...)
+
+	return append(msg, handshakeMAC(key, msg, peerNonce, epoch)...)
+}
+
+// readHandshake reads a handshake message sent by the other side and verifies its MAC with verify.
+// It returns the other side's nonce and the bytes read past the message.
+func readHandshake(conn net.Conn, key Key, role string, peerNonce []byte, verify func(mac []byte, macOf func(int64) []byte) bool) (nonce []byte, rest []byte, err error) {
+	var buf = make([]byte, 0, maxHandSize+4096)
+	var m []byte
+
+	for {
+		if len(buf) >= nonceSize && m == nil {
+			m = mark(key, role, buf[:nonceSize])
+		}
+
+		if m != nil {
+			if i := bytes.Index(buf[nonceSize:], m); i >= 0 {
+				var end = nonceSize + i + markSize
+				if len(buf) >= end+macSize {
+					var mac = buf[end : end+macSize]
+					var macOf = func(epoch int64) []byte {
+						return handshakeMAC(key, buf[:end], peerNonce, epoch)
+					}
+					if !verify(mac, macOf) {
+						return nil, nil, ErrHandshakeFailed
+					}
+					return buf[:nonceSize], buf[end+macSize:], nil
+				}
+			} else if len(buf) >= maxHandSize {
+				return nil, nil, ErrHandshakeFailed
+			}
+		}
+
+		n, err := conn.Read(buf[len(buf):cap(buf)])
+		buf = buf[:len(buf)+n]
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+}
+
+func mark(key Key, role string, nonce []byte) []byte {
+	var mac = hmac.New(sha256.New, key[:])
+	mac.Write([]byte(role + " mark"))
+	mac.Write(nonce)
+	return mac.Sum(nil)[:markSize]
+}
+
+func handshakeMAC(key Key, msg []byte, peerNonce []byte, epoch int64) []byte {
+	var mac = hmac.New(sha256.New, key[:])
+	mac.Write(msg)
+	mac.Write(peerNonce)
+	binary.Write(mac, binary.BigEndian, epoch)
+	return mac.Sum(nil)[:macSize]
+}
+
+func epoch(t time.Time) int64 {
+	return t.Unix() / int64(epochDuration/time.Second)
+}
+
+// plainPrefixSize is the length of the prefix used to tell plain connections apart. Link handshakes
+// start with a version byte of zero followed by a compressed public key.
+const plainPrefixSize = 2
+
+func isPlain(prefix []byte) bool {
+	return prefix[0] == 0 && (prefix[1] == 2 || prefix[1] == 3)
+}
+
+// newNonce returns a random nonce that can't be mistaken for the start of a plain connection
+func newNonce() ([]byte, error) {
+	var nonce = make([]byte, nonceSize)
+	for {
+		if _, err := rand.Read(nonce); err != nil {
+			return nil, err
+		}
+		if !isPlain(nonce) {
+			return nonce, nil
+		}
+	}
+}
+
+// stall keeps reading the connection for a random time before closing it, so that probes can't
+// tell a server apart by how it reacts to invalid handshakes
+func stall(conn net.Conn) {
+	var timer = time.AfterFunc(time.Duration(mrand.Int63n(int64(maxStall))), func() {
+		conn.Close()
+	})
+	defer timer.Stop()
+
+	var buf = make([]byte, 4096)
+	for {
+		if _, err := conn.Read(buf); err != nil {
+			return
+		}
+	}
+}
+
+// closeOnDone closes the connection when the context ends before stop is called
+func closeOnDone(ctx context.Context, conn net.Conn) (stop func()) {
+	var done = make(chan struct{})
+	var exited = make(chan struct{})
+	go func() {
+		defer close(exited)
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+	return func() {
+		close(done)
+		<-exited
+	}
+}
+
+// prefixedConn is a connection with some of its input already read
+type prefixedConn struct {
+	net.Conn
+	prefix []byte
+}
+
+func (conn *prefixedConn) Read(p []byte) (int, error) {
+	if len(conn.prefix) > 0 {
+		var n = copy(p, conn.prefix)
+		conn.prefix = conn.prefix[n:]
+		return n, nil
+	}
+	return conn.Conn.Read(p)
+}
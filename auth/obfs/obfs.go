@@ -0,0 +1,60 @@
+// Package obfs hides link traffic from observers. It wraps a raw connection before the link
+// handshake, so that everything on the wire, including the handshake of the wrapper itself, looks
+// like uniformly random bytes of random lengths.
+//
+// Both sides share a key - the client learns it from the endpoint it dials, the server derives it
+// from its identity. The client opens with a random nonce, random padding and a mark derived from
+// the key and the nonce. The server scans for the mark, verifies a MAC over the whole handshake and
+// answers in the same way. A peer without the key, like a censor probing the server, gets no
+// response to an obfuscated handshake. Servers answer plain link handshakes as well, unless they
+// accept obfuscated connections only (see AcceptObfuscated). After the handshake, data is sent in frames encrypted with keys derived from both
+// nonces, each with an encrypted length and random padding.
+//
+// The layer only provides obfuscation. Authentication, confidentiality and forward secrecy of the
+// link are provided by the link handshake running inside it.
+package obfs
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"github.com/cryptopunkscc/astrald/auth/id"
+	"github.com/jxskiss/base62"
+)
+
+const KeySize = 32
+
+// Key is the shared secret of obfuscated connections
+type Key [KeySize]byte
+
+// NodeKey derives the obfuscation key of a node from its private key. Anyone who knows the key can
+// open obfuscated connections with the node.
+func NodeKey(identity id.Identity) (key Key, err error) {
+	if !identity.HasPrivateKey() {
+		return key, errors.New("private key missing")
+	}
+
+	var mac = hmac.New(sha256.New, identity.PrivateKeyBytes())
+	mac.Write([]byte("astral obfs key"))
+	copy(key[:], mac.Sum(nil))
+
+	return
+}
+
+func ParseKey(s string) (key Key, err error) {
+	b, err := base62.DecodeString(s)
+	if err != nil {
+		return
+	}
+
+	if len(b) != KeySize {
+		return key, errors.New("invalid key length")
+	}
+
+	copy(key[:], b)
+	return
+}
+
+func (key Key) String() string {
+	return base62.EncodeToString(key[:])
+}
@@ -0,0 +1,240 @@
+package obfs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/cryptopunkscc/astrald/auth/id"
+	"github.com/cryptopunkscc/astrald/net"
+	"github.com/cryptopunkscc/astrald/streams"
+	"io"
+	"testing"
+	"time"
+)
+
+type pipeConn struct {
+	io.ReadWriteCloser
+	outbound bool
+	tap      *bytes.Buffer // records written bytes if set
+}
+
+func (c *pipeConn) Write(p []byte) (int, error) {
+	if c.tap != nil {
+		c.tap.Write(p)
+	}
+	return c.ReadWriteCloser.Write(p)
+}
+
+func (c *pipeConn) Outbound() bool               { return c.outbound }
+func (c *pipeConn) LocalEndpoint() net.Endpoint  { return nil }
+func (c *pipeConn) RemoteEndpoint() net.Endpoint { return nil }
+
+func newPipe() (client *pipeConn, server *pipeConn) {
+	var l, r = streams.Pipe()
+	return &pipeConn{ReadWriteCloser: l, outbound: true}, &pipeConn{ReadWriteCloser: r}
+}
+
+func testKey(t *testing.T) Key {
+	identity, err := id.GenerateIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := NodeKey(identity)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+// accept runs Accept in the background
+func accept(ctx context.Context, conn net.Conn, key Key) chan any {
+	return acceptWith(ctx, conn, key, Accept)
+}
+
+func acceptWith(ctx context.Context, conn net.Conn, key Key, fn func(context.Context, net.Conn, Key) (net.Conn, error)) chan any {
+	var res = make(chan any, 1)
+	go func() {
+		c, err := fn(ctx, conn, key)
+		if err != nil {
+			res <- err
+			return
+		}
+		res <- c
+	}()
+	return res
+}
+
+func TestObfuscation(t *testing.T) {
+	var ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var key = testKey(t)
+	var clientPipe, serverPipe = newPipe()
+	clientPipe.tap = &bytes.Buffer{}
+
+	var res = accept(ctx, serverPipe, key)
+
+	client, err := Open(ctx, clientPipe, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server, ok := (<-res).(*Conn)
+	if !ok {
+		t.Fatal("connection was not recognized as obfuscated")
+	}
+
+	var msg = bytes.Repeat([]byte("plain text "), 3*maxPayload/10)
+
+	go client.Write(msg)
+
+	var buf = make([]byte, len(msg))
+	if _, err := io.ReadFull(server, buf); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf, msg) {
+		t.Fatal("received data differs from sent data")
+	}
+
+	go server.Write([]byte("reply"))
+
+	buf = make([]byte, 5)
+	if _, err := io.ReadFull(client, buf); err != nil {
+		t.Fatal(err)
+	}
+	if string(buf) != "reply" {
+		t.Fatalf("received '%s'", buf)
+	}
+
+	var wire = clientPipe.tap.Bytes()
+	if bytes.Contains(wire, []byte("plain text")) {
+		t.Fatal("plain text visible on the wire")
+	}
+	if isPlain(wire) {
+		t.Fatal("obfuscated connection looks like a plain one")
+	}
+}
+
+func TestPlain(t *testing.T) {
+	var ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var clientPipe, serverPipe = newPipe()
+	var res = accept(ctx, serverPipe, testKey(t))
+
+	// the start of a link handshake
+	var msg = append([]byte{0, 2}, bytes.Repeat([]byte{1}, 48)...)
+	go clientPipe.Write(msg)
+
+	conn, ok := (<-res).(net.Conn)
+	if !ok {
+		t.Fatal("plain connection was not accepted")
+	}
+	if _, ok := conn.(*Conn); ok {
+		t.Fatal("plain connection was treated as obfuscated")
+	}
+
+	var buf = make([]byte, len(msg))
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf, msg) {
+		t.Fatal("plain connection was altered")
+	}
+}
+
+func TestObfuscatedOnly(t *testing.T) {
+	var ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	maxStall = 10 * time.Millisecond
+	defer func() { maxStall = 10 * time.Second }()
+
+	var key = testKey(t)
+
+	// plain connections get no response
+	var clientPipe, serverPipe = newPipe()
+	var res = acceptWith(ctx, serverPipe, key, AcceptObfuscated)
+	go clientPipe.Write(append([]byte{0, 2}, bytes.Repeat([]byte{1}, 48)...))
+
+	if err, _ := (<-res).(error); !errors.Is(err, ErrPlainConn) {
+		t.Fatalf("plain connection accepted with %v", err)
+	}
+	if n, _ := clientPipe.Read(make([]byte, 1)); n != 0 {
+		t.Fatal("plain connection got a response")
+	}
+
+	// obfuscated connections are accepted as usual
+	clientPipe, serverPipe = newPipe()
+	res = acceptWith(ctx, serverPipe, key, AcceptObfuscated)
+	if _, err := Open(ctx, clientPipe, key); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := (<-res).(*Conn); !ok {
+		t.Fatal("handshake failed")
+	}
+}
+
+func TestProbes(t *testing.T) {
+	var ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	maxStall, handshakeTimeout = 10*time.Millisecond, 100*time.Millisecond
+	defer func() { maxStall, handshakeTimeout = 10*time.Second, 30*time.Second }()
+
+	var key = testKey(t)
+
+	// a client with a wrong key gets no response
+	var clientPipe, serverPipe = newPipe()
+	var res = accept(ctx, serverPipe, key)
+	go Open(ctx, clientPipe, testKey(t))
+
+	if _, ok := (<-res).(error); !ok {
+		t.Fatal("accepted a handshake with a wrong key")
+	}
+	clientPipe.Close()
+
+	// a recorded handshake can't be replayed
+	clientPipe, serverPipe = newPipe()
+	clientPipe.tap = &bytes.Buffer{}
+	res = accept(ctx, serverPipe, key)
+	if _, err := Open(ctx, clientPipe, key); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := (<-res).(*Conn); !ok {
+		t.Fatal("handshake failed")
+	}
+
+	var recorded = clientPipe.tap.Bytes()
+	clientPipe, serverPipe = newPipe()
+	res = accept(ctx, serverPipe, key)
+	go clientPipe.Write(recorded)
+
+	if _, ok := (<-res).(error); !ok {
+		t.Fatal("accepted a replayed handshake")
+	}
+}
+
+func TestEndpoint(t *testing.T) {
+	var key = testKey(t)
+	var e = NewEndpoint(net.NewGenericEndpoint("tcp", []byte{1, 2, 3}), key)
+
+	if e.Network() != "tcp+obfs" {
+		t.Fatalf("network is %s", e.Network())
+	}
+
+	network, ok := SplitNetwork(e.Network())
+	if !ok || network != "tcp" {
+		t.Fatalf("split network into %s", network)
+	}
+
+	k, address, err := SplitAddress(e.String())
+	if err != nil || k != key || address != "010203" {
+		t.Fatalf("split address into %v %s: %v", k, address, err)
+	}
+
+	k, data, err := SplitPacked(e.Pack())
+	if err != nil || k != key || !bytes.Equal(data, []byte{1, 2, 3}) {
+		t.Fatalf("split packed endpoint into %v %v: %v", k, data, err)
+	}
+}
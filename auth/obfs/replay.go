@@ -0,0 +1,43 @@
+package obfs
+
+import (
+	"sync"
+	"time"
+)
+
+// replayFilter remembers nonces of accepted handshakes, so that a recorded handshake can't be
+// replayed to confirm that a server speaks the protocol
+type replayFilter struct {
+	mu     sync.Mutex
+	ttl    time.Duration
+	seen   map[string]time.Time
+	pruned time.Time
+}
+
+func newReplayFilter(ttl time.Duration) *replayFilter {
+	return &replayFilter{ttl: ttl, seen: map[string]time.Time{}}
+}
+
+// add adds the nonce to the filter. It returns false if the nonce was already added.
+func (f *replayFilter) add(nonce []byte) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var now = time.Now()
+
+	if now.Sub(f.pruned) > f.ttl/3 {
+		for k, t := range f.seen {
+			if now.Sub(t) > f.ttl {
+				delete(f.seen, k)
+			}
+		}
+		f.pruned = now
+	}
+
+	if _, found := f.seen[string(nonce)]; found {
+		return false
+	}
+
+	f.seen[string(nonce)] = now
+	return true
+}
@@ -0,0 +1,162 @@
+package auth
+
+import (
+	"errors"
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/cryptopunkscc/astrald/auth/brontide"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var ErrRekeyNotStarted = errors.New("rekey not started")
+
+// rekeyState holds the progress of replacing the keys of a NoiseConn. Parties exchange ephemeral keys, derive
+// a shared secret and mix it into the keys of both directions. Every direction is switched separately, at a point
+// in the stream agreed on by the parties.
+type rekeyState struct {
+	mu        sync.Mutex
+	ephemeral *btcec.PrivateKey
+	secret    []byte
+	sendDone  bool
+	recvDone  bool
+	keyAt     time.Time
+	count     int
+	bytes     atomic.Uint64
+}
+
+// StartRekey starts a rekey as the initiating party and returns the ephemeral public key that has to be sent
+// to the remote party
+func (conn *NoiseConn) StartRekey() ([]byte, error) {
+	conn.rekey.mu.Lock()
+	defer conn.rekey.mu.Unlock()
+
+	key, err := btcec.NewPrivateKey()
+	if err != nil {
+		return nil, err
+	}
+
+	conn.rekey.ephemeral = key
+	conn.rekey.secret = nil
+
+	return key.PubKey().SerializeCompressed(), nil
+}
+
+// AcceptRekey accepts a rekey started by the remote party and returns the ephemeral public key that has to be
+// sent back
+func (conn *NoiseConn) AcceptRekey(remoteKey []byte) ([]byte, error) {
+	conn.rekey.mu.Lock()
+	defer conn.rekey.mu.Unlock()
+
+	key, err := btcec.NewPrivateKey()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := conn.setSecret(key, remoteKey); err != nil {
+		return nil, err
+	}
+
+	return key.PubKey().SerializeCompressed(), nil
+}
+
+// FinishRekey derives the shared secret from the ephemeral key sent back by the remote party
+func (conn *NoiseConn) FinishRekey(remoteKey []byte) error {
+	conn.rekey.mu.Lock()
+	defer conn.rekey.mu.Unlock()
+
+	if conn.rekey.ephemeral == nil {
+		return ErrRekeyNotStarted
+	}
+
+	return conn.setSecret(conn.rekey.ephemeral, remoteKey)
+}
+
+// SwitchSendKey makes all messages written from now on use the new key
+func (conn *NoiseConn) SwitchSendKey() error {
+	conn.rekey.mu.Lock()
+	defer conn.rekey.mu.Unlock()
+
+	if conn.rekey.secret == nil || conn.rekey.sendDone {
+		return ErrRekeyNotStarted
+	}
+
+	conn.wmu.Lock()
+	conn.brontide.RekeySend(conn.rekey.secret)
+	conn.wmu.Unlock()
+
+	conn.rekey.sendDone = true
+	conn.finishRekey()
+
+	return nil
+}
+
+// SwitchRecvKey makes all messages read from now on use the new key. It has to be called between reads.
+func (conn *NoiseConn) SwitchRecvKey() error {
+	conn.rekey.mu.Lock()
+	defer conn.rekey.mu.Unlock()
+
+	if conn.rekey.secret == nil || conn.rekey.recvDone {
+		return ErrRekeyNotStarted
+	}
+
+	conn.rmu.Lock()
+	err := conn.brontide.RekeyRecv(conn.rekey.secret)
+	conn.rmu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	conn.rekey.recvDone = true
+	conn.finishRekey()
+
+	return nil
+}
+
+// KeyUsage returns the number of bytes encrypted and decrypted since the last rekey and the time of the rekey
+func (conn *NoiseConn) KeyUsage() (bytes uint64, since time.Time) {
+	conn.rekey.mu.Lock()
+	defer conn.rekey.mu.Unlock()
+
+	return conn.rekey.bytes.Load(), conn.rekey.keyAt
+}
+
+// Rekeys returns the number of times the keys of the conn were replaced
+func (conn *NoiseConn) Rekeys() int {
+	conn.rekey.mu.Lock()
+	defer conn.rekey.mu.Unlock()
+
+	return conn.rekey.count
+}
+
+func (conn *NoiseConn) setSecret(key *btcec.PrivateKey, remoteKey []byte) error {
+	remotePub, err := btcec.ParsePubKey(remoteKey)
+	if err != nil {
+		return err
+	}
+
+	var ecdh = &brontide.PrivKeyECDH{PrivKey: key}
+	secret, err := ecdh.ECDH(remotePub)
+	if err != nil {
+		return err
+	}
+
+	conn.rekey.ephemeral = nil
+	conn.rekey.secret = secret[:]
+	conn.rekey.sendDone = false
+	conn.rekey.recvDone = false
+
+	return nil
+}
+
+// finishRekey clears the rekey state once both directions use the new keys
+func (conn *NoiseConn) finishRekey() {
+	if !conn.rekey.sendDone || !conn.rekey.recvDone {
+		return
+	}
+
+	conn.rekey.secret = nil
+	conn.rekey.keyAt = time.Now()
+	conn.rekey.bytes.Store(0)
+	conn.rekey.count++
+}
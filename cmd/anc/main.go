@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"github.com/cryptopunkscc/astrald/auth/id"
 	"github.com/cryptopunkscc/astrald/lib/astral"
+	"github.com/cryptopunkscc/astrald/object"
 	"github.com/cryptopunkscc/astrald/streams"
 	"io"
 	"net"
@@ -308,6 +309,21 @@ func cmdQuery(args []string) {
 func cmdDownload(args []string) {
 	var nodeID, query, filename string
 
+	if len(args) < 1 {
+		log("anc download <node> <service> [filename]")
+		log("anc download <objectID> [filename]")
+		os.Exit(exitHelp)
+	}
+
+	if objectID, err := object.ParseID(args[0]); err == nil {
+		filename = objectID.String()
+		if len(args) >= 2 {
+			filename = args[1]
+		}
+		downloadObject(objectID, filename)
+		return
+	}
+
 	if len(args) < 2 {
 		log("anc download <node> <service> [filename]")
 		os.Exit(exitHelp)
@@ -349,6 +365,76 @@ func cmdDownload(args []string) {
 	os.Exit(exitSuccess)
 }
 
+// downloadObject downloads an object via the local node into filename. Interrupted downloads are resumed
+// from filename.part.
+func downloadObject(objectID object.ID, filename string) {
+	if _, err := os.Stat(filename); err == nil {
+		log("file already exists: %s", filename)
+		os.Exit(exitError)
+	}
+
+	var partName = filename + ".part"
+
+	file, err := os.OpenFile(partName, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		log("error opening file %s: %s", partName, err)
+		os.Exit(exitError)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		log("error: %s", err)
+		os.Exit(exitError)
+	}
+
+	var offset = uint64(info.Size())
+	if offset > objectID.Size {
+		log("%s is larger than the object", partName)
+		os.Exit(exitError)
+	}
+
+	if offset < objectID.Size {
+		if offset > 0 {
+			log("resuming at %d bytes", offset)
+		}
+
+		conn, err := astral.QueryName("localnode", fmt.Sprintf("objects.download?id=%s&offset=%d", objectID, offset))
+		if err != nil {
+			log("error: %s", err)
+			os.Exit(exitError)
+		}
+
+		n, err := io.Copy(file, conn)
+		if err != nil {
+			log("download error: %s", err)
+			os.Exit(exitError)
+		}
+
+		log("read %d bytes", n)
+	}
+
+	file.Close()
+
+	resolvedID, err := object.ResolveFile(partName)
+	if err != nil {
+		log("error: %s", err)
+		os.Exit(exitError)
+	}
+
+	if !resolvedID.IsEqual(objectID) {
+		log("downloaded data does not match the object id, removing %s", partName)
+		os.Remove(partName)
+		os.Exit(exitError)
+	}
+
+	if err := os.Rename(partName, filename); err != nil {
+		log("error: %s", err)
+		os.Exit(exitError)
+	}
+
+	os.Exit(exitSuccess)
+}
+
 func cmdResolve(args []string) {
 	if len(args) < 1 {
 		log("anc resolve <name>")
@@ -357,7 +443,7 @@ func cmdResolve(args []string) {
 
 	identity, err := astral.Resolve(args[0])
 	if err != nil {
-		log("error:", err)
+		log("error: %s", err)
 		os.Exit(exitError)
 	}
 	fmt.Println(identity.String())
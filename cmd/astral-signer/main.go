@@ -0,0 +1,201 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/cryptopunkscc/astrald/auth/id"
+	"github.com/cryptopunkscc/astrald/cslq"
+	"github.com/cryptopunkscc/astrald/lib/adc"
+	"github.com/cryptopunkscc/astrald/lib/astral"
+	"github.com/cryptopunkscc/astrald/mod/keys"
+	"os"
+	"strings"
+	"sync"
+)
+
+const (
+	exitSuccess = iota
+	exitHelp
+	exitError
+)
+
+var stdin = bufio.NewReader(os.Stdin)
+
+func main() {
+	if len(os.Args) < 3 {
+		fmt.Fprintf(os.Stderr, "usage: astral-signer <command> <keyfile>\n\n")
+		fmt.Fprintf(os.Stderr, "commands:\n")
+		fmt.Fprintf(os.Stderr, "  new    generate a new key and save it encrypted\n")
+		fmt.Fprintf(os.Stderr, "  run    serve signing requests with the key\n")
+		os.Exit(exitHelp)
+	}
+
+	var err error
+
+	switch cmd, keyFile := os.Args[1], os.Args[2]; cmd {
+	case "new":
+		err = cmdNew(keyFile)
+	case "run":
+		err = cmdRun(keyFile)
+	default:
+		err = errors.New("unknown command")
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		os.Exit(exitError)
+	}
+
+	os.Exit(exitSuccess)
+}
+
+// cmdNew generates a new key and saves it encrypted with a passphrase
+func cmdNew(keyFile string) error {
+	if _, err := os.Stat(keyFile); err == nil {
+		return errors.New("key file already exists")
+	}
+
+	passphrase, err := readLine("passphrase: ")
+	if err != nil {
+		return err
+	}
+	repeated, err := readLine("repeat passphrase: ")
+	if err != nil {
+		return err
+	}
+	if passphrase != repeated {
+		return errors.New("passphrases do not match")
+	}
+
+	identity, err := id.GenerateIdentity()
+	if err != nil {
+		return err
+	}
+
+	epk, err := keys.EncryptPrivateKey(&keys.PrivateKey{
+		Type:  keys.KeyTypeIdentity,
+		Bytes: identity.PrivateKeyBytes(),
+	}, []byte(passphrase))
+	if err != nil {
+		return err
+	}
+
+	var buf = &bytes.Buffer{}
+	err = cslq.Encode(buf, "vv", adc.Header(keys.EncryptedPrivateKeyDataType), epk)
+	if err != nil {
+		return err
+	}
+
+	err = os.WriteFile(keyFile, buf.Bytes(), 0600)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("created key %s\n", identity.PublicKeyHex())
+
+	return nil
+}
+
+// cmdRun registers the signing service and asks the user to approve every request
+func cmdRun(keyFile string) error {
+	data, err := os.ReadFile(keyFile)
+	if err != nil {
+		return err
+	}
+
+	pk, err := keys.ReadPrivateKey(bytes.NewReader(data), func() ([]byte, error) {
+		line, err := readLine("passphrase: ")
+		return []byte(line), err
+	})
+	if err != nil {
+		return err
+	}
+
+	identity, err := pk.Identity()
+	if err != nil {
+		return err
+	}
+
+	service, err := astral.Register(keys.SignServiceName)
+	if err != nil {
+		return fmt.Errorf("register error: %w", err)
+	}
+	defer service.Close()
+
+	fmt.Fprintf(os.Stderr, "signing as %s\n", identity.PublicKeyHex())
+
+	// ask about one request at a time
+	var mu sync.Mutex
+
+	for query := range service.QueryCh() {
+		go func(query *astral.QueryData) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			if err := serve(query, identity); err != nil {
+				fmt.Fprintf(os.Stderr, "error: %s\n", err)
+			}
+		}(query)
+	}
+
+	return nil
+}
+
+func serve(query *astral.QueryData, identity id.Identity) error {
+	conn, err := query.Accept()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var req keys.SignRequest
+	if err = cslq.Decode(conn, "v", &req); err != nil {
+		return err
+	}
+
+	if !req.Identity.IsEqual(identity) {
+		return cslq.Encode(conn, "c", keys.SignKeyNotFound)
+	}
+
+	fmt.Fprintf(os.Stderr, "\n%s asks to sign:\n  %s\n  hash %x\n",
+		displayName(query.RemoteIdentity()),
+		req.Description,
+		req.Hash,
+	)
+
+	answer, err := readLine("approve? [y/N] ")
+	if err != nil || strings.ToLower(strings.TrimSpace(answer)) != "y" {
+		fmt.Fprintf(os.Stderr, "rejected\n")
+		return cslq.Encode(conn, "c", keys.SignRejected)
+	}
+
+	sig, err := identity.Sign(req.Hash)
+	if err != nil {
+		cslq.Encode(conn, "c", keys.SignRejected)
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "signed\n")
+
+	return cslq.Encode(conn, "c[c]c", keys.SignSigned, sig)
+}
+
+func readLine(prompt string) (string, error) {
+	fmt.Fprint(os.Stderr, prompt)
+
+	line, err := stdin.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func displayName(identity id.Identity) string {
+	if info, err := astral.GetNodeInfo(identity); err == nil {
+		return info.Name
+	}
+	return identity.Fingerprint()
+}
@@ -26,7 +26,7 @@ func main() {
 	ctx, shutdown := context.WithCancel(context.Background())
 
 	// trap ctrl+c
-	sigCh := make(chan os.Signal)
+	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, syscall.SIGINT)
 	go func() {
 		<-sigCh
@@ -3,30 +3,41 @@ package main
 // this file includes all modules that should be compiled into the node
 
 import (
+	_ "github.com/cryptopunkscc/astrald/mod/acl/src"
 	_ "github.com/cryptopunkscc/astrald/mod/admin/src"
 	_ "github.com/cryptopunkscc/astrald/mod/agent/src"
 	_ "github.com/cryptopunkscc/astrald/mod/apphost/src"
 	_ "github.com/cryptopunkscc/astrald/mod/archives/src"
+	_ "github.com/cryptopunkscc/astrald/mod/audit/src"
 	_ "github.com/cryptopunkscc/astrald/mod/content/src"
 	_ "github.com/cryptopunkscc/astrald/mod/dir/src"
 	_ "github.com/cryptopunkscc/astrald/mod/discovery/src"
 	_ "github.com/cryptopunkscc/astrald/mod/fs/src"
+	_ "github.com/cryptopunkscc/astrald/mod/fulltext/src"
 	_ "github.com/cryptopunkscc/astrald/mod/fwd/src"
 	_ "github.com/cryptopunkscc/astrald/mod/gateway/src"
 	_ "github.com/cryptopunkscc/astrald/mod/keys/src"
 	_ "github.com/cryptopunkscc/astrald/mod/media/src"
+	_ "github.com/cryptopunkscc/astrald/mod/mesh/src"
+	_ "github.com/cryptopunkscc/astrald/mod/metrics/src"
 	_ "github.com/cryptopunkscc/astrald/mod/nodes/src"
 	_ "github.com/cryptopunkscc/astrald/mod/objects/src"
 	_ "github.com/cryptopunkscc/astrald/mod/policy/src"
 	_ "github.com/cryptopunkscc/astrald/mod/presence/src"
 	_ "github.com/cryptopunkscc/astrald/mod/profile/src"
+	_ "github.com/cryptopunkscc/astrald/mod/punch/src"
+	_ "github.com/cryptopunkscc/astrald/mod/quic/src"
 	_ "github.com/cryptopunkscc/astrald/mod/reflectlink/src"
 	_ "github.com/cryptopunkscc/astrald/mod/relay/src"
+	_ "github.com/cryptopunkscc/astrald/mod/revocations/src"
 	_ "github.com/cryptopunkscc/astrald/mod/sets/src"
 	_ "github.com/cryptopunkscc/astrald/mod/setup/src"
 	_ "github.com/cryptopunkscc/astrald/mod/shares/src"
 	_ "github.com/cryptopunkscc/astrald/mod/speedtest/src"
 	_ "github.com/cryptopunkscc/astrald/mod/tcp/src"
+	_ "github.com/cryptopunkscc/astrald/mod/tokens/src"
 	_ "github.com/cryptopunkscc/astrald/mod/tor/src"
+	_ "github.com/cryptopunkscc/astrald/mod/traffic/src"
 	_ "github.com/cryptopunkscc/astrald/mod/user/src"
+	_ "github.com/cryptopunkscc/astrald/mod/ws/src"
 )
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"fmt"
@@ -11,11 +12,17 @@ import (
 	"github.com/cryptopunkscc/astrald/mod/keys"
 	"github.com/cryptopunkscc/astrald/node"
 	"github.com/cryptopunkscc/astrald/resources"
+	"golang.org/x/term"
 	"os"
+	"strings"
 	"time"
 )
 
-const resNodeIdentity = "node_identity"
+// envPassphrase is the environment variable holding the passphrase of an encrypted node identity
+const envPassphrase = "ASTRALD_PASSPHRASE"
+
+// envMnemonic is the environment variable holding the mnemonic of the master seed to recover a new node from
+const envMnemonic = "ASTRALD_MNEMONIC"
 
 func run(ctx context.Context, args *Args) error {
 	nodeRes, err := setupResources(args)
@@ -65,35 +72,38 @@ func setupResources(args *Args) (resources.Resources, error) {
 	return nodeRes, err
 }
 
-// setupNodeIdentity reads node's identity from resources or generates one if needed
+// setupNodeIdentity reads node's identity from resources or derives one from a new master seed if needed
 func setupNodeIdentity(resources resources.Resources) (id.Identity, error) {
-	keyBytes, err := resources.Read(resNodeIdentity)
+	keyBytes, err := resources.Read(keys.NodeIdentityResource)
 	if err == nil {
 		if len(keyBytes) == 32 {
 			return id.ParsePrivateKey(keyBytes)
 		}
 
-		var r = bytes.NewReader(keyBytes)
-		err = adc.ExpectHeader(r, keys.PrivateKeyDataType)
+		pk, err := keys.ReadPrivateKey(bytes.NewReader(keyBytes), readPassphrase)
 		if err != nil {
 			return id.Identity{}, err
 		}
-		var pk keys.PrivateKey
-		err = cslq.Decode(r, "v", &pk)
-		if err != nil {
-			return id.Identity{}, err
-		}
-		return id.ParsePrivateKey(pk.Bytes)
+		return pk.Identity()
 	}
 
-	nodeID, err := id.GenerateIdentity()
+	seed, err := newSeed()
 	if err != nil {
 		return id.Identity{}, err
 	}
 
-	var buf = &bytes.Buffer{}
+	nodeID, err := keys.Derive(seed, keys.KeyTypeIdentity, keys.NodeKeyPath)
+	if err != nil {
+		return id.Identity{}, err
+	}
+
+	// the keys module imports the seed on start
+	err = writePrivateKey(resources, keys.NodeSeedResource, seed)
+	if err != nil {
+		return id.Identity{}, err
+	}
 
-	err = cslq.Encode(buf, "vv", adc.Header(keys.PrivateKeyDataType), keys.PrivateKey{
+	err = writePrivateKey(resources, keys.NodeIdentityResource, &keys.PrivateKey{
 		Type:  keys.KeyTypeIdentity,
 		Bytes: nodeID.PrivateKey().Serialize(),
 	})
@@ -101,10 +111,49 @@ func setupNodeIdentity(resources resources.Resources) (id.Identity, error) {
 		return id.Identity{}, err
 	}
 
-	err = resources.Write(resNodeIdentity, buf.Bytes())
+	return nodeID, nil
+}
+
+// newSeed returns the master seed of a new node. The seed is recovered from the mnemonic in the
+// environment if there is one.
+func newSeed() (*keys.PrivateKey, error) {
+	if mnemonic, found := os.LookupEnv(envMnemonic); found {
+		return keys.SeedFromMnemonic(mnemonic)
+	}
+
+	return keys.NewSeed()
+}
+
+func writePrivateKey(resources resources.Resources, name string, pk *keys.PrivateKey) error {
+	var buf = &bytes.Buffer{}
+
+	err := cslq.Encode(buf, "vv", adc.Header(keys.PrivateKeyDataType), pk)
 	if err != nil {
-		return id.Identity{}, err
+		return err
 	}
 
-	return nodeID, nil
+	return resources.Write(name, buf.Bytes())
+}
+
+// readPassphrase returns the passphrase of the node's identity from the environment or asks for it
+func readPassphrase() ([]byte, error) {
+	if passphrase, found := os.LookupEnv(envPassphrase); found {
+		return []byte(passphrase), nil
+	}
+
+	fmt.Fprint(os.Stderr, "node identity passphrase: ")
+
+	// don't echo the passphrase when typed on a terminal
+	if fd := int(os.Stdin.Fd()); term.IsTerminal(fd) {
+		passphrase, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		return passphrase, err
+	}
+
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(strings.TrimRight(line, "\r\n")), nil
 }
@@ -0,0 +1,47 @@
+package acl
+
+import "github.com/cryptopunkscc/astrald/auth/id"
+
+const ModuleName = "acl"
+
+type Module interface {
+	// Explain evaluates the policy for the action and reports how every rule matched
+	Explain(identity id.Identity, action string, args ...any) *Explanation
+	// Reload reloads the policy file
+	Reload() error
+}
+
+// Verdict is the outcome of policy evaluation
+type Verdict int
+
+const (
+	Abstain Verdict = iota // no rule matched, other authorizers decide
+	Allow
+	Deny
+)
+
+func (v Verdict) String() string {
+	switch v {
+	case Allow:
+		return "allow"
+	case Deny:
+		return "deny"
+	}
+	return "abstain"
+}
+
+type Explanation struct {
+	Identity id.Identity
+	Action   string
+	Args     []any
+	Verdict  Verdict
+	Steps    []Step
+}
+
+// Step describes the evaluation of a single rule
+type Step struct {
+	Rule    int     // index of the rule in the policy file
+	Verdict Verdict // verdict of the rule
+	Matched bool
+	Reason  string // why the rule did or did not match
+}
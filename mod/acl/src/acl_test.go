@@ -0,0 +1,141 @@
+package acl
+
+import (
+	"errors"
+	"github.com/cryptopunkscc/astrald/auth/id"
+	"github.com/cryptopunkscc/astrald/mod/acl"
+	"github.com/cryptopunkscc/astrald/object"
+	"gopkg.in/yaml.v2"
+	"testing"
+)
+
+type testEnv struct {
+	aliases map[string]id.Identity
+	owners  map[string]id.Identity
+	sets    map[string][]object.ID
+}
+
+func (env *testEnv) resolve(name string) (id.Identity, error) {
+	if identity, ok := env.aliases[name]; ok {
+		return identity, nil
+	}
+	return id.Identity{}, errors.New("unknown identity")
+}
+
+func (env *testEnv) owner(nodeID id.Identity) id.Identity {
+	return env.owners[nodeID.PublicKeyHex()]
+}
+
+func (env *testEnv) inSet(objectID object.ID, set string) bool {
+	for _, member := range env.sets[set] {
+		if member == objectID {
+			return true
+		}
+	}
+	return false
+}
+
+func mustIdentity(t *testing.T) id.Identity {
+	identity, err := id.GenerateIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return identity
+}
+
+const testPolicy = `
+groups:
+  friends: [alice, nodes:bob]
+rules:
+  - allow: [objects.read]
+    to: [group:friends]
+    sets: [shared]
+  - allow: [objects.*]
+    to: [carol]
+  - deny: [objects.purge]
+    to: ["*"]
+  - allow: [mod.admin.access]
+    to: [mallory]
+`
+
+func TestPolicy(t *testing.T) {
+	var alice, bob, bobNode, carol = mustIdentity(t), mustIdentity(t), mustIdentity(t), mustIdentity(t)
+	var sharedID, privateID = object.ID{Size: 1, Hash: [32]byte{1}}, object.ID{Size: 1, Hash: [32]byte{2}}
+
+	var env = &testEnv{
+		aliases: map[string]id.Identity{"alice": alice, "bob": bob, "carol": carol},
+		owners:  map[string]id.Identity{bobNode.PublicKeyHex(): bob},
+		sets:    map[string][]object.ID{"shared": {sharedID}},
+	}
+
+	var cfg Config
+	if err := yaml.Unmarshal([]byte(testPolicy), &cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := compile(&cfg, env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !p.unresolved {
+		t.Fatal("expected mallory to be unresolved")
+	}
+
+	var tests = []struct {
+		identity id.Identity
+		action   string
+		args     []any
+		verdict  acl.Verdict
+	}{
+		{alice, "objects.read", []any{sharedID}, acl.Allow},
+		{alice, "objects.read", []any{privateID}, acl.Abstain},
+		{alice, "objects.read", nil, acl.Abstain},
+		{bobNode, "objects.read", []any{sharedID}, acl.Allow},
+		{bob, "objects.read", []any{sharedID}, acl.Abstain},
+		{carol, "objects.write", nil, acl.Allow},
+		{carol, "objects.purge", nil, acl.Deny},
+		{carol, "objectsx", nil, acl.Abstain},
+		{alice, "mod.admin.access", nil, acl.Abstain},
+	}
+
+	for _, test := range tests {
+		verdict, _ := p.evaluate(env, test.identity, test.action, test.args, false)
+		if verdict != test.verdict {
+			t.Errorf("%s %v: expected %s, got %s", test.action, test.args, test.verdict, verdict)
+		}
+	}
+
+	verdict, steps := p.evaluate(env, carol, "objects.purge", nil, true)
+	if verdict != acl.Deny {
+		t.Fatalf("expected deny, got %s", verdict)
+	}
+	if len(steps) != len(cfg.Rules) {
+		t.Fatalf("expected %d steps, got %d", len(cfg.Rules), len(steps))
+	}
+	if !steps[1].Matched || !steps[2].Matched || steps[0].Matched {
+		t.Fatalf("unexpected steps: %+v", steps)
+	}
+}
+
+func TestInvalidPolicy(t *testing.T) {
+	var env = &testEnv{}
+
+	var policies = []string{
+		"rules: [{allow: [a], deny: [b], to: ['*']}]",
+		"rules: [{to: ['*']}]",
+		"rules: [{allow: [a]}]",
+		"rules: [{allow: [a], to: [group:missing]}]",
+		"rules: [{allow: [a], to: ['*'], objects: [invalid]}]",
+		"groups: {a: [group:b]}",
+	}
+
+	for _, s := range policies {
+		var cfg Config
+		if err := yaml.Unmarshal([]byte(s), &cfg); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := compile(&cfg, env); err == nil {
+			t.Errorf("expected error for %s", s)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package acl
+
+import (
+	"errors"
+	"fmt"
+	"github.com/cryptopunkscc/astrald/mod/acl"
+	"github.com/cryptopunkscc/astrald/mod/admin"
+	"github.com/cryptopunkscc/astrald/object"
+	"strings"
+)
+
+type Admin struct {
+	mod  *Module
+	cmds map[string]func(admin.Terminal, []string) error
+}
+
+func NewAdmin(mod *Module) *Admin {
+	var adm = &Admin{mod: mod}
+	adm.cmds = map[string]func(admin.Terminal, []string) error{
+		"rules":   adm.rules,
+		"explain": adm.explain,
+		"reload":  adm.reload,
+		"help":    adm.help,
+	}
+
+	return adm
+}
+
+func (adm *Admin) Exec(term admin.Terminal, args []string) error {
+	if len(args) < 2 {
+		return adm.help(term, []string{})
+	}
+
+	cmd, args := args[1], args[2:]
+	if fn, found := adm.cmds[cmd]; found {
+		return fn(term, args)
+	}
+
+	return errors.New("unknown command")
+}
+
+func (adm *Admin) rules(term admin.Terminal, _ []string) error {
+	adm.mod.mu.RLock()
+	var cfg = adm.mod.config
+	adm.mod.mu.RUnlock()
+
+	var f = "%-4s %-8s %-30s %-30s %s\n"
+
+	term.Printf(f, admin.Header("#"), admin.Header("Verdict"), admin.Header("Actions"), admin.Header("Subjects"), admin.Header("Constraints"))
+	for i, r := range cfg.Rules {
+		var verdict, actions = acl.Allow, r.Allow
+		if len(r.Deny) > 0 {
+			verdict, actions = acl.Deny, r.Deny
+		}
+
+		var constraints []string
+		constraints = append(constraints, r.Objects...)
+		for _, set := range r.Sets {
+			constraints = append(constraints, "set:"+set)
+		}
+
+		term.Printf(f,
+			fmt.Sprintf("%d", i),
+			verdict,
+			strings.Join(actions, ","),
+			strings.Join(r.To, ","),
+			strings.Join(constraints, ","),
+		)
+	}
+
+	return nil
+}
+
+func (adm *Admin) explain(term admin.Terminal, args []string) error {
+	if len(args) < 2 {
+		return errors.New("usage: acl explain <identity> <action> [objectID]")
+	}
+
+	identity, err := adm.mod.node.Resolver().Resolve(args[0])
+	if err != nil {
+		return err
+	}
+
+	var action = args[1]
+	var actionArgs []any
+	if len(args) > 2 {
+		objectID, err := object.ParseID(args[2])
+		if err != nil {
+			return err
+		}
+		actionArgs = append(actionArgs, objectID)
+	}
+
+	var e = adm.mod.Explain(identity, action, actionArgs...)
+
+	var f = "%-4s %-8s %-7s %s\n"
+	term.Printf(f, admin.Header("#"), admin.Header("Verdict"), admin.Header("Matched"), admin.Header("Reason"))
+	for _, step := range e.Steps {
+		term.Printf(f,
+			fmt.Sprintf("%d", step.Rule),
+			step.Verdict,
+			fmt.Sprintf("%v", step.Matched),
+			step.Reason,
+		)
+	}
+
+	var decision = "denied"
+	if adm.mod.node.Auth().Authorize(identity, action, actionArgs...) {
+		decision = "allowed"
+	}
+
+	term.Printf("\npolicy verdict: %s\n", e.Verdict)
+	term.Printf("node decision:  %s\n", decision)
+
+	if e.Verdict == acl.Abstain {
+		term.Printf("no rule matched, the decision was made by other authorizers\n")
+	}
+
+	return nil
+}
+
+func (adm *Admin) reload(term admin.Terminal, _ []string) error {
+	if err := adm.mod.Reload(); err != nil {
+		return err
+	}
+
+	adm.mod.mu.RLock()
+	term.Printf("%d rules loaded\n", len(adm.mod.config.Rules))
+	adm.mod.mu.RUnlock()
+
+	return nil
+}
+
+func (adm *Admin) ShortDescription() string {
+	return "authorization policy"
+}
+
+func (adm *Admin) help(term admin.Terminal, _ []string) error {
+	term.Printf("usage: %s <command>\n\n", acl.ModuleName)
+	term.Printf("commands:\n")
+	term.Printf("  rules                                  list policy rules\n")
+	term.Printf("  explain <identity> <action> [objectID] explain the decision for an action\n")
+	term.Printf("  reload                                 reload the policy file\n")
+	term.Printf("  help                                   show help\n")
+	return nil
+}
@@ -0,0 +1,25 @@
+package acl
+
+import "time"
+
+// Config is the policy file
+type Config struct {
+	// Groups maps group names to lists of subjects
+	Groups map[string][]string `yaml:"groups"`
+
+	// Rules are evaluated in order. A matching deny rule always wins over allow rules.
+	Rules []RuleConfig `yaml:"rules"`
+}
+
+type RuleConfig struct {
+	Allow []string `yaml:"allow"` // actions granted by the rule
+	Deny  []string `yaml:"deny"`  // actions denied by the rule
+	To    []string `yaml:"to"`    // subjects of the rule
+
+	// Objects and Sets limit the rule to the listed objects or members of the listed sets
+	Objects []string `yaml:"objects"`
+	Sets    []string `yaml:"sets"`
+}
+
+// reloadInterval sets how often the policy file is checked for changes
+const reloadInterval = 5 * time.Second
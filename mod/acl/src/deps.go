@@ -0,0 +1,22 @@
+package acl
+
+import (
+	"github.com/cryptopunkscc/astrald/mod/acl"
+	"github.com/cryptopunkscc/astrald/mod/admin"
+	"github.com/cryptopunkscc/astrald/mod/sets"
+	"github.com/cryptopunkscc/astrald/mod/user"
+	"github.com/cryptopunkscc/astrald/node/modules"
+)
+
+func (mod *Module) LoadDependencies() error {
+	// load optional dependencies
+	mod.user, _ = modules.Load[user.Module](mod.node, user.ModuleName)
+	mod.sets, _ = modules.Load[sets.Module](mod.node, sets.ModuleName)
+
+	// inject admin command
+	if adm, err := modules.Load[admin.Module](mod.node, admin.ModuleName); err == nil {
+		adm.AddCommand(acl.ModuleName, NewAdmin(mod))
+	}
+
+	return nil
+}
@@ -0,0 +1,35 @@
+package acl
+
+import (
+	"github.com/cryptopunkscc/astrald/log"
+	"github.com/cryptopunkscc/astrald/mod/acl"
+	"github.com/cryptopunkscc/astrald/node/assets"
+	"github.com/cryptopunkscc/astrald/node/modules"
+)
+
+type Loader struct{}
+
+func (Loader) Load(node modules.Node, assets assets.Assets, log *log.Logger) (modules.Module, error) {
+	var mod = &Module{
+		node:   node,
+		log:    log,
+		assets: assets,
+	}
+
+	if err := mod.reload(true); err != nil {
+		mod.log.Error("error loading policy: %v", err)
+	}
+
+	err := node.Auth().Add(mod)
+	if err != nil {
+		return nil, err
+	}
+
+	return mod, nil
+}
+
+func init() {
+	if err := modules.RegisterModule(acl.ModuleName, Loader{}); err != nil {
+		panic(err)
+	}
+}
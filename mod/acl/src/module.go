@@ -0,0 +1,165 @@
+package acl
+
+import (
+	"bytes"
+	"context"
+	"github.com/cryptopunkscc/astrald/auth/id"
+	"github.com/cryptopunkscc/astrald/log"
+	"github.com/cryptopunkscc/astrald/mod/acl"
+	"github.com/cryptopunkscc/astrald/mod/sets"
+	"github.com/cryptopunkscc/astrald/mod/user"
+	"github.com/cryptopunkscc/astrald/node"
+	"github.com/cryptopunkscc/astrald/node/assets"
+	"github.com/cryptopunkscc/astrald/node/authorizer"
+	"github.com/cryptopunkscc/astrald/object"
+	"gopkg.in/yaml.v2"
+	"slices"
+	"sync"
+	"time"
+)
+
+var _ acl.Module = &Module{}
+var _ authorizer.Authorizer = &Module{}
+var _ authorizer.Denier = &Module{}
+
+const policyFile = acl.ModuleName + ".yaml"
+
+type Module struct {
+	node   node.Node
+	log    *log.Logger
+	assets assets.Assets
+	user   user.Module
+	sets   sets.Module
+
+	mu     sync.RWMutex
+	config Config
+	policy *policy
+	source []byte
+}
+
+func (mod *Module) Run(ctx context.Context) error {
+	var ticker = time.NewTicker(reloadInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := mod.reload(false); err != nil {
+				mod.log.Error("error reloading policy: %v", err)
+			}
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+func (mod *Module) Authorize(identity id.Identity, action string, args ...any) bool {
+	verdict, _ := mod.evaluate(identity, action, args, false)
+	return verdict == acl.Allow
+}
+
+func (mod *Module) Deny(identity id.Identity, action string, args ...any) bool {
+	verdict, _ := mod.evaluate(identity, action, args, false)
+	return verdict == acl.Deny
+}
+
+func (mod *Module) Explain(identity id.Identity, action string, args ...any) *acl.Explanation {
+	verdict, steps := mod.evaluate(identity, action, args, true)
+
+	return &acl.Explanation{
+		Identity: identity,
+		Action:   action,
+		Args:     args,
+		Verdict:  verdict,
+		Steps:    steps,
+	}
+}
+
+func (mod *Module) Reload() error {
+	return mod.reload(true)
+}
+
+func (mod *Module) evaluate(identity id.Identity, action string, args []any, explain bool) (acl.Verdict, []acl.Step) {
+	mod.mu.RLock()
+	var p = mod.policy
+	mod.mu.RUnlock()
+
+	if p == nil {
+		return acl.Abstain, nil
+	}
+
+	return p.evaluate(mod, identity, action, args, explain)
+}
+
+// reload reads the policy file and compiles it if it changed. Policies with unresolved
+// subjects are recompiled every time, so that subjects can be resolved once they become known.
+func (mod *Module) reload(force bool) error {
+	source, err := mod.assets.Read(policyFile)
+	if err != nil {
+		source = nil
+	}
+
+	mod.mu.RLock()
+	var changed = !bytes.Equal(source, mod.source)
+	var unresolved = mod.policy != nil && mod.policy.unresolved
+	mod.mu.RUnlock()
+
+	if !force && !changed && !unresolved {
+		return nil
+	}
+
+	var cfg Config
+	if err := yaml.Unmarshal(source, &cfg); err != nil {
+		mod.setSource(source)
+		return err
+	}
+
+	p, err := compile(&cfg, mod)
+	if err != nil {
+		mod.setSource(source)
+		return err
+	}
+
+	mod.mu.Lock()
+	mod.config, mod.policy, mod.source = cfg, p, source
+	mod.mu.Unlock()
+
+	if len(source) > 0 && (changed || force) {
+		mod.log.Info("loaded policy with %v rules", len(p.rules))
+	}
+
+	return nil
+}
+
+// setSource remembers a source that failed to compile, so that it's not reported again
+func (mod *Module) setSource(source []byte) {
+	mod.mu.Lock()
+	mod.source = source
+	mod.mu.Unlock()
+}
+
+func (mod *Module) resolve(name string) (id.Identity, error) {
+	return mod.node.Resolver().Resolve(name)
+}
+
+func (mod *Module) owner(nodeID id.Identity) id.Identity {
+	if mod.user == nil {
+		return id.Identity{}
+	}
+	return mod.user.Owner(nodeID)
+}
+
+func (mod *Module) inSet(objectID object.ID, set string) bool {
+	if mod.sets == nil {
+		return false
+	}
+	names, err := mod.sets.Where(objectID)
+	if err != nil {
+		return false
+	}
+	return slices.Contains(names, set)
+}
+
+func (mod *Module) String() string {
+	return "mod." + acl.ModuleName
+}
@@ -0,0 +1,234 @@
+package acl
+
+import (
+	"errors"
+	"fmt"
+	"github.com/cryptopunkscc/astrald/auth/id"
+	"github.com/cryptopunkscc/astrald/mod/acl"
+	"github.com/cryptopunkscc/astrald/object"
+	"slices"
+	"strings"
+)
+
+const (
+	anySubject    = "*"
+	groupPrefix   = "group:"
+	nodesPrefix   = "nodes:"
+	actionPattern = ".*"
+)
+
+// env provides the policy with information about identities and objects
+type env interface {
+	resolve(name string) (id.Identity, error)
+	owner(nodeID id.Identity) id.Identity
+	inSet(objectID object.ID, set string) bool
+}
+
+type policy struct {
+	rules []*rule
+
+	// unresolved is set if any subject could not be resolved when the policy was compiled
+	unresolved bool
+}
+
+type rule struct {
+	index    int
+	verdict  acl.Verdict
+	actions  []string
+	subjects []*subject
+	objects  []object.ID
+	sets     []string
+}
+
+type subject struct {
+	name     string
+	identity id.Identity // the identity or, for node sets, the user
+	nodes    bool        // match nodes owned by the identity
+	any      bool
+	members  []*subject // members of a group
+	resolved bool
+}
+
+// compile validates the config and resolves all subjects
+func compile(cfg *Config, env env) (*policy, error) {
+	var p = &policy{}
+
+	var groups = map[string][]*subject{}
+	for name, members := range cfg.Groups {
+		for _, m := range members {
+			if strings.HasPrefix(m, groupPrefix) {
+				return nil, fmt.Errorf("group %s: groups cannot contain groups", name)
+			}
+			s := p.subject(m, env)
+			groups[name] = append(groups[name], s)
+		}
+	}
+
+	for i, rc := range cfg.Rules {
+		var r = &rule{index: i, sets: rc.Sets}
+
+		switch {
+		case len(rc.Allow) > 0 && len(rc.Deny) > 0:
+			return nil, fmt.Errorf("rule %d: allow and deny cannot be mixed", i)
+		case len(rc.Allow) > 0:
+			r.verdict, r.actions = acl.Allow, rc.Allow
+		case len(rc.Deny) > 0:
+			r.verdict, r.actions = acl.Deny, rc.Deny
+		default:
+			return nil, fmt.Errorf("rule %d: no actions", i)
+		}
+
+		if len(rc.To) == 0 {
+			return nil, fmt.Errorf("rule %d: no subjects", i)
+		}
+
+		for _, name := range rc.To {
+			if g, found := strings.CutPrefix(name, groupPrefix); found {
+				members, ok := groups[g]
+				if !ok {
+					return nil, fmt.Errorf("rule %d: unknown group %s", i, g)
+				}
+				r.subjects = append(r.subjects, &subject{name: name, members: members, resolved: true})
+				continue
+			}
+			r.subjects = append(r.subjects, p.subject(name, env))
+		}
+
+		for _, s := range rc.Objects {
+			objectID, err := object.ParseID(s)
+			if err != nil {
+				return nil, fmt.Errorf("rule %d: invalid object %s: %w", i, s, err)
+			}
+			r.objects = append(r.objects, objectID)
+		}
+
+		p.rules = append(p.rules, r)
+	}
+
+	return p, nil
+}
+
+func (p *policy) subject(name string, env env) *subject {
+	var s = &subject{name: name}
+
+	var target = name
+	switch {
+	case name == anySubject:
+		s.any, s.resolved = true, true
+		return s
+	case strings.HasPrefix(name, nodesPrefix):
+		s.nodes = true
+		target = strings.TrimPrefix(name, nodesPrefix)
+	}
+
+	identity, err := env.resolve(target)
+	if err != nil || identity.IsZero() {
+		p.unresolved = true
+		return s
+	}
+
+	s.identity, s.resolved = identity, true
+	return s
+}
+
+// evaluate runs all rules against the action. If explain is false, evaluation stops
+// at the first matching deny rule.
+func (p *policy) evaluate(env env, identity id.Identity, action string, args []any, explain bool) (acl.Verdict, []acl.Step) {
+	var verdict = acl.Abstain
+	var steps []acl.Step
+
+	for _, r := range p.rules {
+		err := r.match(env, identity, action, args)
+		if explain {
+			var step = acl.Step{Rule: r.index, Verdict: r.verdict, Matched: err == nil}
+			if err != nil {
+				step.Reason = err.Error()
+			} else {
+				step.Reason = "matched"
+			}
+			steps = append(steps, step)
+		}
+		if err != nil {
+			continue
+		}
+
+		switch r.verdict {
+		case acl.Deny:
+			verdict = acl.Deny
+			if !explain {
+				return verdict, nil
+			}
+		case acl.Allow:
+			if verdict == acl.Abstain {
+				verdict = acl.Allow
+			}
+		}
+	}
+
+	return verdict, steps
+}
+
+func (r *rule) match(env env, identity id.Identity, action string, args []any) error {
+	if !slices.ContainsFunc(r.actions, func(pattern string) bool {
+		return matchAction(pattern, action)
+	}) {
+		return errors.New("action not listed")
+	}
+
+	if !slices.ContainsFunc(r.subjects, func(s *subject) bool {
+		return s.match(env, identity)
+	}) {
+		return errors.New("identity not listed")
+	}
+
+	if len(r.objects) == 0 && len(r.sets) == 0 {
+		return nil
+	}
+
+	var objectID object.ID
+	if len(args) > 0 {
+		objectID, _ = args[0].(object.ID)
+	}
+	if objectID.IsZero() {
+		return errors.New("no object to check against constraints")
+	}
+
+	if slices.Contains(r.objects, objectID) {
+		return nil
+	}
+	for _, set := range r.sets {
+		if env.inSet(objectID, set) {
+			return nil
+		}
+	}
+
+	return errors.New("object not listed")
+}
+
+func (s *subject) match(env env, identity id.Identity) bool {
+	switch {
+	case s.any:
+		return true
+	case s.members != nil:
+		return slices.ContainsFunc(s.members, func(m *subject) bool {
+			return m.match(env, identity)
+		})
+	case !s.resolved || identity.IsZero():
+		return false
+	case s.nodes:
+		return env.owner(identity).IsEqual(s.identity)
+	}
+	return identity.IsEqual(s.identity)
+}
+
+// matchAction matches an action against a pattern. Patterns are either exact action names,
+// prefixes ending with ".*" or a single "*" matching all actions.
+func matchAction(pattern string, action string) bool {
+	if pattern == anySubject {
+		return true
+	}
+	if prefix, found := strings.CutSuffix(pattern, actionPattern); found {
+		return strings.HasPrefix(action, prefix+".")
+	}
+	return pattern == action
+}
@@ -3,6 +3,7 @@ package admin
 import (
 	"cmp"
 	"errors"
+	"fmt"
 	"github.com/cryptopunkscc/astrald/log"
 	"github.com/cryptopunkscc/astrald/mod/admin"
 	"github.com/cryptopunkscc/astrald/net"
@@ -30,6 +31,19 @@ type checkLatency interface {
 	Latency() time.Duration
 }
 
+type checkCompression interface {
+	CompressionRatio() float64
+}
+
+type checkRekey interface {
+	KeyUsage() (bytes uint64, since time.Time)
+	Rekeys() int
+}
+
+type rekeyer interface {
+	Rekey() error
+}
+
 func (cmd *CmdNet) Exec(term admin.Terminal, args []string) error {
 	if len(args) < 2 {
 		return cmd.help(term)
@@ -42,6 +56,9 @@ func (cmd *CmdNet) Exec(term admin.Terminal, args []string) error {
 	case "unlink":
 		return cmd.unlink(term, args[2:])
 
+	case "bond":
+		return cmd.bond(term, args[2:])
+
 	case "links":
 		return cmd.links(term, args[2:])
 
@@ -51,6 +68,9 @@ func (cmd *CmdNet) Exec(term admin.Terminal, args []string) error {
 	case "close":
 		return cmd.close(term, args[2:])
 
+	case "rekey":
+		return cmd.rekey(term, args[2:])
+
 	case "conns":
 		return cmd.conns(term, args[2:])
 
@@ -66,6 +86,9 @@ func (cmd *CmdNet) Exec(term admin.Terminal, args []string) error {
 	case "reroute":
 		return cmd.reroute(term, args[2:])
 
+	case "obfs":
+		return cmd.obfs(term, args[2:])
+
 	case "help":
 		return cmd.help(term)
 
@@ -286,10 +309,23 @@ func (cmd *CmdNet) show(term admin.Terminal, args []string) error {
 		term.Printf("Local endpoint:   %v\n", t.LocalEndpoint())
 		term.Printf("Remote endpoint:  %v\n", t.RemoteEndpoint())
 		term.Printf("Outbound:         %v\n", t.Outbound())
+		if t, ok := t.(checkRekey); ok {
+			bytes, since := t.KeyUsage()
+			term.Printf("Key age:          %v (%v, %d rekeys)\n",
+				time.Since(since).Round(time.Second),
+				log.DataSize(bytes),
+				t.Rekeys(),
+			)
+		}
 	}
 	if l, ok := l.Link.(checkLatency); ok {
 		term.Printf("Latency:          %v\n", l.Latency().Round(time.Millisecond))
 	}
+	if l, ok := l.Link.(checkCompression); ok {
+		if ratio := l.CompressionRatio(); ratio > 0 {
+			term.Printf("Compression:      %.2fx\n", ratio)
+		}
+	}
 	term.Printf("Age:              %v (%v)\n",
 		time.Since(l.AddedAt()).Round(time.Second),
 		l.AddedAt(),
@@ -297,6 +333,22 @@ func (cmd *CmdNet) show(term admin.Terminal, args []string) error {
 	if idler, ok := l.Link.(sig.Idler); ok {
 		term.Printf("Idle:             %v\n", idler.Idle().Round(time.Second))
 	}
+	if bonded, ok := l.Link.(*link.BondedLink); ok {
+		term.Printf("Paths:\n")
+		for _, path := range bonded.Paths() {
+			var network = "unknown"
+			var endpoint = path.Conn().RemoteEndpoint()
+			if endpoint != nil {
+				network = endpoint.Network()
+			}
+			term.Printf("  %-8s %-32v %10v %10v\n",
+				admin.Keyword(network),
+				endpoint,
+				path.Latency().Round(time.Millisecond),
+				time.Since(path.AddedAt()).Round(time.Second),
+			)
+		}
+	}
 	return nil
 }
 
@@ -319,8 +371,37 @@ func (cmd *CmdNet) close(term admin.Terminal, args []string) error {
 	return l.Close()
 }
 
+func (cmd *CmdNet) rekey(term admin.Terminal, args []string) error {
+	if len(args) < 1 {
+		term.Printf("usage: net rekey <linkID>")
+		return nil
+	}
+
+	lid, err := strconv.Atoi(args[0])
+	if err != nil {
+		return err
+	}
+
+	l, err := cmd.mod.node.Network().Links().Find(lid)
+	if err != nil {
+		return err
+	}
+
+	r, ok := l.Link.(rekeyer)
+	if !ok {
+		return errors.New("link does not support rekeying")
+	}
+
+	if err := r.Rekey(); err != nil {
+		return err
+	}
+
+	term.Printf("rekeyed\n")
+	return nil
+}
+
 func (cmd *CmdNet) links(term admin.Terminal, _ []string) error {
-	var f = "%-8d %-24s %-8s %10s %10s %10s\n"
+	var f = "%-8d %-24s %-8s %10s %10s %10s %8s\n"
 
 	links := cmd.mod.node.Network().Links().All()
 	slices.SortFunc(links, func(a, b *network.ActiveLink) int {
@@ -334,6 +415,7 @@ func (cmd *CmdNet) links(term admin.Terminal, _ []string) error {
 		admin.Header("Idle"),
 		admin.Header("Age"),
 		admin.Header("Ping"),
+		admin.Header("Comp"),
 	)
 	for _, l := range links {
 		if l == nil {
@@ -351,6 +433,13 @@ func (cmd *CmdNet) links(term admin.Terminal, _ []string) error {
 			lat = l.Latency()
 		}
 
+		var comp = "-"
+		if l, ok := l.Link.(checkCompression); ok {
+			if ratio := l.CompressionRatio(); ratio > 0 {
+				comp = fmt.Sprintf("%.2fx", ratio)
+			}
+		}
+
 		term.Printf(f,
 			l.ID(),
 			l.RemoteIdentity(),
@@ -358,6 +447,7 @@ func (cmd *CmdNet) links(term admin.Terminal, _ []string) error {
 			idle,
 			time.Since(l.AddedAt()).Round(time.Second),
 			lat.Round(time.Millisecond),
+			comp,
 		)
 	}
 
@@ -421,10 +511,13 @@ func (cmd *CmdNet) help(term admin.Terminal) error {
 	term.Printf("  links     list all links\n")
 	term.Printf("  show      show link info\n")
 	term.Printf("  close     close a link\n")
+	term.Printf("  rekey     replace the transport keys of a link\n")
 	term.Printf("  link      link a node\n")
 	term.Printf("  unlink    unlink a node\n")
+	term.Printf("  bond      add paths to a bonded link\n")
 	term.Printf("  conns     list all connections\n")
 	term.Printf("  check     run health check on all links\n")
+	term.Printf("  obfs      list obfuscated endpoints of the node\n")
 	term.Printf("  help      show help\n")
 	return nil
 }
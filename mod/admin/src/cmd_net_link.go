@@ -7,6 +7,7 @@ import (
 	"github.com/cryptopunkscc/astrald/mod/admin"
 	"github.com/cryptopunkscc/astrald/net"
 	"github.com/cryptopunkscc/astrald/node/link"
+	"strconv"
 )
 
 func (cmd *CmdNet) link(term admin.Terminal, args []string) error {
@@ -19,6 +20,7 @@ func (cmd *CmdNet) link(term admin.Terminal, args []string) error {
 	var network = flags.String("n", "", "link via this network only")
 	var timeout = flags.Duration("t", defaultLinkTimeout, "set timeout")
 	var addr = flags.String("a", "", "link via this address (requires -n)")
+	var bond = flags.Bool("b", false, "bond all reachable endpoints into a single link")
 	err := flags.Parse(args)
 	if err != nil {
 		if errors.Is(err, flag.ErrHelp) {
@@ -66,10 +68,18 @@ func (cmd *CmdNet) link(term admin.Terminal, args []string) error {
 		return errors.New("no usable endpoints")
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
-	defer cancel()
+	ctx, cancel := context.WithTimeout(cmd.mod.ctx, *timeout)
+	defer func() {
+		// bonds keep adding paths in the background until the timeout
+		if !*bond {
+			cancel()
+		}
+	}()
 
-	lnk, err := link.MakeLink(ctx, cmd.mod.node, remoteID, link.Opts{Endpoints: endpoints})
+	lnk, err := link.MakeLink(ctx, cmd.mod.node, remoteID, cmd.mod.node.Network().LinkConfig(), link.Opts{
+		Endpoints: endpoints,
+		Bond:      *bond,
+	})
 	if err != nil {
 		return err
 	}
@@ -84,3 +94,89 @@ func (cmd *CmdNet) link(term admin.Terminal, args []string) error {
 
 	return nil
 }
+
+func (cmd *CmdNet) bond(term admin.Terminal, args []string) error {
+	flags := flag.NewFlagSet("net bond <linkID>", flag.ContinueOnError)
+	flags.SetOutput(term)
+	flags.Usage = func() {
+		term.Printf("Usage:\n\n  net bond [options] <linkID>\n\nOptions:\n")
+		flags.PrintDefaults()
+	}
+	var network = flags.String("n", "", "add paths via this network only")
+	var timeout = flags.Duration("t", defaultLinkTimeout, "set timeout")
+	var addr = flags.String("a", "", "add a path via this address (requires -n)")
+	err := flags.Parse(args)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	args = flags.Args()
+
+	if len(args) < 1 {
+		flags.Usage()
+		return nil
+	}
+
+	lid, err := strconv.Atoi(args[0])
+	if err != nil {
+		return err
+	}
+
+	active, err := cmd.mod.node.Network().Links().Find(lid)
+	if err != nil {
+		return err
+	}
+
+	bonded, ok := active.Link.(*link.BondedLink)
+	if !ok {
+		return errors.New("not a bonded link")
+	}
+
+	var endpoints []net.Endpoint
+
+	if *addr != "" {
+		if *network == "" {
+			return errors.New("adding a path via address requires specifying the network")
+		}
+		e, err := cmd.mod.node.Infra().Parse(*network, *addr)
+		if err != nil {
+			return err
+		}
+		endpoints = []net.Endpoint{e}
+	} else {
+		endpoints, err = cmd.mod.node.Tracker().EndpointsByIdentity(bonded.RemoteIdentity())
+		if err != nil {
+			return err
+		}
+
+		if *network != "" {
+			endpoints = selectEndpoints(endpoints, func(e net.Endpoint) bool {
+				return e.Network() == *network
+			})
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	var added int
+	for _, e := range endpoints {
+		conn, err := cmd.mod.node.Infra().Dial(ctx, e)
+		if err != nil {
+			term.Printf("%s: %v\n", e, err)
+			continue
+		}
+
+		if err := bonded.AddPath(ctx, conn); err != nil {
+			term.Printf("%s: %v\n", e, err)
+			continue
+		}
+		added++
+	}
+
+	term.Printf("added %d path(s)\n", added)
+
+	return nil
+}
@@ -0,0 +1,24 @@
+package admin
+
+import (
+	"github.com/cryptopunkscc/astrald/auth/obfs"
+	"github.com/cryptopunkscc/astrald/mod/admin"
+)
+
+// obfs lists obfuscated variants of the node's endpoints
+func (cmd *CmdNet) obfs(term admin.Terminal, _ []string) error {
+	key, err := obfs.NodeKey(cmd.mod.node.Identity())
+	if err != nil {
+		return err
+	}
+
+	var f = "%-12s %s\n"
+
+	term.Printf(f, admin.Header("Network"), admin.Header("Address"))
+	for _, e := range cmd.mod.node.Infra().Endpoints() {
+		var o = obfs.NewEndpoint(e, key)
+		term.Printf(f, o.Network(), o.String())
+	}
+
+	return nil
+}
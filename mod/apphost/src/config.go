@@ -10,16 +10,25 @@ type Config struct {
 	// Identity to use for anonymous connections
 	DefaultIdentity string `yaml:"default_identity"`
 
+	// Serve the API over HTTP on this address (for example 127.0.0.1:8626). Only loopback addresses are allowed.
+	HTTP string `yaml:"http"`
+
 	Tokens  map[string]string `yaml:"tokens"`
 	Autorun []configRun       `yaml:"autorun"`
 
 	RoutePriority int `yaml:"route_priority"`
 }
 
+// autorunDerive is the identity of autorun entries that run as keys derived from the master seed
+const autorunDerive = "derive"
+
 type configRun struct {
-	Exec     string   `yaml:"exec"`
-	Args     []string `yaml:"args"`
-	Identity string   `yaml:"identity"`
+	Exec string   `yaml:"exec"`
+	Args []string `yaml:"args"`
+
+	// Identity is the identity the app runs as. "derive" runs the app as a key derived from the master seed
+	// at apps/<name>, and "derive:<path>" as a key derived at the path.
+	Identity string `yaml:"identity"`
 }
 
 var defaultConfig = Config{
@@ -3,6 +3,7 @@ package apphost
 import (
 	"github.com/cryptopunkscc/astrald/mod/content"
 	"github.com/cryptopunkscc/astrald/mod/discovery"
+	"github.com/cryptopunkscc/astrald/mod/keys"
 	"github.com/cryptopunkscc/astrald/node/modules"
 )
 
@@ -10,6 +11,7 @@ func (mod *Module) LoadDependencies() error {
 	var err error
 
 	mod.content, _ = modules.Load[content.Module](mod.node, content.ModuleName)
+	mod.keys, _ = modules.Load[keys.Module](mod.node, keys.ModuleName)
 
 	mod.sdp, err = modules.Load[discovery.Module](mod.node, discovery.ModuleName)
 	if err == nil {
@@ -0,0 +1,482 @@
+package apphost
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/cryptopunkscc/astrald/auth/id"
+	"github.com/cryptopunkscc/astrald/mod/apphost/proto"
+	"github.com/cryptopunkscc/astrald/net"
+	"github.com/cryptopunkscc/astrald/streams"
+	"golang.org/x/net/websocket"
+	"io"
+	"mime"
+	_net "net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// httpAcceptTimeout is how long an incoming query waits for the client to accept it
+const httpAcceptTimeout = 30 * time.Second
+
+var errNotLocal = errors.New("http gateway must listen on a loopback address")
+
+// HTTPGateway exposes the apphost API over HTTP. Commands use JSON, queries are streamed over WebSockets.
+//
+//	GET  /resolve?name=<name>                  resolve a name to an identity
+//	GET  /nodeInfo?identity=<identity>         get the display name of an identity
+//	POST /exec                                 run an executable, see httpExecParams
+//	GET  /query?identity=<target>&query=<q>    (WebSocket) query a service
+//	GET  /register?service=<name>              (WebSocket) register a service, see httpQueryEvent
+//	GET  /accept?id=<id>                       (WebSocket) accept an incoming query
+//
+// Clients authenticate with an access token passed in the Authorization header as a bearer token or in
+// the token URL parameter. Requests without a valid token are rejected.
+type HTTPGateway struct {
+	mod     *Module
+	mu      sync.Mutex
+	pending map[uint64]*httpPendingQuery
+	nextID  uint64
+}
+
+// httpQueryEvent is sent over the register socket for every incoming query. The client accepts the query by
+// opening the accept socket with the same id, or rejects it by sending back the event with Reject set.
+type httpQueryEvent struct {
+	ID     uint64      `json:"id"`
+	Caller id.Identity `json:"caller,omitempty"`
+	Query  string      `json:"query,omitempty"`
+	Reject bool        `json:"reject,omitempty"`
+}
+
+type httpExecParams struct {
+	Identity string   `json:"identity"`
+	Exec     string   `json:"exec"`
+	Args     []string `json:"args"`
+	Env      []string `json:"env"`
+}
+
+type httpNodeInfo struct {
+	Identity id.Identity `json:"identity"`
+	Name     string      `json:"name"`
+}
+
+type httpResolveData struct {
+	Identity id.Identity `json:"identity"`
+}
+
+type httpError struct {
+	Error string `json:"error"`
+}
+
+type httpPendingQuery struct {
+	id       uint64
+	identity id.Identity
+	accept   chan *httpAcceptedQuery
+	reject   chan struct{}
+	closed   chan struct{}
+}
+
+type httpAcceptedQuery struct {
+	*websocket.Conn
+	done chan struct{}
+}
+
+func NewHTTPGateway(mod *Module) *HTTPGateway {
+	return &HTTPGateway{
+		mod:     mod,
+		pending: make(map[uint64]*httpPendingQuery),
+	}
+}
+
+func (mod *Module) serveHTTP(ctx context.Context) error {
+	host, _, err := _net.SplitHostPort(mod.config.HTTP)
+	if err != nil {
+		return err
+	}
+	if !isLoopback(host) {
+		return errNotLocal
+	}
+
+	listener, err := _net.Listen("tcp", mod.config.HTTP)
+	if err != nil {
+		return err
+	}
+
+	var server = &http.Server{Handler: NewHTTPGateway(mod)}
+
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+
+	mod.log.Infov(1, "http gateway listening on: %s", listener.Addr())
+
+	err = server.Serve(listener)
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+func (gw *HTTPGateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// refuse requests for other hosts to protect against DNS rebinding
+	if !isLoopback(r.Host) {
+		writeJSON(w, http.StatusForbidden, httpError{Error: "forbidden"})
+		return
+	}
+
+	var identity = gw.auth(r)
+	if identity.IsZero() {
+		writeJSON(w, http.StatusUnauthorized, httpError{Error: proto.ErrUnauthorized.Error()})
+		return
+	}
+
+	switch r.URL.Path {
+	case "/" + proto.CmdResolve:
+		gw.resolve(w, r, identity)
+	case "/" + proto.CmdNodeInfo:
+		gw.nodeInfo(w, r, identity)
+	case "/" + proto.CmdExec:
+		gw.exec(w, r, identity)
+	case "/" + proto.CmdQuery:
+		gw.query(w, r, identity)
+	case "/" + proto.CmdRegister:
+		gw.register(w, r, identity)
+	case "/accept":
+		gw.accept(w, r, identity)
+	default:
+		writeJSON(w, http.StatusNotFound, httpError{Error: proto.ErrUnknownCommand.Error()})
+	}
+}
+
+// auth returns the identity of the client or a zero identity if the client is unauthorized. Unlike local
+// sockets, the gateway can be reached by any web page open in a browser, so it requires a valid token.
+func (gw *HTTPGateway) auth(r *http.Request) (identity id.Identity) {
+	var token = r.URL.Query().Get("token")
+	if h := r.Header.Get("Authorization"); strings.HasPrefix(h, "Bearer ") {
+		token = strings.TrimPrefix(h, "Bearer ")
+	}
+
+	if len(token) > 0 {
+		identity = gw.mod.authToken(token)
+	}
+
+	return
+}
+
+// websocket returns a WebSocket server that only accepts connections from local origins
+func (gw *HTTPGateway) websocket(handler websocket.Handler) websocket.Server {
+	return websocket.Server{
+		Handler: handler,
+		Handshake: func(config *websocket.Config, r *http.Request) (err error) {
+			config.Origin, err = websocket.Origin(config, r)
+			if err != nil {
+				return err
+			}
+
+			// clients other than browsers don't send the origin
+			if config.Origin != nil && !isLoopback(config.Origin.Host) {
+				return errors.New("origin not allowed")
+			}
+
+			return nil
+		},
+	}
+}
+
+func (gw *HTTPGateway) resolve(w http.ResponseWriter, r *http.Request, identity id.Identity) {
+	var name = r.URL.Query().Get("name")
+
+	gw.mod.log.Logv(2, "%s resolve %s (http)", identity, name)
+
+	remoteID, err := gw.mod.node.Resolver().Resolve(name)
+	if err != nil {
+		writeJSON(w, http.StatusNotFound, httpError{Error: proto.ErrFailed.Error()})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, httpResolveData{Identity: remoteID})
+}
+
+func (gw *HTTPGateway) nodeInfo(w http.ResponseWriter, r *http.Request, identity id.Identity) {
+	var data = httpNodeInfo{Identity: identity}
+
+	if name := r.URL.Query().Get("identity"); len(name) > 0 {
+		target, err := gw.mod.node.Resolver().Resolve(name)
+		if err != nil {
+			writeJSON(w, http.StatusNotFound, httpError{Error: proto.ErrFailed.Error()})
+			return
+		}
+		data.Identity = target
+	}
+
+	gw.mod.log.Logv(2, "%s nodeInfo %s (http)", identity, data.Identity)
+
+	data.Name = gw.mod.node.Resolver().DisplayName(data.Identity)
+
+	writeJSON(w, http.StatusOK, data)
+}
+
+func (gw *HTTPGateway) exec(w http.ResponseWriter, r *http.Request, identity id.Identity) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, httpError{Error: "method not allowed"})
+		return
+	}
+
+	// browsers can't send cross-origin JSON without a preflight request
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+		writeJSON(w, http.StatusUnsupportedMediaType, httpError{Error: "content type must be application/json"})
+		return
+	}
+
+	var params httpExecParams
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		writeJSON(w, http.StatusBadRequest, httpError{Error: err.Error()})
+		return
+	}
+
+	if len(params.Identity) > 0 {
+		var err error
+		identity, err = gw.mod.node.Resolver().Resolve(params.Identity)
+		if err != nil {
+			writeJSON(w, http.StatusNotFound, httpError{Error: proto.ErrFailed.Error()})
+			return
+		}
+	}
+
+	if _, err := gw.mod.Exec(identity, params.Exec, params.Args, params.Env); err != nil {
+		writeJSON(w, http.StatusInternalServerError, httpError{Error: proto.ErrFailed.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (gw *HTTPGateway) query(w http.ResponseWriter, r *http.Request, identity id.Identity) {
+	var target = identity
+
+	if name := r.URL.Query().Get("identity"); len(name) > 0 {
+		var err error
+		target, err = gw.mod.node.Resolver().Resolve(name)
+		if err != nil {
+			writeJSON(w, http.StatusNotFound, httpError{Error: proto.ErrRouteNotFound.Error()})
+			return
+		}
+	}
+
+	var query = net.NewQuery(identity, target, r.URL.Query().Get("query"))
+
+	// route the query before upgrading the connection, so that errors have proper status codes
+	conn, err := net.RouteWithHints(r.Context(), gw.mod.node.Router(), query, queryHints(query))
+	switch {
+	case err == nil:
+
+	case errors.Is(err, net.ErrRejected):
+		writeJSON(w, http.StatusForbidden, httpError{Error: proto.ErrRejected.Error()})
+		return
+
+	case errors.Is(err, &net.ErrRouteNotFound{}):
+		writeJSON(w, http.StatusNotFound, httpError{Error: proto.ErrRouteNotFound.Error()})
+		return
+
+	default:
+		gw.mod.log.Error("unexpected error processing query: %s", err)
+		writeJSON(w, http.StatusInternalServerError, httpError{Error: proto.ErrUnexpected.Error()})
+		return
+	}
+	defer conn.Close()
+
+	gw.websocket(func(ws *websocket.Conn) {
+		ws.PayloadType = websocket.BinaryFrame
+		streams.Join(ws, conn)
+	}).ServeHTTP(w, r)
+}
+
+func (gw *HTTPGateway) register(w http.ResponseWriter, r *http.Request, identity id.Identity) {
+	var service = r.URL.Query().Get("service")
+	var router = &httpRouter{gw: gw, identity: identity}
+
+	gw.mod.log.Logv(2, "%s register %s (http)", identity, service)
+
+	var err error
+	if identity.IsEqual(gw.mod.node.Identity()) {
+		err = gw.mod.addNodeRouter(service, router)
+	} else {
+		err = gw.mod.addGuestRouter(identity, service, router)
+	}
+	if err != nil {
+		writeJSON(w, http.StatusConflict, httpError{Error: proto.ErrAlreadyRegistered.Error()})
+		return
+	}
+
+	defer func() {
+		if identity.IsEqual(gw.mod.node.Identity()) {
+			gw.mod.removeNodeRoute(service)
+		} else {
+			gw.mod.removeGuestRoute(identity, service)
+		}
+	}()
+
+	gw.websocket(func(ws *websocket.Conn) {
+		router.setConn(ws)
+
+		// read rejections until the client closes the socket
+		for {
+			var event httpQueryEvent
+			if err := websocket.JSON.Receive(ws, &event); err != nil {
+				return
+			}
+			if event.Reject {
+				gw.rejectPending(event.ID, identity)
+			}
+		}
+	}).ServeHTTP(w, r)
+}
+
+func (gw *HTTPGateway) accept(w http.ResponseWriter, r *http.Request, identity id.Identity) {
+	queryID, err := strconv.ParseUint(r.URL.Query().Get("id"), 10, 64)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, httpError{Error: err.Error()})
+		return
+	}
+
+	var pending = gw.getPending(queryID, identity)
+	if pending == nil {
+		writeJSON(w, http.StatusNotFound, httpError{Error: "query not found"})
+		return
+	}
+
+	gw.websocket(func(ws *websocket.Conn) {
+		ws.PayloadType = websocket.BinaryFrame
+
+		var accepted = &httpAcceptedQuery{Conn: ws, done: make(chan struct{})}
+
+		select {
+		case pending.accept <- accepted:
+			<-accepted.done
+		case <-pending.closed:
+		}
+	}).ServeHTTP(w, r)
+}
+
+func (gw *HTTPGateway) addPending(identity id.Identity) *httpPendingQuery {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+
+	gw.nextID++
+
+	var pending = &httpPendingQuery{
+		id:       gw.nextID,
+		identity: identity,
+		accept:   make(chan *httpAcceptedQuery),
+		reject:   make(chan struct{}, 1),
+		closed:   make(chan struct{}),
+	}
+	gw.pending[pending.id] = pending
+
+	return pending
+}
+
+func (gw *HTTPGateway) removePending(pending *httpPendingQuery) {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+
+	delete(gw.pending, pending.id)
+	close(pending.closed)
+}
+
+func (gw *HTTPGateway) getPending(queryID uint64, identity id.Identity) *httpPendingQuery {
+	gw.mu.Lock()
+	defer gw.mu.Unlock()
+
+	pending, found := gw.pending[queryID]
+	if !found || !pending.identity.IsEqual(identity) {
+		return nil
+	}
+
+	return pending
+}
+
+func (gw *HTTPGateway) rejectPending(queryID uint64, identity id.Identity) {
+	if pending := gw.getPending(queryID, identity); pending != nil {
+		select {
+		case pending.reject <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// httpRouter passes queries to a service registered over the http gateway
+type httpRouter struct {
+	gw       *HTTPGateway
+	identity id.Identity
+	mu       sync.Mutex
+	ws       *websocket.Conn
+}
+
+func (router *httpRouter) setConn(ws *websocket.Conn) {
+	router.mu.Lock()
+	defer router.mu.Unlock()
+
+	router.ws = ws
+}
+
+func (router *httpRouter) RouteQuery(ctx context.Context, query net.Query, caller net.SecureWriteCloser, hints net.Hints) (net.SecureWriteCloser, error) {
+	var pending = router.gw.addPending(router.identity)
+	defer router.gw.removePending(pending)
+
+	router.mu.Lock()
+	var err = errors.New("not connected")
+	if router.ws != nil {
+		err = websocket.JSON.Send(router.ws, httpQueryEvent{
+			ID:     pending.id,
+			Caller: query.Caller(),
+			Query:  query.Query(),
+		})
+	}
+	router.mu.Unlock()
+	if err != nil {
+		return net.Reject()
+	}
+
+	select {
+	case conn := <-pending.accept:
+		go func() {
+			defer close(conn.done)
+			io.Copy(caller, conn)
+			caller.Close()
+		}()
+
+		return net.NewSecurePipeWriter(conn, query.Target()), nil
+
+	case <-pending.reject:
+	case <-time.After(httpAcceptTimeout):
+	case <-ctx.Done():
+	}
+
+	return net.Reject()
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// isLoopback checks if a host (with an optional port) refers to the local machine
+func isLoopback(host string) bool {
+	if h, _, err := _net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	if host == "localhost" {
+		return true
+	}
+
+	ip := _net.ParseIP(strings.Trim(host, "[]"))
+
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,250 @@
+package apphost
+
+import (
+	"bytes"
+	"context"
+	"github.com/cryptopunkscc/astrald/auth/id"
+	"github.com/cryptopunkscc/astrald/log"
+	"github.com/cryptopunkscc/astrald/net"
+	"github.com/cryptopunkscc/astrald/node"
+	"github.com/cryptopunkscc/astrald/node/resolver"
+	"github.com/cryptopunkscc/astrald/node/router"
+	"github.com/glebarez/sqlite"
+	"golang.org/x/net/websocket"
+	"gorm.io/gorm"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+type testNode struct {
+	node.Node
+	identity id.Identity
+	router   *testRouter
+}
+
+func (n *testNode) Identity() id.Identity {
+	return n.identity
+}
+
+func (n *testNode) Resolver() resolver.Resolver {
+	return testResolver{}
+}
+
+func (n *testNode) Router() router.Router {
+	return n.router
+}
+
+type testResolver struct {
+	resolver.Resolver
+}
+
+func (testResolver) Resolve(s string) (id.Identity, error) {
+	return id.ParsePublicKeyHex(s)
+}
+
+func (testResolver) DisplayName(identity id.Identity) string {
+	return identity.Fingerprint()
+}
+
+// testRouter echoes queries for the echo service, rejects queries for the reject service and has no routes
+// for other queries
+type testRouter struct {
+	router.Router
+	hints net.Hints
+}
+
+func (r *testRouter) RouteQuery(ctx context.Context, query net.Query, caller net.SecureWriteCloser, hints net.Hints) (net.SecureWriteCloser, error) {
+	r.hints = hints
+
+	switch query.Query() {
+	case "echo":
+		return net.NewSecurePipeWriter(caller, query.Target()), nil
+	case "reject":
+		return net.Reject()
+	}
+	return net.RouteNotFound(r)
+}
+
+func newTestGateway(t *testing.T) (gw *HTTPGateway, token string, identity id.Identity) {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&dbAccessToken{}); err != nil {
+		t.Fatal(err)
+	}
+
+	nodeID, _ := id.GenerateIdentity()
+	identity, _ = id.GenerateIdentity()
+
+	var mod = &Module{
+		node: &testNode{identity: nodeID, router: &testRouter{}},
+		log:  log.NewLogger(log.NewLinePrinter(log.NewMonoOutput(io.Discard))),
+		db:   db,
+	}
+
+	token, err = mod.CreateAccessToken(identity)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return NewHTTPGateway(mod), token, identity
+}
+
+// serve sends a request to the gateway and checks the response status and content type
+func serve(t *testing.T, gw *HTTPGateway, r *http.Request, status int, contentType string) {
+	t.Helper()
+
+	var w = httptest.NewRecorder()
+	gw.ServeHTTP(w, r)
+
+	if w.Code != status {
+		t.Fatalf("%s %s: status %d, expected %d", r.Method, r.URL, w.Code, status)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != contentType {
+		t.Fatalf("%s %s: content type %q, expected %q", r.Method, r.URL, ct, contentType)
+	}
+}
+
+func TestHTTPAuth(t *testing.T) {
+	gw, token, identity := newTestGateway(t)
+
+	var path = "/nodeInfo?identity=" + identity.PublicKeyHex()
+
+	serve(t, gw, httptest.NewRequest(http.MethodGet, "http://localhost"+path, nil),
+		http.StatusUnauthorized, "application/json")
+	serve(t, gw, httptest.NewRequest(http.MethodGet, "http://localhost"+path+"&token=invalid", nil),
+		http.StatusUnauthorized, "application/json")
+
+	var r = httptest.NewRequest(http.MethodGet, "http://localhost"+path, nil)
+	r.Header.Set("Authorization", "Bearer invalid")
+	serve(t, gw, r, http.StatusUnauthorized, "application/json")
+
+	serve(t, gw, httptest.NewRequest(http.MethodGet, "http://localhost"+path+"&token="+token, nil),
+		http.StatusOK, "application/json")
+
+	r = httptest.NewRequest(http.MethodGet, "http://localhost"+path, nil)
+	r.Header.Set("Authorization", "Bearer "+token)
+	serve(t, gw, r, http.StatusOK, "application/json")
+}
+
+func TestHTTPForeignHost(t *testing.T) {
+	gw, token, _ := newTestGateway(t)
+
+	// a valid token doesn't help requests that name another host, like the ones of DNS rebinding attacks
+	for _, host := range []string{"example.com", "example.com:8625", "10.0.0.1:8625"} {
+		var r = httptest.NewRequest(http.MethodGet, "http://"+host+"/nodeInfo?token="+token, nil)
+		serve(t, gw, r, http.StatusForbidden, "application/json")
+	}
+
+	for _, host := range []string{"localhost:8625", "127.0.0.1:8625", "[::1]:8625"} {
+		var r = httptest.NewRequest(http.MethodGet, "http://"+host+"/nodeInfo?token="+token, nil)
+		serve(t, gw, r, http.StatusOK, "application/json")
+	}
+}
+
+func TestHTTPExec(t *testing.T) {
+	gw, token, _ := newTestGateway(t)
+
+	var url = "http://localhost/exec?token=" + token
+	var request = func(method string, contentType string, body string) *http.Request {
+		var r = httptest.NewRequest(method, url, strings.NewReader(body))
+		r.Header.Set("Content-Type", contentType)
+		return r
+	}
+
+	serve(t, gw, request(http.MethodGet, "application/json", `{}`),
+		http.StatusMethodNotAllowed, "application/json")
+
+	// forms can be posted across origins without a preflight request
+	serve(t, gw, request(http.MethodPost, "application/x-www-form-urlencoded", `exec=/bin/sh`),
+		http.StatusUnsupportedMediaType, "application/json")
+	serve(t, gw, request(http.MethodPost, "text/plain", `{"exec":"/bin/sh"}`),
+		http.StatusUnsupportedMediaType, "application/json")
+
+	serve(t, gw, request(http.MethodPost, "application/json", `{"exec":`),
+		http.StatusBadRequest, "application/json")
+	serve(t, gw, request(http.MethodPost, "application/json", `{"identity":"invalid","exec":"true"}`),
+		http.StatusNotFound, "application/json")
+	serve(t, gw, request(http.MethodPost, "application/json", `{"exec":"/nonexistent/executable"}`),
+		http.StatusInternalServerError, "application/json")
+
+	if path, err := exec.LookPath("true"); err == nil {
+		serve(t, gw, request(http.MethodPost, "application/json; charset=utf-8", `{"exec":"`+path+`"}`),
+			http.StatusNoContent, "")
+	}
+}
+
+func TestHTTPQuery(t *testing.T) {
+	gw, token, identity := newTestGateway(t)
+
+	var url = "http://localhost/query?token=" + token + "&identity=" + identity.PublicKeyHex()
+
+	serve(t, gw, httptest.NewRequest(http.MethodGet, url+"&query=reject", nil),
+		http.StatusForbidden, "application/json")
+	serve(t, gw, httptest.NewRequest(http.MethodGet, url+"&query=unknown", nil),
+		http.StatusNotFound, "application/json")
+	serve(t, gw, httptest.NewRequest(http.MethodGet, "http://localhost/query?token="+token+"&identity=invalid", nil),
+		http.StatusNotFound, "application/json")
+
+	// routed queries are upgraded to WebSockets
+	var server = httptest.NewServer(gw)
+	defer server.Close()
+
+	var wsURL = "ws" + strings.TrimPrefix(server.URL, "http") + "/query?token=" + token +
+		"&identity=" + identity.PublicKeyHex() + "&query=echo"
+
+	ws, err := websocket.Dial(wsURL, "", "http://localhost/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ws.Close()
+
+	if _, err := ws.Write([]byte("ping")); err != nil {
+		t.Fatal(err)
+	}
+	var buf = make([]byte, 4)
+	if _, err := io.ReadFull(ws, buf); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf, []byte("ping")) {
+		t.Fatalf("received %q, expected ping", buf)
+	}
+
+	// object reads are routed with resumption allowed
+	serve(t, gw, httptest.NewRequest(http.MethodGet, url+"&query=objects.read?id=0", nil),
+		http.StatusNotFound, "application/json")
+	if !gw.mod.node.(*testNode).router.hints.Resume {
+		t.Fatal("object read routed without the resume hint")
+	}
+}
+
+func TestHTTPForeignOrigin(t *testing.T) {
+	gw, token, identity := newTestGateway(t)
+
+	var server = httptest.NewServer(gw)
+	defer server.Close()
+
+	var wsURL = "ws" + strings.TrimPrefix(server.URL, "http") + "/query?token=" + token +
+		"&identity=" + identity.PublicKeyHex() + "&query=echo"
+
+	// web pages of other origins cannot open sockets even if they got hold of a token
+	if ws, err := websocket.Dial(wsURL, "", "http://example.com/"); err == nil {
+		ws.Close()
+		t.Fatal("socket opened from a foreign origin")
+	}
+
+	for _, origin := range []string{"http://localhost/", "http://127.0.0.1:8080/"} {
+		ws, err := websocket.Dial(wsURL, "", origin)
+		if err != nil {
+			t.Fatalf("origin %s: %s", origin, err)
+		}
+		ws.Close()
+	}
+}
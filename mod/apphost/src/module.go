@@ -3,17 +3,21 @@ package apphost
 import (
 	"context"
 	"errors"
+	"fmt"
 	"github.com/cryptopunkscc/astrald/auth/id"
 	"github.com/cryptopunkscc/astrald/debug"
 	"github.com/cryptopunkscc/astrald/log"
 	"github.com/cryptopunkscc/astrald/mod/apphost"
 	"github.com/cryptopunkscc/astrald/mod/content"
 	"github.com/cryptopunkscc/astrald/mod/discovery"
+	"github.com/cryptopunkscc/astrald/mod/keys"
+	"github.com/cryptopunkscc/astrald/net"
 	"github.com/cryptopunkscc/astrald/node"
 	"gorm.io/gorm"
-	"net"
+	_net "net"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 )
 
@@ -24,11 +28,12 @@ type Module struct {
 	node    node.Node
 	content content.Module
 	sdp     discovery.Module
+	keys    keys.Module
 	log     *log.Logger
 	db      *gorm.DB
 
-	listeners []net.Listener
-	conns     <-chan net.Conn
+	listeners []_net.Listener
+	conns     <-chan _net.Conn
 	defaultID id.Identity
 	guests    map[string]*Guest
 	guestsMu  sync.Mutex
@@ -55,6 +60,14 @@ func (mod *Module) Run(ctx context.Context) error {
 		}(i)
 	}
 
+	if len(mod.config.HTTP) > 0 {
+		go func() {
+			if err := mod.serveHTTP(ctx); err != nil {
+				mod.log.Error("http gateway error: %s", err)
+			}
+		}()
+	}
+
 	if len(mod.config.Autorun) > 0 {
 		mod.log.Infov(1, "%d autorun entries", len(mod.config.Autorun))
 	}
@@ -62,14 +75,14 @@ func (mod *Module) Run(ctx context.Context) error {
 	for _, run := range mod.config.Autorun {
 		run := run
 		go func() {
-			identity, err := mod.node.Resolver().Resolve(run.Identity)
+			var basename = filepath.Base(run.Exec)
+
+			identity, err := mod.autorunIdentity(run, basename)
 			if err != nil {
-				mod.log.Error("unknown identity: %s", run.Identity)
+				mod.log.Error("%s: %s", basename, err)
 				return
 			}
 
-			var basename = filepath.Base(run.Exec)
-
 			mod.log.Infov(1, "starting %s as %s...", basename, identity)
 
 			exec, err := mod.Exec(identity, run.Exec, run.Args, os.Environ())
@@ -92,6 +105,37 @@ func (mod *Module) Run(ctx context.Context) error {
 	return nil
 }
 
+// autorunIdentity returns the identity of an autorun entry. Entries opt in to run as a key derived from
+// the master seed with "derive" or "derive:<path>".
+func (mod *Module) autorunIdentity(run configRun, basename string) (id.Identity, error) {
+	var path string
+	switch {
+	case run.Identity == autorunDerive:
+		path = "apps/" + basename
+	case strings.HasPrefix(run.Identity, autorunDerive+":"):
+		path = strings.TrimPrefix(run.Identity, autorunDerive+":")
+	default:
+		identity, err := mod.node.Resolver().Resolve(run.Identity)
+		if err != nil {
+			return id.Identity{}, fmt.Errorf("unknown identity: %s", run.Identity)
+		}
+		return identity, nil
+	}
+
+	if mod.keys == nil {
+		return id.Identity{}, errors.New("cannot derive identity: keys module not loaded")
+	}
+
+	identity, _, err := mod.keys.DeriveKey(basename, path)
+	if err != nil {
+		return id.Identity{}, fmt.Errorf("cannot derive identity at %s: %w", path, err)
+	}
+
+	mod.log.Infov(1, "%s runs as %s derived at %s", basename, identity, path)
+
+	return identity, nil
+}
+
 func (mod *Module) SetDefaultIdentity(identity id.Identity) error {
 	mod.defaultID = identity
 	return nil
@@ -102,6 +146,14 @@ func (mod *Module) DefaultIdentity() id.Identity {
 }
 
 func (mod *Module) addGuestRoute(identity id.Identity, name string, target string) error {
+	return mod.addGuestRouter(identity, name, &RelayRouter{
+		log:      mod.log,
+		target:   target,
+		identity: identity,
+	})
+}
+
+func (mod *Module) addGuestRouter(identity id.Identity, name string, router net.Router) error {
 	mod.guestsMu.Lock()
 	defer mod.guestsMu.Unlock()
 
@@ -120,13 +172,7 @@ func (mod *Module) addGuestRoute(identity id.Identity, name string, target strin
 		mod.guests[key] = guest
 	}
 
-	relay := &RelayRouter{
-		log:      mod.log,
-		target:   target,
-		identity: identity,
-	}
-
-	return guest.AddRoute(name, relay)
+	return guest.AddRoute(name, router)
 }
 
 func (mod *Module) removeGuestRoute(identity id.Identity, name string) error {
@@ -153,17 +199,19 @@ func (mod *Module) removeGuestRoute(identity id.Identity, name string) error {
 }
 
 func (mod *Module) addNodeRoute(name string, target string) error {
-	if len(name) == 0 {
-		return errors.New("invalid name")
-	}
-
-	relay := &RelayRouter{
+	return mod.addNodeRouter(name, &RelayRouter{
 		log:      mod.log,
 		target:   target,
 		identity: mod.node.Identity(),
+	})
+}
+
+func (mod *Module) addNodeRouter(name string, router net.Router) error {
+	if len(name) == 0 {
+		return errors.New("invalid name")
 	}
 
-	return mod.node.LocalRouter().AddRoute(name, relay)
+	return mod.node.LocalRouter().AddRoute(name, router)
 }
 
 func (mod *Module) removeNodeRoute(name string) error {
@@ -5,7 +5,9 @@ import (
 	"errors"
 	"github.com/cryptopunkscc/astrald/cslq"
 	"github.com/cryptopunkscc/astrald/mod/apphost/proto"
+	"github.com/cryptopunkscc/astrald/mod/objects"
 	"github.com/cryptopunkscc/astrald/net"
+	"github.com/cryptopunkscc/astrald/node/router"
 	"github.com/cryptopunkscc/astrald/streams"
 	"io"
 )
@@ -50,7 +52,7 @@ func (s *Session) query(params proto.QueryParams) error {
 	}
 
 	var query = net.NewQuery(s.remoteID, params.Identity, params.Query)
-	conn, err = net.Route(s.ctx, s.mod.node.Router(), query)
+	conn, err = net.RouteWithHints(s.ctx, s.mod.node.Router(), query, queryHints(query))
 
 	if err == nil {
 		s.WriteErr(nil)
@@ -151,3 +153,15 @@ func (s *Session) registerGuest(p proto.RegisterParams) error {
 
 	return s.mod.removeGuestRoute(s.remoteID, p.Service)
 }
+
+// queryHints returns the hints for routing a query of an app
+func queryHints(query net.Query) net.Hints {
+	var hints = net.DefaultHints()
+
+	// object reads can be long transfers, so let them survive the loss of a link
+	if path, _ := router.ParseQuery(query.Query()); path == objects.ReadServiceName {
+		hints = hints.SetResume()
+	}
+
+	return hints
+}
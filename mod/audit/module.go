@@ -0,0 +1,34 @@
+package audit
+
+import (
+	"github.com/cryptopunkscc/astrald/auth/id"
+	"time"
+)
+
+const ModuleName = "audit"
+const DBPrefix = "audit__"
+
+type Module interface {
+	// Find returns logged decisions matching the filter, newest first
+	Find(filter *Filter) ([]*Entry, error)
+}
+
+// Entry is a logged authorization decision. Times are in UTC.
+type Entry struct {
+	Time       time.Time
+	Identity   id.Identity
+	Action     string
+	Args       string // arguments of the action formatted as text
+	Allowed    bool
+	Authorizer string // authorizer that made the decision, empty if nothing allowed the action
+}
+
+// Filter narrows down the results of Find. Zero values match everything.
+type Filter struct {
+	Identity id.Identity
+	Action   string // action name or a prefix ending with ".*"
+	Since    time.Time
+	Until    time.Time
+	Denied   bool // return only denied actions
+	Limit    int
+}
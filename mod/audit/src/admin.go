@@ -0,0 +1,162 @@
+package audit
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/cryptopunkscc/astrald/mod/admin"
+	"github.com/cryptopunkscc/astrald/mod/audit"
+	"time"
+)
+
+const timeFormat = "2006-01-02 15:04:05"
+
+type Admin struct {
+	mod  *Module
+	cmds map[string]func(admin.Terminal, []string) error
+}
+
+func NewAdmin(mod *Module) *Admin {
+	var adm = &Admin{mod: mod}
+	adm.cmds = map[string]func(admin.Terminal, []string) error{
+		"log":   adm.logs,
+		"prune": adm.prune,
+		"help":  adm.help,
+	}
+
+	return adm
+}
+
+func (adm *Admin) Exec(term admin.Terminal, args []string) error {
+	if len(args) < 2 {
+		return adm.help(term, []string{})
+	}
+
+	cmd, args := args[1], args[2:]
+	if fn, found := adm.cmds[cmd]; found {
+		return fn(term, args)
+	}
+
+	return errors.New("unknown command")
+}
+
+// logs lists logged decisions matching the options
+func (adm *Admin) logs(term admin.Terminal, args []string) error {
+	flags := flag.NewFlagSet("audit log", flag.ContinueOnError)
+	flags.SetOutput(term)
+	flags.Usage = func() {
+		term.Printf("Usage:\n\n  audit log [options]\n\nOptions:\n")
+		flags.PrintDefaults()
+	}
+	var identity = flags.String("i", "", "only decisions about this identity")
+	var action = flags.String("a", "", "only this action (or a prefix ending with .*)")
+	var since = flags.String("since", "", "only decisions since a date, time or duration ago (e.g. 2024-01-02, 1h)")
+	var until = flags.String("until", "", "only decisions before a date, time or duration ago")
+	var denied = flags.Bool("denied", false, "only denied actions")
+	var limit = flags.Int("n", 50, "maximum number of decisions (0 for all)")
+	err := flags.Parse(args)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+
+	var filter = &audit.Filter{
+		Action: *action,
+		Denied: *denied,
+		Limit:  *limit,
+	}
+
+	if *identity != "" {
+		filter.Identity, err = adm.mod.node.Resolver().Resolve(*identity)
+		if err != nil {
+			return err
+		}
+	}
+
+	if filter.Since, err = parseTime(*since); err != nil {
+		return err
+	}
+	if filter.Until, err = parseTime(*until); err != nil {
+		return err
+	}
+
+	list, err := adm.mod.Find(filter)
+	if err != nil {
+		return err
+	}
+
+	var f = "%-19s %-20s %-7s %-20s %-16s %s\n"
+	term.Printf(f,
+		admin.Header("Time"),
+		admin.Header("Identity"),
+		admin.Header("Result"),
+		admin.Header("Action"),
+		admin.Header("Authorizer"),
+		admin.Header("Args"),
+	)
+
+	// print the oldest first
+	for i := len(list) - 1; i >= 0; i-- {
+		var e = list[i]
+		var result = "denied"
+		if e.Allowed {
+			result = "allowed"
+		}
+
+		term.Printf(f,
+			e.Time.Format(timeFormat),
+			e.Identity,
+			result,
+			e.Action,
+			e.Authorizer,
+			e.Args,
+		)
+	}
+
+	return nil
+}
+
+func (adm *Admin) prune(term admin.Terminal, _ []string) error {
+	n, err := adm.mod.prune()
+	if err != nil {
+		return err
+	}
+
+	term.Printf("removed %d decisions\n", n)
+
+	return nil
+}
+
+func (adm *Admin) ShortDescription() string {
+	return "authorization audit log"
+}
+
+func (adm *Admin) help(term admin.Terminal, _ []string) error {
+	term.Printf("usage: %s <command>\n\n", audit.ModuleName)
+	term.Printf("commands:\n")
+	term.Printf("  log [options]  list authorization decisions (see log -h)\n")
+	term.Printf("  prune          remove decisions older than the retention period\n")
+	term.Printf("  help           show help\n")
+	return nil
+}
+
+// parseTime parses a date, a date with time or a duration before now
+func parseTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+
+	if d, err := time.ParseDuration(s); err == nil {
+		return time.Now().Add(-d), nil
+	}
+
+	for _, layout := range []string{timeFormat, time.DateOnly, time.RFC3339} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("invalid time: %s", s)
+}
@@ -0,0 +1,125 @@
+package audit
+
+import (
+	"github.com/cryptopunkscc/astrald/auth/id"
+	"github.com/cryptopunkscc/astrald/mod/audit"
+	"github.com/cryptopunkscc/astrald/node/authorizer"
+	"github.com/cryptopunkscc/astrald/object"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"testing"
+	"time"
+)
+
+func TestAuditLog(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&dbEntry{}); err != nil {
+		t.Fatal(err)
+	}
+
+	var mod = &Module{
+		db:     db,
+		config: Config{Retention: time.Hour, Ignore: []string{"ignored.*"}},
+		flush:  make(chan struct{}, 1),
+	}
+
+	alice, _ := id.GenerateIdentity()
+	bob, _ := id.GenerateIdentity()
+	var objectID = object.ID{Size: 1, Hash: [32]byte{1}}
+
+	mod.record(authorizer.EventDecision{Identity: alice, Action: "objects.read", Args: []any{objectID}, Allowed: true, Authorizer: "mod.user"})
+	mod.record(authorizer.EventDecision{Identity: bob, Action: "objects.read", Args: []any{objectID}})
+	mod.record(authorizer.EventDecision{Identity: bob, Action: "mod.admin.access"})
+	mod.record(authorizer.EventDecision{Action: "objects.search"})
+	mod.record(authorizer.EventDecision{Identity: alice, Action: "ignored.action"})
+
+	var tests = []struct {
+		filter *audit.Filter
+		count  int
+	}{
+		{nil, 4},
+		{&audit.Filter{Identity: bob}, 2},
+		{&audit.Filter{Action: "objects.*"}, 3},
+		{&audit.Filter{Action: "objects.read", Denied: true}, 1},
+		{&audit.Filter{Since: time.Now().Add(-time.Minute)}, 4},
+		{&audit.Filter{Until: time.Now().Add(-time.Minute)}, 0},
+		{&audit.Filter{Limit: 2}, 2},
+	}
+
+	for _, test := range tests {
+		list, err := mod.Find(test.filter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(list) != test.count {
+			t.Errorf("filter %+v: expected %d entries, got %d", test.filter, test.count, len(list))
+		}
+	}
+
+	list, err := mod.Find(&audit.Filter{Identity: alice})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || !list[0].Allowed || list[0].Authorizer != "mod.user" || list[0].Args != objectID.String() {
+		t.Fatalf("unexpected entries %+v", list)
+	}
+
+	// anonymous callers are logged with a zero identity
+	list, _ = mod.Find(&audit.Filter{Action: "objects.search"})
+	if len(list) != 1 || !list[0].Identity.IsZero() {
+		t.Fatalf("unexpected entries %+v", list)
+	}
+
+	db.Model(&dbEntry{}).Where("identity = ?", bob.PublicKeyHex()).
+		Update("created_at", time.Now().UTC().Add(-2*time.Hour))
+
+	n, err := mod.prune()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Fatalf("expected 2 pruned entries, got %d", n)
+	}
+}
+
+func TestAuditDenialsSaved(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&dbEntry{}); err != nil {
+		t.Fatal(err)
+	}
+
+	var mod = &Module{
+		db:    db,
+		flush: make(chan struct{}, 1),
+	}
+
+	alice, _ := id.GenerateIdentity()
+
+	var count = func() (n int64) {
+		db.Model(&dbEntry{}).Count(&n)
+		return
+	}
+
+	mod.record(authorizer.EventDecision{Identity: alice, Action: "objects.read", Allowed: true})
+	if n := count(); n != 0 {
+		t.Fatalf("allowed decision saved before a flush, %d entries", n)
+	}
+
+	// a denial is saved right away along with the decisions buffered before it
+	mod.record(authorizer.EventDecision{Identity: alice, Action: "mod.admin.access"})
+	if n := count(); n != 2 {
+		t.Fatalf("expected 2 saved entries, got %d", n)
+	}
+
+	var rows []*dbEntry
+	db.Order("id").Find(&rows)
+	if !rows[0].Allowed || rows[1].Allowed {
+		t.Fatal("entries saved out of order")
+	}
+}
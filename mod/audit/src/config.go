@@ -0,0 +1,26 @@
+package audit
+
+import "time"
+
+type Config struct {
+	// Retention sets how long decisions are kept. Zero keeps them forever.
+	Retention time.Duration `yaml:"retention"`
+
+	// FlushInterval sets how often buffered decisions are saved to the database. Allowed decisions made within
+	// the interval before a crash are lost. Denials are saved right away.
+	FlushInterval time.Duration `yaml:"flush_interval"`
+
+	// Ignore lists actions that are not logged (names or prefixes ending with ".*")
+	Ignore []string `yaml:"ignore"`
+}
+
+var defaultConfig = Config{
+	Retention:     30 * 24 * time.Hour,
+	FlushInterval: 5 * time.Second,
+}
+
+// pruneInterval sets how often decisions older than the retention period are removed
+const pruneInterval = time.Hour
+
+// maxBuffered is the number of buffered decisions that triggers an early flush
+const maxBuffered = 1000
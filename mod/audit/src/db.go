@@ -0,0 +1,35 @@
+package audit
+
+import (
+	"github.com/cryptopunkscc/astrald/auth/id"
+	"github.com/cryptopunkscc/astrald/mod/audit"
+	"time"
+)
+
+type dbEntry struct {
+	ID         uint      `gorm:"primarykey"`
+	CreatedAt  time.Time `gorm:"index"`
+	Identity   string    `gorm:"index"` // public key in hex, empty for anonymous callers
+	Action     string    `gorm:"index"`
+	Args       string
+	Allowed    bool
+	Authorizer string
+}
+
+func (dbEntry) TableName() string {
+	return audit.DBPrefix + "entries"
+}
+
+func (e *dbEntry) toEntry() *audit.Entry {
+	// the identity of anonymous callers stays zero
+	identity, _ := id.ParsePublicKeyHex(e.Identity)
+
+	return &audit.Entry{
+		Time:       e.CreatedAt,
+		Identity:   identity,
+		Action:     e.Action,
+		Args:       e.Args,
+		Allowed:    e.Allowed,
+		Authorizer: e.Authorizer,
+	}
+}
@@ -0,0 +1,40 @@
+package audit
+
+import (
+	"github.com/cryptopunkscc/astrald/log"
+	"github.com/cryptopunkscc/astrald/mod/audit"
+	"github.com/cryptopunkscc/astrald/node/assets"
+	"github.com/cryptopunkscc/astrald/node/modules"
+)
+
+type Loader struct{}
+
+func (Loader) Load(node modules.Node, assets assets.Assets, log *log.Logger) (modules.Module, error) {
+	var mod = &Module{
+		node:   node,
+		config: defaultConfig,
+		log:    log,
+		flush:  make(chan struct{}, 1),
+	}
+
+	_ = assets.LoadYAML(audit.ModuleName, &mod.config)
+
+	if mod.config.FlushInterval <= 0 {
+		mod.config.FlushInterval = defaultConfig.FlushInterval
+	}
+
+	mod.db = assets.Database()
+
+	err := mod.db.AutoMigrate(&dbEntry{})
+	if err != nil {
+		return nil, err
+	}
+
+	return mod, nil
+}
+
+func init() {
+	if err := modules.RegisterModule(audit.ModuleName, Loader{}); err != nil {
+		panic(err)
+	}
+}
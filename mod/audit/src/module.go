@@ -0,0 +1,197 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"github.com/cryptopunkscc/astrald/log"
+	"github.com/cryptopunkscc/astrald/mod/audit"
+	"github.com/cryptopunkscc/astrald/node"
+	"github.com/cryptopunkscc/astrald/node/authorizer"
+	"github.com/cryptopunkscc/astrald/node/events"
+	"gorm.io/gorm"
+	"strings"
+	"sync"
+	"time"
+)
+
+var _ audit.Module = &Module{}
+
+type Module struct {
+	config Config
+	node   node.Node
+	log    *log.Logger
+	db     *gorm.DB
+
+	mu      sync.Mutex
+	buffer  []*dbEntry
+	flush   chan struct{}
+	flushMu sync.Mutex // keeps flushes in order, so that entry ids follow the order of decisions
+}
+
+func (mod *Module) Run(ctx context.Context) error {
+	var handled = make(chan struct{})
+	go func() {
+		defer close(handled)
+		events.Handle(ctx, mod.node.Events(), func(e authorizer.EventDecision) error {
+			mod.record(e)
+			return nil
+		})
+	}()
+
+	mod.prune()
+
+	var flushTicker = time.NewTicker(mod.config.FlushInterval)
+	defer flushTicker.Stop()
+
+	var pruneTicker = time.NewTicker(pruneInterval)
+	defer pruneTicker.Stop()
+
+	for {
+		select {
+		case <-flushTicker.C:
+			mod.flushBuffer()
+		case <-mod.flush:
+			mod.flushBuffer()
+		case <-pruneTicker.C:
+			mod.prune()
+		case <-ctx.Done():
+			// save what the handler recorded before it stopped
+			<-handled
+			mod.flushBuffer()
+			return nil
+		}
+	}
+}
+
+func (mod *Module) Find(filter *audit.Filter) ([]*audit.Entry, error) {
+	mod.flushBuffer()
+
+	var tx = mod.db.Model(&dbEntry{})
+
+	if filter != nil {
+		if !filter.Identity.IsZero() {
+			tx = tx.Where("identity = ?", filter.Identity.PublicKeyHex())
+		}
+		if prefix, found := strings.CutSuffix(filter.Action, ".*"); found {
+			tx = tx.Where(`action LIKE ? ESCAPE '\'`, escapeLike(prefix)+".%")
+		} else if filter.Action != "" {
+			tx = tx.Where("action = ?", filter.Action)
+		}
+		if !filter.Since.IsZero() {
+			tx = tx.Where("created_at >= ?", filter.Since.UTC())
+		}
+		if !filter.Until.IsZero() {
+			tx = tx.Where("created_at < ?", filter.Until.UTC())
+		}
+		if filter.Denied {
+			tx = tx.Where("allowed = ?", false)
+		}
+		if filter.Limit > 0 {
+			tx = tx.Limit(filter.Limit)
+		}
+	}
+
+	var rows []*dbEntry
+	err := tx.Order("id desc").Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var list = make([]*audit.Entry, 0, len(rows))
+	for _, row := range rows {
+		list = append(list, row.toEntry())
+	}
+
+	return list, nil
+}
+
+// record buffers a decision until the next flush. Allowed decisions that are still buffered are lost if the node
+// stops without a clean shutdown, which is at most FlushInterval or maxBuffered decisions. Denials are what
+// the log is mostly read for, so a denial is saved right away together with the buffered decisions before it.
+func (mod *Module) record(e authorizer.EventDecision) {
+	if mod.ignored(e.Action) {
+		return
+	}
+
+	var args = make([]string, 0, len(e.Args))
+	for _, arg := range e.Args {
+		args = append(args, fmt.Sprint(arg))
+	}
+
+	mod.mu.Lock()
+	mod.buffer = append(mod.buffer, &dbEntry{
+		CreatedAt:  time.Now().UTC(),
+		Identity:   e.Identity.PublicKeyHex(),
+		Action:     e.Action,
+		Args:       strings.Join(args, " "),
+		Allowed:    e.Allowed,
+		Authorizer: e.Authorizer,
+	})
+
+	var full = len(mod.buffer) >= maxBuffered
+	mod.mu.Unlock()
+
+	switch {
+	case !e.Allowed:
+		mod.flushBuffer()
+
+	case full:
+		select {
+		case mod.flush <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (mod *Module) flushBuffer() {
+	mod.flushMu.Lock()
+	defer mod.flushMu.Unlock()
+
+	mod.mu.Lock()
+	var buffer = mod.buffer
+	mod.buffer = nil
+	mod.mu.Unlock()
+
+	if len(buffer) == 0 {
+		return
+	}
+
+	err := mod.db.CreateInBatches(buffer, 100).Error
+	if err != nil {
+		mod.log.Error("error saving %v decisions: %v", len(buffer), err)
+	}
+}
+
+// prune removes decisions older than the retention period
+func (mod *Module) prune() (int64, error) {
+	if mod.config.Retention <= 0 {
+		return 0, nil
+	}
+
+	var tx = mod.db.
+		Where("created_at < ?", time.Now().UTC().Add(-mod.config.Retention)).
+		Delete(&dbEntry{})
+	if tx.Error != nil {
+		mod.log.Error("error pruning decisions: %v", tx.Error)
+	}
+
+	return tx.RowsAffected, tx.Error
+}
+
+func (mod *Module) ignored(action string) bool {
+	for _, pattern := range mod.config.Ignore {
+		if prefix, found := strings.CutSuffix(pattern, ".*"); found {
+			if strings.HasPrefix(action, prefix+".") {
+				return true
+			}
+		} else if pattern == action {
+			return true
+		}
+	}
+	return false
+}
+
+func escapeLike(s string) string {
+	var r = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return r.Replace(s)
+}
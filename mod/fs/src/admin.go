@@ -1,11 +1,15 @@
 package fs
 
 import (
+	"context"
 	"errors"
 	"github.com/cryptopunkscc/astrald/log"
 	"github.com/cryptopunkscc/astrald/mod/admin"
 	"github.com/cryptopunkscc/astrald/object"
+	"path/filepath"
 	"slices"
+	"strconv"
+	"time"
 )
 
 type Admin struct {
@@ -22,6 +26,8 @@ func NewAdmin(mod *Module) *Admin {
 		"find":   adm.find,
 		"path":   adm.path,
 		"info":   adm.info,
+		"chunks": adm.chunks,
+		"gc":     adm.gc,
 		"help":   adm.help,
 	}
 
@@ -134,6 +140,108 @@ func (adm *Admin) info(term admin.Terminal, args []string) error {
 	for _, path := range paths {
 		term.Printf("%s\n", path)
 	}
+
+	stats, err := adm.mod.chunkStats()
+	if err != nil {
+		return err
+	}
+
+	term.Printf("\n%s\n", admin.Header("Chunked Storage"))
+	term.Printf("Enabled:       %v\n", adm.mod.config.Chunked)
+	term.Printf("Objects:       %d (%v)\n", stats.Objects, log.DataSize(stats.LogicalSize))
+	term.Printf("Chunks:        %d (%v)\n", stats.Chunks, log.DataSize(stats.StoredSize))
+	if stats.LogicalSize > stats.StoredSize {
+		term.Printf("Saved:         %v (%.2fx)\n", log.DataSize(stats.LogicalSize-stats.StoredSize), stats.Ratio())
+	}
+
+	return nil
+}
+
+func (adm *Admin) chunks(term admin.Terminal, args []string) error {
+	if len(args) < 1 {
+		return errors.New("missing argument")
+	}
+
+	objectID, err := object.ParseID(args[0])
+	if err != nil {
+		return err
+	}
+
+	var rows []*dbObjectChunk
+	err = adm.mod.db.Where("object_id = ?", objectID).Order("seq").Find(&rows).Error
+	if err != nil {
+		return err
+	}
+	if len(rows) == 0 {
+		return errors.New("object not in chunked storage")
+	}
+
+	var f = "%-64s %12s %10s %6s\n"
+	term.Printf(f, admin.Header("Chunk"), admin.Header("Offset"), admin.Header("Size"), admin.Header("Refs"))
+
+	var shared uint64
+	for _, row := range rows {
+		var chunk dbChunk
+		adm.mod.db.Where("id = ?", row.ChunkID).First(&chunk)
+		if chunk.Refs > 1 {
+			shared += row.Size
+		}
+
+		term.Printf(f, row.ChunkID, strconv.FormatUint(row.Offset, 10), log.DataSize(row.Size), strconv.Itoa(chunk.Refs))
+	}
+
+	term.Printf("%d chunks, %v in shared chunks\n", len(rows), log.DataSize(shared))
+
+	return nil
+}
+
+func (adm *Admin) gc(term admin.Terminal, args []string) error {
+	var dryRun = slices.Contains(args, "-n")
+
+	report, err := adm.mod.collect(context.Background(), dryRun)
+	if err != nil {
+		return err
+	}
+
+	var f = "%-64s %10s %-19s %s\n"
+	term.Printf(f, admin.Header("ID"), admin.Header("Size"), admin.Header("Last Access"), admin.Header("Reason"))
+
+	var freed uint64
+	for _, o := range report.Garbage {
+		freed += o.Size()
+		term.Printf(f, o.ObjectID, log.DataSize(o.Size()), o.AccessedAt.Format(time.DateTime), admin.Keyword(o.Reason))
+	}
+
+	var quotas = make(map[string]uint64)
+	for path, quota := range adm.mod.config.GC.Quotas {
+		quotas[filepath.Clean(path)] = quota
+	}
+
+	var stores []string
+	for store := range report.Usage {
+		stores = append(stores, store)
+	}
+	slices.Sort(stores)
+
+	f = "%-64s %10s %10s %10s\n"
+	term.Printf("\n"+f, admin.Header("Store Path"), admin.Header("Used"), admin.Header("Quota"), admin.Header("Freed"))
+	for _, store := range stores {
+		var quota = "-"
+		if q, found := quotas[store]; found {
+			quota = log.DataSize(q).HumanReadable()
+		}
+
+		term.Printf(f, store, log.DataSize(report.Usage[store]), quota, log.DataSize(report.Freed(store)))
+	}
+
+	term.Printf("\n")
+	if dryRun {
+		term.Printf("%d object(s) would be purged (%v), ", len(report.Garbage), log.DataSize(freed))
+	} else {
+		term.Printf("purged %d object(s) (%v), ", report.Purged, log.DataSize(freed))
+	}
+	term.Printf("%d reachable object(s) kept\n", report.Reachable)
+
 	return nil
 }
 
@@ -147,7 +255,9 @@ func (adm *Admin) help(term admin.Terminal, _ []string) error {
 	term.Printf("  watch <path>               watch a directory tree for changes\n")
 	term.Printf("  find                       list all indexed files\n")
 	term.Printf("  path <objectID>            show local path(s) for the object\n")
-	term.Printf("  info                       show index info\n")
+	term.Printf("  info                       show index and chunked storage info\n")
+	term.Printf("  chunks <objectID>          show chunks of an object in chunked storage\n")
+	term.Printf("  gc [-n]                    purge unreachable objects (-n for a dry run)\n")
 	term.Printf("  help                       show help\n")
 	return nil
 }
@@ -0,0 +1,90 @@
+package fs
+
+import (
+	"errors"
+	"io"
+	"os"
+)
+
+// ChunkReader reads an object from chunked storage
+type ChunkReader struct {
+	chunks []readerChunk
+	size   int64
+	pos    int64
+	file   *os.File // currently open chunk
+	index  int      // index of the open chunk
+}
+
+type readerChunk struct {
+	path   string
+	offset int64
+	size   int64
+}
+
+func (r *ChunkReader) Read(p []byte) (n int, err error) {
+	if r.pos >= r.size {
+		return 0, io.EOF
+	}
+
+	// find the chunk that contains the current position
+	var index = r.index
+	for index > 0 && r.chunks[index].offset > r.pos {
+		index--
+	}
+	for index < len(r.chunks)-1 && r.chunks[index].offset+r.chunks[index].size <= r.pos {
+		index++
+	}
+
+	if r.file == nil || index != r.index {
+		if r.file != nil {
+			r.file.Close()
+			r.file = nil
+		}
+		r.file, err = os.Open(r.chunks[index].path)
+		if err != nil {
+			return 0, err
+		}
+		r.index = index
+	}
+
+	var chunk = r.chunks[index]
+	var left = chunk.offset + chunk.size - r.pos
+	if int64(len(p)) > left {
+		p = p[:left]
+	}
+
+	n, err = r.file.ReadAt(p, r.pos-chunk.offset)
+	r.pos += int64(n)
+	if errors.Is(err, io.EOF) && n == len(p) {
+		err = nil
+	}
+
+	return
+}
+
+func (r *ChunkReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += r.pos
+	case io.SeekEnd:
+		offset += r.size
+	default:
+		return r.pos, errors.New("invalid whence")
+	}
+
+	if offset < 0 {
+		return r.pos, errors.New("negative position")
+	}
+
+	r.pos = offset
+
+	return r.pos, nil
+}
+
+func (r *ChunkReader) Close() error {
+	if r.file != nil {
+		return r.file.Close()
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+package fs
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"github.com/cryptopunkscc/astrald/mod/objects"
+	"github.com/cryptopunkscc/astrald/net"
+	"github.com/cryptopunkscc/astrald/object"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+)
+
+var _ objects.Writer = &ChunkWriter{}
+
+// chunkDirName is the name of the directory inside a store path that holds chunks
+const chunkDirName = ".chunks"
+
+// ChunkWriter writes an object to chunked storage. Chunks already present in the storage are not written again.
+type ChunkWriter struct {
+	mod       *Module
+	path      string
+	buf       []byte
+	chunks    []object.ID
+	claimed   []object.ID
+	resolver  *object.WriteResolver
+	finalized atomic.Bool
+}
+
+func NewChunkWriter(mod *Module, path string) (*ChunkWriter, error) {
+	if err := os.MkdirAll(filepath.Join(path, chunkDirName), 0700); err != nil {
+		return nil, err
+	}
+
+	return &ChunkWriter{
+		mod:      mod,
+		path:     path,
+		resolver: object.NewWriteResolver(nil),
+	}, nil
+}
+
+func (w *ChunkWriter) Write(p []byte) (n int, err error) {
+	if w.finalized.Load() {
+		return 0, errors.New("writer closed")
+	}
+
+	w.resolver.Write(p)
+	w.buf = append(w.buf, p...)
+
+	for len(w.buf) >= maxChunkSize {
+		var size = chunkBoundary(w.buf)
+		if err := w.writeChunk(w.buf[:size]); err != nil {
+			return 0, err
+		}
+		w.buf = append(w.buf[:0], w.buf[size:]...)
+	}
+
+	return len(p), nil
+}
+
+func (w *ChunkWriter) Commit() (object.ID, error) {
+	if !w.finalized.CompareAndSwap(false, true) {
+		return object.ID{}, errors.New("writer closed")
+	}
+
+	defer w.cleanup()
+
+	for len(w.buf) > 0 || len(w.chunks) == 0 {
+		var size = chunkBoundary(w.buf)
+		if err := w.writeChunk(w.buf[:size]); err != nil {
+			return object.ID{}, err
+		}
+		w.buf = w.buf[size:]
+	}
+
+	objectID := w.resolver.Resolve()
+
+	if err := w.mod.indexChunks(objectID, w.path, w.chunks); err != nil {
+		return object.ID{}, err
+	}
+
+	w.mod.touch(objectID)
+
+	w.mod.events.Emit(objects.EventDiscovered{
+		ObjectID: objectID,
+		Zone:     net.ZoneDevice,
+	})
+
+	return objectID, nil
+}
+
+func (w *ChunkWriter) Discard() error {
+	if !w.finalized.CompareAndSwap(false, true) {
+		return errors.New("writer closed")
+	}
+
+	w.cleanup()
+
+	return nil
+}
+
+// writeChunk writes the chunk to the store path unless it's already there
+func (w *ChunkWriter) writeChunk(data []byte) error {
+	var chunkID = object.Resolve(data)
+	var path = chunkPath(w.path, chunkID)
+
+	w.chunks = append(w.chunks, chunkID)
+
+	// claim the chunk, so that other writers don't remove its file until we're done
+	w.mod.chunksMu.Lock()
+	w.mod.chunkClaims[chunkID]++
+	w.mod.chunksMu.Unlock()
+	w.claimed = append(w.claimed, chunkID)
+
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	var rbytes = make([]byte, 8)
+	rand.Read(rbytes)
+	var tempPath = filepath.Join(w.path, chunkDirName, tempFilePrefix+hex.EncodeToString(rbytes))
+
+	if err := os.WriteFile(tempPath, data, 0600); err != nil {
+		os.Remove(tempPath)
+		return err
+	}
+
+	if err := os.Rename(tempPath, path); err != nil {
+		os.Remove(tempPath)
+		return err
+	}
+
+	return nil
+}
+
+// cleanup releases claimed chunks and removes the files of those that didn't make it to the index
+func (w *ChunkWriter) cleanup() {
+	w.buf = nil
+
+	w.mod.chunksMu.Lock()
+	defer w.mod.chunksMu.Unlock()
+
+	for _, chunkID := range w.claimed {
+		if w.mod.chunkClaims[chunkID]--; w.mod.chunkClaims[chunkID] > 0 {
+			continue
+		}
+		delete(w.mod.chunkClaims, chunkID)
+
+		var row dbChunk
+		err := w.mod.db.Where("id = ?", chunkID).First(&row).Error
+		if err == nil && row.Path == w.path {
+			continue
+		}
+		os.Remove(chunkPath(w.path, chunkID))
+	}
+}
+
+// chunkPath returns the path of a chunk file in a store path
+func chunkPath(storePath string, chunkID object.ID) string {
+	var name = chunkID.String()
+	return filepath.Join(storePath, chunkDirName, name[len(name)-2:], name)
+}
@@ -0,0 +1,60 @@
+package fs
+
+// Objects in chunked storage are split at content-defined boundaries using a gear rolling hash (as in FastCDC).
+// A boundary depends only on the bytes since the previous boundary, so an insertion or a deletion changes only
+// the chunks around it and the remaining chunks are deduplicated.
+const (
+	minChunkSize = 16 * 1024
+	avgChunkSize = 64 * 1024
+	maxChunkSize = 256 * 1024
+)
+
+// boundary masks with more bits set before the average chunk size and fewer bits after it, which keeps
+// chunk sizes close to the average
+const (
+	chunkMaskS uint64 = 0x036269122de00000 // 18 bits
+	chunkMaskL uint64 = 0x0162491221e00000 // 14 bits
+)
+
+var gearTable [256]uint64
+
+func init() {
+	// fill the table with fixed pseudo-random values (splitmix64), chunk boundaries must never change
+	var x uint64 = 0x617374726f6e6f6d
+	for i := range gearTable {
+		x += 0x9e3779b97f4a7c15
+		z := x
+		z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+		z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+		gearTable[i] = z ^ (z >> 31)
+	}
+}
+
+// chunkBoundary returns the length of the first chunk of data. If data is shorter than maxChunkSize and has
+// no boundary, the whole data is returned as a chunk, so callers should only pass shorter data at the end
+// of the stream.
+func chunkBoundary(data []byte) int {
+	var n = min(len(data), maxChunkSize)
+	if n <= minChunkSize {
+		return n
+	}
+
+	var hash uint64
+	var i = minChunkSize
+
+	for ; i < min(n, avgChunkSize); i++ {
+		hash = (hash << 1) + gearTable[data[i]]
+		if hash&chunkMaskS == 0 {
+			return i + 1
+		}
+	}
+
+	for ; i < n; i++ {
+		hash = (hash << 1) + gearTable[data[i]]
+		if hash&chunkMaskL == 0 {
+			return i + 1
+		}
+	}
+
+	return n
+}
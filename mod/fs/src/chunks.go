@@ -0,0 +1,185 @@
+package fs
+
+import (
+	"errors"
+	"github.com/cryptopunkscc/astrald/mod/objects"
+	"github.com/cryptopunkscc/astrald/object"
+	"gorm.io/gorm"
+	"io"
+	"os"
+)
+
+// ChunkStats holds deduplication statistics of chunked storage
+type ChunkStats struct {
+	Objects     int    // number of objects in chunked storage
+	Chunks      int    // number of unique chunks
+	LogicalSize uint64 // total size of all objects
+	StoredSize  uint64 // total size of all unique chunks
+}
+
+// Ratio returns the deduplication ratio of the storage
+func (s ChunkStats) Ratio() float64 {
+	if s.StoredSize == 0 {
+		return 0
+	}
+	return float64(s.LogicalSize) / float64(s.StoredSize)
+}
+
+// indexChunks adds an object made of the chunks to the index. Chunk files must already be in the store path.
+func (mod *Module) indexChunks(objectID object.ID, path string, chunks []object.ID) error {
+	mod.chunksMu.Lock()
+	defer mod.chunksMu.Unlock()
+
+	return mod.db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		err := tx.Model(&dbObjectChunk{}).Where("object_id = ?", objectID).Count(&count).Error
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return nil // already stored
+		}
+
+		var offset uint64
+		for seq, chunkID := range chunks {
+			err = tx.Create(&dbObjectChunk{
+				ObjectID: objectID,
+				Seq:      seq,
+				ChunkID:  chunkID,
+				Offset:   offset,
+				Size:     chunkID.Size,
+			}).Error
+			if err != nil {
+				return err
+			}
+			offset += chunkID.Size
+
+			var row dbChunk
+			err = tx.Where("id = ?", chunkID).First(&row).Error
+			switch {
+			case err == nil:
+				err = tx.Model(&row).Update("refs", gorm.Expr("refs + 1")).Error
+			case errors.Is(err, gorm.ErrRecordNotFound):
+				err = tx.Create(&dbChunk{ID: chunkID, Path: path, Size: chunkID.Size, Refs: 1}).Error
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// openChunked opens an object from chunked storage
+func (mod *Module) openChunked(objectID object.ID, offset uint64) (objects.Reader, error) {
+	var rows []*dbObjectChunk
+
+	err := mod.db.Where("object_id = ?", objectID).Order("seq").Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, objects.ErrNotFound
+	}
+
+	var reader = &ChunkReader{size: int64(objectID.Size)}
+	for _, row := range rows {
+		var chunk dbChunk
+		if err := mod.db.Where("id = ?", row.ChunkID).First(&chunk).Error; err != nil {
+			return nil, err
+		}
+
+		reader.chunks = append(reader.chunks, readerChunk{
+			path:   chunkPath(chunk.Path, chunk.ID),
+			offset: int64(row.Offset),
+			size:   int64(row.Size),
+		})
+	}
+
+	if _, err := reader.Seek(int64(offset), io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	return &Reader{
+		ReadSeekCloser: reader,
+		name:           "chunked:" + objectID.String(),
+	}, nil
+}
+
+// purgeChunked removes an object from chunked storage along with the chunks no other object uses
+func (mod *Module) purgeChunked(objectID object.ID) (int, error) {
+	mod.chunksMu.Lock()
+	defer mod.chunksMu.Unlock()
+
+	var removed []*dbChunk
+	var found bool
+
+	err := mod.db.Transaction(func(tx *gorm.DB) error {
+		var rows []*dbObjectChunk
+		err := tx.Where("object_id = ?", objectID).Find(&rows).Error
+		if err != nil || len(rows) == 0 {
+			return err
+		}
+		found = true
+
+		for _, row := range rows {
+			var chunk dbChunk
+			err = tx.Where("id = ?", row.ChunkID).First(&chunk).Error
+			if err != nil {
+				return err
+			}
+
+			if chunk.Refs > 1 {
+				err = tx.Model(&chunk).Update("refs", gorm.Expr("refs - 1")).Error
+			} else {
+				err = tx.Delete(&chunk).Error
+				removed = append(removed, &chunk)
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		return tx.Where("object_id = ?", objectID).Delete(&dbObjectChunk{}).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for _, chunk := range removed {
+		if mod.chunkClaims[chunk.ID] > 0 {
+			continue
+		}
+		os.Remove(chunkPath(chunk.Path, chunk.ID))
+	}
+
+	if found {
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// chunkStats returns deduplication statistics of chunked storage
+func (mod *Module) chunkStats() (stats ChunkStats, err error) {
+	var row struct {
+		Count int
+		Size  uint64
+	}
+
+	err = mod.db.Model(&dbChunk{}).Select("count(*) as count, coalesce(sum(size), 0) as size").Scan(&row).Error
+	if err != nil {
+		return
+	}
+	stats.Chunks, stats.StoredSize = row.Count, row.Size
+
+	err = mod.db.Model(&dbObjectChunk{}).
+		Select("count(distinct object_id) as count, coalesce(sum(size), 0) as size").
+		Scan(&row).Error
+	if err != nil {
+		return
+	}
+	stats.Objects, stats.LogicalSize = row.Count, row.Size
+
+	return
+}
@@ -0,0 +1,106 @@
+package fs
+
+import (
+	"bytes"
+	"github.com/cryptopunkscc/astrald/mod/objects"
+	"github.com/cryptopunkscc/astrald/object"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestChunkedStorage(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&dbChunk{}, &dbObjectChunk{}); err != nil {
+		t.Fatal(err)
+	}
+
+	var mod = &Module{db: db, chunkClaims: make(map[object.ID]int)}
+	var dir = t.TempDir()
+
+	var original = make([]byte, 4*1024*1024)
+	rand.New(rand.NewSource(1)).Read(original)
+
+	// insert some data in the middle
+	var edited = append([]byte{}, original[:1500000]...)
+	edited = append(edited, []byte("a few inserted bytes")...)
+	edited = append(edited, original[1500000:]...)
+
+	var ids []object.ID
+	for _, data := range [][]byte{original, edited} {
+		w, err := NewChunkWriter(mod, dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// write in odd-sized pieces to make sure boundaries don't depend on writes
+		for buf := data; len(buf) > 0; {
+			n := min(len(buf), 77777)
+			if _, err := w.Write(buf[:n]); err != nil {
+				t.Fatal(err)
+			}
+			buf = buf[n:]
+		}
+		objectID, err := w.Commit()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !objectID.IsEqual(object.Resolve(data)) {
+			t.Fatalf("object id mismatch")
+		}
+		ids = append(ids, objectID)
+	}
+
+	stats, err := mod.chunkStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Objects != 2 || stats.LogicalSize != uint64(len(original)+len(edited)) {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+	if stats.StoredSize > uint64(len(edited))+2*maxChunkSize {
+		t.Fatalf("stored %d bytes, expected most chunks to be shared", stats.StoredSize)
+	}
+
+	for i, data := range [][]byte{original, edited} {
+		r, err := mod.openChunked(ids[i], 1000)
+		if err != nil {
+			t.Fatal(err)
+		}
+		read, err := io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(read, data[1000:]) {
+			t.Fatalf("read data does not match")
+		}
+	}
+
+	for _, objectID := range ids {
+		if n, err := mod.purgeChunked(objectID); n != 1 || err != nil {
+			t.Fatalf("purge returned %d, %v", n, err)
+		}
+	}
+
+	if _, err := mod.openChunked(ids[0], 0); err != objects.ErrNotFound {
+		t.Fatalf("open after purge returned %v", err)
+	}
+
+	var files int
+	filepath.Walk(filepath.Join(dir, chunkDirName), func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			files++
+		}
+		return nil
+	})
+	if files != 0 {
+		t.Fatalf("%d chunk files left after purge", files)
+	}
+}
@@ -1,8 +1,48 @@
 package fs
 
+import "time"
+
 type Config struct {
 	Watch []string // list of paths to index for read-only storage
 	Store []string // list of paths to use for read-write storage
+
+	// Store new objects in content-defined chunks, so that similar objects share the storage of their common parts
+	Chunked bool
+
+	// GC configures the removal of unreachable objects from the store paths
+	GC GCConfig `yaml:"gc"`
+}
+
+// GCConfig sets the retention policies of the store paths. An object is unreachable when it has no
+// holders and doesn't belong to any set. Objects in watched paths are never removed.
+type GCConfig struct {
+	// Interval sets how often the collector runs in the background. Zero disables background runs.
+	Interval time.Duration `yaml:"interval"`
+
+	// MaxAge removes unreachable objects that weren't accessed for longer than this. Zero keeps them forever.
+	MaxAge time.Duration `yaml:"max_age"`
+
+	// CacheBytes limits the total size of unreachable objects kept in the store paths. Least recently
+	// used objects are evicted first. Zero means no limit.
+	CacheBytes uint64 `yaml:"cache_bytes"`
+
+	// Quotas limit the size of objects in store paths (by path). Unreachable objects are evicted from
+	// a path over its quota, least recently used first.
+	Quotas map[string]uint64 `yaml:"quotas"`
+
+	// Rate limits the number of objects purged per second. Zero means no limit.
+	Rate int `yaml:"rate"`
 }
 
-var defaultConfig = Config{}
+var defaultConfig = Config{
+	GC: GCConfig{
+		Interval: time.Hour,
+		Rate:     10,
+	},
+}
+
+// gcGracePeriod protects new objects before their creators get a chance to hold them
+const gcGracePeriod = time.Hour
+
+// accessResolution limits how often the access time of an object is updated
+const accessResolution = time.Minute
@@ -0,0 +1,15 @@
+package fs
+
+import (
+	"github.com/cryptopunkscc/astrald/mod/fs"
+	"github.com/cryptopunkscc/astrald/object"
+	"time"
+)
+
+// dbAccess holds the last access time of an object in a store path
+type dbAccess struct {
+	ObjectID   object.ID `gorm:"primaryKey"`
+	AccessedAt time.Time `gorm:"index"`
+}
+
+func (dbAccess) TableName() string { return fs.DBPrefix + "access" }
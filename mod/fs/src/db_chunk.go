@@ -0,0 +1,27 @@
+package fs
+
+import (
+	"github.com/cryptopunkscc/astrald/mod/fs"
+	"github.com/cryptopunkscc/astrald/object"
+)
+
+// dbChunk is a chunk stored in one of the store paths
+type dbChunk struct {
+	ID   object.ID `gorm:"primaryKey"`
+	Path string    // store path that holds the chunk
+	Size uint64
+	Refs int // number of dbObjectChunk rows using the chunk
+}
+
+func (dbChunk) TableName() string { return fs.DBPrefix + "chunks" }
+
+// dbObjectChunk is a chunk of an object in chunked storage
+type dbObjectChunk struct {
+	ObjectID object.ID `gorm:"primaryKey"`
+	Seq      int       `gorm:"primaryKey"`
+	ChunkID  object.ID `gorm:"index"`
+	Offset   uint64
+	Size     uint64
+}
+
+func (dbObjectChunk) TableName() string { return fs.DBPrefix + "object_chunks" }
@@ -6,6 +6,7 @@ import (
 	"github.com/cryptopunkscc/astrald/mod/admin"
 	"github.com/cryptopunkscc/astrald/mod/content"
 	"github.com/cryptopunkscc/astrald/mod/fs"
+	"github.com/cryptopunkscc/astrald/mod/metrics"
 	"github.com/cryptopunkscc/astrald/mod/objects"
 	"github.com/cryptopunkscc/astrald/mod/sets"
 	"github.com/cryptopunkscc/astrald/node/modules"
@@ -44,6 +45,11 @@ func (mod *Module) LoadDependencies() error {
 		adm.AddCommand(fs.ModuleName, NewAdmin(mod))
 	}
 
+	// optional
+	if m, err := modules.Load[metrics.Module](mod.node, metrics.ModuleName); err == nil {
+		m.AddCollector(mod)
+	}
+
 	mod.objects.AddSearcher(NewFinder(mod))
 	mod.objects.AddPrototypes(fs.FileDesc{})
 
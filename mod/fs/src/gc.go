@@ -0,0 +1,355 @@
+package fs
+
+import (
+	"context"
+	"github.com/cryptopunkscc/astrald/log"
+	"github.com/cryptopunkscc/astrald/object"
+	"gorm.io/gorm/clause"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+const (
+	gcReasonAge   = "age"
+	gcReasonCache = "cache"
+	gcReasonQuota = "quota"
+)
+
+// gcObject is an unreachable object in the store paths
+type gcObject struct {
+	ObjectID   object.ID
+	Stores     map[string]uint64 // bytes freed in each store path by purging the object
+	AccessedAt time.Time
+	Reason     string
+}
+
+// Size returns the total number of bytes freed by purging the object
+func (o *gcObject) Size() (size uint64) {
+	for _, n := range o.Stores {
+		size += n
+	}
+	return
+}
+
+// gcReport is the result of a collection
+type gcReport struct {
+	Usage     map[string]uint64 // usage of store paths before the collection
+	Reachable int               // number of stored objects that are reachable
+	Garbage   []*gcObject       // objects selected for purging
+	Purged    int               // number of objects purged
+}
+
+// Freed returns the number of bytes freed in a store path
+func (r *gcReport) Freed(store string) (size uint64) {
+	for _, o := range r.Garbage {
+		size += o.Stores[store]
+	}
+	return
+}
+
+// runGC runs the collector at the configured interval
+func (mod *Module) runGC(ctx context.Context) {
+	if mod.config.GC.Interval <= 0 {
+		return
+	}
+
+	var ticker = time.NewTicker(mod.config.GC.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		report, err := mod.collect(ctx, false)
+		if err != nil {
+			mod.log.Error("gc error: %v", err)
+			continue
+		}
+
+		if report.Purged > 0 {
+			var freed uint64
+			for _, o := range report.Garbage {
+				freed += o.Size()
+			}
+			mod.log.Info("gc purged %v objects (%v)", report.Purged, log.DataSize(freed))
+		}
+	}
+}
+
+// collect purges unreachable objects selected by the retention policies. In a dry run it only
+// reports the objects it would purge.
+func (mod *Module) collect(ctx context.Context, dryRun bool) (*gcReport, error) {
+	mod.gcMu.Lock()
+	defer mod.gcMu.Unlock()
+
+	stored, usage, err := mod.storedObjects()
+	if err != nil {
+		return nil, err
+	}
+
+	var report = &gcReport{Usage: usage}
+	var now = time.Now()
+	var unreachable []*gcObject
+
+	for _, o := range stored {
+		if mod.isReachable(o.ObjectID) {
+			report.Reachable++
+			continue
+		}
+
+		o.AccessedAt, err = mod.accessedAt(o.ObjectID, now, dryRun)
+		if err != nil {
+			return nil, err
+		}
+
+		unreachable = append(unreachable, o)
+	}
+
+	report.Garbage = selectGarbage(unreachable, usage, mod.config.GC, now)
+
+	if dryRun {
+		return report, nil
+	}
+
+	var limit <-chan time.Time
+	if mod.config.GC.Rate > 0 {
+		var ticker = time.NewTicker(time.Second / time.Duration(mod.config.GC.Rate))
+		defer ticker.Stop()
+		limit = ticker.C
+	}
+
+	for _, o := range report.Garbage {
+		if limit != nil {
+			select {
+			case <-ctx.Done():
+				return report, ctx.Err()
+			case <-limit:
+			}
+		}
+
+		// the object could have been held since the scan
+		if mod.isReachable(o.ObjectID) {
+			continue
+		}
+
+		n, err := mod.objects.Purge(o.ObjectID, nil)
+		if err != nil {
+			mod.log.Errorv(1, "gc: error purging %v: %v", o.ObjectID, err)
+		}
+		if n > 0 {
+			mod.log.Logv(1, "gc: purged %v (%v)", o.ObjectID, o.Reason)
+			report.Purged++
+		}
+	}
+
+	return report, nil
+}
+
+// selectGarbage selects unreachable objects to purge according to the config
+func selectGarbage(unreachable []*gcObject, usage map[string]uint64, config GCConfig, now time.Time) (garbage []*gcObject) {
+	var usageLeft = make(map[string]uint64)
+	for store, n := range usage {
+		usageLeft[store] = n
+	}
+
+	var cached uint64
+	var lru []*gcObject
+
+	for _, o := range unreachable {
+		cached += o.Size()
+
+		if now.Sub(o.AccessedAt) < gcGracePeriod {
+			continue
+		}
+
+		lru = append(lru, o)
+	}
+
+	slices.SortStableFunc(lru, func(a, b *gcObject) int {
+		return a.AccessedAt.Compare(b.AccessedAt)
+	})
+
+	var evict = func(o *gcObject, reason string) {
+		o.Reason = reason
+		garbage = append(garbage, o)
+		cached -= o.Size()
+		for store, n := range o.Stores {
+			usageLeft[store] -= n
+		}
+	}
+
+	// remove objects past their max age
+	if config.MaxAge > 0 {
+		var left []*gcObject
+		for _, o := range lru {
+			if now.Sub(o.AccessedAt) > config.MaxAge {
+				evict(o, gcReasonAge)
+			} else {
+				left = append(left, o)
+			}
+		}
+		lru = left
+	}
+
+	// evict least recently used objects to fit the cache limit
+	if config.CacheBytes > 0 {
+		for len(lru) > 0 && cached > config.CacheBytes {
+			evict(lru[0], gcReasonCache)
+			lru = lru[1:]
+		}
+	}
+
+	// evict least recently used objects from store paths over their quota
+	for store, quota := range config.Quotas {
+		store = filepath.Clean(store)
+
+		var left []*gcObject
+		for _, o := range lru {
+			if usageLeft[store] > quota && o.Stores[store] > 0 {
+				evict(o, gcReasonQuota)
+			} else {
+				left = append(left, o)
+			}
+		}
+		lru = left
+	}
+
+	return
+}
+
+// storedObjects returns all objects in the store paths along with the usage of every store path
+func (mod *Module) storedObjects() (map[object.ID]*gcObject, map[string]uint64, error) {
+	var stored = make(map[object.ID]*gcObject)
+	var usage = make(map[string]uint64)
+
+	var add = func(objectID object.ID, store string, size uint64) {
+		o, found := stored[objectID]
+		if !found {
+			o = &gcObject{ObjectID: objectID, Stores: make(map[string]uint64)}
+			stored[objectID] = o
+		}
+		o.Stores[store] += size
+	}
+
+	for _, store := range mod.config.Store {
+		usage[filepath.Clean(store)] = 0
+	}
+
+	// objects stored as files
+	var files []*dbLocalFile
+	if err := mod.db.Find(&files).Error; err != nil {
+		return nil, nil, err
+	}
+	for _, file := range files {
+		// only files created by writers belong to the store, others are just watched
+		if !mod.isStorePath(file.Path) || filepath.Base(file.Path) != file.DataID.String() {
+			continue
+		}
+
+		var store = filepath.Dir(file.Path)
+		usage[store] += file.DataID.Size
+		add(file.DataID, store, file.DataID.Size)
+	}
+
+	// objects in chunked storage
+	var chunks []*dbChunk
+	if err := mod.db.Find(&chunks).Error; err != nil {
+		return nil, nil, err
+	}
+	for _, chunk := range chunks {
+		var store = filepath.Clean(chunk.Path)
+		if _, found := usage[store]; found {
+			usage[store] += chunk.Size
+		}
+	}
+
+	var rows []struct {
+		ObjectID object.ID
+		Path     string
+		Size     uint64
+		Refs     int
+	}
+	err := mod.db.
+		Model(&dbObjectChunk{}).
+		Select("object_id, path, chunks.size as size, refs").
+		Joins("join " + dbChunk{}.TableName() + " as chunks on chunks.id = chunk_id").
+		Find(&rows).Error
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, row := range rows {
+		var size uint64
+		// shared chunks stay after the object is purged
+		if row.Refs <= 1 {
+			size = row.Size
+		}
+		add(row.ObjectID, filepath.Clean(row.Path), size)
+	}
+
+	return stored, usage, nil
+}
+
+// isReachable checks if an object has holders or belongs to a set
+func (mod *Module) isReachable(objectID object.ID) bool {
+	if len(mod.objects.Holders(objectID)) > 0 {
+		return true
+	}
+
+	sets, err := mod.sets.Where(objectID)
+	if err != nil {
+		mod.log.Errorv(1, "gc: error checking sets of %v: %v", objectID, err)
+		return true
+	}
+
+	return len(sets) > 0
+}
+
+// accessedAt returns the last access time of a stored object. Objects without a recorded access
+// are treated as accessed now.
+func (mod *Module) accessedAt(objectID object.ID, now time.Time, dryRun bool) (time.Time, error) {
+	var row dbAccess
+	err := mod.db.Where("object_id = ?", objectID).Limit(1).Find(&row).Error
+	switch {
+	case err != nil:
+		return time.Time{}, err
+	case !row.AccessedAt.IsZero():
+		return row.AccessedAt, nil
+	case dryRun:
+		return now, nil
+	}
+
+	return now, mod.db.Create(&dbAccess{ObjectID: objectID, AccessedAt: now}).Error
+}
+
+// touch records an access to a stored object
+func (mod *Module) touch(objectID object.ID) {
+	var now = time.Now()
+
+	tx := mod.db.
+		Model(&dbAccess{}).
+		Where("object_id = ? and accessed_at < ?", objectID, now.Add(-accessResolution)).
+		Update("accessed_at", now)
+	if tx.Error != nil || tx.RowsAffected > 0 {
+		return
+	}
+
+	mod.db.
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&dbAccess{ObjectID: objectID, AccessedAt: now})
+}
+
+// isStorePath checks if the file at path was created in one of the store paths
+func (mod *Module) isStorePath(path string) bool {
+	var dir = filepath.Dir(path)
+	for _, store := range mod.config.Store {
+		if filepath.Clean(store) == dir {
+			return true
+		}
+	}
+	return false
+}
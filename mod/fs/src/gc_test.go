@@ -0,0 +1,119 @@
+package fs
+
+import (
+	"github.com/cryptopunkscc/astrald/object"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"testing"
+	"time"
+)
+
+func TestSelectGarbage(t *testing.T) {
+	var now = time.Now()
+
+	var obj = func(n byte, store string, size uint64, age time.Duration) *gcObject {
+		return &gcObject{
+			ObjectID:   object.ID{Size: size, Hash: [32]byte{n}},
+			Stores:     map[string]uint64{store: size},
+			AccessedAt: now.Add(-age),
+		}
+	}
+
+	var (
+		old    = obj(1, "/a", 100, 30*24*time.Hour)
+		stale  = obj(2, "/a", 100, 3*time.Hour)
+		recent = obj(3, "/b", 100, 2*time.Hour)
+		fresh  = obj(4, "/a", 100, time.Minute) // within the grace period
+	)
+
+	var selected = func(config GCConfig, usage map[string]uint64) map[byte]string {
+		var list = []*gcObject{fresh, recent, old, stale}
+		var reasons = make(map[byte]string)
+		for _, o := range selectGarbage(list, usage, config, now) {
+			reasons[o.ObjectID.Hash[0]] = o.Reason
+		}
+		return reasons
+	}
+
+	// no policies, nothing to remove
+	if r := selected(GCConfig{}, nil); len(r) != 0 {
+		t.Fatalf("unexpected selection %v", r)
+	}
+
+	// max age
+	r := selected(GCConfig{MaxAge: 7 * 24 * time.Hour}, nil)
+	if len(r) != 1 || r[1] != gcReasonAge {
+		t.Fatalf("unexpected selection %v", r)
+	}
+
+	// the cache holds 400 bytes of unreachable objects, evict the least recently used down to 250
+	r = selected(GCConfig{CacheBytes: 250}, nil)
+	if len(r) != 2 || r[1] != gcReasonCache || r[2] != gcReasonCache {
+		t.Fatalf("unexpected selection %v", r)
+	}
+
+	// objects in the grace period are never evicted
+	r = selected(GCConfig{CacheBytes: 1}, nil)
+	if len(r) != 3 || r[4] != "" {
+		t.Fatalf("unexpected selection %v", r)
+	}
+
+	// /a is 50 bytes over its quota, evict the least recently used object from /a only
+	r = selected(GCConfig{Quotas: map[string]uint64{"/a/": 1000}}, map[string]uint64{"/a": 1050, "/b": 5000})
+	if len(r) != 1 || r[1] != gcReasonQuota {
+		t.Fatalf("unexpected selection %v", r)
+	}
+
+	// objects removed for their age count towards the quota
+	r = selected(
+		GCConfig{MaxAge: 7 * 24 * time.Hour, Quotas: map[string]uint64{"/a": 1000}},
+		map[string]uint64{"/a": 1050},
+	)
+	if len(r) != 1 || r[1] != gcReasonAge {
+		t.Fatalf("unexpected selection %v", r)
+	}
+}
+
+func TestStoredObjects(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&dbLocalFile{}, &dbChunk{}, &dbObjectChunk{}); err != nil {
+		t.Fatal(err)
+	}
+
+	var mod = &Module{db: db, config: Config{Store: []string{"/store/"}}}
+
+	var stored = object.ID{Size: 10, Hash: [32]byte{1}}
+	var watched = object.ID{Size: 20, Hash: [32]byte{2}}
+	var chunked = object.ID{Size: 30, Hash: [32]byte{3}}
+	var shared, own = object.ID{Size: 10, Hash: [32]byte{4}}, object.ID{Size: 20, Hash: [32]byte{5}}
+
+	db.Create(&dbLocalFile{Path: "/store/" + stored.String(), DataID: stored})
+	db.Create(&dbLocalFile{Path: "/store/photo.jpg", DataID: watched})
+	db.Create(&dbLocalFile{Path: "/photos/" + watched.String(), DataID: watched})
+	db.Create(&dbChunk{ID: shared, Path: "/store", Size: shared.Size, Refs: 2})
+	db.Create(&dbChunk{ID: own, Path: "/store", Size: own.Size, Refs: 1})
+	db.Create(&dbObjectChunk{ObjectID: chunked, Seq: 0, ChunkID: shared, Size: shared.Size})
+	db.Create(&dbObjectChunk{ObjectID: chunked, Seq: 1, ChunkID: own, Offset: shared.Size, Size: own.Size})
+
+	objs, usage, err := mod.storedObjects()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(objs) != 2 || objs[stored] == nil || objs[chunked] == nil {
+		t.Fatalf("unexpected objects %v", objs)
+	}
+	if n := objs[stored].Size(); n != stored.Size {
+		t.Fatalf("stored object frees %d bytes", n)
+	}
+	// purging the chunked object leaves the shared chunk
+	if n := objs[chunked].Stores["/store"]; n != own.Size {
+		t.Fatalf("chunked object frees %d bytes", n)
+	}
+	if n := usage["/store"]; n != stored.Size+shared.Size+own.Size {
+		t.Fatalf("unexpected usage %d", n)
+	}
+}
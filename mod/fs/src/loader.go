@@ -5,6 +5,7 @@ import (
 	"github.com/cryptopunkscc/astrald/mod/fs"
 	"github.com/cryptopunkscc/astrald/node/assets"
 	"github.com/cryptopunkscc/astrald/node/modules"
+	"github.com/cryptopunkscc/astrald/object"
 	"github.com/cryptopunkscc/astrald/sig"
 )
 
@@ -18,6 +19,8 @@ func (Loader) Load(node modules.Node, assets assets.Assets, log *log.Logger) (mo
 		log:     log,
 		config:  defaultConfig,
 		updates: make(chan sig.Task, updatesLen),
+
+		chunkClaims: make(map[object.ID]int),
 	}
 
 	mod.events.SetParent(node.Events())
@@ -27,7 +30,7 @@ func (Loader) Load(node modules.Node, assets assets.Assets, log *log.Logger) (mo
 	// set up database
 	mod.db = assets.Database()
 
-	err = mod.db.AutoMigrate(&dbLocalFile{})
+	err = mod.db.AutoMigrate(&dbLocalFile{}, &dbChunk{}, &dbObjectChunk{}, &dbAccess{})
 	if err != nil {
 		return nil, err
 	}
@@ -42,7 +45,9 @@ func (Loader) Load(node modules.Node, assets assets.Assets, log *log.Logger) (mo
 	mod.watcher.OnRenamed = mod.enqueueUpdate
 	mod.watcher.OnChmod = mod.enqueueUpdate
 	mod.watcher.OnDirCreated = func(s string) {
-		mod.watcher.Add(s, true)
+		if !mod.isPathIgnored(s) {
+			mod.watcher.Add(s, true)
+		}
 	}
 
 	for _, path := range mod.config.Watch {
@@ -0,0 +1,62 @@
+package fs
+
+import (
+	"github.com/cryptopunkscc/astrald/mod/metrics"
+)
+
+// Collect returns metrics of the object store
+func (mod *Module) Collect() []metrics.Metric {
+	var free = metrics.Metric{
+		Name: "astral_fs_store_free_bytes",
+		Type: metrics.TypeGauge,
+		Help: "Free space in a store path.",
+		Unit: "bytes",
+	}
+	var total = metrics.Metric{
+		Name: "astral_fs_store_size_bytes",
+		Type: metrics.TypeGauge,
+		Help: "Size of the filesystem of a store path.",
+		Unit: "bytes",
+	}
+
+	for _, path := range mod.config.Store {
+		usage, err := DiskUsage(path)
+		if err != nil {
+			continue
+		}
+		free.Add(float64(usage.Available), metrics.L("path", path))
+		total.Add(float64(usage.Total), metrics.L("path", path))
+	}
+
+	var files int64
+	mod.db.Model(&dbLocalFile{}).Count(&files)
+
+	var list = []metrics.Metric{
+		free,
+		total,
+		metrics.Gauge("astral_fs_files", "Number of indexed local files.", float64(files)),
+	}
+
+	if stats, err := mod.chunkStats(); err == nil {
+		list = append(list,
+			metrics.Gauge("astral_fs_chunked_objects", "Number of objects in chunked storage.", float64(stats.Objects)),
+			metrics.Gauge("astral_fs_chunks", "Number of unique chunks in chunked storage.", float64(stats.Chunks)),
+			metrics.Metric{
+				Name:    "astral_fs_chunked_logical_bytes",
+				Type:    metrics.TypeGauge,
+				Help:    "Total size of objects in chunked storage.",
+				Unit:    "bytes",
+				Samples: []metrics.Sample{{Value: float64(stats.LogicalSize)}},
+			},
+			metrics.Metric{
+				Name:    "astral_fs_chunked_stored_bytes",
+				Type:    metrics.TypeGauge,
+				Help:    "Total size of unique chunks in chunked storage.",
+				Unit:    "bytes",
+				Samples: []metrics.Sample{{Value: float64(stats.StoredSize)}},
+			},
+		)
+	}
+
+	return list
+}
@@ -19,7 +19,9 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
 )
 
 var _ fs.Module = &Module{}
@@ -43,6 +45,11 @@ type Module struct {
 
 	watcher *Watcher
 	updates chan sig.Task
+
+	chunksMu    sync.Mutex
+	chunkClaims map[object.ID]int // chunks used by writers in progress
+
+	gcMu sync.Mutex
 }
 
 func (mod *Module) Run(ctx context.Context) error {
@@ -51,6 +58,7 @@ func (mod *Module) Run(ctx context.Context) error {
 	updatesDone := sig.Workers(ctx, mod.updates, workers)
 
 	go mod.verifyIndex(ctx)
+	go mod.runGC(ctx)
 
 	<-ctx.Done()
 	<-updatesDone
@@ -92,13 +100,22 @@ func (mod *Module) Open(_ context.Context, objectID object.ID, opts *objects.Ope
 			continue
 		}
 
+		if mod.isStorePath(path) {
+			mod.touch(objectID)
+		}
+
 		return &Reader{
 			ReadSeekCloser: f,
 			name:           path,
 		}, nil
 	}
 
-	return nil, objects.ErrNotFound
+	r, err := mod.openChunked(objectID, opts.Offset)
+	if err == nil {
+		mod.touch(objectID)
+	}
+
+	return r, err
 }
 
 func (mod *Module) Create(opts *objects.CreateOpts) (objects.Writer, error) {
@@ -123,6 +140,13 @@ func (mod *Module) Purge(objectID object.ID, opts *objects.PurgeOpts) (count int
 		}
 	}
 
+	n, err := mod.purgeChunked(objectID)
+	count += n
+
+	if count > 0 {
+		mod.db.Where("object_id = ?", objectID).Delete(&dbAccess{})
+	}
+
 	return
 }
 
@@ -161,6 +185,10 @@ func (mod *Module) Path(objectID object.ID) []string {
 
 // Watch a directory tree for updates
 func (mod *Module) Watch(path string) (added []string, err error) {
+	if mod.isPathIgnored(path) {
+		return
+	}
+
 	mod.watcher.Add(path, false)
 
 	entries, err := os.ReadDir(path)
@@ -354,6 +382,10 @@ func (mod *Module) onWriteDone(path string) {
 }
 
 func (mod *Module) enqueueUpdate(path string) {
+	if mod.isPathIgnored(path) {
+		return
+	}
+
 	mod.updates <- func(_ context.Context) {
 		_, _ = mod.update(path)
 	}
@@ -366,6 +398,11 @@ func (mod *Module) isPathIgnored(path string) bool {
 		return true
 	}
 
+	// chunks are indexed by the chunked storage
+	if slices.Contains(strings.Split(path, string(filepath.Separator)), chunkDirName) {
+		return true
+	}
+
 	return false
 }
 
@@ -379,9 +416,11 @@ func (mod *Module) createObjectAt(path string, alloc int) (objects.Writer, error
 		return nil, errors.New("not enough space left")
 	}
 
-	w, err := NewWriter(mod, path)
+	if mod.config.Chunked {
+		return NewChunkWriter(mod, path)
+	}
 
-	return w, err
+	return NewWriter(mod, path)
 }
 
 func (mod *Module) verifyIndex(ctx context.Context) {
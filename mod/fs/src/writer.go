@@ -86,6 +86,7 @@ func (w *Writer) Commit() (object.ID, error) {
 		ModTime: stat.ModTime(),
 	}).Error
 	if err == nil {
+		w.mod.touch(objectID)
 		w.mod.events.Emit(fs.EventFileAdded{
 			Path:     newPath,
 			ObjectID: objectID,
@@ -0,0 +1,20 @@
+package fulltext
+
+import (
+	"context"
+	"errors"
+	"github.com/cryptopunkscc/astrald/object"
+)
+
+const ModuleName = "fulltext"
+const DBPrefix = "fulltext__"
+
+var ErrNotText = errors.New("not a text object")
+
+type Module interface {
+	// Index adds the text of an object to the full-text index
+	Index(ctx context.Context, objectID object.ID) error
+
+	// Forget removes an object from the full-text index
+	Forget(objectID object.ID) error
+}
@@ -0,0 +1,130 @@
+package fulltext
+
+import (
+	"context"
+	"errors"
+	"github.com/cryptopunkscc/astrald/mod/admin"
+	"github.com/cryptopunkscc/astrald/mod/objects"
+	"github.com/cryptopunkscc/astrald/object"
+	"strconv"
+	"strings"
+)
+
+type Admin struct {
+	mod  *Module
+	cmds map[string]func(admin.Terminal, []string) error
+}
+
+func NewAdmin(mod *Module) *Admin {
+	var adm = &Admin{mod: mod}
+	adm.cmds = map[string]func(admin.Terminal, []string) error{
+		"search": adm.search,
+		"index":  adm.index,
+		"forget": adm.forget,
+		"info":   adm.info,
+		"help":   adm.help,
+	}
+
+	return adm
+}
+
+func (adm *Admin) Exec(term admin.Terminal, args []string) error {
+	if len(args) < 2 {
+		return adm.help(term, []string{})
+	}
+
+	cmd, args := args[1], args[2:]
+	if fn, found := adm.cmds[cmd]; found {
+		return fn(term, args)
+	}
+
+	return errors.New("unknown command")
+}
+
+func (adm *Admin) search(term admin.Terminal, args []string) error {
+	if len(args) < 1 {
+		return errors.New("missing argument")
+	}
+
+	matches, err := adm.mod.Search(context.Background(), strings.Join(args, " "), objects.DefaultSearchOpts())
+	if err != nil {
+		return err
+	}
+
+	var f = "%-64s %5s %s\n"
+	term.Printf(f, admin.Header("ID"), admin.Header("Score"), admin.Header("Match"))
+	for _, match := range matches {
+		term.Printf(f, match.ObjectID, strconv.Itoa(match.Score), admin.Faded(match.Exp))
+	}
+
+	return nil
+}
+
+func (adm *Admin) index(term admin.Terminal, args []string) error {
+	if len(args) < 1 {
+		return errors.New("missing argument")
+	}
+
+	objectID, err := object.ParseID(args[0])
+	if err != nil {
+		return err
+	}
+
+	return adm.mod.Index(context.Background(), objectID)
+}
+
+func (adm *Admin) forget(term admin.Terminal, args []string) error {
+	if len(args) < 1 {
+		return errors.New("missing argument")
+	}
+
+	objectID, err := object.ParseID(args[0])
+	if err != nil {
+		return err
+	}
+
+	return adm.mod.Forget(objectID)
+}
+
+func (adm *Admin) info(term admin.Terminal, _ []string) error {
+	var rows []struct {
+		Type  string
+		Count int
+	}
+
+	err := adm.mod.db.
+		Model(&dbDocument{}).
+		Select("type, count(*) as count").
+		Group("type").
+		Order("count desc").
+		Scan(&rows).Error
+	if err != nil {
+		return err
+	}
+
+	var total int
+	var f = "%-40s %8s\n"
+	term.Printf(f, admin.Header("Type"), admin.Header("Objects"))
+	for _, row := range rows {
+		total += row.Count
+		term.Printf(f, row.Type, strconv.Itoa(row.Count))
+	}
+	term.Printf("%d object(s) indexed\n", total)
+
+	return nil
+}
+
+func (adm *Admin) ShortDescription() string {
+	return "full-text search index"
+}
+
+func (adm *Admin) help(term admin.Terminal, _ []string) error {
+	term.Printf("usage: fulltext <command>\n\n")
+	term.Printf("commands:\n")
+	term.Printf("  search <query>             search the text of indexed objects\n")
+	term.Printf("  index <objectID>           add an object to the index\n")
+	term.Printf("  forget <objectID>          remove an object from the index\n")
+	term.Printf("  info                       show the number of indexed objects by type\n")
+	term.Printf("  help                       show help\n")
+	return nil
+}
@@ -0,0 +1,19 @@
+package fulltext
+
+type Config struct {
+	// MaxSize limits the number of bytes of an object that are indexed
+	MaxSize int64 `yaml:"max_size"`
+
+	// Types lists additional data types to index as text
+	Types []string `yaml:"types"`
+}
+
+var defaultConfig = Config{
+	MaxSize: 1 << 20,
+}
+
+// searchLimit is the maximum number of matches returned by a search
+const searchLimit = 100
+
+// snippetTokens is the number of tokens in a snippet of the matched text
+const snippetTokens = 16
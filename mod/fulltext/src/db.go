@@ -0,0 +1,36 @@
+package fulltext
+
+import (
+	"fmt"
+	"github.com/cryptopunkscc/astrald/mod/fulltext"
+	"github.com/cryptopunkscc/astrald/object"
+	"gorm.io/gorm"
+	"time"
+)
+
+// ftsTable is the FTS5 table holding the text of documents. Its rowid is the ID of the document.
+const ftsTable = fulltext.DBPrefix + "index"
+
+// dbDocument is an object in the full-text index
+type dbDocument struct {
+	ID        uint      `gorm:"primaryKey"`
+	ObjectID  object.ID `gorm:"uniqueIndex"`
+	Type      string
+	IndexedAt time.Time
+}
+
+func (dbDocument) TableName() string { return fulltext.DBPrefix + "documents" }
+
+func migrate(db *gorm.DB) error {
+	err := db.AutoMigrate(&dbDocument{})
+	if err != nil {
+		return err
+	}
+
+	err = db.Exec("CREATE VIRTUAL TABLE IF NOT EXISTS " + ftsTable + " USING fts5(content, tokenize='porter unicode61')").Error
+	if err != nil {
+		return fmt.Errorf("cannot create fts5 table: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,32 @@
+package fulltext
+
+import (
+	"github.com/cryptopunkscc/astrald/mod/admin"
+	"github.com/cryptopunkscc/astrald/mod/content"
+	"github.com/cryptopunkscc/astrald/mod/fulltext"
+	"github.com/cryptopunkscc/astrald/mod/objects"
+	"github.com/cryptopunkscc/astrald/node/modules"
+)
+
+func (mod *Module) LoadDependencies() error {
+	var err error
+
+	mod.content, err = modules.Load[content.Module](mod.node, content.ModuleName)
+	if err != nil {
+		return err
+	}
+
+	mod.objects, err = modules.Load[objects.Module](mod.node, objects.ModuleName)
+	if err != nil {
+		return err
+	}
+
+	// inject admin command
+	if adm, err := modules.Load[admin.Module](mod.node, admin.ModuleName); err == nil {
+		adm.AddCommand(fulltext.ModuleName, NewAdmin(mod))
+	}
+
+	mod.objects.AddSearcher(mod)
+
+	return nil
+}
@@ -0,0 +1,120 @@
+package fulltext
+
+import (
+	"context"
+	"github.com/cryptopunkscc/astrald/log"
+	"github.com/cryptopunkscc/astrald/mod/objects"
+	"github.com/cryptopunkscc/astrald/net"
+	"github.com/cryptopunkscc/astrald/object"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestSearch(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := migrate(db); err != nil {
+		t.Fatal(err)
+	}
+
+	var mod = &Module{
+		db:     db,
+		log:    log.NewLogger(log.NewLinePrinter(log.NewMonoOutput(io.Discard))),
+		config: defaultConfig,
+	}
+
+	var docs = []string{
+		"Notes from the meeting about the garden. We planted tomatoes and basil.",
+		"Tomatoes need plenty of sun. Tomatoes grow best in warm weather, so plant tomatoes late.",
+		`{"recipe": "pasta", "ingredients": ["tomatoes", "garlic"]}`,
+		"func main() { fmt.Println(\"hello\") }",
+	}
+
+	var ids []object.ID
+	for i, text := range docs {
+		var objectID = object.Resolve([]byte(text))
+		if err := mod.addDocument(objectID, "text/plain", text); err != nil {
+			t.Fatal(i, err)
+		}
+		ids = append(ids, objectID)
+	}
+
+	var search = func(query string) []objects.Match {
+		matches, err := mod.Search(context.Background(), query, objects.DefaultSearchOpts())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return matches
+	}
+
+	// the document mentioning tomatoes the most ranks first
+	matches := search("tomato")
+	if len(matches) != 3 {
+		t.Fatalf("expected 3 matches, got %d", len(matches))
+	}
+	if !matches[0].ObjectID.IsEqual(ids[1]) || matches[0].Score != 100 {
+		t.Fatalf("unexpected best match %+v", matches[0])
+	}
+	for _, m := range matches[1:] {
+		if m.Score < 1 || m.Score > matches[0].Score {
+			t.Fatalf("unexpected score %d", m.Score)
+		}
+	}
+	if !strings.Contains(matches[0].Exp, "Tomatoes") {
+		t.Fatalf("snippet missing from %q", matches[0].Exp)
+	}
+
+	// all words have to match
+	if matches = search("tomatoes garlic"); len(matches) != 1 || !matches[0].ObjectID.IsEqual(ids[2]) {
+		t.Fatalf("unexpected matches %+v", matches)
+	}
+
+	// query syntax is treated as text
+	for _, query := range []string{`"hello`, "fmt.Println(", "AND", "basil OR NOT*"} {
+		if _, err := mod.Search(context.Background(), query, objects.DefaultSearchOpts()); err != nil {
+			t.Fatalf("%q: %v", query, err)
+		}
+	}
+	if matches = search(`"hello"`); len(matches) != 1 || !matches[0].ObjectID.IsEqual(ids[3]) {
+		t.Fatalf("unexpected matches %+v", matches)
+	}
+
+	// the index only holds local objects
+	_, err = mod.Search(context.Background(), "tomato", &objects.SearchOpts{Scope: &net.Scope{Zone: net.ZoneNetwork}})
+	if err != net.ErrZoneExcluded {
+		t.Fatalf("expected ErrZoneExcluded, got %v", err)
+	}
+
+	if err := mod.Forget(ids[1]); err != nil {
+		t.Fatal(err)
+	}
+	if matches = search("tomato"); len(matches) != 2 {
+		t.Fatalf("expected 2 matches after forget, got %d", len(matches))
+	}
+}
+
+func TestIsText(t *testing.T) {
+	var mod = &Module{config: Config{Types: []string{"application/x-tex"}}}
+
+	for _, dataType := range []string{
+		"text/plain; charset=utf-8",
+		"text/x-python",
+		"application/json",
+		"application/x-tex",
+	} {
+		if !mod.isText(dataType) {
+			t.Errorf("%s not recognized as text", dataType)
+		}
+	}
+
+	for _, dataType := range []string{"image/png", "application/zip", "application/pdf"} {
+		if mod.isText(dataType) {
+			t.Errorf("%s recognized as text", dataType)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package fulltext
+
+import (
+	"github.com/cryptopunkscc/astrald/log"
+	"github.com/cryptopunkscc/astrald/mod/fulltext"
+	"github.com/cryptopunkscc/astrald/node/assets"
+	"github.com/cryptopunkscc/astrald/node/modules"
+)
+
+type Loader struct{}
+
+func (Loader) Load(node modules.Node, assets assets.Assets, log *log.Logger) (modules.Module, error) {
+	var err error
+	var mod = &Module{
+		node:   node,
+		log:    log,
+		config: defaultConfig,
+	}
+
+	_ = assets.LoadYAML(fulltext.ModuleName, &mod.config)
+
+	mod.db = assets.Database()
+
+	err = migrate(mod.db)
+	if err != nil {
+		return nil, err
+	}
+
+	return mod, nil
+}
+
+func init() {
+	if err := modules.RegisterModule(fulltext.ModuleName, Loader{}); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,245 @@
+package fulltext
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/cryptopunkscc/astrald/log"
+	"github.com/cryptopunkscc/astrald/mod/content"
+	"github.com/cryptopunkscc/astrald/mod/fulltext"
+	"github.com/cryptopunkscc/astrald/mod/objects"
+	"github.com/cryptopunkscc/astrald/net"
+	"github.com/cryptopunkscc/astrald/node"
+	"github.com/cryptopunkscc/astrald/node/events"
+	"github.com/cryptopunkscc/astrald/object"
+	"gorm.io/gorm"
+	"io"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+var _ fulltext.Module = &Module{}
+var _ objects.Searcher = &Module{}
+
+// textTypes are data types other than text/* that hold text
+var textTypes = []string{
+	"application/json",
+	"application/geo+json",
+	"application/x-ndjson",
+	"application/javascript",
+	"application/x-sh",
+	"application/x-subrip",
+	"application/xml",
+}
+
+type Module struct {
+	config Config
+	node   node.Node
+	log    *log.Logger
+	db     *gorm.DB
+
+	content content.Module
+	objects objects.Module
+
+	mu sync.Mutex
+}
+
+func (mod *Module) Run(ctx context.Context) error {
+	// index objects written to local storage
+	go events.Handle(ctx, mod.node.Events(), func(event objects.EventCommitted) error {
+		mod.index(ctx, event.ObjectID)
+		return nil
+	})
+
+	go events.Handle(ctx, mod.node.Events(), func(event objects.EventPurged) error {
+		// other copies of the object could still be available
+		if r, err := mod.objects.Open(ctx, event.ObjectID, openOpts()); err == nil {
+			r.Close()
+			return nil
+		}
+
+		if err := mod.Forget(event.ObjectID); err != nil {
+			mod.log.Errorv(1, "error removing %v from the index: %v", event.ObjectID, err)
+		}
+		return nil
+	})
+
+	// index existing and newly identified objects
+	for info := range mod.content.Scan(ctx, nil) {
+		if mod.isText(info.Type) {
+			mod.index(ctx, info.ObjectID)
+		}
+	}
+
+	return nil
+}
+
+// Index adds the text of an object to the index
+func (mod *Module) Index(ctx context.Context, objectID object.ID) error {
+	info, err := mod.content.Identify(objectID)
+	if err != nil {
+		return err
+	}
+
+	if !mod.isText(info.Type) {
+		return fulltext.ErrNotText
+	}
+
+	mod.mu.Lock()
+	defer mod.mu.Unlock()
+
+	if mod.isIndexed(objectID) {
+		return nil
+	}
+
+	r, err := mod.objects.Open(ctx, objectID, openOpts())
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	data, err := io.ReadAll(io.LimitReader(r, mod.config.MaxSize))
+	if err != nil {
+		return err
+	}
+
+	// binary data can pass for text with a misleading extension
+	if bytes.IndexByte(data, 0) != -1 {
+		return fulltext.ErrNotText
+	}
+
+	return mod.addDocument(objectID, info.Type, strings.ToValidUTF8(string(data), ""))
+}
+
+// Forget removes an object from the index
+func (mod *Module) Forget(objectID object.ID) error {
+	mod.mu.Lock()
+	defer mod.mu.Unlock()
+
+	return mod.db.Transaction(func(tx *gorm.DB) error {
+		var doc dbDocument
+		err := tx.Where("object_id = ?", objectID).First(&doc).Error
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			return nil
+		case err != nil:
+			return err
+		}
+
+		err = tx.Exec("DELETE FROM "+ftsTable+" WHERE rowid = ?", doc.ID).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Delete(&doc).Error
+	})
+}
+
+// Search returns objects with text matching all words of the query, best matches first
+func (mod *Module) Search(ctx context.Context, query string, opts *objects.SearchOpts) (matches []objects.Match, err error) {
+	if opts.Zone&(net.ZoneDevice|net.ZoneVirtual) == 0 {
+		return nil, net.ErrZoneExcluded
+	}
+
+	var expr = matchExpr(query)
+	if expr == "" {
+		return
+	}
+
+	var rows []struct {
+		ObjectID object.ID
+		Rank     float64
+		Snippet  string
+	}
+
+	err = mod.db.Raw(
+		"SELECT d.object_id, bm25("+ftsTable+") AS rank, snippet("+ftsTable+", 0, '', '', '…', ?) AS snippet"+
+			" FROM "+ftsTable+" JOIN "+dbDocument{}.TableName()+" AS d ON d.id = "+ftsTable+".rowid"+
+			" WHERE "+ftsTable+" MATCH ? ORDER BY rank LIMIT ?",
+		snippetTokens, expr, searchLimit,
+	).Scan(&rows).Error
+	if err != nil {
+		mod.log.Error("db error: %v", err)
+		return
+	}
+
+	for _, row := range rows {
+		matches = append(matches, objects.Match{
+			ObjectID: row.ObjectID,
+			Score:    score(row.Rank, rows[0].Rank),
+			Exp:      "text matches: " + strings.Join(strings.Fields(row.Snippet), " "),
+		})
+	}
+
+	return
+}
+
+// index indexes an object and logs the result
+func (mod *Module) index(ctx context.Context, objectID object.ID) {
+	err := mod.Index(ctx, objectID)
+	switch {
+	case err == nil:
+		mod.log.Logv(2, "indexed %v", objectID)
+	case errors.Is(err, fulltext.ErrNotText):
+	default:
+		mod.log.Errorv(2, "error indexing %v: %v", objectID, err)
+	}
+}
+
+// addDocument adds the text of an object to the index. Requires mod.mu.
+func (mod *Module) addDocument(objectID object.ID, dataType string, text string) error {
+	return mod.db.Transaction(func(tx *gorm.DB) error {
+		var doc = &dbDocument{
+			ObjectID:  objectID,
+			Type:      dataType,
+			IndexedAt: time.Now(),
+		}
+
+		if err := tx.Create(doc).Error; err != nil {
+			return err
+		}
+
+		return tx.Exec("INSERT INTO "+ftsTable+"(rowid, content) VALUES (?, ?)", doc.ID, text).Error
+	})
+}
+
+func (mod *Module) isIndexed(objectID object.ID) bool {
+	var count int64
+	mod.db.Model(&dbDocument{}).Where("object_id = ?", objectID).Count(&count)
+	return count > 0
+}
+
+// isText checks if objects of the data type hold text
+func (mod *Module) isText(dataType string) bool {
+	mediaType, _, _ := strings.Cut(dataType, ";")
+	mediaType = strings.TrimSpace(mediaType)
+
+	return strings.HasPrefix(mediaType, "text/") ||
+		slices.Contains(textTypes, mediaType) ||
+		slices.Contains(mod.config.Types, mediaType)
+}
+
+// openOpts returns options for opening indexed objects, which are local or inside local archives
+func openOpts() *objects.OpenOpts {
+	return &objects.OpenOpts{Zone: net.ZoneDevice | net.ZoneVirtual}
+}
+
+// matchExpr converts a query to an FTS5 expression matching all of its words
+func matchExpr(query string) string {
+	var terms []string
+	for _, word := range strings.Fields(query) {
+		terms = append(terms, `"`+strings.ReplaceAll(word, `"`, `""`)+`"`)
+	}
+	return strings.Join(terms, " ")
+}
+
+// score scales the bm25 rank of a match to 1-100 relative to the best match. Lower ranks are better.
+func score(rank float64, best float64) int {
+	if best >= 0 {
+		return 100
+	}
+	return max(1, int(math.Round(100*rank/best)))
+}
@@ -60,7 +60,7 @@ func (srv *RouteService) RouteQuery(ctx context.Context, query net.Query, caller
 			actx, cancel := context.WithTimeout(context.Background(), acceptTimeout)
 			defer cancel()
 
-			l, err := link.Accept(actx, gwConn, srv.node.Identity())
+			l, err := link.Accept(actx, gwConn, srv.node.Identity(), srv.node.Network().LinkConfig())
 			if err != nil {
 				return
 			}
@@ -0,0 +1,157 @@
+package keys
+
+import (
+	"crypto/hmac"
+	"crypto/sha512"
+	"errors"
+	"github.com/btcsuite/btcd/btcec/v2"
+	"github.com/cryptopunkscc/astrald/auth/id"
+	"github.com/tyler-smith/go-bip39"
+	"strings"
+)
+
+// KeyTypeSeed is the type of master seeds. The bytes of a seed are BIP39 entropy.
+const KeyTypeSeed = "seed"
+
+const seedEntropyBits = 256
+
+var ErrInvalidPath = errors.New("invalid derivation path")
+
+// NewSeed returns a new random master seed
+func NewSeed() (*PrivateKey, error) {
+	entropy, err := bip39.NewEntropy(seedEntropyBits)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PrivateKey{Type: KeyTypeSeed, Bytes: entropy}, nil
+}
+
+// SeedFromMnemonic returns the master seed encoded by the mnemonic phrase
+func SeedFromMnemonic(mnemonic string) (*PrivateKey, error) {
+	entropy, err := bip39.EntropyFromMnemonic(normalizeMnemonic(mnemonic))
+	if err != nil {
+		return nil, err
+	}
+
+	return &PrivateKey{Type: KeyTypeSeed, Bytes: entropy}, nil
+}
+
+// Mnemonic returns the mnemonic phrase of a master seed
+func (pk PrivateKey) Mnemonic() (string, error) {
+	if pk.Type != KeyTypeSeed {
+		return "", errors.New("not a seed")
+	}
+
+	return bip39.NewMnemonic(pk.Bytes)
+}
+
+// Derive derives a key of the type from a master seed. The path is a list of labels separated
+// with slashes, like apps/chat/0.
+//
+// Keys are derived like hardened BIP32 (or SLIP-0010 for ed25519) children, except that every
+// child is selected by the label of a path segment instead of a 32-bit index.
+func Derive(seed *PrivateKey, keyType string, path string) (id.Identity, error) {
+	if seed.Type != KeyTypeSeed {
+		return id.Identity{}, errors.New("not a seed")
+	}
+
+	labels, err := parsePath(path)
+	if err != nil {
+		return id.Identity{}, err
+	}
+
+	mnemonic, err := seed.Mnemonic()
+	if err != nil {
+		return id.Identity{}, err
+	}
+
+	var masterKey string
+	switch keyType {
+	case KeyTypeIdentity:
+		masterKey = "Bitcoin seed"
+	case KeyTypeEd25519:
+		masterKey = "ed25519 seed"
+	default:
+		return id.Identity{}, ErrUnsupportedKeyType
+	}
+
+	key, chainCode := split(hmacSHA512([]byte(masterKey), bip39.NewSeed(mnemonic, "")))
+
+	for _, label := range labels {
+		var data = append([]byte{0}, key...)
+		data = append(data, label...)
+
+		var childKey []byte
+		childKey, chainCode = split(hmacSHA512(chainCode, data))
+
+		switch keyType {
+		case KeyTypeIdentity:
+			key, err = addModN(childKey, key)
+			if err != nil {
+				return id.Identity{}, err
+			}
+		case KeyTypeEd25519:
+			key = childKey
+		}
+	}
+
+	switch keyType {
+	case KeyTypeEd25519:
+		return id.ParseEd25519PrivateKey(key)
+	}
+
+	if _, err = addModN(key, nil); err != nil {
+		return id.Identity{}, err
+	}
+
+	return id.ParsePrivateKey(key)
+}
+
+func parsePath(path string) ([]string, error) {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return nil, nil
+	}
+
+	var labels = strings.Split(path, "/")
+	for _, label := range labels {
+		if label == "" || len(label) > 255 {
+			return nil, ErrInvalidPath
+		}
+	}
+
+	return labels, nil
+}
+
+// addModN returns (a + b) mod n of the secp256k1 curve and checks if the result is a valid key
+func addModN(a []byte, b []byte) ([]byte, error) {
+	var sa, sb btcec.ModNScalar
+
+	if overflow := sa.SetByteSlice(a); overflow {
+		return nil, errors.New("derived key out of range")
+	}
+	sb.SetByteSlice(b)
+
+	sa.Add(&sb)
+	if sa.IsZero() {
+		return nil, errors.New("derived key out of range")
+	}
+
+	var bytes = sa.Bytes()
+	return bytes[:], nil
+}
+
+func hmacSHA512(key []byte, data []byte) []byte {
+	var mac = hmac.New(sha512.New, key)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+func split(b []byte) ([]byte, []byte) {
+	return b[:32], b[32:]
+}
+
+func normalizeMnemonic(mnemonic string) string {
+	return strings.Join(strings.Fields(strings.ToLower(mnemonic)), " ")
+}
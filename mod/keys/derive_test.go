@@ -0,0 +1,76 @@
+package keys
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDerive(t *testing.T) {
+	seed, err := NewSeed()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mnemonic, err := seed.Mnemonic()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(strings.Fields(mnemonic)); n != 24 {
+		t.Fatalf("expected 24 words, got %d", n)
+	}
+
+	// the seed is recovered from the mnemonic, regardless of case and spacing
+	recovered, err := SeedFromMnemonic("  " + strings.ToUpper(mnemonic) + "\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, keyType := range []string{KeyTypeIdentity, KeyTypeEd25519} {
+		chat, err := Derive(seed, keyType, "apps/chat/0")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		again, err := Derive(recovered, keyType, "/apps/chat/0/")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !chat.IsEqual(again) {
+			t.Fatal("derivation is not deterministic")
+		}
+		if KeyType(chat) != keyType {
+			t.Fatalf("derived %s key instead of %s", KeyType(chat), keyType)
+		}
+
+		for _, path := range []string{"apps/chat/1", "apps/chat", "apps/mail/0", "apps/chat0"} {
+			other, err := Derive(seed, keyType, path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if other.IsEqual(chat) {
+				t.Fatalf("%s derived the same key as apps/chat/0", path)
+			}
+		}
+
+		if _, err = Derive(seed, keyType, "apps//0"); err != ErrInvalidPath {
+			t.Fatalf("expected ErrInvalidPath, got %v", err)
+		}
+	}
+
+	// the identity of a seed is its master key
+	master, err := seed.Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := Derive(seed, KeyTypeIdentity, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !master.IsEqual(root) {
+		t.Fatal("seed identity differs from the master key")
+	}
+
+	if _, err = SeedFromMnemonic("not a valid mnemonic"); err == nil {
+		t.Fatal("invalid mnemonic accepted")
+	}
+}
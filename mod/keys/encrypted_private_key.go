@@ -0,0 +1,196 @@
+package keys
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"github.com/cryptopunkscc/astrald/auth/id"
+	"github.com/cryptopunkscc/astrald/cslq"
+	"github.com/cryptopunkscc/astrald/lib/adc"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/scrypt"
+	"io"
+)
+
+const EncryptedPrivateKeyDataType = "keys.encrypted_private_key"
+const KDFScrypt = "scrypt"
+
+var ErrLocked = errors.New("keys are locked")
+var ErrInvalidPassphrase = errors.New("invalid passphrase")
+
+// Scrypt parameters used for newly encrypted keys
+var (
+	ScryptN = 1 << 15
+	ScryptR = 8
+	ScryptP = 1
+)
+
+// Maximum scrypt parameters accepted when decrypting keys, so that a crafted key cannot exhaust memory or CPU
+const (
+	MaxScryptN = 1 << 20
+	MaxScryptR = 32
+	MaxScryptP = 16
+)
+
+const saltSize = 16
+
+// EncryptedPrivateKey is a private key encrypted with a key derived from a passphrase.
+// The type and the public key are stored in the clear, so that keys can be indexed without
+// the passphrase, but are authenticated along with the ciphertext.
+type EncryptedPrivateKey struct {
+	Type       string
+	PublicKey  id.Identity
+	KDF        string
+	Salt       []byte
+	N, R, P    int
+	Nonce      []byte
+	Ciphertext []byte
+}
+
+// EncryptPrivateKey encrypts the private key with the passphrase
+func EncryptPrivateKey(pk *PrivateKey, passphrase []byte) (*EncryptedPrivateKey, error) {
+	publicKey, err := pk.Identity()
+	if err != nil {
+		return nil, err
+	}
+
+	var epk = &EncryptedPrivateKey{
+		Type:      pk.Type,
+		PublicKey: publicKey.Public(),
+		KDF:       KDFScrypt,
+		Salt:      make([]byte, saltSize),
+		N:         ScryptN,
+		R:         ScryptR,
+		P:         ScryptP,
+		Nonce:     make([]byte, chacha20poly1305.NonceSizeX),
+	}
+
+	if _, err = rand.Read(epk.Salt); err != nil {
+		return nil, err
+	}
+	if _, err = rand.Read(epk.Nonce); err != nil {
+		return nil, err
+	}
+
+	aead, err := epk.aead(passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	epk.Ciphertext = aead.Seal(nil, epk.Nonce, pk.Bytes, epk.additionalData())
+
+	return epk, nil
+}
+
+// ReadPrivateKey reads a plaintext or an encrypted private key. The passphrase function is only
+// called if the key is encrypted.
+func ReadPrivateKey(r io.Reader, passphrase func() ([]byte, error)) (*PrivateKey, error) {
+	header, err := adc.ReadHeader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	switch header {
+	case PrivateKeyDataType:
+		var pk PrivateKey
+		if err = cslq.Decode(r, "v", &pk); err != nil {
+			return nil, err
+		}
+		return &pk, nil
+
+	case EncryptedPrivateKeyDataType:
+		var epk EncryptedPrivateKey
+		if err = cslq.Decode(r, "v", &epk); err != nil {
+			return nil, err
+		}
+		pass, err := passphrase()
+		if err != nil {
+			return nil, err
+		}
+		return epk.Decrypt(pass)
+	}
+
+	return nil, errors.New("not a private key")
+}
+
+// Decrypt decrypts the private key. Returns ErrInvalidPassphrase if the passphrase is wrong.
+func (epk *EncryptedPrivateKey) Decrypt(passphrase []byte) (*PrivateKey, error) {
+	aead, err := epk.aead(passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	plain, err := aead.Open(nil, epk.Nonce, epk.Ciphertext, epk.additionalData())
+	if err != nil {
+		return nil, ErrInvalidPassphrase
+	}
+
+	var pk = &PrivateKey{Type: epk.Type, Bytes: plain}
+
+	identity, err := pk.Identity()
+	if err != nil {
+		return nil, err
+	}
+	if !identity.IsEqual(epk.PublicKey) {
+		return nil, errors.New("public key mismatch")
+	}
+
+	return pk, nil
+}
+
+func (epk *EncryptedPrivateKey) aead(passphrase []byte) (cipher.AEAD, error) {
+	if epk.KDF != KDFScrypt {
+		return nil, errors.New("unsupported key derivation function")
+	}
+	if len(epk.Nonce) != chacha20poly1305.NonceSizeX {
+		return nil, errors.New("invalid nonce")
+	}
+	if epk.N > MaxScryptN || epk.R > MaxScryptR || epk.P > MaxScryptP {
+		return nil, errors.New("scrypt parameters too large")
+	}
+
+	key, err := scrypt.Key(passphrase, epk.Salt, epk.N, epk.R, epk.P, chacha20poly1305.KeySize)
+	if err != nil {
+		return nil, err
+	}
+
+	return chacha20poly1305.NewX(key)
+}
+
+func (epk *EncryptedPrivateKey) additionalData() []byte {
+	var buf = &bytes.Buffer{}
+	_ = cslq.Encode(buf, "[c]cv", epk.Type, epk.PublicKey)
+	return buf.Bytes()
+}
+
+func (epk EncryptedPrivateKey) MarshalCSLQ(enc *cslq.Encoder) error {
+	return enc.Encodef("[c]cv[c]c[c]clll[c]c[s]c",
+		epk.Type,
+		epk.PublicKey,
+		epk.KDF,
+		epk.Salt,
+		uint32(epk.N),
+		uint32(epk.R),
+		uint32(epk.P),
+		epk.Nonce,
+		epk.Ciphertext,
+	)
+}
+
+func (epk *EncryptedPrivateKey) UnmarshalCSLQ(dec *cslq.Decoder) error {
+	var n, r, p uint32
+	err := dec.Decodef("[c]cv[c]c[c]clll[c]c[s]c",
+		&epk.Type,
+		&epk.PublicKey,
+		&epk.KDF,
+		&epk.Salt,
+		&n,
+		&r,
+		&p,
+		&epk.Nonce,
+		&epk.Ciphertext,
+	)
+	epk.N, epk.R, epk.P = int(n), int(r), int(p)
+	return err
+}
@@ -0,0 +1,110 @@
+package keys
+
+import (
+	"bytes"
+	"errors"
+	"github.com/cryptopunkscc/astrald/auth/id"
+	"github.com/cryptopunkscc/astrald/cslq"
+	"github.com/cryptopunkscc/astrald/lib/adc"
+	"testing"
+)
+
+func TestEncryptedPrivateKey(t *testing.T) {
+	ScryptN = 1 << 10
+
+	for _, keyType := range []string{KeyTypeIdentity, KeyTypeEd25519} {
+		identity, err := GenerateKey(keyType)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var pk = &PrivateKey{Type: keyType, Bytes: identity.PrivateKeyBytes()}
+		var passphrase = []byte("correct horse battery staple")
+
+		epk, err := EncryptPrivateKey(pk, passphrase)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bytes.Contains(epk.Ciphertext, pk.Bytes) {
+			t.Fatal("ciphertext contains the private key")
+		}
+
+		// encode and read back
+		var buf = &bytes.Buffer{}
+		err = cslq.Encode(buf, "vv", adc.Header(EncryptedPrivateKeyDataType), epk)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var data = buf.Bytes()
+
+		decrypted, err := ReadPrivateKey(bytes.NewReader(data), func() ([]byte, error) {
+			return passphrase, nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		decryptedID, err := decrypted.Identity()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !decryptedID.IsEqual(identity) || !bytes.Equal(decrypted.Bytes, pk.Bytes) {
+			t.Fatal("decrypted key differs")
+		}
+
+		// wrong passphrase
+		_, err = ReadPrivateKey(bytes.NewReader(data), func() ([]byte, error) {
+			return []byte("wrong"), nil
+		})
+		if !errors.Is(err, ErrInvalidPassphrase) {
+			t.Fatalf("expected ErrInvalidPassphrase, got %v", err)
+		}
+
+		// the public key is authenticated
+		other, err := id.GenerateIdentity()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var tampered = *epk
+		tampered.PublicKey = other
+		if _, err = tampered.Decrypt(passphrase); err == nil {
+			t.Fatal("decrypted a key with a swapped public key")
+		}
+
+		// excessive scrypt parameters are refused before deriving the key
+		for _, params := range [][3]int{{1 << 30, 8, 1}, {1 << 10, 1 << 20, 1}, {1 << 10, 8, 1 << 20}} {
+			var costly = *epk
+			costly.N, costly.R, costly.P = params[0], params[1], params[2]
+			if _, err = costly.Decrypt(passphrase); err == nil || errors.Is(err, ErrInvalidPassphrase) {
+				t.Fatalf("expected an error for %v, got %v", params, err)
+			}
+		}
+	}
+}
+
+func TestReadPlaintextPrivateKey(t *testing.T) {
+	identity, err := id.GenerateIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf = &bytes.Buffer{}
+	err = cslq.Encode(buf, "vv", adc.Header(PrivateKeyDataType), PrivateKey{
+		Type:  KeyTypeIdentity,
+		Bytes: identity.PrivateKeyBytes(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pk, err := ReadPrivateKey(buf, func() ([]byte, error) {
+		t.Fatal("passphrase requested for a plaintext key")
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(pk.Bytes, identity.PrivateKeyBytes()) {
+		t.Fatal("read key differs")
+	}
+}
@@ -1,6 +1,7 @@
 package keys
 
 import (
+	"errors"
 	"fmt"
 	"github.com/cryptopunkscc/astrald/auth/id"
 	"github.com/cryptopunkscc/astrald/object"
@@ -11,19 +12,88 @@ const DBPrefix = "keys__"
 
 type Module interface {
 	CreateKey(alias string) (id.Identity, object.ID, error)
+	CreateKeyOfType(alias string, keyType string) (id.Identity, object.ID, error)
+	// DeriveKey derives a key at the path (like apps/chat/0) from the master seed and assigns
+	// the alias to it. Deriving the same path again returns the same key.
+	DeriveKey(alias string, path string) (id.Identity, object.ID, error)
+	DeriveKeyOfType(alias string, keyType string, path string) (id.Identity, object.ID, error)
+	// ExportMnemonic returns the master seed as a mnemonic phrase. Creates the seed if needed.
+	ExportMnemonic() (string, error)
+	// ImportMnemonic sets the master seed to the one encoded by the mnemonic phrase
+	ImportMnemonic(mnemonic string) error
 	LoadPrivateKey(object.ID) (*PrivateKey, error)
 	FindIdentity(hex string) (id.Identity, error)
 	Sign(identity id.Identity, hash []byte) ([]byte, error)
+	// SignWithDescription signs the hash like Sign. Remote signers show the description to the user
+	// when asking for approval.
+	SignWithDescription(identity id.Identity, hash []byte, description string) ([]byte, error)
+
+	// Unlock decrypts stored keys with the passphrase
+	Unlock(passphrase []byte) error
+	// Lock forgets the passphrase and all decrypted keys
+	Lock()
+	// Locked returns true if keys are encrypted and the passphrase wasn't provided
+	Locked() bool
+	// SetPassphrase encrypts all stored keys with a new passphrase. If keys are already
+	// encrypted, the current passphrase needs to be provided.
+	SetPassphrase(current []byte, passphrase []byte) error
 }
 
+// NodeIdentityResource is the name of the resource holding the private key of the node
+const NodeIdentityResource = "node_identity"
+
+// NodeSeedResource is the name of the resource holding the master seed of a new node until the
+// module imports it
+const NodeSeedResource = "node_seed"
+
+// NodeKeyPath is the derivation path of the node's identity
+const NodeKeyPath = "node"
+
 const PrivateKeyDataType = "keys.private_key"
 const KeyTypeIdentity = "ecdsa-secp256k1"
+const KeyTypeEd25519 = "ed25519"
+
+var ErrUnsupportedKeyType = errors.New("unsupported key type")
 
 type PrivateKey struct {
 	Type  string `cslq:"[c]c"`
 	Bytes []byte `cslq:"[c]c"`
 }
 
+// Identity returns the identity of the private key
+func (pk PrivateKey) Identity() (id.Identity, error) {
+	switch pk.Type {
+	case KeyTypeIdentity:
+		return id.ParsePrivateKey(pk.Bytes)
+	case KeyTypeEd25519:
+		return id.ParseEd25519PrivateKey(pk.Bytes)
+	case KeyTypeSeed:
+		// the identity of a seed is its master key
+		return Derive(&pk, KeyTypeIdentity, "")
+	}
+	return id.Identity{}, ErrUnsupportedKeyType
+}
+
+// KeyType returns the type of private key used by the identity
+func KeyType(identity id.Identity) string {
+	switch identity.Algorithm() {
+	case id.AlgorithmEd25519:
+		return KeyTypeEd25519
+	}
+	return KeyTypeIdentity
+}
+
+// GenerateKey returns a new identity with a private key of the provided type
+func GenerateKey(keyType string) (id.Identity, error) {
+	switch keyType {
+	case KeyTypeIdentity:
+		return id.GenerateIdentity()
+	case KeyTypeEd25519:
+		return id.GenerateEd25519Identity()
+	}
+	return id.Identity{}, ErrUnsupportedKeyType
+}
+
 type KeyDesc struct {
 	KeyType   string
 	PublicKey id.Identity
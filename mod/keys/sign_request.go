@@ -0,0 +1,37 @@
+package keys
+
+import (
+	"errors"
+	"github.com/cryptopunkscc/astrald/auth/id"
+	"github.com/cryptopunkscc/astrald/cslq"
+)
+
+// SignServiceName is the service of remote signers - processes holding private keys outside the node.
+//
+// The caller sends a SignRequest, the signer responds with a code and, if the code is SignSigned,
+// with the signature encoded as [c]c.
+const SignServiceName = "keys.sign"
+
+// Response codes of a remote signer
+const (
+	SignSigned = iota
+	SignRejected
+	SignKeyNotFound
+)
+
+var ErrSignRejected = errors.New("signing rejected by the signer")
+
+// SignRequest asks a remote signer to sign a hash
+type SignRequest struct {
+	Identity    id.Identity // identity to sign with
+	Hash        []byte
+	Description string // human-readable description of the signed data
+}
+
+func (req SignRequest) MarshalCSLQ(enc *cslq.Encoder) error {
+	return enc.Encodef("v[c]c[s]c", req.Identity, req.Hash, req.Description)
+}
+
+func (req *SignRequest) UnmarshalCSLQ(dec *cslq.Decoder) error {
+	return dec.Decodef("v[c]c[s]c", &req.Identity, &req.Hash, &req.Description)
+}
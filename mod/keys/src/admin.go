@@ -2,7 +2,6 @@ package keys
 
 import (
 	"errors"
-	"github.com/cryptopunkscc/astrald/auth/id"
 	"github.com/cryptopunkscc/astrald/mod/admin"
 	"github.com/cryptopunkscc/astrald/mod/keys"
 	"github.com/cryptopunkscc/astrald/object"
@@ -16,17 +15,28 @@ type Admin struct {
 func NewAdmin(mod *Module) *Admin {
 	var adm = &Admin{mod: mod}
 	adm.cmds = map[string]func(admin.Terminal, []string) error{
-		"index": adm.index,
-		"list":  adm.list,
-		"new":   adm.new,
-		"help":  adm.help,
+		"index":    adm.index,
+		"list":     adm.list,
+		"new":      adm.new,
+		"unlock":   adm.unlock,
+		"lock":     adm.lock,
+		"passwd":   adm.passwd,
+		"derive":   adm.derive,
+		"mnemonic": adm.mnemonic,
+		"recover":  adm.recover,
+		"help":     adm.help,
 	}
 
 	return adm
 }
 
 func (adm *Admin) new(term admin.Terminal, args []string) error {
-	key, err := id.GenerateIdentity()
+	var keyType = keys.KeyTypeIdentity
+	if len(args) >= 2 {
+		keyType = args[1]
+	}
+
+	key, err := keys.GenerateKey(keyType)
 	if err != nil {
 		return err
 	}
@@ -59,14 +69,143 @@ func (adm *Admin) list(term admin.Terminal, args []string) error {
 		return tx.Error
 	}
 
-	term.Printf("Found %d key(s)\n", len(rows))
+	term.Printf("Found %d key(s)", len(rows))
+	switch {
+	case adm.mod.Locked():
+		term.Printf(", locked")
+	case adm.mod.isEncrypted():
+		term.Printf(", unlocked")
+	}
+	term.Printf("\n")
+
 	for _, row := range rows {
-		term.Printf("%-24s %-64s %v\n", admin.Keyword(row.Type), row.DataID, row.PublicKey)
+		var state = "plaintext"
+		if row.Encrypted {
+			state = "encrypted"
+		}
+		term.Printf("%-24s %-10s %-64s %v\n", admin.Keyword(row.Type), state, row.DataID, row.PublicKey)
+	}
+
+	return nil
+}
+
+func (adm *Admin) unlock(term admin.Terminal, _ []string) error {
+	passphrase, err := adm.prompt(term, "passphrase")
+	if err != nil {
+		return err
+	}
+
+	if err = adm.mod.Unlock(passphrase); err != nil {
+		return err
+	}
+
+	term.Printf("keys unlocked\n")
+	return nil
+}
+
+func (adm *Admin) lock(term admin.Terminal, _ []string) error {
+	if !adm.mod.isEncrypted() {
+		return errors.New("keys are not encrypted, use passwd to set a passphrase")
+	}
+
+	adm.mod.Lock()
+
+	term.Printf("keys locked\n")
+	return nil
+}
+
+func (adm *Admin) passwd(term admin.Terminal, _ []string) error {
+	var current []byte
+	var err error
+
+	if adm.mod.isEncrypted() {
+		current, err = adm.prompt(term, "current passphrase")
+		if err != nil {
+			return err
+		}
+	}
+
+	passphrase, err := adm.prompt(term, "new passphrase")
+	if err != nil {
+		return err
+	}
+
+	repeated, err := adm.prompt(term, "repeat new passphrase")
+	if err != nil {
+		return err
+	}
+
+	if string(passphrase) != string(repeated) {
+		return errors.New("passphrases do not match")
+	}
+
+	if err = adm.mod.SetPassphrase(current, passphrase); err != nil {
+		return err
+	}
+
+	term.Printf("passphrase set, all keys are encrypted\n")
+	return nil
+}
+
+func (adm *Admin) derive(term admin.Terminal, args []string) error {
+	if len(args) < 2 {
+		return errors.New("usage: keys derive <alias> <path> [type]")
+	}
+
+	var keyType = keys.KeyTypeIdentity
+	if len(args) >= 3 {
+		keyType = args[2]
+	}
+
+	key, objectID, err := adm.mod.DeriveKeyOfType(args[0], keyType, args[1])
+	if err != nil {
+		return err
+	}
+
+	term.Printf("derived key %s (%s) objectID %v\n",
+		key,
+		admin.Faded(key.String()),
+		objectID,
+	)
+
+	return nil
+}
+
+func (adm *Admin) mnemonic(term admin.Terminal, _ []string) error {
+	mnemonic, err := adm.mod.ExportMnemonic()
+	if err != nil {
+		return err
+	}
+
+	term.Printf("Write down the phrase and keep it secret. It recovers all derived keys.\n\n")
+	term.Printf("%s\n", mnemonic)
+
+	return nil
+}
+
+func (adm *Admin) recover(term admin.Terminal, _ []string) error {
+	line, err := adm.prompt(term, "mnemonic")
+	if err != nil {
+		return err
+	}
+
+	if err = adm.mod.ImportMnemonic(string(line)); err != nil {
+		return err
 	}
 
+	term.Printf("seed imported, derive keys again to recover them\n")
 	return nil
 }
 
+func (adm *Admin) prompt(term admin.Terminal, prompt string) ([]byte, error) {
+	term.Printf("%s: ", prompt)
+	line, err := term.ScanLine()
+	if err != nil {
+		return nil, err
+	}
+	return []byte(line), nil
+}
+
 func (adm *Admin) index(term admin.Terminal, args []string) error {
 	if len(args) < 1 {
 		return errors.New("missing argument")
@@ -100,8 +239,14 @@ func (adm *Admin) ShortDescription() string {
 func (adm *Admin) help(term admin.Terminal, _ []string) error {
 	term.Printf("usage: %s <command>\n\n", keys.ModuleName)
 	term.Printf("commands:\n")
-	term.Printf("  new <alias>     create new key with provided alias\n")
-	term.Printf("  list            list all keys\n")
-	term.Printf("  help            show help\n")
+	term.Printf("  new <alias> [type]            create new key with provided alias (%s or %s)\n", keys.KeyTypeIdentity, keys.KeyTypeEd25519)
+	term.Printf("  derive <alias> <path> [type]  derive a key at the path (like apps/chat/0) from the seed\n")
+	term.Printf("  mnemonic                      show the seed as a mnemonic phrase\n")
+	term.Printf("  recover                       import a seed from a mnemonic phrase\n")
+	term.Printf("  list                          list all keys\n")
+	term.Printf("  unlock                        decrypt keys with the passphrase\n")
+	term.Printf("  lock                          forget the passphrase and decrypted keys\n")
+	term.Printf("  passwd                        set or change the passphrase and encrypt all keys\n")
+	term.Printf("  help                          show help\n")
 	return nil
 }
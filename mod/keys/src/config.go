@@ -1,6 +1,16 @@
 package keys
 
+import "time"
+
 type Config struct {
+	// Signers maps identities to remote signers holding their private keys. Signing requests for
+	// these identities are forwarded to the signer and need its approval.
+	Signers map[string]string `yaml:"signers"`
+
+	// SignTimeout limits the time a remote signer has to respond
+	SignTimeout time.Duration `yaml:"sign_timeout"`
 }
 
-var defaultConfig = Config{}
+var defaultConfig = Config{
+	SignTimeout: 2 * time.Minute,
+}
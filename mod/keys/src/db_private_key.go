@@ -10,6 +10,8 @@ type dbPrivateKey struct {
 	DataID    object.ID   `gorm:"uniqueIndex"`
 	Type      string      `gorm:"index"`
 	PublicKey id.Identity `gorm:"index"`
+	Encrypted bool
+	Local     bool // stored by this module, as opposed to found in storage
 }
 
 func (dbPrivateKey) TableName() string {
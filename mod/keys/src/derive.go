@@ -0,0 +1,187 @@
+package keys
+
+import (
+	"bytes"
+	"errors"
+	"github.com/cryptopunkscc/astrald/auth/id"
+	"github.com/cryptopunkscc/astrald/mod/keys"
+	"github.com/cryptopunkscc/astrald/object"
+	"gorm.io/gorm"
+	"strings"
+)
+
+func (mod *Module) DeriveKey(alias string, path string) (id.Identity, object.ID, error) {
+	return mod.DeriveKeyOfType(alias, keys.KeyTypeIdentity, path)
+}
+
+// DeriveKeyOfType derives a key of the provided type at the path from the master seed and
+// assigns the alias to it
+func (mod *Module) DeriveKeyOfType(alias string, keyType string, path string) (identity id.Identity, objectID object.ID, err error) {
+	// the empty path holds the master key, which is the identity of the seed itself
+	if strings.Trim(path, "/") == "" {
+		err = keys.ErrInvalidPath
+		return
+	}
+
+	mod.mu.Lock()
+	defer mod.mu.Unlock()
+
+	seed, err := mod.seed(true)
+	if err != nil {
+		return
+	}
+
+	identity, err = keys.Derive(seed, keyType, path)
+	if err != nil {
+		return
+	}
+
+	if alias != "" {
+		if aliasID, err := mod.node.Tracker().IdentityByAlias(alias); err == nil && !aliasID.IsEqual(identity) {
+			return identity, objectID, errors.New("alias already in use")
+		}
+	}
+
+	// the key could have been derived before
+	var row dbPrivateKey
+	err = mod.db.Where("public_key = ?", identity.PublicKeyHex()).First(&row).Error
+	if err == nil {
+		objectID = row.DataID
+	} else {
+		objectID, err = mod.saveKey(identity)
+		if err != nil {
+			return
+		}
+	}
+
+	if alias != "" {
+		err = mod.node.Tracker().SetAlias(identity, alias)
+	}
+
+	return
+}
+
+func (mod *Module) ExportMnemonic() (string, error) {
+	mod.mu.Lock()
+	defer mod.mu.Unlock()
+
+	seed, err := mod.seed(true)
+	if err != nil {
+		return "", err
+	}
+
+	return seed.Mnemonic()
+}
+
+func (mod *Module) ImportMnemonic(mnemonic string) error {
+	imported, err := keys.SeedFromMnemonic(mnemonic)
+	if err != nil {
+		return err
+	}
+
+	mod.mu.Lock()
+	defer mod.mu.Unlock()
+
+	current, err := mod.seed(false)
+	switch {
+	case err == nil:
+		currentID, _ := current.Identity()
+		importedID, _ := imported.Identity()
+		if currentID.IsEqual(importedID) {
+			return nil
+		}
+		return errors.New("a different seed already exists")
+
+	case !errors.Is(err, errNoSeed):
+		return err
+	}
+
+	_, err = mod.savePrivateKey(imported)
+
+	return err
+}
+
+// importNodeSeed imports the master seed the node's identity was derived from when the node was
+// created. The resource is cleared only once the seed is stored, so a seed that fails to import stays
+// in the resource until the problem is fixed.
+func (mod *Module) importNodeSeed() {
+	data, err := mod.assets.Read(keys.NodeSeedResource)
+	if err != nil || len(data) == 0 {
+		return
+	}
+
+	imported, err := keys.ReadPrivateKey(bytes.NewReader(data), nil)
+	switch {
+	case err != nil:
+		mod.log.Error("node seed not imported, %s is invalid: %v", keys.NodeSeedResource, err)
+		return
+	case imported.Type != keys.KeyTypeSeed:
+		mod.log.Error("node seed not imported, %s holds a key of type %s", keys.NodeSeedResource, imported.Type)
+		return
+	}
+
+	mod.mu.Lock()
+	defer mod.mu.Unlock()
+
+	current, err := mod.seed(false)
+	switch {
+	case err == nil:
+		currentID, _ := current.Identity()
+		importedID, _ := imported.Identity()
+		if !currentID.IsEqual(importedID) {
+			mod.log.Error("node seed not imported, a different master seed already exists; "+
+				"the node seed is kept in %s", keys.NodeSeedResource)
+			return
+		}
+
+	case errors.Is(err, errNoSeed):
+		if _, err = mod.savePrivateKey(imported); err != nil {
+			mod.log.Error("node seed not imported, %s is kept: %v", keys.NodeSeedResource, err)
+			return
+		}
+		mod.log.Info("imported the node's master seed")
+
+	default:
+		mod.log.Error("node seed not imported, %s is kept: %v", keys.NodeSeedResource, err)
+		return
+	}
+
+	if err = mod.assets.Write(keys.NodeSeedResource, nil); err != nil {
+		mod.log.Error("node seed imported, but %s could not be cleared: %v", keys.NodeSeedResource, err)
+	}
+}
+
+var errNoSeed = errors.New("no seed")
+
+// seed loads the master seed, creating one if requested. Requires mod.mu.
+func (mod *Module) seed(create bool) (*keys.PrivateKey, error) {
+	if mod.locked() {
+		return nil, keys.ErrLocked
+	}
+
+	var row dbPrivateKey
+	err := mod.db.Where("type = ?", keys.KeyTypeSeed).First(&row).Error
+	switch {
+	case err == nil:
+		return mod.loadPrivateKey(row.DataID, mod.passphrase)
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return nil, err
+	}
+
+	if !create {
+		return nil, errNoSeed
+	}
+
+	seed, err := keys.NewSeed()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = mod.savePrivateKey(seed); err != nil {
+		return nil, err
+	}
+
+	mod.log.Info("created a new master seed")
+
+	return seed, nil
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"github.com/cryptopunkscc/astrald/mod/content"
 	"github.com/cryptopunkscc/astrald/mod/keys"
+	"sync"
 )
 
 type IndexerService struct {
@@ -12,7 +13,25 @@ type IndexerService struct {
 }
 
 func (srv *IndexerService) Run(ctx context.Context) error {
-	for event := range srv.content.Scan(ctx, &content.ScanOpts{Type: keys.PrivateKeyDataType}) {
+	var wg sync.WaitGroup
+
+	for _, dataType := range []string{keys.PrivateKeyDataType, keys.EncryptedPrivateKeyDataType} {
+		wg.Add(1)
+		go func(dataType string) {
+			defer wg.Done()
+			srv.scan(ctx, dataType)
+		}(dataType)
+	}
+
+	wg.Wait()
+
+	<-ctx.Done()
+
+	return nil
+}
+
+func (srv *IndexerService) scan(ctx context.Context, dataType string) {
+	for event := range srv.content.Scan(ctx, &content.ScanOpts{Type: dataType}) {
 		err := srv.IndexKey(event.ObjectID)
 		switch {
 		case err == nil:
@@ -21,8 +40,4 @@ func (srv *IndexerService) Run(ctx context.Context) error {
 			srv.log.Errorv(1, "IndexKey: %v", err)
 		}
 	}
-
-	<-ctx.Done()
-
-	return nil
 }
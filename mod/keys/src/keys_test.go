@@ -0,0 +1,172 @@
+package keys
+
+import (
+	"context"
+	"errors"
+	"github.com/cryptopunkscc/astrald/auth/id"
+	"github.com/cryptopunkscc/astrald/cslq"
+	"github.com/cryptopunkscc/astrald/log"
+	"github.com/cryptopunkscc/astrald/mod/keys"
+	"github.com/cryptopunkscc/astrald/net"
+	"github.com/cryptopunkscc/astrald/node"
+	"github.com/cryptopunkscc/astrald/node/resolver"
+	"github.com/cryptopunkscc/astrald/node/router"
+	"github.com/cryptopunkscc/astrald/object"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"io"
+	"testing"
+	"time"
+)
+
+type testNode struct {
+	node.Node
+	identity id.Identity
+	router   router.Router
+}
+
+func (n *testNode) Identity() id.Identity       { return n.identity }
+func (n *testNode) Router() router.Router       { return n.router }
+func (n *testNode) Resolver() resolver.Resolver { return testResolver{} }
+
+type testResolver struct {
+	resolver.Resolver
+}
+
+func (testResolver) Resolve(s string) (id.Identity, error) {
+	return id.ParsePublicKeyHex(s)
+}
+
+// testSigner serves keys.sign with the key and approves requests with the description
+type testSigner struct {
+	router.Router
+	key     id.Identity
+	forge   id.Identity // if set, signs with this key instead
+	approve string
+}
+
+func (s *testSigner) RouteQuery(ctx context.Context, query net.Query, caller net.SecureWriteCloser, hints net.Hints) (net.SecureWriteCloser, error) {
+	if query.Query() != keys.SignServiceName {
+		return net.Reject()
+	}
+
+	return net.Accept(query, caller, func(conn net.SecureConn) {
+		defer conn.Close()
+
+		var req keys.SignRequest
+		if err := cslq.Decode(conn, "v", &req); err != nil {
+			return
+		}
+
+		switch {
+		case !req.Identity.IsEqual(s.key):
+			cslq.Encode(conn, "c", keys.SignKeyNotFound)
+		case req.Description != s.approve:
+			cslq.Encode(conn, "c", keys.SignRejected)
+		default:
+			var key = s.key
+			if !s.forge.IsZero() {
+				key = s.forge
+			}
+			sig, _ := key.Sign(req.Hash)
+			cslq.Encode(conn, "c[c]c", keys.SignSigned, sig)
+		}
+	})
+}
+
+func TestRemoteSigner(t *testing.T) {
+	var nodeID, userID, signerID, otherID id.Identity
+	for _, i := range []*id.Identity{&nodeID, &userID, &signerID, &otherID} {
+		var err error
+		if *i, err = id.GenerateIdentity(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var signer = &testSigner{key: userID, approve: "contract"}
+
+	var mod = &Module{
+		node: &testNode{identity: nodeID, router: signer},
+		log:  log.NewLogger(log.NewLinePrinter(log.NewMonoOutput(io.Discard))),
+		config: Config{
+			Signers: map[string]string{
+				userID.PublicKeyHex():  signerID.PublicKeyHex(),
+				otherID.PublicKeyHex(): signerID.PublicKeyHex(),
+			},
+			SignTimeout: 5 * time.Second,
+		},
+	}
+
+	var hash = make([]byte, 32)
+	hash[0] = 1
+
+	// the request is forwarded to the signer and approved
+	sig, err := mod.SignWithDescription(userID.Public(), hash, "contract")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !userID.Verify(hash, sig) {
+		t.Fatal("invalid signature")
+	}
+
+	// the signer rejects the request
+	_, err = mod.SignWithDescription(userID.Public(), hash, "something else")
+	if !errors.Is(err, keys.ErrSignRejected) {
+		t.Fatalf("expected ErrSignRejected, got %v", err)
+	}
+
+	// the signer doesn't hold the key
+	_, err = mod.SignWithDescription(otherID.Public(), hash, "contract")
+	if err == nil {
+		t.Fatal("signed without the key")
+	}
+
+	// a signature made with a different key is refused
+	signer.forge = otherID
+	_, err = mod.SignWithDescription(userID.Public(), hash, "contract")
+	if err == nil {
+		t.Fatal("accepted a signature made with a different key")
+	}
+
+	if _, found := mod.remoteSigner(nodeID); found {
+		t.Fatal("signer found for an unconfigured identity")
+	}
+}
+
+func TestLocked(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&dbPrivateKey{}); err != nil {
+		t.Fatal(err)
+	}
+
+	var mod = &Module{db: db}
+
+	// encrypted keys found in storage don't lock the module
+	db.Create(&dbPrivateKey{DataID: object.ID{Size: 1, Hash: [32]byte{1}}, Encrypted: true})
+	if mod.locked() {
+		t.Fatal("locked by a foreign key")
+	}
+
+	db.Create(&dbPrivateKey{DataID: object.ID{Size: 1, Hash: [32]byte{2}}, Encrypted: true, Local: true})
+	if !mod.locked() {
+		t.Fatal("not locked by a local key")
+	}
+
+	mod.passphrase = []byte("passphrase")
+	if mod.locked() {
+		t.Fatal("locked with the passphrase")
+	}
+}
+
+func TestDeriveEmptyPath(t *testing.T) {
+	var mod = &Module{}
+
+	for _, path := range []string{"", "/", "//"} {
+		if _, _, err := mod.DeriveKey("", path); !errors.Is(err, keys.ErrInvalidPath) {
+			t.Fatalf("%q: expected ErrInvalidPath, got %v", path, err)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package keys
 
 import (
+	"github.com/cryptopunkscc/astrald/auth/id"
 	"github.com/cryptopunkscc/astrald/log"
 	"github.com/cryptopunkscc/astrald/mod/keys"
 	"github.com/cryptopunkscc/astrald/node/assets"
@@ -15,10 +16,17 @@ func (Loader) Load(node modules.Node, assets assets.Assets, log *log.Logger) (mo
 		node:   node,
 		log:    log,
 		assets: assets,
+		config: defaultConfig,
+
+		unlocked: map[string]id.Identity{},
 	}
 
 	_ = assets.LoadYAML(keys.ModuleName, &mod.config)
 
+	if mod.config.SignTimeout <= 0 {
+		mod.config.SignTimeout = defaultConfig.SignTimeout
+	}
+
 	mod.db = mod.assets.Database()
 
 	err = mod.db.AutoMigrate(&dbPrivateKey{})
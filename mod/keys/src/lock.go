@@ -0,0 +1,248 @@
+package keys
+
+import (
+	"bytes"
+	"errors"
+	"github.com/cryptopunkscc/astrald/auth/id"
+	"github.com/cryptopunkscc/astrald/cslq"
+	"github.com/cryptopunkscc/astrald/lib/adc"
+	"github.com/cryptopunkscc/astrald/mod/keys"
+	"github.com/cryptopunkscc/astrald/object"
+)
+
+func (mod *Module) Unlock(passphrase []byte) error {
+	mod.mu.Lock()
+	defer mod.mu.Unlock()
+
+	if !mod.isEncrypted() {
+		return errors.New("keys are not encrypted")
+	}
+
+	unlocked, err := mod.decryptAll(passphrase)
+	if err != nil {
+		return err
+	}
+
+	mod.passphrase = bytes.Clone(passphrase)
+	mod.unlocked = map[string]id.Identity{}
+	for _, key := range unlocked {
+		mod.cache(key.pk)
+	}
+
+	mod.log.Info("keys unlocked")
+
+	// import the node's key if it was skipped while locked
+	if !mod.hasKey(mod.node.Identity()) {
+		if _, err := mod.saveKey(mod.node.Identity()); err != nil {
+			mod.log.Errorv(0, "error importing node identity: %v", err)
+		}
+	}
+
+	return nil
+}
+
+func (mod *Module) Lock() {
+	mod.mu.Lock()
+	defer mod.mu.Unlock()
+
+	clear(mod.passphrase)
+	mod.passphrase = nil
+	mod.unlocked = map[string]id.Identity{}
+}
+
+func (mod *Module) Locked() bool {
+	mod.mu.Lock()
+	defer mod.mu.Unlock()
+
+	return mod.locked()
+}
+
+func (mod *Module) SetPassphrase(current []byte, passphrase []byte) error {
+	if len(passphrase) == 0 {
+		return errors.New("passphrase cannot be empty")
+	}
+
+	mod.mu.Lock()
+	defer mod.mu.Unlock()
+
+	// decrypt all keys with the current passphrase (this also verifies the passphrase)
+	var all []storedKey
+	if mod.isEncrypted() {
+		var err error
+		all, err = mod.decryptAll(current)
+		if err != nil {
+			return err
+		}
+	}
+
+	// migrate plaintext keys
+	var rows []dbPrivateKey
+	err := mod.db.Where("encrypted = ?", false).Find(&rows).Error
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		pk, err := mod.loadPrivateKey(row.DataID, nil)
+		if err != nil {
+			mod.log.Errorv(1, "error loading key %v: %v", row.DataID, err)
+			continue
+		}
+		all = append(all, storedKey{objectID: row.DataID, pk: pk})
+	}
+
+	// store keys encrypted with the new passphrase
+	mod.passphrase = bytes.Clone(passphrase)
+	mod.unlocked = map[string]id.Identity{}
+
+	var errs []error
+	for _, key := range all {
+		epk, err := keys.EncryptPrivateKey(key.pk, passphrase)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		objectID, err := mod.storeKey(keys.EncryptedPrivateKeyDataType, epk)
+		if err == nil {
+			err = mod.indexKey(objectID, true)
+			if errors.Is(err, ErrAlreadyIndexed) {
+				err = nil
+			}
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		mod.cache(key.pk)
+
+		mod.removeKey(key.objectID)
+	}
+
+	if err := mod.encryptNodeIdentity(current, passphrase); err != nil {
+		errs = append(errs, err)
+	}
+
+	mod.log.Info("%v key(s) encrypted with the new passphrase", len(all))
+
+	return errors.Join(errs...)
+}
+
+type storedKey struct {
+	objectID object.ID
+	pk       *keys.PrivateKey
+}
+
+// decryptAll decrypts all encrypted keys that the passphrase opens. Keys found in storage can be encrypted
+// with other passphrases and are skipped, but the passphrase has to open the local keys. Requires mod.mu.
+func (mod *Module) decryptAll(passphrase []byte) ([]storedKey, error) {
+	var rows []dbPrivateKey
+	err := mod.db.Where("encrypted = ?", true).Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var list []storedKey
+	var verified bool
+	for _, row := range rows {
+		pk, err := mod.loadPrivateKey(row.DataID, passphrase)
+		if err != nil {
+			if row.Local || !errors.Is(err, keys.ErrInvalidPassphrase) {
+				mod.log.Errorv(1, "error loading key %v: %v", row.DataID, err)
+			}
+			continue
+		}
+
+		verified = verified || row.Local
+		list = append(list, storedKey{objectID: row.DataID, pk: pk})
+	}
+
+	if !verified {
+		return nil, keys.ErrInvalidPassphrase
+	}
+
+	return list, nil
+}
+
+// removeKey removes a key from the index and purges it from storage
+func (mod *Module) removeKey(objectID object.ID) {
+	err := mod.db.Where("data_id = ?", objectID).Delete(&dbPrivateKey{}).Error
+	if err != nil {
+		mod.log.Errorv(1, "error removing key %v from index: %v", objectID, err)
+	}
+
+	if err = mod.objects.Release(mod.node.Identity(), objectID); err != nil {
+		mod.log.Errorv(1, "error releasing key %v: %v", objectID, err)
+	}
+
+	if _, err = mod.objects.Purge(objectID, nil); err != nil {
+		mod.log.Errorv(1, "error purging key %v: %v", objectID, err)
+	}
+}
+
+// encryptNodeIdentity rewrites the node's key resource encrypted with the new passphrase
+func (mod *Module) encryptNodeIdentity(current []byte, passphrase []byte) error {
+	data, err := mod.assets.Read(keys.NodeIdentityResource)
+	if err != nil {
+		return nil // the node's key isn't stored
+	}
+
+	var pk *keys.PrivateKey
+	if len(data) == 32 {
+		pk = &keys.PrivateKey{Type: keys.KeyTypeIdentity, Bytes: data}
+	} else {
+		pk, err = keys.ReadPrivateKey(bytes.NewReader(data), func() ([]byte, error) {
+			return current, nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	epk, err := keys.EncryptPrivateKey(pk, passphrase)
+	if err != nil {
+		return err
+	}
+
+	var buf = &bytes.Buffer{}
+	err = cslq.Encode(buf, "vv", adc.Header(keys.EncryptedPrivateKeyDataType), epk)
+	if err != nil {
+		return err
+	}
+
+	return mod.assets.Write(keys.NodeIdentityResource, buf.Bytes())
+}
+
+// cache keeps the decrypted signing key in memory until the keys are locked. Requires mod.mu.
+func (mod *Module) cache(pk *keys.PrivateKey) {
+	if pk.Type == keys.KeyTypeSeed {
+		return
+	}
+
+	identity, err := pk.Identity()
+	if err != nil {
+		return
+	}
+
+	mod.unlocked[identity.PublicKeyHex()] = identity
+}
+
+// isEncrypted returns true if any of the local keys is encrypted. Encrypted keys found in storage don't
+// count, since they could be encrypted by anyone.
+func (mod *Module) isEncrypted() bool {
+	var count int64
+	mod.db.Model(&dbPrivateKey{}).Where("encrypted = ? and local = ?", true, true).Count(&count)
+	return count > 0
+}
+
+// locked returns true if keys are encrypted and the passphrase is unknown. Requires mod.mu.
+func (mod *Module) locked() bool {
+	return mod.passphrase == nil && mod.isEncrypted()
+}
+
+// hasKey returns true if the private key of the identity is stored
+func (mod *Module) hasKey(identity id.Identity) bool {
+	var count int64
+	mod.db.Model(&dbPrivateKey{}).Where("public_key = ?", identity.PublicKeyHex()).Count(&count)
+	return count > 0
+}
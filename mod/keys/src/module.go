@@ -2,8 +2,6 @@ package keys
 
 import (
 	"context"
-	"crypto/ecdsa"
-	"crypto/rand"
 	"errors"
 	"github.com/cryptopunkscc/astrald/auth/id"
 	"github.com/cryptopunkscc/astrald/cslq"
@@ -18,6 +16,7 @@ import (
 	"github.com/cryptopunkscc/astrald/tasks"
 	"gorm.io/gorm"
 	"strings"
+	"sync"
 )
 
 var _ keys.Module = &Module{}
@@ -30,23 +29,34 @@ type Module struct {
 	objects objects.Module
 	content content.Module
 	db      *gorm.DB
+
+	mu         sync.Mutex
+	passphrase []byte                 // nil while locked
+	unlocked   map[string]id.Identity // keys decrypted with the passphrase by public key
 }
 
 var ErrAlreadyIndexed = errors.New("already indexed")
 var privateKeyHeader = adc.Header(keys.PrivateKeyDataType)
 
 func (mod *Module) Run(ctx context.Context) error {
+	mod.importNodeSeed()
+
 	return tasks.Group(
 		&IndexerService{Module: mod},
 	).Run(ctx)
 }
 
 func (mod *Module) CreateKey(alias string) (identity id.Identity, objectID object.ID, err error) {
+	return mod.CreateKeyOfType(alias, keys.KeyTypeIdentity)
+}
+
+// CreateKeyOfType creates a new key of the provided type and assigns the alias to it
+func (mod *Module) CreateKeyOfType(alias string, keyType string) (identity id.Identity, objectID object.ID, err error) {
 	if _, err := mod.node.Tracker().IdentityByAlias(alias); err == nil {
 		return identity, objectID, errors.New("alias already in use")
 	}
 
-	identity, err = id.GenerateIdentity()
+	identity, err = keys.GenerateKey(keyType)
 	if err != nil {
 		return
 	}
@@ -62,42 +72,84 @@ func (mod *Module) CreateKey(alias string) (identity id.Identity, objectID objec
 }
 
 func (mod *Module) SaveKey(key id.Identity) (object.ID, error) {
-	if key.PrivateKey() == nil {
+	if !key.HasPrivateKey() {
 		return object.ID{}, errors.New("private key is nil")
 	}
 
-	pk := keys.PrivateKey{
-		Type:  keys.KeyTypeIdentity,
-		Bytes: key.PrivateKey().Serialize(),
-	}
+	mod.mu.Lock()
+	defer mod.mu.Unlock()
 
-	w, err := mod.objects.Create(&objects.CreateOpts{Alloc: 70})
-	if err != nil {
-		return object.ID{}, err
-	}
+	return mod.saveKey(key)
+}
 
-	err = adc.WriteHeader(w, privateKeyHeader)
+// saveKey stores the private key of the identity. Requires mod.mu.
+func (mod *Module) saveKey(key id.Identity) (object.ID, error) {
+	return mod.savePrivateKey(&keys.PrivateKey{
+		Type:  keys.KeyType(key),
+		Bytes: key.PrivateKeyBytes(),
+	})
+}
+
+// savePrivateKey stores the private key, encrypted if keys are encrypted. Requires mod.mu.
+func (mod *Module) savePrivateKey(pk *keys.PrivateKey) (objectID object.ID, err error) {
+	if !mod.isEncrypted() {
+		objectID, err = mod.storeKey(keys.PrivateKeyDataType, pk)
+	} else {
+		if mod.passphrase == nil {
+			return object.ID{}, keys.ErrLocked
+		}
+
+		var epk *keys.EncryptedPrivateKey
+		epk, err = keys.EncryptPrivateKey(pk, mod.passphrase)
+		if err != nil {
+			return
+		}
+
+		objectID, err = mod.storeKey(keys.EncryptedPrivateKeyDataType, epk)
+		if err == nil {
+			mod.cache(pk)
+		}
+	}
 	if err != nil {
-		return object.ID{}, nil
+		mod.log.Errorv(1, "error importing private key %v: %v", pk.Type, err)
+		return
 	}
 
-	err = cslq.Encode(w, "v", &pk)
+	return objectID, mod.indexKey(objectID, true)
+}
+
+func (mod *Module) storeKey(dataType string, key any) (object.ID, error) {
+	w, err := mod.objects.Create(&objects.CreateOpts{Alloc: 256})
 	if err != nil {
 		return object.ID{}, err
 	}
 
-	objectID, err := w.Commit()
+	err = cslq.Encode(w, "vv", adc.Header(dataType), key)
 	if err != nil {
-		mod.log.Errorv(1, "error importing private key %v: %v", key, err)
+		w.Discard()
+		return object.ID{}, err
 	}
 
-	return objectID, mod.IndexKey(objectID)
+	return w.Commit()
 }
 
 func (mod *Module) IndexKey(objectID object.ID) error {
+	return mod.indexKey(objectID, false)
+}
+
+// indexKey adds the key to the index. Local keys are the ones stored by this module, which decide whether
+// the module is locked.
+func (mod *Module) indexKey(objectID object.ID, local bool) error {
 	var row dbPrivateKey
 	var err = mod.db.Where("data_id = ?", objectID).First(&row).Error
 	if err == nil {
+		if local && !row.Local {
+			err = mod.db.Model(&row).Where("data_id = ?", objectID).Update("local", true).Error
+			if err != nil {
+				return err
+			}
+		}
+		mod.hold(objectID)
 		return ErrAlreadyIndexed
 	}
 
@@ -107,86 +159,128 @@ func (mod *Module) IndexKey(objectID object.ID) error {
 	}
 	defer r.Close()
 
-	err = adc.ExpectHeader(r, keys.PrivateKeyDataType)
+	header, err := adc.ReadHeader(r)
 	if err != nil {
 		return err
 	}
 
-	var pk keys.PrivateKey
-	if err = cslq.Decode(r, "v", &pk); err != nil {
-		return err
-	}
+	row = dbPrivateKey{DataID: objectID, Local: local}
 
-	if pk.Type != keys.KeyTypeIdentity {
-		return errors.New("unsupported key type")
-	}
+	switch header {
+	case keys.PrivateKeyDataType:
+		var pk keys.PrivateKey
+		if err = cslq.Decode(r, "v", &pk); err != nil {
+			return err
+		}
 
-	identity, err := id.ParsePrivateKey(pk.Bytes)
-	if err != nil {
-		return err
+		row.Type = pk.Type
+		row.PublicKey, err = pk.Identity()
+		if err != nil {
+			return err
+		}
+
+	case keys.EncryptedPrivateKeyDataType:
+		var epk keys.EncryptedPrivateKey
+		if err = cslq.Decode(r, "v", &epk); err != nil {
+			return err
+		}
+
+		row.Type = epk.Type
+		row.PublicKey = epk.PublicKey
+		row.Encrypted = true
+
+	default:
+		return errors.New("not a private key")
 	}
 
-	err = mod.db.Create(&dbPrivateKey{
-		DataID:    objectID,
-		Type:      pk.Type,
-		PublicKey: identity,
-	}).Error
+	err = mod.db.Create(&row).Error
 
 	switch {
 	case err == nil:
-		return nil
 	case strings.Contains(err.Error(), "UNIQUE constraint failed"):
-		return nil
 	default:
 		return err
 	}
+
+	mod.hold(objectID)
+
+	return nil
+}
+
+// hold keeps the key object from being garbage collected
+func (mod *Module) hold(objectID object.ID) {
+	if err := mod.objects.Hold(mod.node.Identity(), objectID); err != nil {
+		mod.log.Errorv(1, "error holding key %v: %v", objectID, err)
+	}
 }
 
 func (mod *Module) LoadPrivateKey(objectID object.ID) (*keys.PrivateKey, error) {
+	mod.mu.Lock()
+	defer mod.mu.Unlock()
+
+	return mod.loadPrivateKey(objectID, mod.passphrase)
+}
+
+// loadPrivateKey reads the private key, decrypting it with the passphrase if needed
+func (mod *Module) loadPrivateKey(objectID object.ID, passphrase []byte) (*keys.PrivateKey, error) {
 	r, err := mod.objects.Open(context.Background(), objectID, objects.DefaultOpenOpts())
 	if err != nil {
 		return nil, err
 	}
 	defer r.Close()
 
-	err = adc.ExpectHeader(r, keys.PrivateKeyDataType)
-	if err != nil {
-		return nil, err
-	}
+	return keys.ReadPrivateKey(r, func() ([]byte, error) {
+		if passphrase == nil {
+			return nil, keys.ErrLocked
+		}
+		return passphrase, nil
+	})
+}
 
-	var pk keys.PrivateKey
-	if err = cslq.Decode(r, "v", &pk); err != nil {
-		return nil, err
+func (mod *Module) FindIdentity(hex string) (id.Identity, error) {
+	mod.mu.Lock()
+	defer mod.mu.Unlock()
+
+	if identity, found := mod.unlocked[hex]; found {
+		return identity, nil
 	}
 
-	return &pk, nil
-}
+	if mod.locked() {
+		return id.Identity{}, keys.ErrLocked
+	}
 
-func (mod *Module) FindIdentity(hex string) (id.Identity, error) {
 	var row dbPrivateKey
 
-	tx := mod.db.Where("type = ? and public_key = ?", keys.KeyTypeIdentity, hex).First(&row)
+	tx := mod.db.Where("type in ? and public_key = ?", []string{keys.KeyTypeIdentity, keys.KeyTypeEd25519}, hex).First(&row)
 	if tx.Error != nil {
 		return id.Identity{}, tx.Error
 	}
 
-	pk, err := mod.LoadPrivateKey(row.DataID)
+	pk, err := mod.loadPrivateKey(row.DataID, mod.passphrase)
 	if err != nil {
 		return id.Identity{}, err
 	}
 
-	return id.ParsePrivateKey(pk.Bytes)
+	return pk.Identity()
 }
 
 func (mod *Module) Sign(identity id.Identity, hash []byte) ([]byte, error) {
-	var err error
+	return mod.SignWithDescription(identity, hash, "")
+}
 
-	if identity.PrivateKey() == nil {
-		identity, err = mod.FindIdentity(identity.PublicKeyHex())
-		if err != nil {
-			return nil, err
-		}
+func (mod *Module) SignWithDescription(identity id.Identity, hash []byte, description string) ([]byte, error) {
+	if identity.HasPrivateKey() {
+		return identity.Sign(hash)
+	}
+
+	if signerID, found := mod.remoteSigner(identity); found {
+		return mod.signRemote(signerID, identity, hash, description)
+	}
+
+	key, err := mod.FindIdentity(identity.PublicKeyHex())
+	if err != nil {
+		return nil, err
 	}
 
-	return ecdsa.SignASN1(rand.Reader, identity.PrivateKey().ToECDSA(), hash)
+	return key.Sign(hash)
 }
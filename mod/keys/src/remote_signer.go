@@ -0,0 +1,93 @@
+package keys
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/cryptopunkscc/astrald/auth/id"
+	"github.com/cryptopunkscc/astrald/cslq"
+	"github.com/cryptopunkscc/astrald/mod/keys"
+	"github.com/cryptopunkscc/astrald/net"
+)
+
+// remoteSigner returns the remote signer configured for the identity
+func (mod *Module) remoteSigner(identity id.Identity) (id.Identity, bool) {
+	for name, signer := range mod.config.Signers {
+		target, err := mod.node.Resolver().Resolve(name)
+		if err != nil || !target.IsEqual(identity) {
+			continue
+		}
+
+		signerID, err := mod.node.Resolver().Resolve(signer)
+		if err != nil {
+			mod.log.Errorv(1, "cannot resolve signer %v: %v", signer, err)
+			return id.Identity{}, false
+		}
+
+		return signerID, true
+	}
+
+	return id.Identity{}, false
+}
+
+// signRemote asks the remote signer to sign the hash with the identity and waits for its approval
+func (mod *Module) signRemote(signerID id.Identity, identity id.Identity, hash []byte, description string) ([]byte, error) {
+	if description == "" {
+		description = fmt.Sprintf("hash %x", hash)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), mod.config.SignTimeout)
+	defer cancel()
+
+	var query = net.NewQuery(mod.node.Identity(), signerID, keys.SignServiceName)
+
+	conn, err := net.Route(ctx, mod.node.Router(), query)
+	if err != nil {
+		return nil, fmt.Errorf("signer %v unavailable: %w", signerID, err)
+	}
+	defer conn.Close()
+
+	// the signer waits for the user, so make sure we don't wait longer than the timeout
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	mod.log.Info("waiting for %v to sign %v as %v", signerID, description, identity)
+
+	err = cslq.Encode(conn, "v", &keys.SignRequest{
+		Identity:    identity,
+		Hash:        hash,
+		Description: description,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var code int
+	if err = cslq.Decode(conn, "c", &code); err != nil {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("signer %v did not respond in time", signerID)
+		}
+		return nil, err
+	}
+
+	switch code {
+	case keys.SignSigned:
+	case keys.SignRejected:
+		return nil, keys.ErrSignRejected
+	case keys.SignKeyNotFound:
+		return nil, fmt.Errorf("signer %v has no key for %v", signerID, identity)
+	default:
+		return nil, errors.New("invalid response from signer")
+	}
+
+	var sig []byte
+	if err = cslq.Decode(conn, "[c]c", &sig); err != nil {
+		return nil, err
+	}
+
+	if !identity.Verify(hash, sig) {
+		return nil, errors.New("signer returned an invalid signature")
+	}
+
+	return sig, nil
+}
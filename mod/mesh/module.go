@@ -0,0 +1,30 @@
+package mesh
+
+import (
+	"github.com/cryptopunkscc/astrald/auth/id"
+	"time"
+)
+
+const ModuleName = "mesh"
+
+// TableServiceName is the service that serves reachability tables to linked peers
+const TableServiceName = "mesh.table"
+
+// ForwardServiceName is the service that forwards link connections towards their target
+const ForwardServiceName = "mesh.forward"
+
+// NetworkName is the network name of links established through other nodes
+const NetworkName = "mesh"
+
+type Module interface {
+	// Routes returns the routes to identities reachable through linked peers
+	Routes() []Route
+}
+
+// Route is a path to an identity through a linked peer
+type Route struct {
+	Target    id.Identity // identity at the end of the route
+	NextHop   id.Identity // linked peer that forwards to the target
+	Hops      int         // number of hops to the target
+	UpdatedAt time.Time   // time when the route was last advertised
+}
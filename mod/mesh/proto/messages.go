@@ -0,0 +1,13 @@
+package proto
+
+import "github.com/cryptopunkscc/astrald/auth/id"
+
+// Table is a list of identities reachable from the node that sends it
+type Table struct {
+	Entries []Entry `cslq:"[s]v"`
+}
+
+type Entry struct {
+	Identity id.Identity `cslq:"v"`
+	Hops     int         `cslq:"c"`
+}
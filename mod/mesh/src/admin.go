@@ -0,0 +1,98 @@
+package mesh
+
+import (
+	"errors"
+	"fmt"
+	"github.com/cryptopunkscc/astrald/mod/admin"
+	"time"
+)
+
+type Admin struct {
+	mod  *Module
+	cmds map[string]func(admin.Terminal, []string) error
+}
+
+func NewAdmin(mod *Module) *Admin {
+	var adm = &Admin{mod: mod}
+	adm.cmds = map[string]func(admin.Terminal, []string) error{
+		"routes":  adm.routes,
+		"peers":   adm.peers,
+		"refresh": adm.refresh,
+		"help":    adm.help,
+	}
+
+	return adm
+}
+
+func (adm *Admin) Exec(term admin.Terminal, args []string) error {
+	if len(args) < 2 {
+		return adm.help(term, []string{})
+	}
+
+	cmd, args := args[1], args[2:]
+	if fn, found := adm.cmds[cmd]; found {
+		return fn(term, args)
+	}
+
+	return errors.New("unknown command")
+}
+
+func (adm *Admin) routes(term admin.Terminal, _ []string) error {
+	var f = "%-20s %-20s %4s %s\n"
+
+	term.Printf(f, admin.Header("Target"), admin.Header("Next hop"), admin.Header("Hops"), admin.Header("Age"))
+	for _, r := range adm.mod.Routes() {
+		term.Printf(f,
+			r.Target,
+			r.NextHop,
+			fmt.Sprintf("%d", r.Hops),
+			time.Since(r.UpdatedAt).Round(time.Second),
+		)
+	}
+
+	return nil
+}
+
+func (adm *Admin) peers(term admin.Terminal, _ []string) error {
+	adm.mod.mu.Lock()
+	var list = make([]*peerTable, 0, len(adm.mod.peers))
+	for _, p := range adm.mod.peers {
+		list = append(list, p)
+	}
+	adm.mod.mu.Unlock()
+
+	var f = "%-20s %7s %s\n"
+
+	term.Printf(f, admin.Header("Peer"), admin.Header("Entries"), admin.Header("Age"))
+	for _, p := range list {
+		term.Printf(f,
+			p.identity,
+			fmt.Sprintf("%d", len(p.entries)),
+			time.Since(p.updatedAt).Round(time.Second),
+		)
+	}
+
+	return nil
+}
+
+func (adm *Admin) refresh(term admin.Terminal, _ []string) error {
+	(&Gossip{Module: adm.mod}).refresh(adm.mod.ctx)
+
+	term.Printf("%d routes\n", len(adm.mod.Routes()))
+
+	return nil
+}
+
+func (adm *Admin) ShortDescription() string {
+	return "multi-hop routes"
+}
+
+func (adm *Admin) help(term admin.Terminal, _ []string) error {
+	term.Printf("usage: %s <command>\n\n", "mesh")
+	term.Printf("commands:\n")
+	term.Printf("  routes         list routes through linked peers\n")
+	term.Printf("  peers          list reachability tables of linked peers\n")
+	term.Printf("  refresh        fetch tables from linked peers now\n")
+	term.Printf("  help           show help\n")
+	return nil
+}
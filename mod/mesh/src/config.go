@@ -0,0 +1,23 @@
+package mesh
+
+import "time"
+
+type Config struct {
+	// MaxHops is the maximum number of hops of a route, including the last one
+	MaxHops int `yaml:"max_hops"`
+
+	// Forward lets linked peers route through this node. Routes are not advertised when disabled. Relaying
+	// traffic of other nodes costs bandwidth, so it's disabled by default.
+	Forward bool `yaml:"forward"`
+
+	// GossipInterval sets how often reachability tables are fetched from linked peers
+	GossipInterval time.Duration `yaml:"gossip_interval"`
+}
+
+var defaultConfig = Config{
+	MaxHops:        4,
+	GossipInterval: time.Minute,
+}
+
+// minMaxHops is the lowest sensible hop limit - routes of a single hop are direct links
+const minMaxHops = 2
@@ -0,0 +1,39 @@
+package mesh
+
+import (
+	"github.com/cryptopunkscc/astrald/auth/id"
+	"github.com/cryptopunkscc/astrald/mod/mesh"
+	"github.com/cryptopunkscc/astrald/net"
+)
+
+var _ net.Conn = &Conn{}
+
+// Conn is a connection forwarded through other nodes. Links run their own handshake over it, so
+// forwarding nodes can't read or alter the traffic.
+type Conn struct {
+	net.SecureConn
+	localEndpoint  net.Endpoint
+	remoteEndpoint net.Endpoint
+	outbound       bool
+}
+
+func newConn(conn net.SecureConn, localID id.Identity, remoteID id.Identity, outbound bool) *Conn {
+	return &Conn{
+		SecureConn:     conn,
+		localEndpoint:  net.NewGenericEndpoint(mesh.NetworkName, localID.PublicKeyBytes()),
+		remoteEndpoint: net.NewGenericEndpoint(mesh.NetworkName, remoteID.PublicKeyBytes()),
+		outbound:       outbound,
+	}
+}
+
+func (conn *Conn) LocalEndpoint() net.Endpoint {
+	return conn.localEndpoint
+}
+
+func (conn *Conn) RemoteEndpoint() net.Endpoint {
+	return conn.remoteEndpoint
+}
+
+func (conn *Conn) Outbound() bool {
+	return conn.outbound
+}
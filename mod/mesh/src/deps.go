@@ -0,0 +1,16 @@
+package mesh
+
+import (
+	"github.com/cryptopunkscc/astrald/mod/admin"
+	"github.com/cryptopunkscc/astrald/mod/mesh"
+	"github.com/cryptopunkscc/astrald/node/modules"
+)
+
+func (mod *Module) LoadDependencies() error {
+	// inject admin command
+	if adm, err := modules.Load[admin.Module](mod.node, admin.ModuleName); err == nil {
+		adm.AddCommand(mesh.ModuleName, NewAdmin(mod))
+	}
+
+	return nil
+}
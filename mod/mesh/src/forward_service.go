@@ -0,0 +1,119 @@
+package mesh
+
+import (
+	"context"
+	"errors"
+	"github.com/cryptopunkscc/astrald/auth/id"
+	"github.com/cryptopunkscc/astrald/mod/mesh"
+	"github.com/cryptopunkscc/astrald/net"
+	"github.com/cryptopunkscc/astrald/node/link"
+	"github.com/cryptopunkscc/astrald/node/router"
+	"strconv"
+	"time"
+)
+
+const acceptTimeout = 15 * time.Second
+
+// ForwardService forwards link connections to their target or to the next hop towards it
+type ForwardService struct {
+	*Module
+}
+
+func (srv *ForwardService) Run(ctx context.Context) error {
+	err := srv.node.LocalRouter().AddRoute(mesh.ForwardServiceName, srv)
+	if err != nil {
+		return err
+	}
+	defer srv.node.LocalRouter().RemoveRoute(mesh.ForwardServiceName)
+
+	<-ctx.Done()
+
+	return nil
+}
+
+func (srv *ForwardService) RouteQuery(ctx context.Context, query net.Query, caller net.SecureWriteCloser, hints net.Hints) (net.SecureWriteCloser, error) {
+	_, params := router.ParseQuery(query.Query())
+
+	targetID, err := id.ParsePublicKeyHex(params["target"])
+	if err != nil {
+		return net.Reject()
+	}
+
+	ttl, err := params.GetInt("ttl")
+	if err != nil {
+		return net.Reject()
+	}
+
+	// peers cannot make routes longer than our own limit
+	ttl = min(ttl, srv.config.MaxHops)
+
+	// accept the connection if we're the target
+	if targetID.IsEqual(srv.node.Identity()) {
+		return net.Accept(query, caller, func(conn net.SecureConn) {
+			srv.accept(conn, query.Caller())
+		})
+	}
+
+	if !srv.config.Forward {
+		return net.Reject()
+	}
+
+	if ttl < 1 {
+		return net.RouteNotFound(srv, errors.New("hop limit reached"))
+	}
+
+	// find the next hop
+	var next = srv.directLink(targetID)
+	if next == nil {
+		r, found := srv.route(targetID)
+		if !found {
+			return net.RouteNotFound(srv, errors.New("no route to target"))
+		}
+		next = srv.directLink(r.NextHop)
+		if next == nil {
+			return net.RouteNotFound(srv, errors.New("next hop not linked"))
+		}
+	}
+
+	srv.log.Logv(2, "[%v] forwarding %v to %v via %v", query.Nonce(), query.Caller(), targetID, next.RemoteIdentity())
+
+	var nextQuery = net.NewQueryNonce(
+		srv.node.Identity(),
+		next.RemoteIdentity(),
+		forwardQuery(targetID, ttl-1),
+		query.Nonce(),
+	)
+
+	// route directly over the link - the next hop locks the nonce in its router, so if the query comes
+	// back to us, our router (which holds the lock until we return) will refuse it
+	dst, err := next.RouteQuery(ctx, nextQuery, net.NewIdentityTranslation(caller, srv.node.Identity()), hints)
+	if err != nil {
+		return nil, err
+	}
+
+	return net.NewIdentityTranslation(dst, srv.node.Identity()), nil
+}
+
+// accept runs the link handshake over the forwarded connection
+func (srv *ForwardService) accept(conn net.SecureConn, callerID id.Identity) {
+	ctx, cancel := context.WithTimeout(srv.ctx, acceptTimeout)
+	defer cancel()
+
+	l, err := link.Accept(ctx, newConn(conn, srv.node.Identity(), callerID, false), srv.node.Identity(), srv.node.Network().LinkConfig())
+	if err != nil {
+		srv.log.Errorv(1, "error accepting forwarded link: %v", err)
+		conn.Close()
+		return
+	}
+
+	if err = srv.node.Network().AddLink(l); err != nil {
+		l.Close()
+	}
+}
+
+func forwardQuery(target id.Identity, ttl int) string {
+	return router.Query(mesh.ForwardServiceName, router.Params{
+		"target": target.PublicKeyHex(),
+		"ttl":    strconv.Itoa(ttl),
+	})
+}
@@ -0,0 +1,104 @@
+package mesh
+
+import (
+	"context"
+	"errors"
+	"github.com/cryptopunkscc/astrald/auth/id"
+	"github.com/cryptopunkscc/astrald/log"
+	"github.com/cryptopunkscc/astrald/net"
+	"github.com/cryptopunkscc/astrald/node"
+	"github.com/cryptopunkscc/astrald/node/network"
+	"github.com/cryptopunkscc/astrald/node/router"
+	"io"
+	"testing"
+)
+
+type testNode struct {
+	node.Node
+	identity id.Identity
+	network  *testNetwork
+}
+
+func (n *testNode) Identity() id.Identity {
+	return n.identity
+}
+
+func (n *testNode) Network() network.Network {
+	return n.network
+}
+
+type testNetwork struct {
+	network.Network
+	links *network.LinkSet
+}
+
+func (n *testNetwork) Links() *network.LinkSet {
+	return n.links
+}
+
+// testLink records queries routed over it
+type testLink struct {
+	net.Link
+	remoteID id.Identity
+	queries  []net.Query
+}
+
+func (l *testLink) RemoteIdentity() id.Identity {
+	return l.remoteID
+}
+
+func (l *testLink) Transport() net.SecureConn {
+	return nil
+}
+
+func (l *testLink) RouteQuery(ctx context.Context, query net.Query, caller net.SecureWriteCloser, hints net.Hints) (net.SecureWriteCloser, error) {
+	l.queries = append(l.queries, query)
+	return net.Reject()
+}
+
+func TestForwardService(t *testing.T) {
+	var nodeID, callerID, targetID id.Identity
+	for _, i := range []*id.Identity{&nodeID, &callerID, &targetID} {
+		var err error
+		if *i, err = id.GenerateIdentity(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var next = &testLink{remoteID: targetID}
+	var links = network.NewLinkSet()
+	links.Add(next)
+
+	var srv = &ForwardService{Module: &Module{
+		config: Config{MaxHops: 4},
+		node:   &testNode{identity: nodeID, network: &testNetwork{links: links}},
+		log:    log.NewLogger(log.NewLinePrinter(log.NewMonoOutput(io.Discard))),
+	}}
+
+	var forward = func(ttl int) error {
+		var query = net.NewQuery(callerID, nodeID, forwardQuery(targetID, ttl))
+		_, err := srv.RouteQuery(context.Background(), query, net.NewSecurePipeWriter(nil, callerID), net.DefaultHints())
+		return err
+	}
+
+	// forwarding is disabled by default
+	if err := forward(3); !errors.Is(err, net.ErrRejected) || len(next.queries) != 0 {
+		t.Fatalf("forwarded with forwarding disabled: %v", err)
+	}
+
+	srv.config.Forward = true
+
+	if err := forward(0); err == nil || len(next.queries) != 0 {
+		t.Fatal("forwarded past the hop limit")
+	}
+
+	// the ttl set by the caller is clamped to the local limit
+	forward(100)
+	if len(next.queries) != 1 {
+		t.Fatalf("expected 1 forwarded query, got %d", len(next.queries))
+	}
+	_, params := router.ParseQuery(next.queries[0].Query())
+	if ttl, _ := params.GetInt("ttl"); ttl != srv.config.MaxHops-1 {
+		t.Fatalf("forwarded with ttl %d", ttl)
+	}
+}
@@ -0,0 +1,109 @@
+package mesh
+
+import (
+	"context"
+	"github.com/cryptopunkscc/astrald/auth/id"
+	"github.com/cryptopunkscc/astrald/cslq"
+	"github.com/cryptopunkscc/astrald/mod/mesh"
+	"github.com/cryptopunkscc/astrald/mod/mesh/proto"
+	"github.com/cryptopunkscc/astrald/net"
+	"github.com/cryptopunkscc/astrald/node/network"
+	"sync"
+	"time"
+)
+
+const fetchTimeout = 15 * time.Second
+
+// Gossip periodically fetches reachability tables from linked peers and updates routes
+type Gossip struct {
+	*Module
+}
+
+func (g *Gossip) Run(ctx context.Context) error {
+	var events = g.node.Events().Subscribe(ctx)
+	var ticker = time.NewTicker(g.config.GossipInterval)
+	defer ticker.Stop()
+
+	g.refresh(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case <-ticker.C:
+			g.refresh(ctx)
+
+		case event, ok := <-events:
+			if !ok {
+				return nil
+			}
+
+			switch event := event.(type) {
+			case network.EventLinkAdded:
+				if net.Network(event.Link.Link) == mesh.NetworkName {
+					continue
+				}
+				go func(peer id.Identity) {
+					g.fetch(ctx, peer)
+					g.updateRoutes()
+				}(event.Link.RemoteIdentity())
+
+			case network.EventLinkRemoved:
+				g.prunePeers()
+				g.updateRoutes()
+			}
+		}
+	}
+}
+
+// refresh fetches tables from all linked peers
+func (g *Gossip) refresh(ctx context.Context) {
+	var wg sync.WaitGroup
+
+	for _, peer := range g.directPeers() {
+		wg.Add(1)
+		go func(peer id.Identity) {
+			defer wg.Done()
+			g.fetch(ctx, peer)
+		}(peer)
+	}
+
+	wg.Wait()
+
+	g.prunePeers()
+	g.updateRoutes()
+}
+
+// fetch fetches the table of a peer
+func (g *Gossip) fetch(ctx context.Context, peer id.Identity) {
+	ctx, cancel := context.WithTimeout(ctx, fetchTimeout)
+	defer cancel()
+
+	var l = g.directLink(peer)
+	if l == nil {
+		return
+	}
+
+	var query = net.NewQuery(g.node.Identity(), peer, mesh.TableServiceName)
+
+	conn, err := net.RouteWithHints(ctx, l, query, net.DefaultHints().SetSilent())
+	if err != nil {
+		g.log.Logv(2, "error fetching table from %v: %v", peer, err)
+		return
+	}
+	defer conn.Close()
+
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	var table proto.Table
+	if err := cslq.Decode(conn, "v", &table); err != nil {
+		g.log.Logv(2, "error reading table from %v: %v", peer, err)
+		return
+	}
+
+	g.setPeerTable(peer, &table)
+}
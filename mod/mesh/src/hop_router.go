@@ -0,0 +1,103 @@
+package mesh
+
+import (
+	"context"
+	"errors"
+	"github.com/cryptopunkscc/astrald/auth/id"
+	"github.com/cryptopunkscc/astrald/net"
+	"github.com/cryptopunkscc/astrald/node/link"
+	"github.com/cryptopunkscc/astrald/node/router"
+)
+
+var _ net.Router = &HopRouter{}
+
+// HopRouter routes queries to an identity that is reachable only through other nodes. It establishes
+// a link with the target over a connection forwarded by the hops and routes the query over it.
+type HopRouter struct {
+	*Module
+	target id.Identity
+}
+
+// dial is a link being established through hops
+type dial struct {
+	done chan struct{}
+	link net.Link
+	err  error
+}
+
+func (r *HopRouter) RouteQuery(ctx context.Context, query net.Query, caller net.SecureWriteCloser, hints net.Hints) (net.SecureWriteCloser, error) {
+	l, err := r.linkVia(ctx, r.target)
+	if err != nil {
+		return net.RouteNotFound(r, err)
+	}
+
+	return l.RouteQuery(ctx, query, caller, hints)
+}
+
+// linkVia establishes a link with the target through hops. Concurrent calls for the same target share
+// a single attempt.
+func (mod *Module) linkVia(ctx context.Context, target id.Identity) (net.Link, error) {
+	var key = target.PublicKeyHex()
+
+	mod.dialMu.Lock()
+	d, found := mod.dials[key]
+	if !found {
+		d = &dial{done: make(chan struct{})}
+		mod.dials[key] = d
+		go func() {
+			d.link, d.err = mod.dialVia(mod.ctx, target)
+			close(d.done)
+
+			mod.dialMu.Lock()
+			delete(mod.dials, key)
+			mod.dialMu.Unlock()
+		}()
+	}
+	mod.dialMu.Unlock()
+
+	select {
+	case <-d.done:
+		return d.link, d.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (mod *Module) dialVia(ctx context.Context, target id.Identity) (net.Link, error) {
+	r, found := mod.route(target)
+	if !found {
+		return nil, errors.New("no route")
+	}
+
+	var next = mod.directLink(r.NextHop)
+	if next == nil {
+		return nil, errors.New("next hop not linked")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, acceptTimeout)
+	defer cancel()
+
+	mod.log.Logv(1, "linking with %v via %v (%v hops)", target, r.NextHop, r.Hops)
+
+	// route through our router, so that the nonce is locked while the hops forward the query
+	var query = net.NewQuery(mod.node.Identity(), r.NextHop, forwardQuery(target, mod.config.MaxHops-1))
+	var hints = net.DefaultHints().SetSilent().WithValue(router.ViaRouterHintKey, next)
+
+	conn, err := net.RouteWithHints(ctx, mod.node.Router(), query, hints)
+	if err != nil {
+		return nil, err
+	}
+
+	l, err := link.Open(ctx, newConn(conn, mod.node.Identity(), target, true), target, mod.node.Identity(), mod.node.Network().LinkConfig())
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if err = mod.node.Network().AddLink(l); err != nil {
+		l.Close()
+		return nil, err
+	}
+
+	return l, nil
+}
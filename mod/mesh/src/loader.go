@@ -0,0 +1,38 @@
+package mesh
+
+import (
+	"github.com/cryptopunkscc/astrald/log"
+	"github.com/cryptopunkscc/astrald/mod/mesh"
+	"github.com/cryptopunkscc/astrald/node/assets"
+	"github.com/cryptopunkscc/astrald/node/modules"
+)
+
+type Loader struct{}
+
+func (Loader) Load(node modules.Node, assets assets.Assets, log *log.Logger) (modules.Module, error) {
+	var mod = &Module{
+		node:      node,
+		config:    defaultConfig,
+		log:       log,
+		peers:     map[string]*peerTable{},
+		installed: map[string]*installedRoute{},
+		dials:     map[string]*dial{},
+	}
+
+	_ = assets.LoadYAML(mesh.ModuleName, &mod.config)
+
+	if mod.config.MaxHops < minMaxHops {
+		mod.config.MaxHops = minMaxHops
+	}
+	if mod.config.GossipInterval <= 0 {
+		mod.config.GossipInterval = defaultConfig.GossipInterval
+	}
+
+	return mod, nil
+}
+
+func init() {
+	if err := modules.RegisterModule(mesh.ModuleName, Loader{}); err != nil {
+		panic(err)
+	}
+}
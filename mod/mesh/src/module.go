@@ -0,0 +1,277 @@
+package mesh
+
+import (
+	"cmp"
+	"context"
+	"github.com/cryptopunkscc/astrald/auth/id"
+	"github.com/cryptopunkscc/astrald/log"
+	"github.com/cryptopunkscc/astrald/mod/mesh"
+	"github.com/cryptopunkscc/astrald/mod/mesh/proto"
+	"github.com/cryptopunkscc/astrald/net"
+	"github.com/cryptopunkscc/astrald/node"
+	"github.com/cryptopunkscc/astrald/tasks"
+	"slices"
+	"sync"
+	"time"
+)
+
+var _ mesh.Module = &Module{}
+
+// routePriority is lower than the priority of links, so that direct links are always preferred
+const routePriority = 10
+
+type Module struct {
+	config Config
+	node   node.Node
+	log    *log.Logger
+	ctx    context.Context
+
+	mu        sync.Mutex
+	peers     map[string]*peerTable      // tables advertised by linked peers
+	installed map[string]*installedRoute // routes added to the router
+
+	dialMu sync.Mutex
+	dials  map[string]*dial
+}
+
+// peerTable is the reachability table advertised by a linked peer
+type peerTable struct {
+	identity  id.Identity
+	entries   []proto.Entry
+	updatedAt time.Time
+}
+
+type installedRoute struct {
+	mesh.Route
+	router *HopRouter
+}
+
+func (mod *Module) Run(ctx context.Context) error {
+	mod.ctx = ctx
+
+	return tasks.Group(
+		&TableService{Module: mod},
+		&ForwardService{Module: mod},
+		&Gossip{Module: mod},
+	).Run(ctx)
+}
+
+func (mod *Module) Routes() []mesh.Route {
+	mod.mu.Lock()
+	defer mod.mu.Unlock()
+
+	var list = make([]mesh.Route, 0, len(mod.installed))
+	for _, r := range mod.installed {
+		list = append(list, r.Route)
+	}
+
+	slices.SortFunc(list, func(a, b mesh.Route) int {
+		if c := cmp.Compare(a.Hops, b.Hops); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Target.PublicKeyHex(), b.Target.PublicKeyHex())
+	})
+
+	return list
+}
+
+// route returns the current route to the target
+func (mod *Module) route(target id.Identity) (mesh.Route, bool) {
+	mod.mu.Lock()
+	defer mod.mu.Unlock()
+
+	r, found := mod.installed[target.PublicKeyHex()]
+	if !found {
+		return mesh.Route{}, false
+	}
+	return r.Route, true
+}
+
+// directPeers returns identities linked with the node without going through other nodes
+func (mod *Module) directPeers() map[string]id.Identity {
+	var peers = map[string]id.Identity{}
+
+	for _, l := range mod.node.Network().Links().All() {
+		if net.Network(l.Link) == mesh.NetworkName {
+			continue
+		}
+		peers[l.RemoteIdentity().PublicKeyHex()] = l.RemoteIdentity()
+	}
+
+	return peers
+}
+
+// directLink returns a link with the identity that doesn't go through other nodes
+func (mod *Module) directLink(identity id.Identity) net.Link {
+	for _, l := range mod.node.Network().Links().ByRemoteIdentity(identity).All() {
+		if net.Network(l.Link) != mesh.NetworkName {
+			return l.Link
+		}
+	}
+	return nil
+}
+
+// bestRoutes computes the shortest routes from tables of linked peers. Routes through the excluded
+// identity are skipped.
+func (mod *Module) bestRoutes(exclude id.Identity) map[string]mesh.Route {
+	var direct = mod.directPeers()
+
+	mod.mu.Lock()
+	defer mod.mu.Unlock()
+
+	return computeRoutes(mod.node.Identity(), direct, mod.peers, exclude, mod.config.MaxHops)
+}
+
+// advertise returns the reachability table for a peer
+func (mod *Module) advertise(peer id.Identity) *proto.Table {
+	if !mod.config.Forward {
+		return &proto.Table{}
+	}
+
+	return buildTable(mod.directPeers(), mod.bestRoutes(peer), peer, mod.config.MaxHops)
+}
+
+func computeRoutes(
+	self id.Identity,
+	direct map[string]id.Identity,
+	peers map[string]*peerTable,
+	exclude id.Identity,
+	maxHops int,
+) map[string]mesh.Route {
+	var routes = map[string]mesh.Route{}
+
+	for peerKey, peer := range peers {
+		if _, linked := direct[peerKey]; !linked {
+			continue
+		}
+		if !exclude.IsZero() && peer.identity.IsEqual(exclude) {
+			continue
+		}
+
+		for _, entry := range peer.entries {
+			var key = entry.Identity.PublicKeyHex()
+			if entry.Identity.IsEqual(self) {
+				continue
+			}
+			if _, linked := direct[key]; linked {
+				continue
+			}
+
+			var hops = entry.Hops + 1
+			if entry.Hops < 1 || hops > maxHops {
+				continue
+			}
+
+			if r, found := routes[key]; found {
+				if r.Hops < hops {
+					continue
+				}
+				// break ties deterministically to avoid flapping
+				if r.Hops == hops && r.NextHop.PublicKeyHex() < peerKey {
+					continue
+				}
+			}
+
+			routes[key] = mesh.Route{
+				Target:    entry.Identity,
+				NextHop:   peer.identity,
+				Hops:      hops,
+				UpdatedAt: peer.updatedAt,
+			}
+		}
+	}
+
+	return routes
+}
+
+// buildTable builds the table advertised to a peer. The routes must not go through the peer (split horizon).
+func buildTable(direct map[string]id.Identity, routes map[string]mesh.Route, peer id.Identity, maxHops int) *proto.Table {
+	var table = &proto.Table{}
+
+	// the peer would need one more hop to reach any of the entries
+	if maxHops < 2 {
+		return table
+	}
+
+	for _, identity := range direct {
+		if identity.IsEqual(peer) {
+			continue
+		}
+		table.Entries = append(table.Entries, proto.Entry{Identity: identity, Hops: 1})
+	}
+
+	for _, r := range routes {
+		if r.Hops >= maxHops || r.Target.IsEqual(peer) {
+			continue
+		}
+		table.Entries = append(table.Entries, proto.Entry{Identity: r.Target, Hops: r.Hops})
+	}
+
+	return table
+}
+
+// updateRoutes adds and removes router routes to match the best routes
+func (mod *Module) updateRoutes() {
+	var best = mod.bestRoutes(id.Identity{})
+	var router = mod.node.Router()
+
+	mod.mu.Lock()
+	defer mod.mu.Unlock()
+
+	for key, r := range mod.installed {
+		if _, found := best[key]; found {
+			continue
+		}
+		router.RemoveRoute(mod.node.Identity(), r.Target, r.router)
+		delete(mod.installed, key)
+		mod.log.Logv(1, "removed route to %v", r.Target)
+	}
+
+	for key, r := range best {
+		if i, found := mod.installed[key]; found {
+			if !i.NextHop.IsEqual(r.NextHop) || i.Hops != r.Hops {
+				mod.log.Logv(2, "route to %v changed to %v hops via %v", r.Target, r.Hops, r.NextHop)
+			}
+			i.Route = r
+			continue
+		}
+
+		var i = &installedRoute{
+			Route:  r,
+			router: &HopRouter{Module: mod, target: r.Target},
+		}
+		if err := router.AddRoute(mod.node.Identity(), r.Target, i.router, routePriority); err != nil {
+			mod.log.Error("error adding route to %v: %v", r.Target, err)
+			continue
+		}
+		mod.installed[key] = i
+		mod.log.Logv(1, "added route to %v (%v hops via %v)", r.Target, r.Hops, r.NextHop)
+	}
+}
+
+// setPeerTable stores the table advertised by a peer
+func (mod *Module) setPeerTable(peer id.Identity, table *proto.Table) {
+	mod.mu.Lock()
+	defer mod.mu.Unlock()
+
+	mod.peers[peer.PublicKeyHex()] = &peerTable{
+		identity:  peer,
+		entries:   table.Entries,
+		updatedAt: time.Now(),
+	}
+}
+
+// prunePeers removes tables of peers that are no longer linked or stopped advertising
+func (mod *Module) prunePeers() {
+	var direct = mod.directPeers()
+	var deadline = time.Now().Add(-3 * mod.config.GossipInterval)
+
+	mod.mu.Lock()
+	defer mod.mu.Unlock()
+
+	for key, peer := range mod.peers {
+		if _, linked := direct[key]; !linked || peer.updatedAt.Before(deadline) {
+			delete(mod.peers, key)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package mesh
+
+import (
+	"github.com/cryptopunkscc/astrald/auth/id"
+	"testing"
+)
+
+// testNet simulates gossip between nodes without any networking
+type testNet struct {
+	nodes []id.Identity
+	links map[[2]int]bool
+	peers []map[string]*peerTable
+}
+
+func newTestNet(t *testing.T, n int) *testNet {
+	var tn = &testNet{links: map[[2]int]bool{}}
+	for i := 0; i < n; i++ {
+		identity, err := id.GenerateIdentity()
+		if err != nil {
+			t.Fatal(err)
+		}
+		tn.nodes = append(tn.nodes, identity)
+		tn.peers = append(tn.peers, map[string]*peerTable{})
+	}
+	return tn
+}
+
+func (tn *testNet) link(a, b int, linked bool) {
+	tn.links[[2]int{a, b}] = linked
+	tn.links[[2]int{b, a}] = linked
+}
+
+func (tn *testNet) direct(n int) map[string]id.Identity {
+	var direct = map[string]id.Identity{}
+	for i, identity := range tn.nodes {
+		if tn.links[[2]int{n, i}] {
+			direct[identity.PublicKeyHex()] = identity
+		}
+	}
+	return direct
+}
+
+// round lets every node fetch the tables of its linked peers
+func (tn *testNet) round(maxHops int) {
+	var next = make([]map[string]*peerTable, len(tn.nodes))
+	for n := range tn.nodes {
+		next[n] = map[string]*peerTable{}
+		for p, peer := range tn.nodes {
+			if !tn.links[[2]int{n, p}] {
+				continue
+			}
+			var routes = computeRoutes(peer, tn.direct(p), tn.peers[p], tn.nodes[n], maxHops)
+			var table = buildTable(tn.direct(p), routes, tn.nodes[n], maxHops)
+			next[n][peer.PublicKeyHex()] = &peerTable{identity: peer, entries: table.Entries}
+		}
+	}
+	tn.peers = next
+}
+
+func TestRoutes(t *testing.T) {
+	const maxHops = 3
+
+	// a line of nodes: 0 - 1 - 2 - 3 - 4
+	var tn = newTestNet(t, 5)
+	for i := 0; i < 4; i++ {
+		tn.link(i, i+1, true)
+	}
+
+	for i := 0; i < 5; i++ {
+		tn.round(maxHops)
+	}
+
+	var routes = computeRoutes(tn.nodes[0], tn.direct(0), tn.peers[0], id.Identity{}, maxHops)
+
+	for target, hops := range map[int]int{2: 2, 3: 3} {
+		r, found := routes[tn.nodes[target].PublicKeyHex()]
+		if !found {
+			t.Fatalf("no route to node %d", target)
+		}
+		if r.Hops != hops || !r.NextHop.IsEqual(tn.nodes[1]) {
+			t.Fatalf("route to node %d: expected %d hops via node 1, got %d via %v", target, hops, r.Hops, r.NextHop)
+		}
+	}
+	if _, found := routes[tn.nodes[4].PublicKeyHex()]; found {
+		t.Fatal("route exceeds the hop limit")
+	}
+	if _, found := routes[tn.nodes[1].PublicKeyHex()]; found {
+		t.Fatal("route to a directly linked node")
+	}
+
+	// a shortcut 0 - 3 makes node 4 reachable in 2 hops
+	tn.link(0, 3, true)
+	for i := 0; i < 5; i++ {
+		tn.round(maxHops)
+	}
+	routes = computeRoutes(tn.nodes[0], tn.direct(0), tn.peers[0], id.Identity{}, maxHops)
+	if r := routes[tn.nodes[4].PublicKeyHex()]; r.Hops != 2 || !r.NextHop.IsEqual(tn.nodes[3]) {
+		t.Fatalf("expected route to node 4 via node 3, got %+v", r)
+	}
+
+	// cut the network in half, routes across the cut must disappear
+	tn.link(0, 3, false)
+	tn.link(1, 2, false)
+	for i := 0; i < 2*maxHops; i++ {
+		tn.round(maxHops)
+	}
+	for n := 0; n < 2; n++ {
+		routes = computeRoutes(tn.nodes[n], tn.direct(n), tn.peers[n], id.Identity{}, maxHops)
+		if len(routes) != 0 {
+			t.Fatalf("node %d has %d routes after the cut", n, len(routes))
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package mesh
+
+import (
+	"context"
+	"github.com/cryptopunkscc/astrald/cslq"
+	"github.com/cryptopunkscc/astrald/mod/mesh"
+	"github.com/cryptopunkscc/astrald/net"
+)
+
+// TableService serves the reachability table of the node to linked peers
+type TableService struct {
+	*Module
+}
+
+func (srv *TableService) Run(ctx context.Context) error {
+	err := srv.node.LocalRouter().AddRoute(mesh.TableServiceName, srv)
+	if err != nil {
+		return err
+	}
+	defer srv.node.LocalRouter().RemoveRoute(mesh.TableServiceName)
+
+	<-ctx.Done()
+
+	return nil
+}
+
+func (srv *TableService) RouteQuery(ctx context.Context, query net.Query, caller net.SecureWriteCloser, hints net.Hints) (net.SecureWriteCloser, error) {
+	// tables are only exchanged between linked nodes
+	if srv.directLink(query.Caller()) == nil {
+		return net.Reject()
+	}
+
+	return net.Accept(query, caller, func(conn net.SecureConn) {
+		defer conn.Close()
+
+		if err := cslq.Encode(conn, "v", srv.advertise(query.Caller())); err != nil {
+			srv.log.Errorv(2, "error sending table to %v: %v", query.Caller(), err)
+		}
+	})
+}
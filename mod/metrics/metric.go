@@ -0,0 +1,61 @@
+package metrics
+
+type Type string
+
+const (
+	TypeGauge   Type = "gauge"
+	TypeCounter Type = "counter"
+	TypeUnknown Type = "unknown"
+)
+
+// Metric is a metric family with its current samples. Names of counters should not include
+// the _total suffix, it's added to the samples when they're exported.
+type Metric struct {
+	Name    string
+	Type    Type
+	Help    string
+	Unit    string
+	Samples []Sample
+}
+
+// Sample is a single value of a metric
+type Sample struct {
+	Labels []Label
+	Value  float64
+}
+
+// Label is a name-value pair that distinguishes samples of a metric
+type Label struct {
+	Name  string
+	Value string
+}
+
+// Gauge returns a gauge with a single unlabeled sample
+func Gauge(name string, help string, value float64) Metric {
+	return Metric{
+		Name:    name,
+		Type:    TypeGauge,
+		Help:    help,
+		Samples: []Sample{{Value: value}},
+	}
+}
+
+// Counter returns a counter with a single unlabeled sample
+func Counter(name string, help string, value float64) Metric {
+	return Metric{
+		Name:    name,
+		Type:    TypeCounter,
+		Help:    help,
+		Samples: []Sample{{Value: value}},
+	}
+}
+
+// Add adds a labeled sample to the metric
+func (m *Metric) Add(value float64, labels ...Label) {
+	m.Samples = append(m.Samples, Sample{Labels: labels, Value: value})
+}
+
+// L is a shorthand for creating a Label
+func L(name string, value string) Label {
+	return Label{Name: name, Value: value}
+}
@@ -0,0 +1,22 @@
+package metrics
+
+const ModuleName = "metrics"
+
+type Module interface {
+	// AddCollector adds a collector whose metrics are included in every scrape
+	AddCollector(collector Collector) error
+	// RemoveCollector removes a collector
+	RemoveCollector(collector Collector) error
+}
+
+// Collector provides the current values of a set of metrics
+type Collector interface {
+	Collect() []Metric
+}
+
+// CollectorFunc is a function that can be used as a Collector
+type CollectorFunc func() []Metric
+
+func (fn CollectorFunc) Collect() []Metric {
+	return fn()
+}
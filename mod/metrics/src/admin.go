@@ -0,0 +1,50 @@
+package metrics
+
+import (
+	"errors"
+	"github.com/cryptopunkscc/astrald/mod/admin"
+)
+
+type Admin struct {
+	mod  *Module
+	cmds map[string]func(admin.Terminal, []string) error
+}
+
+func NewAdmin(mod *Module) *Admin {
+	var adm = &Admin{mod: mod}
+	adm.cmds = map[string]func(admin.Terminal, []string) error{
+		"show": adm.show,
+		"help": adm.help,
+	}
+
+	return adm
+}
+
+func (adm *Admin) Exec(term admin.Terminal, args []string) error {
+	if len(args) < 2 {
+		return adm.help(term, []string{})
+	}
+
+	cmd, args := args[1], args[2:]
+	if fn, found := adm.cmds[cmd]; found {
+		return fn(term, args)
+	}
+
+	return errors.New("unknown command")
+}
+
+func (adm *Admin) show(term admin.Terminal, _ []string) error {
+	return writeOpenMetrics(term, adm.mod.Collect())
+}
+
+func (adm *Admin) ShortDescription() string {
+	return "node metrics"
+}
+
+func (adm *Admin) help(term admin.Terminal, _ []string) error {
+	term.Printf("usage: %s <command>\n\n", "metrics")
+	term.Printf("commands:\n")
+	term.Printf("  show         show current metrics in OpenMetrics format\n")
+	term.Printf("  help         show help\n")
+	return nil
+}
@@ -0,0 +1,8 @@
+package metrics
+
+type Config struct {
+	// Listen is the local address of the HTTP listener serving metrics, disabled if empty
+	Listen string `yaml:"listen"`
+}
+
+var defaultConfig = Config{}
@@ -0,0 +1,30 @@
+package metrics
+
+import (
+	"github.com/cryptopunkscc/astrald/log"
+	"github.com/cryptopunkscc/astrald/mod/metrics"
+	"github.com/cryptopunkscc/astrald/node/assets"
+	"github.com/cryptopunkscc/astrald/node/modules"
+)
+
+type Loader struct{}
+
+func (Loader) Load(node modules.Node, assets assets.Assets, log *log.Logger) (modules.Module, error) {
+	var mod = &Module{
+		node:   node,
+		config: defaultConfig,
+		log:    log,
+	}
+
+	_ = assets.LoadYAML(metrics.ModuleName, &mod.config)
+
+	mod.AddCollector(&NodeCollector{node: node})
+
+	return mod, nil
+}
+
+func init() {
+	if err := modules.RegisterModule(metrics.ModuleName, Loader{}); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,113 @@
+package metrics
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"github.com/cryptopunkscc/astrald/log"
+	"github.com/cryptopunkscc/astrald/mod/metrics"
+	"github.com/cryptopunkscc/astrald/node"
+	"github.com/cryptopunkscc/astrald/sig"
+	_net "net"
+	"net/http"
+	"slices"
+)
+
+var _ metrics.Module = &Module{}
+
+var errNotLocal = errors.New("metrics listener must use a loopback address")
+
+type Module struct {
+	config     Config
+	node       node.Node
+	log        *log.Logger
+	collectors sig.Set[metrics.Collector]
+}
+
+func (mod *Module) Run(ctx context.Context) error {
+	if mod.config.Listen != "" {
+		go func() {
+			if err := mod.serve(ctx); err != nil {
+				mod.log.Error("metrics listener error: %v", err)
+			}
+		}()
+	}
+
+	<-ctx.Done()
+	return nil
+}
+
+func (mod *Module) AddCollector(collector metrics.Collector) error {
+	return mod.collectors.Add(collector)
+}
+
+func (mod *Module) RemoveCollector(collector metrics.Collector) error {
+	return mod.collectors.Remove(collector)
+}
+
+// Collect returns metrics from all collectors sorted by name. Invalid and duplicate metrics are skipped.
+func (mod *Module) Collect() []metrics.Metric {
+	var list []metrics.Metric
+	var names = map[string]struct{}{}
+
+	for _, c := range mod.collectors.Clone() {
+		for _, m := range c.Collect() {
+			if err := validateMetric(m); err != nil {
+				mod.log.Errorv(1, "skipping metric: %v", err)
+				continue
+			}
+			if _, found := names[m.Name]; found {
+				mod.log.Errorv(1, "skipping duplicate metric %v", m.Name)
+				continue
+			}
+			names[m.Name] = struct{}{}
+			list = append(list, m)
+		}
+	}
+
+	slices.SortFunc(list, func(a, b metrics.Metric) int {
+		return cmp.Compare(a.Name, b.Name)
+	})
+
+	return list
+}
+
+func (mod *Module) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/metrics" && r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", openMetricsContentType)
+	writeOpenMetrics(w, mod.Collect())
+}
+
+func (mod *Module) serve(ctx context.Context) error {
+	host, _, err := _net.SplitHostPort(mod.config.Listen)
+	if err != nil {
+		return err
+	}
+	if ip := _net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		return errNotLocal
+	}
+
+	listener, err := _net.Listen("tcp", mod.config.Listen)
+	if err != nil {
+		return err
+	}
+
+	var server = &http.Server{Handler: mod}
+
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+
+	mod.log.Infov(1, "serving metrics on http://%s/metrics", listener.Addr())
+
+	err = server.Serve(listener)
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,87 @@
+package metrics
+
+import (
+	"github.com/cryptopunkscc/astrald/mod/metrics"
+	"github.com/cryptopunkscc/astrald/net"
+	"github.com/cryptopunkscc/astrald/node"
+	"github.com/cryptopunkscc/astrald/node/router"
+	"strconv"
+	"time"
+)
+
+var _ metrics.Collector = &NodeCollector{}
+
+// NodeCollector collects metrics of the node's network and router
+type NodeCollector struct {
+	node node.Node
+}
+
+type routerStats interface {
+	Stats() router.Stats
+}
+
+type checkLatency interface {
+	Latency() time.Duration
+}
+
+type connLister interface {
+	Conns() *router.ConnSet
+}
+
+type checkStartedAt interface {
+	StartedAt() time.Time
+}
+
+func (c *NodeCollector) Collect() []metrics.Metric {
+	var links = metrics.Metric{
+		Name: "astral_links",
+		Type: metrics.TypeGauge,
+		Help: "Number of active links by network.",
+	}
+	var latency = metrics.Metric{
+		Name: "astral_link_latency_seconds",
+		Type: metrics.TypeGauge,
+		Help: "Last measured latency of a link.",
+		Unit: "seconds",
+	}
+
+	var counts = map[string]int{}
+	for _, l := range c.node.Network().Links().All() {
+		var network = net.Network(l.Link)
+		counts[network]++
+
+		if cl, ok := l.Link.(checkLatency); ok {
+			latency.Add(cl.Latency().Seconds(),
+				metrics.L("link", strconv.Itoa(l.ID())),
+				metrics.L("remote", l.RemoteIdentity().PublicKeyHex()),
+				metrics.L("network", network),
+			)
+		}
+	}
+	for network, count := range counts {
+		links.Add(float64(count), metrics.L("network", network))
+	}
+
+	var list = []metrics.Metric{links, latency}
+
+	if n, ok := c.node.(checkStartedAt); ok {
+		list = append(list, metrics.Gauge("astral_uptime_seconds", "Time since the node started.",
+			time.Since(n.StartedAt()).Seconds()))
+	}
+
+	if r, ok := c.node.Router().(routerStats); ok {
+		var stats = r.Stats()
+		list = append(list,
+			metrics.Counter("astral_queries_routed", "Queries routed successfully.", float64(stats.Routed)),
+			metrics.Counter("astral_queries_failed", "Queries that could not be routed.", float64(stats.Failed)),
+			metrics.Counter("astral_queries_refused", "Queries refused before routing.", float64(stats.Refused)),
+		)
+	}
+
+	if n, ok := c.node.(connLister); ok {
+		list = append(list, metrics.Gauge("astral_conns", "Active routed connections.",
+			float64(len(n.Conns().All()))))
+	}
+
+	return list
+}
@@ -0,0 +1,109 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/cryptopunkscc/astrald/mod/metrics"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const openMetricsContentType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+
+var metricNameRe = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+var labelNameRe = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+var helpEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+
+// writeOpenMetrics writes metric families in the OpenMetrics text format
+func writeOpenMetrics(w io.Writer, list []metrics.Metric) error {
+	var bw = bufio.NewWriter(w)
+
+	for _, m := range list {
+		var typ = m.Type
+		if typ == "" {
+			typ = metrics.TypeUnknown
+		}
+
+		fmt.Fprintf(bw, "# TYPE %s %s\n", m.Name, typ)
+		if m.Unit != "" {
+			fmt.Fprintf(bw, "# UNIT %s %s\n", m.Name, m.Unit)
+		}
+		if m.Help != "" {
+			fmt.Fprintf(bw, "# HELP %s %s\n", m.Name, helpEscaper.Replace(m.Help))
+		}
+
+		var sampleName = m.Name
+		if typ == metrics.TypeCounter {
+			sampleName += "_total"
+		}
+
+		for _, s := range m.Samples {
+			bw.WriteString(sampleName)
+			if len(s.Labels) > 0 {
+				bw.WriteByte('{')
+				for i, l := range s.Labels {
+					if i > 0 {
+						bw.WriteByte(',')
+					}
+					fmt.Fprintf(bw, `%s="%s"`, l.Name, labelValueEscaper.Replace(l.Value))
+				}
+				bw.WriteByte('}')
+			}
+			bw.WriteByte(' ')
+			bw.WriteString(formatValue(s.Value))
+			bw.WriteByte('\n')
+		}
+	}
+
+	bw.WriteString("# EOF\n")
+
+	return bw.Flush()
+}
+
+// validateMetric checks if the metric can be exported
+func validateMetric(m metrics.Metric) error {
+	if !metricNameRe.MatchString(m.Name) {
+		return fmt.Errorf("invalid metric name: %q", m.Name)
+	}
+
+	switch m.Type {
+	case "", metrics.TypeGauge, metrics.TypeCounter, metrics.TypeUnknown:
+	default:
+		return fmt.Errorf("%s: unsupported type %s", m.Name, m.Type)
+	}
+
+	if m.Type == metrics.TypeCounter && strings.HasSuffix(m.Name, "_total") {
+		return fmt.Errorf("%s: counter names must not end with _total", m.Name)
+	}
+
+	if m.Unit != "" && !strings.HasSuffix(m.Name, "_"+m.Unit) {
+		return fmt.Errorf("%s: name must end with the unit %s", m.Name, m.Unit)
+	}
+
+	for _, s := range m.Samples {
+		for _, l := range s.Labels {
+			if !labelNameRe.MatchString(l.Name) {
+				return fmt.Errorf("%s: invalid label name: %q", m.Name, l.Name)
+			}
+		}
+	}
+
+	return nil
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsNaN(v):
+		return "NaN"
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,74 @@
+package metrics
+
+import (
+	"bytes"
+	"github.com/cryptopunkscc/astrald/mod/metrics"
+	"math"
+	"testing"
+)
+
+func TestWriteOpenMetrics(t *testing.T) {
+	var links = metrics.Metric{
+		Name: "astral_links",
+		Type: metrics.TypeGauge,
+		Help: "Number of active links.",
+	}
+	links.Add(2, metrics.L("network", "tcp"))
+	links.Add(1, metrics.L("network", `we"ird\`))
+
+	var list = []metrics.Metric{
+		links,
+		metrics.Counter("astral_queries_routed", "Queries\nrouted.", 42),
+		{
+			Name:    "astral_link_latency_seconds",
+			Type:    metrics.TypeGauge,
+			Unit:    "seconds",
+			Samples: []metrics.Sample{{Value: 0.025}, {Value: math.Inf(1)}},
+		},
+	}
+
+	for _, m := range list {
+		if err := validateMetric(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var buf = &bytes.Buffer{}
+	if err := writeOpenMetrics(buf, list); err != nil {
+		t.Fatal(err)
+	}
+
+	var expected = `# TYPE astral_links gauge
+# HELP astral_links Number of active links.
+astral_links{network="tcp"} 2
+astral_links{network="we\"ird\\"} 1
+# TYPE astral_queries_routed counter
+# HELP astral_queries_routed Queries\nrouted.
+astral_queries_routed_total 42
+# TYPE astral_link_latency_seconds gauge
+# UNIT astral_link_latency_seconds seconds
+astral_link_latency_seconds 0.025
+astral_link_latency_seconds +Inf
+# EOF
+`
+	if buf.String() != expected {
+		t.Fatalf("unexpected output:\n%s", buf.String())
+	}
+}
+
+func TestValidateMetric(t *testing.T) {
+	var invalid = []metrics.Metric{
+		{Name: "1links"},
+		{Name: "astral-links"},
+		{Name: "queries_total", Type: metrics.TypeCounter},
+		{Name: "latency", Unit: "seconds"},
+		{Name: "links", Type: "histogram"},
+		{Name: "links", Samples: []metrics.Sample{{Labels: []metrics.Label{metrics.L("bad-label", "")}}}},
+	}
+
+	for _, m := range invalid {
+		if err := validateMetric(m); err == nil {
+			t.Fatalf("metric %+v passed validation", m)
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package objects
+
+import (
+	"github.com/cryptopunkscc/astrald/auth/id"
+)
+
+// DownloadBlockSize is the size of blocks in which objects are downloaded and verified
+const DownloadBlockSize = 1024 * 1024
+
+type DownloadOpts struct {
+	// Number of blocks fetched concurrently (0 = default)
+	Workers int
+
+	// Download only from identities accepted by the filter
+	QueryFilter id.Filter
+
+	// Progress is called after every verified block with the number of blocks stored so far
+	Progress func(done int, total int)
+
+	// HolderID holds the object once it's stored, so that it's not collected as garbage. A downloaded
+	// object is held for the node if it's zero.
+	HolderID id.Identity
+}
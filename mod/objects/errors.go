@@ -13,4 +13,5 @@ var (
 	ErrClosedPipe         = errors.New("pipe closed")
 	ErrAccessDenied       = errors.New("access denied")
 	ErrHashMismatch       = errors.New("hash mismatch (data corrupted?)")
+	ErrNoProviders        = errors.New("no providers available")
 )
@@ -11,6 +11,9 @@ import (
 const ModuleName = "objects"
 const DBPrefix = "objects__"
 
+// ReadServiceName is the service that streams object data to other nodes
+const ReadServiceName = "objects.read"
+
 // ReadAllMaxSize is the size limit for loading objects into memory
 const ReadAllMaxSize = 64 * 1024 * 1024 // 64 MB
 
@@ -67,6 +70,11 @@ type Module interface {
 	Holdings(id.Identity) []object.ID
 
 	Connect(caller id.Identity, target id.Identity) (Consumer, error)
+
+	// Download fetches an object from its holders in the network into local storage. Blocks of the object are
+	// fetched from many holders in parallel and verified as they arrive. An interrupted download resumes from
+	// the last stored block.
+	Download(ctx context.Context, objectID object.ID, opts *DownloadOpts) error
 }
 
 type Consumer interface {
@@ -32,18 +32,20 @@ type Admin struct {
 func NewAdmin(mod *Module) *Admin {
 	var adm = &Admin{mod: mod}
 	adm.cmds = map[string]func(admin.Terminal, []string) error{
-		"read":     adm.read,
-		"purge":    adm.purge,
-		"describe": adm.describe,
-		"search":   adm.search,
-		"fetch":    adm.fetch,
-		"hold":     adm.hold,
-		"release":  adm.release,
-		"holders":  adm.holders,
-		"inv":      adm.inv,
-		"show":     adm.show,
-		"info":     adm.info,
-		"help":     adm.help,
+		"read":      adm.read,
+		"purge":     adm.purge,
+		"describe":  adm.describe,
+		"search":    adm.search,
+		"fetch":     adm.fetch,
+		"download":  adm.download,
+		"downloads": adm.downloads,
+		"hold":      adm.hold,
+		"release":   adm.release,
+		"holders":   adm.holders,
+		"inv":       adm.inv,
+		"show":      adm.show,
+		"info":      adm.info,
+		"help":      adm.help,
 	}
 
 	return adm
@@ -333,6 +335,63 @@ func (adm *Admin) fetch(term admin.Terminal, args []string) error {
 	return nil
 }
 
+func (adm *Admin) download(term admin.Terminal, args []string) error {
+	if len(args) < 1 {
+		return errors.New("argument missing")
+	}
+
+	objectID, err := object.ParseID(args[0])
+	if err != nil {
+		return err
+	}
+
+	term.Printf("downloading %v (%s)...\n", objectID, log.DataSize(objectID.Size))
+
+	err = adm.mod.Download(context.Background(), objectID, &objects.DownloadOpts{
+		Progress: func(done, total int) {
+			term.Printf("%v/%v blocks\n", done, total)
+		},
+		HolderID: term.UserIdentity(),
+	})
+	if err != nil {
+		return err
+	}
+
+	term.Printf("stored as %v\n", objectID)
+
+	return nil
+}
+
+func (adm *Admin) downloads(term admin.Terminal, _ []string) error {
+	var rows []*dbDownload
+
+	err := adm.mod.db.Order("created_at").Find(&rows).Error
+	if err != nil {
+		return err
+	}
+
+	var f = "%-64s %8s %10s %s\n"
+	term.Printf(f, admin.Header("Object"), admin.Header("Blocks"), admin.Header("Size"), admin.Header("Status"))
+	for _, row := range rows {
+		var count int64
+		adm.mod.db.Model(&dbDownloadBlock{}).Where("object_id = ?", row.ObjectID).Count(&count)
+
+		var status = "paused"
+		if _, running := adm.mod.downloads.Get(row.ObjectID.String()); running {
+			status = "running"
+		}
+
+		term.Printf(f,
+			row.ObjectID,
+			fmt.Sprintf("%d/%d", count, blockCount(row.ObjectID)),
+			log.DataSize(row.ObjectID.Size),
+			status,
+		)
+	}
+
+	return nil
+}
+
 func (adm *Admin) holders(term admin.Terminal, args []string) error {
 	if len(args) < 1 {
 		return errors.New("argument missing")
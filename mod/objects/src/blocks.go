@@ -0,0 +1,77 @@
+package objects
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"github.com/cryptopunkscc/astrald/mod/objects"
+	"github.com/cryptopunkscc/astrald/net"
+	"github.com/cryptopunkscc/astrald/object"
+	"io"
+)
+
+// blockCount returns the number of download blocks of an object
+func blockCount(objectID object.ID) int {
+	return int((objectID.Size + objects.DownloadBlockSize - 1) / objects.DownloadBlockSize)
+}
+
+// blockSize returns the size of a download block of an object
+func blockSize(objectID object.ID, index int) int {
+	var offset = uint64(index) * objects.DownloadBlockSize
+	return int(min(objectID.Size-offset, objects.DownloadBlockSize))
+}
+
+// blockHashes returns the list of SHA-256 hashes of all download blocks of a locally stored object
+func (mod *Module) blockHashes(ctx context.Context, objectID object.ID) ([]byte, error) {
+	var row dbBlockHashes
+	if err := mod.db.Where("object_id = ?", objectID).First(&row).Error; err == nil {
+		return row.Hashes, nil
+	}
+
+	r, err := mod.Open(ctx, objectID, &objects.OpenOpts{Zone: net.ZoneDevice | net.ZoneVirtual})
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	var hashes = make([]byte, 0, blockCount(objectID)*sha256.Size)
+	for i := 0; i < blockCount(objectID); i++ {
+		var h = sha256.New()
+		if _, err := io.CopyN(h, r, int64(blockSize(objectID, i))); err != nil {
+			return nil, err
+		}
+		hashes = h.Sum(hashes)
+	}
+
+	if n, _ := r.Read(make([]byte, 1)); n > 0 {
+		return nil, errors.New("object larger than its size")
+	}
+
+	mod.db.Create(&dbBlockHashes{ObjectID: objectID, Hashes: hashes})
+
+	return hashes, nil
+}
+
+// verifyBlock checks the block against its hash from the list
+func verifyBlock(hashes []byte, index int, data []byte) bool {
+	var sum = sha256.Sum256(data)
+	var off = index * sha256.Size
+	if off+sha256.Size > len(hashes) {
+		return false
+	}
+	return string(sum[:]) == string(hashes[off:off+sha256.Size])
+}
+
+// verifyHashes checks what can be checked about a hash list before any data is downloaded. The list needs to
+// have a hash for every block, and the hash of a single block object is the object's own hash. Lists of larger
+// objects can only be verified by assembling the object.
+func verifyHashes(objectID object.ID, hashes []byte) bool {
+	var count = blockCount(objectID)
+	if len(hashes) != count*sha256.Size {
+		return false
+	}
+	if count == 1 {
+		return string(hashes) == string(objectID.Hash[:])
+	}
+	return true
+}
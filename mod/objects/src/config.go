@@ -5,16 +5,19 @@ import (
 	"github.com/cryptopunkscc/astrald/mod/content"
 	"github.com/cryptopunkscc/astrald/mod/keys"
 	"github.com/cryptopunkscc/astrald/mod/media"
+	"github.com/cryptopunkscc/astrald/mod/objects"
 	"github.com/cryptopunkscc/astrald/mod/relay"
 )
 
 const (
 	methodPut      = "objects.put"
-	methodRead     = "objects.read"
+	methodRead     = objects.ReadServiceName
 	methodDescribe = "objects.describe"
 	methodRelease  = "objects.release"
 	methodSearch   = "objects.search"
 	methodHold     = "objects.hold"
+	methodBlocks   = "objects.blocks"
+	methodDownload = "objects.download"
 )
 
 type Config struct {
@@ -2,6 +2,7 @@ package objects
 
 import (
 	"context"
+	"crypto/sha256"
 	"encoding/json"
 	"errors"
 	"github.com/cryptopunkscc/astrald/auth/id"
@@ -92,7 +93,8 @@ func (c *Consumer) Open(ctx context.Context, objectID object.ID, opts *objects.O
 
 	var query = net.NewQuery(c.consumerID, c.providerID, router.Query(methodRead, params))
 
-	conn, err := net.Route(ctx, c.mod.node.Router(), query)
+	// reads can be long transfers, so let them survive the loss of a link
+	conn, err := net.RouteWithHints(ctx, c.mod.node.Router(), query, net.DefaultHints().SetResume())
 	if err != nil {
 		return nil, err
 	}
@@ -109,6 +111,28 @@ func (c *Consumer) Open(ctx context.Context, objectID object.ID, opts *objects.O
 	return
 }
 
+// Blocks fetches the list of block hashes of an object
+func (c *Consumer) Blocks(ctx context.Context, objectID object.ID) ([]byte, error) {
+	var query = net.NewQuery(
+		c.consumerID,
+		c.providerID,
+		router.Query(methodBlocks, router.Params{"id": objectID.String()}),
+	)
+
+	conn, err := net.Route(ctx, c.mod.node.Router(), query)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	var hashes = make([]byte, blockCount(objectID)*sha256.Size)
+	if _, err := io.ReadFull(conn, hashes); err != nil {
+		return nil, err
+	}
+
+	return hashes, nil
+}
+
 func (c *Consumer) Put(ctx context.Context, p []byte) (object.ID, error) {
 	params := router.Params{
 		"size": strconv.FormatInt(int64(len(p)), 10),
@@ -0,0 +1,35 @@
+package objects
+
+import (
+	"github.com/cryptopunkscc/astrald/auth/id"
+	"github.com/cryptopunkscc/astrald/mod/objects"
+	"github.com/cryptopunkscc/astrald/object"
+	"time"
+)
+
+// dbDownload is a download in progress
+type dbDownload struct {
+	ObjectID  object.ID   `gorm:"primaryKey"`
+	SourceID  id.Identity // provider of the block hashes
+	Hashes    []byte
+	CreatedAt time.Time
+}
+
+func (dbDownload) TableName() string { return objects.DBPrefix + "downloads" }
+
+// dbDownloadBlock is a verified block of a download stored as a separate object
+type dbDownloadBlock struct {
+	ObjectID object.ID `gorm:"primaryKey"`
+	Index    int       `gorm:"primaryKey"`
+	BlockID  object.ID
+}
+
+func (dbDownloadBlock) TableName() string { return objects.DBPrefix + "download_blocks" }
+
+// dbBlockHashes caches block hashes of local objects served to downloaders
+type dbBlockHashes struct {
+	ObjectID object.ID `gorm:"primaryKey"`
+	Hashes   []byte
+}
+
+func (dbBlockHashes) TableName() string { return objects.DBPrefix + "block_hashes" }
@@ -0,0 +1,449 @@
+package objects
+
+import (
+	"context"
+	"github.com/cryptopunkscc/astrald/auth/id"
+	"github.com/cryptopunkscc/astrald/mod/objects"
+	"github.com/cryptopunkscc/astrald/net"
+	"github.com/cryptopunkscc/astrald/object"
+	"io"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const defaultDownloadWorkers = 4
+const blockFetchTimeout = time.Minute
+
+// maxProviderErrors is the number of failed fetches after which a provider is dropped from a download
+const maxProviderErrors = 3
+
+// blacklistDuration is how long providers that served bad data are excluded from downloads
+const blacklistDuration = time.Hour
+
+var localOpenOpts = objects.OpenOpts{Zone: net.ZoneDevice | net.ZoneVirtual}
+
+// download is a download in progress
+type download struct {
+	mod      *Module
+	objectID object.ID
+	done     chan struct{}
+	err      error
+}
+
+// downloadProvider is a holder of the object that serves blocks
+type downloadProvider struct {
+	identity id.Identity
+	errors   int
+}
+
+// providerPool picks providers for blocks in turns and drops the failing ones
+type providerPool struct {
+	mu   sync.Mutex
+	list []*downloadProvider
+	next int
+}
+
+func (mod *Module) Download(ctx context.Context, objectID object.ID, opts *objects.DownloadOpts) error {
+	if opts == nil {
+		opts = &objects.DownloadOpts{}
+	}
+
+	// nothing to do if we already have the object
+	var local = localOpenOpts
+	if r, err := mod.Open(ctx, objectID, &local); err == nil {
+		r.Close()
+		return mod.holdDownloaded(objectID, opts.HolderID)
+	}
+
+	var d = &download{
+		mod:      mod,
+		objectID: objectID,
+		done:     make(chan struct{}),
+	}
+
+	// wait for the download of the same object if there's one already running
+	if running, ok := mod.downloads.Set(objectID.String(), d); !ok {
+		select {
+		case <-running.done:
+			if running.err != nil {
+				return running.err
+			}
+			return mod.holdDownloaded(objectID, opts.HolderID)
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	defer func() {
+		mod.downloads.Delete(objectID.String())
+		close(d.done)
+	}()
+
+	d.err = d.run(ctx, opts)
+
+	return d.err
+}
+
+func (d *download) run(ctx context.Context, opts *objects.DownloadOpts) error {
+	var mod = d.mod
+	var pool = &providerPool{}
+
+	for _, providerID := range mod.Find(ctx, d.objectID, net.DefaultScope()) {
+		switch {
+		case providerID.IsEqual(mod.node.Identity()):
+		case mod.isBlacklisted(providerID):
+		case opts.QueryFilter != nil && !opts.QueryFilter(providerID):
+		default:
+			pool.list = append(pool.list, &downloadProvider{identity: providerID})
+		}
+	}
+
+	// resume the download or start a new one
+	var state dbDownload
+	if err := mod.db.Where("object_id = ?", d.objectID).First(&state).Error; err != nil || !verifyHashes(d.objectID, state.Hashes) {
+		mod.db.Where("object_id = ?", d.objectID).Delete(&dbDownload{})
+
+		state, err = d.fetchHashes(ctx, pool)
+		if err != nil {
+			return err
+		}
+
+		if err = mod.db.Create(&state).Error; err != nil {
+			return err
+		}
+	}
+
+	var blocks = d.storedBlocks()
+	var missing []int
+	for i := 0; i < blockCount(d.objectID); i++ {
+		if _, found := blocks[i]; !found {
+			missing = append(missing, i)
+		}
+	}
+
+	if len(missing) > 0 {
+		mod.log.Logv(1, "downloading %v blocks of %v from %v providers", len(missing), d.objectID, len(pool.list))
+
+		if err := d.fetchBlocks(ctx, state, missing, pool, opts); err != nil {
+			return err
+		}
+		blocks = d.storedBlocks()
+	}
+
+	return d.assemble(ctx, state, blocks, opts.HolderID)
+}
+
+// fetchHashes fetches the list of block hashes from the first provider that has it
+func (d *download) fetchHashes(ctx context.Context, pool *providerPool) (dbDownload, error) {
+	for _, provider := range slices.Clone(pool.list) {
+		var c = NewConsumer(d.mod, d.mod.node.Identity(), provider.identity)
+
+		hashes, err := c.Blocks(ctx, d.objectID)
+		if err != nil {
+			continue
+		}
+
+		if !verifyHashes(d.objectID, hashes) {
+			d.mod.log.Errorv(1, "%v served invalid block hashes of %v", provider.identity, d.objectID)
+			d.mod.blacklist(provider.identity)
+			pool.remove(provider)
+			continue
+		}
+
+		return dbDownload{
+			ObjectID: d.objectID,
+			SourceID: provider.identity,
+			Hashes:   hashes,
+		}, nil
+	}
+
+	return dbDownload{}, objects.ErrNoProviders
+}
+
+// storedBlocks returns the blocks of the download that are present in local storage
+func (d *download) storedBlocks() map[int]object.ID {
+	var rows []*dbDownloadBlock
+	var blocks = map[int]object.ID{}
+
+	d.mod.db.Where("object_id = ?", d.objectID).Find(&rows)
+
+	for _, row := range rows {
+		var local = localOpenOpts
+		r, err := d.mod.Open(context.Background(), row.BlockID, &local)
+		if err != nil {
+			d.mod.db.Delete(row)
+			continue
+		}
+		r.Close()
+
+		blocks[row.Index] = row.BlockID
+	}
+
+	return blocks
+}
+
+// fetchBlocks fetches the missing blocks concurrently from the providers in the pool
+func (d *download) fetchBlocks(ctx context.Context, state dbDownload, missing []int, pool *providerPool, opts *objects.DownloadOpts) error {
+	var workers = opts.Workers
+	if workers <= 0 {
+		workers = defaultDownloadWorkers
+	}
+
+	var total = blockCount(d.objectID)
+	var stored atomic.Int32
+	stored.Store(int32(total - len(missing)))
+
+	var queue = make(chan int, len(missing))
+	for _, index := range missing {
+		queue <- index
+	}
+	var remaining atomic.Int32
+	remaining.Store(int32(len(missing)))
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for {
+				var index int
+				var ok bool
+
+				select {
+				case <-ctx.Done():
+					return
+				case index, ok = <-queue:
+					if !ok {
+						return
+					}
+				}
+
+				provider := pool.pick()
+				if provider == nil {
+					cancel(objects.ErrNoProviders)
+					return
+				}
+
+				data, err := d.fetchBlock(ctx, provider.identity, index)
+				if err != nil {
+					d.mod.log.Logv(2, "block %v of %v from %v: %v", index, d.objectID, provider.identity, err)
+					pool.fail(provider)
+					queue <- index
+					continue
+				}
+
+				if !d.checkBlock(pool, provider, state.Hashes, index, data) {
+					queue <- index
+					continue
+				}
+
+				blockID, err := d.mod.Put(data, nil)
+				if err == nil {
+					// hold the block until the download is assembled
+					err = d.mod.Hold(d.mod.node.Identity(), blockID)
+				}
+				if err == nil {
+					err = d.mod.db.Create(&dbDownloadBlock{
+						ObjectID: d.objectID,
+						Index:    index,
+						BlockID:  blockID,
+					}).Error
+				}
+				if err != nil {
+					cancel(err)
+					return
+				}
+
+				if opts.Progress != nil {
+					opts.Progress(int(stored.Add(1)), total)
+				}
+
+				if remaining.Add(-1) == 0 {
+					close(queue)
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if remaining.Load() > 0 {
+		return context.Cause(ctx)
+	}
+
+	return nil
+}
+
+// checkBlock verifies a block against the hash list and blames the provider if the block doesn't match. The list
+// is trusted, because it comes from the source and the hash of a single block object is checked against the
+// object ID. A wrong list of a larger object fails the check of the assembled object, which blames the source.
+func (d *download) checkBlock(pool *providerPool, provider *downloadProvider, hashes []byte, index int, data []byte) bool {
+	if verifyBlock(hashes, index, data) {
+		return true
+	}
+
+	d.mod.log.Errorv(1, "%v served a corrupted block of %v", provider.identity, d.objectID)
+	d.mod.blacklist(provider.identity)
+	pool.remove(provider)
+
+	return false
+}
+
+// fetchBlock reads a single block from the provider
+func (d *download) fetchBlock(ctx context.Context, providerID id.Identity, index int) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, blockFetchTimeout)
+	defer cancel()
+
+	var c = NewConsumer(d.mod, d.mod.node.Identity(), providerID)
+
+	r, err := c.Open(ctx, d.objectID, &objects.OpenOpts{
+		Offset: uint64(index) * objects.DownloadBlockSize,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// close the reader if the provider stalls
+	go func() {
+		<-ctx.Done()
+		r.Close()
+	}()
+
+	var buf = make([]byte, blockSize(d.objectID, index))
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, err
+	}
+
+	return buf, nil
+}
+
+// assemble joins the blocks into the object and removes the download
+func (d *download) assemble(ctx context.Context, state dbDownload, blocks map[int]object.ID, holderID id.Identity) error {
+	var mod = d.mod
+
+	w, err := mod.Create(&objects.CreateOpts{Alloc: int(d.objectID.Size)})
+	if err != nil {
+		return err
+	}
+	defer w.Discard()
+
+	for i := 0; i < blockCount(d.objectID); i++ {
+		var local = localOpenOpts
+		r, err := mod.Open(ctx, blocks[i], &local)
+		if err != nil {
+			return err
+		}
+
+		_, err = io.Copy(w, r)
+		r.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	objectID, err := w.Commit()
+	if err != nil {
+		return err
+	}
+
+	defer d.clear(blocks)
+
+	// all blocks matched their hashes, so the hash list was wrong. Clearing the download drops the list.
+	if !objectID.IsEqual(d.objectID) {
+		mod.log.Errorv(1, "%v served invalid block hashes of %v", state.SourceID, d.objectID)
+		mod.blacklist(state.SourceID)
+		mod.Purge(objectID, nil)
+		return objects.ErrHashMismatch
+	}
+
+	// hold the object before its blocks are released, so that it's never unreachable
+	if holderID.IsZero() {
+		holderID = mod.node.Identity()
+	}
+
+	return mod.Hold(holderID, objectID)
+}
+
+// holdDownloaded holds an object that was already stored for the holder of a download
+func (mod *Module) holdDownloaded(objectID object.ID, holderID id.Identity) error {
+	if holderID.IsZero() {
+		return nil
+	}
+	return mod.Hold(holderID, objectID)
+}
+
+// clear removes the state and the blocks of the download
+func (d *download) clear(blocks map[int]object.ID) {
+	var mod = d.mod
+
+	mod.db.Where("object_id = ?", d.objectID).Delete(&dbDownloadBlock{})
+	mod.db.Where("object_id = ?", d.objectID).Delete(&dbDownload{})
+
+	var blockIDs []object.ID
+	for _, blockID := range blocks {
+		blockIDs = append(blockIDs, blockID)
+	}
+	mod.Release(mod.node.Identity(), blockIDs...)
+
+	for _, blockID := range blocks {
+		// don't remove blocks that happen to be objects on their own
+		if blockID.IsEqual(d.objectID) || len(mod.Holders(blockID)) > 0 {
+			continue
+		}
+		mod.Purge(blockID, nil)
+	}
+}
+
+func (mod *Module) blacklist(identity id.Identity) {
+	mod.blacklisted.Replace(identity.PublicKeyHex(), time.Now().Add(blacklistDuration))
+}
+
+func (mod *Module) isBlacklisted(identity id.Identity) bool {
+	until, found := mod.blacklisted.Get(identity.PublicKeyHex())
+	if !found {
+		return false
+	}
+	if time.Now().After(until) {
+		mod.blacklisted.Delete(identity.PublicKeyHex())
+		return false
+	}
+	return true
+}
+
+func (pool *providerPool) pick() *downloadProvider {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	if len(pool.list) == 0 {
+		return nil
+	}
+
+	pool.next = (pool.next + 1) % len(pool.list)
+
+	return pool.list[pool.next]
+}
+
+func (pool *providerPool) fail(provider *downloadProvider) {
+	pool.mu.Lock()
+	provider.errors++
+	var drop = provider.errors >= maxProviderErrors
+	pool.mu.Unlock()
+
+	if drop {
+		pool.remove(provider)
+	}
+}
+
+func (pool *providerPool) remove(provider *downloadProvider) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	pool.list = slices.DeleteFunc(pool.list, func(p *downloadProvider) bool {
+		return p == provider
+	})
+}
@@ -0,0 +1,155 @@
+package objects
+
+import (
+	"crypto/sha256"
+	"github.com/cryptopunkscc/astrald/auth/id"
+	"github.com/cryptopunkscc/astrald/log"
+	"github.com/cryptopunkscc/astrald/mod/objects"
+	"github.com/cryptopunkscc/astrald/object"
+	"io"
+	"testing"
+)
+
+func TestBlocks(t *testing.T) {
+	var tests = []struct {
+		size  uint64
+		count int
+		last  int
+	}{
+		{0, 0, 0},
+		{1, 1, 1},
+		{objects.DownloadBlockSize, 1, objects.DownloadBlockSize},
+		{objects.DownloadBlockSize + 1, 2, 1},
+		{3*objects.DownloadBlockSize - 10, 3, objects.DownloadBlockSize - 10},
+	}
+
+	for _, test := range tests {
+		var objectID = object.ID{Size: test.size}
+
+		if n := blockCount(objectID); n != test.count {
+			t.Fatalf("size %d: expected %d blocks, got %d", test.size, test.count, n)
+		}
+		if test.count == 0 {
+			continue
+		}
+		if n := blockSize(objectID, test.count-1); n != test.last {
+			t.Fatalf("size %d: expected last block of %d bytes, got %d", test.size, test.last, n)
+		}
+	}
+
+	var blocks = [][]byte{[]byte("first block"), []byte("second block")}
+	var hashes []byte
+	for _, block := range blocks {
+		var sum = sha256.Sum256(block)
+		hashes = append(hashes, sum[:]...)
+	}
+
+	for i, block := range blocks {
+		if !verifyBlock(hashes, i, block) {
+			t.Fatalf("block %d failed verification", i)
+		}
+	}
+	if verifyBlock(hashes, 0, blocks[1]) {
+		t.Fatal("swapped block passed verification")
+	}
+	if verifyBlock(hashes, 2, blocks[0]) {
+		t.Fatal("block out of range passed verification")
+	}
+}
+
+func TestVerifyHashes(t *testing.T) {
+	var data = []byte("single block")
+	var sum = sha256.Sum256(data)
+	var single = object.Resolve(data)
+
+	if !verifyHashes(single, sum[:]) {
+		t.Fatal("valid list failed verification")
+	}
+	if verifyHashes(single, make([]byte, sha256.Size)) {
+		t.Fatal("wrong hash of a single block passed verification")
+	}
+
+	var large = object.ID{Size: 2*objects.DownloadBlockSize + 1}
+	if !verifyHashes(large, make([]byte, 3*sha256.Size)) {
+		t.Fatal("list of a large object failed verification")
+	}
+	for _, n := range []int{0, 2, 4} {
+		if verifyHashes(large, make([]byte, n*sha256.Size)) {
+			t.Fatalf("list of %d hashes passed verification", n)
+		}
+	}
+}
+
+func TestCheckBlock(t *testing.T) {
+	var data = []byte("single block")
+	var sum = sha256.Sum256(data)
+
+	var d = &download{
+		mod:      &Module{log: log.NewLogger(log.NewLinePrinter(log.NewMonoOutput(io.Discard)))},
+		objectID: object.Resolve(data),
+	}
+
+	var pool = &providerPool{}
+	for i := 0; i < 2; i++ {
+		identity, err := id.GenerateIdentity()
+		if err != nil {
+			t.Fatal(err)
+		}
+		pool.list = append(pool.list, &downloadProvider{identity: identity})
+	}
+	var honest, corrupt = pool.list[0], pool.list[1]
+
+	if !d.checkBlock(pool, honest, sum[:], 0, data) {
+		t.Fatal("valid block failed the check")
+	}
+	if d.mod.isBlacklisted(honest.identity) || len(pool.list) != 2 {
+		t.Fatal("provider of a valid block was blamed")
+	}
+
+	// the provider is blamed no matter how many others serve the same data
+	if d.checkBlock(pool, corrupt, sum[:], 0, []byte("corrupted")) {
+		t.Fatal("corrupted block passed the check")
+	}
+	if !d.mod.isBlacklisted(corrupt.identity) {
+		t.Fatal("provider of a corrupted block was not blacklisted")
+	}
+	if len(pool.list) != 1 || pool.list[0] != honest {
+		t.Fatal("provider of a corrupted block was not dropped")
+	}
+}
+
+func TestProviderPool(t *testing.T) {
+	var pool = &providerPool{}
+	for i := 0; i < 3; i++ {
+		identity, err := id.GenerateIdentity()
+		if err != nil {
+			t.Fatal(err)
+		}
+		pool.list = append(pool.list, &downloadProvider{identity: identity})
+	}
+
+	// providers are picked in turns
+	var seen = map[*downloadProvider]bool{}
+	for i := 0; i < 3; i++ {
+		seen[pool.pick()] = true
+	}
+	if len(seen) != 3 {
+		t.Fatalf("expected 3 different providers, got %d", len(seen))
+	}
+
+	var failing = pool.pick()
+	for i := 0; i < maxProviderErrors; i++ {
+		pool.fail(failing)
+	}
+	for i := 0; i < 4; i++ {
+		if pool.pick() == failing {
+			t.Fatal("failing provider was not dropped")
+		}
+	}
+
+	pool.remove(pool.pick())
+	pool.remove(pool.pick())
+	if p := pool.pick(); p != nil {
+		t.Fatal("expected empty pool")
+	}
+}
@@ -22,7 +22,7 @@ func (Loader) Load(node modules.Node, assets assets.Assets, log *log.Logger) (mo
 
 	mod.db = assets.Database()
 
-	err := mod.db.AutoMigrate(&dbHolding{})
+	err := mod.db.AutoMigrate(&dbHolding{}, &dbDownload{}, &dbDownloadBlock{}, &dbBlockHashes{})
 	if err != nil {
 		return nil, err
 	}
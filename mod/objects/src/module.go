@@ -20,6 +20,7 @@ import (
 	"gorm.io/gorm"
 	"io"
 	"reflect"
+	"time"
 )
 
 var _ objects.Module = &Module{}
@@ -43,6 +44,9 @@ type Module struct {
 
 	provider *Provider
 
+	downloads   sig.Map[string, *download]
+	blacklisted sig.Map[string, time.Time]
+
 	content content.Module
 }
 
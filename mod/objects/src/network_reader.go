@@ -59,7 +59,7 @@ func (r *NetworkReader) Seek(offset int64, whence int) (int64, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
 	defer cancel()
 
-	conn, err := net.Route(ctx, r.mod.node.Router(), q)
+	conn, err := net.RouteWithHints(ctx, r.mod.node.Router(), q, net.DefaultHints().SetResume())
 	if err != nil {
 		return 0, err
 	}
@@ -36,6 +36,8 @@ func NewProvider(mod *Module) *Provider {
 	srv.router.AddRouteFunc(methodHold, srv.Hold)
 	srv.router.AddRouteFunc(methodRelease, srv.Release)
 	srv.router.AddRouteFunc(methodSearch, srv.Search)
+	srv.router.AddRouteFunc(methodBlocks, srv.Blocks)
+	srv.router.AddRouteFunc(methodDownload, srv.Download)
 
 	return srv
 }
@@ -79,6 +81,80 @@ func (srv *Provider) Read(ctx context.Context, query net.Query, caller net.Secur
 	})
 }
 
+// Blocks serves the list of block hashes of a locally stored object, which lets downloaders verify blocks
+// as they arrive
+func (srv *Provider) Blocks(ctx context.Context, query net.Query, caller net.SecureWriteCloser, hints net.Hints) (net.SecureWriteCloser, error) {
+	_, params := router.ParseQuery(query.Query())
+
+	objectID, err := params.GetObjectID("id")
+	if err != nil {
+		srv.mod.log.Errorv(2, "invalid id: %v", err)
+		return net.Reject()
+	}
+
+	if !srv.mod.node.Auth().Authorize(query.Caller(), objects.ActionRead, objectID) {
+		return net.Reject()
+	}
+
+	hashes, err := srv.mod.blockHashes(ctx, objectID)
+	if err != nil {
+		return net.Reject()
+	}
+
+	return net.Accept(query, caller, func(conn net.SecureConn) {
+		defer conn.Close()
+
+		conn.Write(hashes)
+	})
+}
+
+// Download downloads an object to local storage and then streams it from the offset. Only local apps can
+// start downloads.
+func (srv *Provider) Download(ctx context.Context, query net.Query, caller net.SecureWriteCloser, hints net.Hints) (net.SecureWriteCloser, error) {
+	_, params := router.ParseQuery(query.Query())
+
+	if hints.Origin != net.OriginLocal {
+		return net.Reject()
+	}
+
+	objectID, err := params.GetObjectID("id")
+	if err != nil {
+		srv.mod.log.Errorv(2, "invalid id: %v", err)
+		return net.Reject()
+	}
+
+	offset, err := params.GetUint64("offset")
+	if err != nil && !errors.Is(err, router.ErrKeyNotFound) {
+		srv.mod.log.Errorv(2, "offset: invalid argument: %v", err)
+		return net.Reject()
+	}
+
+	if !srv.mod.node.Auth().Authorize(query.Caller(), objects.ActionRead, objectID) {
+		return net.Reject()
+	}
+
+	return net.Accept(query, caller, func(conn net.SecureConn) {
+		defer conn.Close()
+
+		err := srv.mod.Download(srv.mod.ctx, objectID, &objects.DownloadOpts{HolderID: query.Caller()})
+		if err != nil {
+			srv.mod.log.Errorv(1, "download %v: %v", objectID, err)
+			return
+		}
+
+		r, err := srv.mod.Open(ctx, objectID, &objects.OpenOpts{
+			Zone:   net.ZoneDevice | net.ZoneVirtual,
+			Offset: offset,
+		})
+		if err != nil {
+			return
+		}
+		defer r.Close()
+
+		io.Copy(conn, r)
+	})
+}
+
 func (srv *Provider) Release(ctx context.Context, query net.Query, caller net.SecureWriteCloser, hints net.Hints) (net.SecureWriteCloser, error) {
 	_, params := router.ParseQuery(query.Query())
 
@@ -164,7 +164,7 @@ func (worker *alwaysLinkedWorker) Run(ctx context.Context) error {
 			continue
 		}
 
-		lnk, err := link.MakeLink(ctx, worker.node, worker.target, link.Opts{})
+		lnk, err := link.MakeLink(ctx, worker.node, worker.target, worker.node.Network().LinkConfig(), link.Opts{})
 		if err != nil {
 			worker.errCount++
 
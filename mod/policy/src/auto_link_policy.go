@@ -56,7 +56,7 @@ func (policy *AutoLinkPolicy) RouteQuery(ctx context.Context, query net.Query, c
 		return net.RouteNotFound(policy)
 	}
 
-	lnk, err := link.MakeLink(ctx, policy.node, query.Target(), link.Opts{})
+	lnk, err := link.MakeLink(ctx, policy.node, query.Target(), policy.node.Network().LinkConfig(), link.Opts{})
 	if err != nil {
 		switch {
 		case errors.Is(err, context.Canceled):
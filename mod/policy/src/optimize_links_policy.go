@@ -131,7 +131,7 @@ func (worker *optimizeLinksWorker) Run(ctx context.Context) error {
 			continue
 		}
 
-		lnk, err := link.MakeLink(ctx, worker.node, worker.target, link.Opts{
+		lnk, err := link.MakeLink(ctx, worker.node, worker.target, worker.node.Network().LinkConfig(), link.Opts{
 			Endpoints: try,
 		})
 		if err == nil {
@@ -186,8 +186,12 @@ func scoreNetwork(network string) int {
 		return 20
 	case "gw":
 		return 30
+	case "ws", "wss":
+		return 35
 	case "inet", "tcp":
 		return 40
+	case "quic":
+		return 50
 	}
 	return 0
 }
@@ -3,7 +3,6 @@ package presence
 import (
 	"bytes"
 	"context"
-	"crypto/ecdsa"
 	"errors"
 	"github.com/cryptopunkscc/astrald/cslq"
 	"github.com/cryptopunkscc/astrald/mod/presence"
@@ -106,7 +105,7 @@ func (srv *DiscoverService) readAd() (*Ad, error) {
 		}
 
 		// verify signature
-		if !ecdsa.VerifyASN1(msg.Identity.PublicKey().ToECDSA(), msg.Hash(), msg.Sig) {
+		if !msg.Identity.Verify(msg.Hash(), msg.Sig) {
 			return nil, errors.New("invalid ad signature")
 		}
 
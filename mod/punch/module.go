@@ -0,0 +1,22 @@
+package punch
+
+import (
+	"context"
+	"github.com/cryptopunkscc/astrald/auth/id"
+	"github.com/cryptopunkscc/astrald/net"
+)
+
+const ModuleName = "punch"
+
+// RendezvousServiceName is the service that introduces a caller to one of the node's linked peers
+const RendezvousServiceName = "punch.rendezvous"
+
+// InviteServiceName is the service through which a rendezvous node asks a peer to punch a path
+// to the node that requested the introduction
+const InviteServiceName = "punch.invite"
+
+type Module interface {
+	// Punch punches a UDP path to the target with the help of a node both are linked with and
+	// establishes a direct link over it
+	Punch(ctx context.Context, target id.Identity, via id.Identity) (net.Link, error)
+}
@@ -0,0 +1,12 @@
+package proto
+
+// Session is sent by the rendezvous node to the node that requested the introduction
+type Session struct {
+	ID   []byte `cslq:"[c]c"`
+	Port int    `cslq:"s"`
+}
+
+// Peer carries the address of the other side as observed by the rendezvous node
+type Peer struct {
+	Address string `cslq:"[c]c"`
+}
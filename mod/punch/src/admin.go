@@ -0,0 +1,86 @@
+package punch
+
+import (
+	"errors"
+	"github.com/cryptopunkscc/astrald/auth/id"
+	"github.com/cryptopunkscc/astrald/mod/admin"
+	"github.com/cryptopunkscc/astrald/net"
+)
+
+type Admin struct {
+	mod  *Module
+	cmds map[string]func(admin.Terminal, []string) error
+}
+
+func NewAdmin(mod *Module) *Admin {
+	var adm = &Admin{mod: mod}
+	adm.cmds = map[string]func(admin.Terminal, []string) error{
+		"link": adm.link,
+		"help": adm.help,
+	}
+
+	return adm
+}
+
+func (adm *Admin) Exec(term admin.Terminal, args []string) error {
+	if len(args) < 2 {
+		return adm.help(term, []string{})
+	}
+
+	cmd, args := args[1], args[2:]
+	if fn, found := adm.cmds[cmd]; found {
+		return fn(term, args)
+	}
+
+	return errors.New("unknown command")
+}
+
+func (adm *Admin) link(term admin.Terminal, args []string) error {
+	if len(args) < 1 {
+		return errors.New("missing target")
+	}
+
+	target, err := adm.mod.node.Resolver().Resolve(args[0])
+	if err != nil {
+		return err
+	}
+
+	var vias []id.Identity
+	if len(args) > 1 {
+		via, err := adm.mod.node.Resolver().Resolve(args[1])
+		if err != nil {
+			return err
+		}
+		vias = append(vias, via)
+	} else {
+		vias = adm.mod.gateways(target)
+		if len(vias) == 0 {
+			return errors.New("no rendezvous node, specify one")
+		}
+	}
+
+	for _, via := range vias {
+		l, err := adm.mod.Punch(adm.mod.ctx, target, via)
+		if err != nil {
+			term.Printf("via %v: %v\n", via, err)
+			continue
+		}
+
+		term.Printf("linked with %v over %v\n", l.RemoteIdentity(), admin.Keyword(net.Network(l)))
+		return nil
+	}
+
+	return errors.New("punching failed")
+}
+
+func (adm *Admin) ShortDescription() string {
+	return "direct links through NATs"
+}
+
+func (adm *Admin) help(term admin.Terminal, _ []string) error {
+	term.Printf("usage: %s <command>\n\n", "punch")
+	term.Printf("commands:\n")
+	term.Printf("  link <target> [via]    punch a direct link to the target via a node both are linked with\n")
+	term.Printf("  help                   show help\n")
+	return nil
+}
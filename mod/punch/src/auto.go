@@ -0,0 +1,55 @@
+package punch
+
+import (
+	"context"
+	"github.com/cryptopunkscc/astrald/net"
+	"github.com/cryptopunkscc/astrald/node/events"
+	"github.com/cryptopunkscc/astrald/node/network"
+	"github.com/cryptopunkscc/astrald/tasks"
+)
+
+// Auto punches direct paths to nodes linked through a gateway. The gateway link is left in place and
+// keeps serving as a fallback.
+type Auto struct {
+	*Module
+}
+
+func (a *Auto) Run(ctx context.Context) error {
+	if !a.config.Auto {
+		return nil
+	}
+
+	return tasks.Group(
+		events.Runner(a.node.Events(), func(e network.EventLinkAdded) error {
+			a.handleLinkAdded(ctx, e)
+			return nil
+		}),
+	).Run(ctx)
+}
+
+func (a *Auto) handleLinkAdded(ctx context.Context, event network.EventLinkAdded) {
+	// the dialing side initiates
+	if !event.Link.Transport().Outbound() {
+		return
+	}
+
+	if net.Network(event.Link.Link) != gatewayNetwork {
+		return
+	}
+
+	gate, ok := gateOf(event.Link.Link)
+	if !ok {
+		return
+	}
+
+	var target = event.Link.RemoteIdentity()
+	if a.directLink(target) != nil {
+		return
+	}
+
+	go func() {
+		if _, err := a.Punch(ctx, target, gate); err != nil {
+			a.log.Logv(1, "error punching a path to %v via %v: %v", target, gate, err)
+		}
+	}()
+}
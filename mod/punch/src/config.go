@@ -0,0 +1,22 @@
+package punch
+
+import "time"
+
+type Config struct {
+	// Rendezvous lets linked peers use this node to punch paths to each other
+	Rendezvous bool `yaml:"rendezvous"`
+
+	// ReflectorPort is the UDP port on which the node observes addresses of punching peers. Zero picks
+	// a random port, which is announced to the peers.
+	ReflectorPort int `yaml:"reflector_port"`
+
+	// Auto punches a direct path to every node linked through a gateway
+	Auto bool `yaml:"auto"`
+
+	// Timeout limits the time of a single punching attempt
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+var defaultConfig = Config{
+	Timeout: 15 * time.Second,
+}
@@ -0,0 +1,25 @@
+package punch
+
+import (
+	"github.com/cryptopunkscc/astrald/mod/admin"
+	"github.com/cryptopunkscc/astrald/mod/punch"
+	"github.com/cryptopunkscc/astrald/mod/quic"
+	"github.com/cryptopunkscc/astrald/node/modules"
+)
+
+func (mod *Module) LoadDependencies() error {
+	var err error
+
+	// punched paths carry quic links
+	mod.quic, err = modules.Load[quic.Module](mod.node, quic.ModuleName)
+	if err != nil {
+		return err
+	}
+
+	// inject admin command
+	if adm, err := modules.Load[admin.Module](mod.node, admin.ModuleName); err == nil {
+		adm.AddCommand(punch.ModuleName, NewAdmin(mod))
+	}
+
+	return nil
+}
@@ -0,0 +1,128 @@
+package punch
+
+import (
+	"context"
+	"errors"
+	"github.com/cryptopunkscc/astrald/auth/id"
+	"github.com/cryptopunkscc/astrald/mod/punch"
+	"github.com/cryptopunkscc/astrald/net"
+	"github.com/cryptopunkscc/astrald/node/link"
+	"github.com/cryptopunkscc/astrald/node/router"
+	_net "net"
+)
+
+// InviteService punches paths to nodes introduced by a rendezvous node
+type InviteService struct {
+	*Module
+}
+
+func (srv *InviteService) Run(ctx context.Context) error {
+	err := srv.node.LocalRouter().AddRoute(punch.InviteServiceName, srv)
+	if err != nil {
+		return err
+	}
+	defer srv.node.LocalRouter().RemoveRoute(punch.InviteServiceName)
+
+	<-ctx.Done()
+
+	return nil
+}
+
+func (srv *InviteService) RouteQuery(ctx context.Context, query net.Query, caller net.SecureWriteCloser, hints net.Hints) (net.SecureWriteCloser, error) {
+	_, params := router.ParseQuery(query.Query())
+
+	session, err := parseSessionID(params["session"])
+	if err != nil {
+		return net.Reject()
+	}
+
+	port, err := params.GetInt("port")
+	if err != nil {
+		return net.Reject()
+	}
+
+	peerID, err := id.ParsePublicKeyHex(params["peer"])
+	if err != nil {
+		return net.Reject()
+	}
+
+	// we need a direct link with the rendezvous node to reach its reflector
+	var l = srv.directLink(query.Caller())
+	if l == nil {
+		return net.Reject()
+	}
+
+	ip, err := remoteIP(l)
+	if err != nil {
+		return net.Reject()
+	}
+
+	// nothing to punch if we already have a direct link
+	if srv.directLink(peerID) != nil {
+		return net.Reject()
+	}
+
+	if err := srv.punching.Add(peerID.PublicKeyHex()); err != nil {
+		return net.Reject()
+	}
+
+	return net.Accept(query, caller, func(conn net.SecureConn) {
+		defer srv.punching.Remove(peerID.PublicKeyHex())
+
+		var reflector = &_net.UDPAddr{IP: ip, Port: port}
+		if err := srv.accept(conn, reflector, session, peerID); err != nil {
+			srv.log.Errorv(1, "error punching a path to %v: %v", peerID, err)
+		}
+	})
+}
+
+// accept punches a path to the peer and accepts a link from it
+func (srv *InviteService) accept(conn net.SecureConn, reflector _net.Addr, session sessionID, peerID id.Identity) error {
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(srv.ctx, srv.config.Timeout)
+	defer cancel()
+
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	pconn, err := _net.ListenPacket("udp", ":0")
+	if err != nil {
+		return err
+	}
+
+	addr, err := srv.rendezvous(ctx, pconn, conn, reflector, session, roleResponder)
+	if err != nil {
+		pconn.Close()
+		return err
+	}
+
+	qconn, err := srv.quic.AcceptPacketConn(ctx, pconn)
+	if err != nil {
+		return err
+	}
+
+	l, err := link.Accept(ctx, qconn, srv.node.Identity(), srv.node.Network().LinkConfig())
+	if err != nil {
+		qconn.Close()
+		return err
+	}
+
+	if !l.RemoteIdentity().IsEqual(peerID) {
+		l.Close()
+		return errors.New("linked with an unexpected identity")
+	}
+
+	if err := srv.node.Network().AddLink(l); err != nil {
+		l.Close()
+		return err
+	}
+
+	srv.fallBack(peerID)
+
+	srv.log.Info("punched a direct link with %v at %v", peerID, addr)
+
+	return nil
+}
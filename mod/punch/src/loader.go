@@ -0,0 +1,32 @@
+package punch
+
+import (
+	"github.com/cryptopunkscc/astrald/log"
+	"github.com/cryptopunkscc/astrald/mod/punch"
+	"github.com/cryptopunkscc/astrald/node/assets"
+	"github.com/cryptopunkscc/astrald/node/modules"
+)
+
+type Loader struct{}
+
+func (Loader) Load(node modules.Node, assets assets.Assets, log *log.Logger) (modules.Module, error) {
+	var mod = &Module{
+		node:   node,
+		config: defaultConfig,
+		log:    log,
+	}
+
+	_ = assets.LoadYAML(punch.ModuleName, &mod.config)
+
+	if mod.config.Timeout <= 0 {
+		mod.config.Timeout = defaultConfig.Timeout
+	}
+
+	return mod, nil
+}
+
+func init() {
+	if err := modules.RegisterModule(punch.ModuleName, Loader{}); err != nil {
+		panic(err)
+	}
+}
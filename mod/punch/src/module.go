@@ -0,0 +1,120 @@
+package punch
+
+import (
+	"context"
+	"errors"
+	"github.com/cryptopunkscc/astrald/auth/id"
+	"github.com/cryptopunkscc/astrald/log"
+	"github.com/cryptopunkscc/astrald/mod/punch"
+	"github.com/cryptopunkscc/astrald/mod/quic"
+	"github.com/cryptopunkscc/astrald/net"
+	"github.com/cryptopunkscc/astrald/node"
+	"github.com/cryptopunkscc/astrald/sig"
+	"github.com/cryptopunkscc/astrald/tasks"
+	_net "net"
+	"strconv"
+)
+
+var _ punch.Module = &Module{}
+
+// gatewayNetwork is the network of links established through a gateway
+const gatewayNetwork = "gw"
+
+// fallbackPriority is the route priority of gateway links to nodes with a punched link. It's lower
+// than the priority of links, so that the punched link is used first.
+const fallbackPriority = 40
+
+type Module struct {
+	config    Config
+	node      node.Node
+	log       *log.Logger
+	ctx       context.Context
+	quic      quic.Module
+	reflector *Reflector
+	punching  sig.Set[string]
+}
+
+func (mod *Module) Run(ctx context.Context) error {
+	mod.ctx = ctx
+
+	var runners = []tasks.Runner{
+		&InviteService{Module: mod},
+		&Auto{Module: mod},
+	}
+
+	if mod.config.Rendezvous {
+		conn, err := _net.ListenPacket("udp", ":"+strconv.Itoa(mod.config.ReflectorPort))
+		if err != nil {
+			mod.log.Errorv(0, "error starting reflector: %v", err)
+			return err
+		}
+
+		mod.reflector = NewReflector(conn)
+		mod.log.Info("reflector listening on udp port %v", mod.reflector.Port())
+
+		runners = append(runners, mod.reflector, &RendezvousService{Module: mod})
+	}
+
+	return tasks.Group(runners...).Run(ctx)
+}
+
+// directLink returns a link with the identity that doesn't go through a gateway
+func (mod *Module) directLink(identity id.Identity) net.Link {
+	for _, l := range mod.node.Network().Links().ByRemoteIdentity(identity).All() {
+		if net.Network(l.Link) != gatewayNetwork {
+			return l.Link
+		}
+	}
+	return nil
+}
+
+// gateways returns the gateways through which the node is linked with the identity
+func (mod *Module) gateways(identity id.Identity) []id.Identity {
+	var list []id.Identity
+	for _, l := range mod.node.Network().Links().ByRemoteIdentity(identity).All() {
+		if gate, ok := gateOf(l.Link); ok {
+			list = append(list, gate)
+		}
+	}
+	return list
+}
+
+// fallBack lowers the priority of gateway links with the identity, so that they're only used if
+// direct links fail
+func (mod *Module) fallBack(identity id.Identity) {
+	for _, l := range mod.node.Network().Links().ByRemoteIdentity(identity).All() {
+		if net.Network(l.Link) == gatewayNetwork {
+			mod.node.Router().AddRoute(l.LocalIdentity(), l.RemoteIdentity(), l.Link, fallbackPriority)
+		}
+	}
+}
+
+// gateOf returns the gateway of a link established through one
+func gateOf(l net.Link) (id.Identity, bool) {
+	var t = l.Transport()
+	if t == nil {
+		return id.Identity{}, false
+	}
+
+	e, ok := t.RemoteEndpoint().(interface{ Gate() id.Identity })
+	if !ok {
+		return id.Identity{}, false
+	}
+
+	return e.Gate(), true
+}
+
+// remoteIP returns the IP address of the remote end of a link
+func remoteIP(l net.Link) (_net.IP, error) {
+	var t = l.Transport()
+	if t == nil {
+		return nil, errors.New("link has no transport")
+	}
+
+	e, ok := t.RemoteEndpoint().(interface{ IP() _net.IP })
+	if !ok {
+		return nil, errors.New("link is not over an IP network")
+	}
+
+	return e.IP(), nil
+}
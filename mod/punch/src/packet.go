@@ -0,0 +1,77 @@
+package punch
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+)
+
+// Packets start with a zero byte, so that QUIC, which takes over the socket after punching, treats
+// stray ones as non-QUIC packets and drops them.
+const packetMagic = 0x00
+
+const (
+	packetRegister = 'R' // sent to the reflector to have the sender's address observed
+	packetProbe    = 'P' // sent to the peer to open a path through NATs
+)
+
+const (
+	roleInitiator = 0
+	roleResponder = 1
+)
+
+const sessionIDSize = 16
+
+const packetSize = 3 + sessionIDSize
+
+type sessionID [sessionIDSize]byte
+
+func newSessionID() (s sessionID, err error) {
+	_, err = rand.Read(s[:])
+	return
+}
+
+func parseSessionID(s string) (id sessionID, err error) {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return
+	}
+	if len(b) != sessionIDSize {
+		return id, errors.New("invalid session id length")
+	}
+	copy(id[:], b)
+	return
+}
+
+func (s sessionID) String() string {
+	return hex.EncodeToString(s[:])
+}
+
+// packet is a datagram exchanged while punching. For register packets arg is the sender's role, for
+// probes it is set when the sender has already received a probe from the peer.
+type packet struct {
+	typ     byte
+	session sessionID
+	arg     byte
+}
+
+func (p packet) marshal() []byte {
+	var buf = make([]byte, 0, packetSize)
+	buf = append(buf, packetMagic, p.typ)
+	buf = append(buf, p.session[:]...)
+	return append(buf, p.arg)
+}
+
+func parsePacket(b []byte) (p packet, ok bool) {
+	if len(b) != packetSize || b[0] != packetMagic {
+		return
+	}
+	p.typ = b[1]
+	copy(p.session[:], b[2:2+sessionIDSize])
+	p.arg = b[packetSize-1]
+	return p, true
+}
+
+func (p packet) is(typ byte, session sessionID) bool {
+	return p.typ == typ && p.session == session
+}
@@ -0,0 +1,252 @@
+package punch
+
+import (
+	"context"
+	"errors"
+	"github.com/cryptopunkscc/astrald/auth/id"
+	"github.com/cryptopunkscc/astrald/cslq"
+	"github.com/cryptopunkscc/astrald/mod/punch"
+	"github.com/cryptopunkscc/astrald/mod/punch/proto"
+	"github.com/cryptopunkscc/astrald/net"
+	"github.com/cryptopunkscc/astrald/node/link"
+	"github.com/cryptopunkscc/astrald/node/router"
+	"io"
+	_net "net"
+	"sync"
+	"time"
+)
+
+const registerInterval = 250 * time.Millisecond
+const probeInterval = 100 * time.Millisecond
+
+// finalProbes is the number of probes sent after punching succeeds, so that the peer learns about
+// it even if some of them get lost
+const finalProbes = 3
+
+func (mod *Module) Punch(ctx context.Context, target id.Identity, via id.Identity) (net.Link, error) {
+	if err := mod.punching.Add(target.PublicKeyHex()); err != nil {
+		return nil, errors.New("already punching")
+	}
+	defer mod.punching.Remove(target.PublicKeyHex())
+
+	ctx, cancel := context.WithTimeout(ctx, mod.config.Timeout)
+	defer cancel()
+
+	var l = mod.directLink(via)
+	if l == nil {
+		return nil, errors.New("not linked with the rendezvous node")
+	}
+
+	ip, err := remoteIP(l)
+	if err != nil {
+		return nil, err
+	}
+
+	var query = net.NewQuery(mod.node.Identity(), via, router.Query(punch.RendezvousServiceName, router.Params{
+		"target": target.PublicKeyHex(),
+	}))
+
+	conn, err := net.RouteWithHints(ctx, l, query, net.DefaultHints().SetSilent())
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	var msg proto.Session
+	if err := cslq.Decode(conn, "v", &msg); err != nil {
+		return nil, err
+	}
+
+	if len(msg.ID) != sessionIDSize {
+		return nil, errors.New("invalid session id length")
+	}
+	var session sessionID
+	copy(session[:], msg.ID)
+
+	mod.log.Logv(1, "punching a path to %v via %v", target, via)
+
+	pconn, err := _net.ListenPacket("udp", ":0")
+	if err != nil {
+		return nil, err
+	}
+
+	addr, err := mod.rendezvous(ctx, pconn, conn, &_net.UDPAddr{IP: ip, Port: msg.Port}, session, roleInitiator)
+	if err != nil {
+		pconn.Close()
+		return nil, err
+	}
+
+	qconn, err := mod.quic.DialPacketConn(ctx, pconn, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	nl, err := link.Open(ctx, qconn, target, mod.node.Identity(), mod.node.Network().LinkConfig())
+	if err != nil {
+		qconn.Close()
+		return nil, err
+	}
+
+	if err := mod.node.Network().AddLink(nl); err != nil {
+		nl.Close()
+		return nil, err
+	}
+
+	mod.fallBack(target)
+
+	mod.log.Info("punched a direct link with %v at %v", target, addr)
+
+	return nl, nil
+}
+
+// rendezvous registers with the reflector, waits for the peer's address sent by the rendezvous node
+// over conn and punches a path to it. It returns the address to use for the peer.
+func (mod *Module) rendezvous(ctx context.Context, pconn _net.PacketConn, conn io.Reader, reflector _net.Addr, session sessionID, role int) (_net.Addr, error) {
+	var registerCtx, stopRegister = context.WithCancel(ctx)
+	defer stopRegister()
+
+	go register(registerCtx, pconn, reflector, session, role)
+
+	var msg proto.Peer
+	if err := cslq.Decode(conn, "v", &msg); err != nil {
+		return nil, err
+	}
+	stopRegister()
+
+	peer, err := _net.ResolveUDPAddr("udp", msg.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	mod.log.Logv(2, "peer observed at %v", peer)
+
+	// the invited side has no say in the punching, so it doesn't send probes anywhere the rendezvous node
+	// didn't observe the peer
+	return punchPath(ctx, pconn, session, peer, role == roleInitiator)
+}
+
+// register sends register packets to the reflector until the context is done
+func register(ctx context.Context, pconn _net.PacketConn, reflector _net.Addr, session sessionID, role int) {
+	var p = packet{typ: packetRegister, session: session, arg: byte(role)}.marshal()
+	var ticker = time.NewTicker(registerInterval)
+	defer ticker.Stop()
+
+	for {
+		pconn.WriteTo(p, reflector)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// punchPath sends probes to the peer until both sides have received a probe from each other, which
+// means packets pass through NATs on the path in both directions. It returns the address of the
+// peer's probes, which behind some NATs differs from the address observed by the reflector. If follow
+// is false, probes from other addresses than the observed one are ignored.
+func punchPath(ctx context.Context, pconn _net.PacketConn, session sessionID, peer _net.Addr, follow bool) (_net.Addr, error) {
+	var mu sync.Mutex
+	var seen bool
+	var done = make(chan struct{})
+	var readErr = make(chan error, 1)
+
+	var probe = func() {
+		mu.Lock()
+		var p = packet{typ: packetProbe, session: session}
+		if seen {
+			p.arg = 1
+		}
+		var addr = peer
+		mu.Unlock()
+
+		pconn.WriteTo(p.marshal(), addr)
+	}
+
+	go func() {
+		var buf = make([]byte, packetSize+1)
+		var finished bool
+		for {
+			n, addr, err := pconn.ReadFrom(buf)
+			if err != nil {
+				readErr <- err
+				return
+			}
+			if finished {
+				continue
+			}
+
+			mu.Lock()
+			var fromPeer = addr.String() == peer.String()
+			mu.Unlock()
+
+			p, ok := parsePacket(buf[:n])
+			switch {
+			case ok && p.is(packetProbe, session):
+				if !fromPeer && !follow {
+					continue
+				}
+				mu.Lock()
+				seen, peer = true, addr
+				mu.Unlock()
+				if p.arg == 0 {
+					continue
+				}
+
+			case fromPeer:
+				// anything else from the peer means it finished punching and moved on
+
+			default:
+				continue
+			}
+
+			finished = true
+			close(done)
+		}
+	}()
+
+	var ticker = time.NewTicker(probeInterval)
+	defer ticker.Stop()
+
+	var err error
+	for loop := true; loop; {
+		probe()
+
+		select {
+		case <-done:
+			for i := 0; i < finalProbes; i++ {
+				probe()
+			}
+			loop = false
+
+		case err = <-readErr:
+			return nil, err
+
+		case <-ctx.Done():
+			err = ctx.Err()
+			loop = false
+
+		case <-ticker.C:
+		}
+	}
+
+	// stop the reader and hand the socket over
+	pconn.SetReadDeadline(time.Now())
+	<-readErr
+	pconn.SetReadDeadline(time.Time{})
+
+	if err != nil {
+		return nil, err
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	return peer, nil
+}
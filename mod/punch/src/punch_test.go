@@ -0,0 +1,193 @@
+package punch
+
+import (
+	"context"
+	_net "net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// natConn simulates a NAT in front of a socket. It only lets in packets from addresses the socket
+// has sent packets to.
+type natConn struct {
+	_net.PacketConn
+	mu   sync.Mutex
+	open map[string]bool
+}
+
+func newNATConn(t *testing.T) *natConn {
+	conn, err := _net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return &natConn{PacketConn: conn, open: map[string]bool{}}
+}
+
+func (c *natConn) WriteTo(p []byte, addr _net.Addr) (int, error) {
+	c.mu.Lock()
+	c.open[addr.String()] = true
+	c.mu.Unlock()
+
+	return c.PacketConn.WriteTo(p, addr)
+}
+
+func (c *natConn) ReadFrom(p []byte) (int, _net.Addr, error) {
+	for {
+		n, addr, err := c.PacketConn.ReadFrom(p)
+		if err != nil {
+			return n, addr, err
+		}
+
+		c.mu.Lock()
+		var open = c.open[addr.String()]
+		c.mu.Unlock()
+
+		if open {
+			return n, addr, err
+		}
+	}
+}
+
+func TestPunch(t *testing.T) {
+	var ctx, cancel = context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	conn, err := _net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var reflector = NewReflector(conn)
+	go reflector.Run(ctx)
+
+	var a, b = newNATConn(t), newNATConn(t)
+
+	session, err := newSessionID()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// both sides register with the reflector
+	reflector.Expect(session)
+	defer reflector.Forget(session)
+
+	var registerCtx, stopRegister = context.WithCancel(ctx)
+	go register(registerCtx, a, conn.LocalAddr(), session, roleInitiator)
+	go register(registerCtx, b, conn.LocalAddr(), session, roleResponder)
+
+	initiator, responder, err := reflector.Wait(ctx, session)
+	stopRegister()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if initiator.String() != a.LocalAddr().String() || responder.String() != b.LocalAddr().String() {
+		t.Fatalf("observed %v and %v, expected %v and %v", initiator, responder, a.LocalAddr(), b.LocalAddr())
+	}
+
+	// the path is closed before punching
+	b.WriteTo([]byte("early"), initiator)
+	a.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+	if _, _, err := a.ReadFrom(make([]byte, 16)); err == nil {
+		t.Fatal("packet passed through a closed path")
+	}
+	a.SetReadDeadline(time.Time{})
+
+	// both sides punch simultaneously
+	var wg sync.WaitGroup
+	var addrs [2]_net.Addr
+	var errs [2]error
+	for i, side := range []struct {
+		conn _net.PacketConn
+		peer _net.Addr
+	}{{a, responder}, {b, initiator}} {
+		wg.Add(1)
+		go func(i int, conn _net.PacketConn, peer _net.Addr) {
+			defer wg.Done()
+			addrs[i], errs[i] = punchPath(ctx, conn, session, peer, i == 0)
+		}(i, side.conn, side.peer)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("side %d: %v", i, err)
+		}
+	}
+	if addrs[0].String() != b.LocalAddr().String() || addrs[1].String() != a.LocalAddr().String() {
+		t.Fatalf("punched to %v and %v", addrs[0], addrs[1])
+	}
+
+	// the path is open in both directions
+	for _, dir := range []struct {
+		from, to *natConn
+	}{{a, b}, {b, a}} {
+		var msg = "hello from " + dir.from.LocalAddr().String()
+		if _, err := dir.from.WriteTo([]byte(msg), dir.to.LocalAddr()); err != nil {
+			t.Fatal(err)
+		}
+
+		dir.to.SetReadDeadline(time.Now().Add(time.Second))
+		var buf = make([]byte, 64)
+		for {
+			n, _, err := dir.to.ReadFrom(buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// skip probes still in flight
+			if _, ok := parsePacket(buf[:n]); ok {
+				continue
+			}
+			if string(buf[:n]) != msg {
+				t.Fatalf("received '%s', expected '%s'", buf[:n], msg)
+			}
+			break
+		}
+	}
+}
+
+func TestPunchPathObserved(t *testing.T) {
+	var ctx, cancel = context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	var listen = func() _net.PacketConn {
+		conn, err := _net.ListenPacket("udp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		return conn
+	}
+
+	var invited, observed, stranger = listen(), listen(), listen()
+
+	session, err := newSessionID()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// someone else than the observed peer sends valid probes
+	go func() {
+		var p = packet{typ: packetProbe, session: session, arg: 1}.marshal()
+		for ctx.Err() == nil {
+			stranger.WriteTo(p, invited.LocalAddr())
+			time.Sleep(10 * time.Millisecond)
+		}
+	}()
+
+	addr, err := punchPath(ctx, invited, session, observed.LocalAddr(), false)
+	if err == nil {
+		t.Fatalf("punched a path to %v", addr)
+	}
+
+	// probes only went to the observed address
+	observed.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+	if _, _, err := observed.ReadFrom(make([]byte, 64)); err != nil {
+		t.Fatal("no probes sent to the observed address")
+	}
+	stranger.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+	if _, _, err := stranger.ReadFrom(make([]byte, 64)); err == nil {
+		t.Fatal("probes sent to an unobserved address")
+	}
+}
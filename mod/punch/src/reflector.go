@@ -0,0 +1,110 @@
+package punch
+
+import (
+	"context"
+	"errors"
+	_net "net"
+	"sync"
+)
+
+// Reflector observes the public UDP addresses of peers taking part in punching sessions
+type Reflector struct {
+	conn _net.PacketConn
+
+	mu       sync.Mutex
+	sessions map[sessionID]*observation
+}
+
+// observation holds the addresses observed in a session, indexed by role
+type observation struct {
+	addrs [2]_net.Addr
+	done  chan struct{}
+}
+
+func NewReflector(conn _net.PacketConn) *Reflector {
+	return &Reflector{
+		conn:     conn,
+		sessions: map[sessionID]*observation{},
+	}
+}
+
+func (r *Reflector) Run(ctx context.Context) error {
+	go func() {
+		<-ctx.Done()
+		r.conn.Close()
+	}()
+
+	var buf = make([]byte, packetSize+1)
+	for {
+		n, addr, err := r.conn.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		p, ok := parsePacket(buf[:n])
+		if !ok || p.typ != packetRegister || p.arg > roleResponder {
+			continue
+		}
+
+		r.observe(p.session, int(p.arg), addr)
+	}
+}
+
+// Port returns the UDP port of the reflector
+func (r *Reflector) Port() int {
+	if addr, ok := r.conn.LocalAddr().(*_net.UDPAddr); ok {
+		return addr.Port
+	}
+	return 0
+}
+
+// Expect starts observing the session
+func (r *Reflector) Expect(session sessionID) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.sessions[session] = &observation{done: make(chan struct{})}
+}
+
+// Wait waits until both sides of an expected session register and returns their addresses
+func (r *Reflector) Wait(ctx context.Context, session sessionID) (initiator _net.Addr, responder _net.Addr, err error) {
+	r.mu.Lock()
+	o, found := r.sessions[session]
+	r.mu.Unlock()
+	if !found {
+		return nil, nil, errors.New("session not expected")
+	}
+
+	select {
+	case <-o.done:
+		return o.addrs[roleInitiator], o.addrs[roleResponder], nil
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	}
+}
+
+// Forget stops observing the session
+func (r *Reflector) Forget(session sessionID) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.sessions, session)
+}
+
+func (r *Reflector) observe(session sessionID, role int, addr _net.Addr) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	o, found := r.sessions[session]
+	if !found || o.addrs[role] != nil {
+		return
+	}
+
+	o.addrs[role] = addr
+	if o.addrs[roleInitiator] != nil && o.addrs[roleResponder] != nil {
+		close(o.done)
+	}
+}
@@ -0,0 +1,104 @@
+package punch
+
+import (
+	"context"
+	"errors"
+	"github.com/cryptopunkscc/astrald/auth/id"
+	"github.com/cryptopunkscc/astrald/cslq"
+	"github.com/cryptopunkscc/astrald/mod/punch"
+	"github.com/cryptopunkscc/astrald/mod/punch/proto"
+	"github.com/cryptopunkscc/astrald/net"
+	"github.com/cryptopunkscc/astrald/node/router"
+	"strconv"
+)
+
+// RendezvousService introduces callers to linked peers, so that they can punch a path to each other
+type RendezvousService struct {
+	*Module
+}
+
+func (srv *RendezvousService) Run(ctx context.Context) error {
+	err := srv.node.LocalRouter().AddRoute(punch.RendezvousServiceName, srv)
+	if err != nil {
+		return err
+	}
+	defer srv.node.LocalRouter().RemoveRoute(punch.RendezvousServiceName)
+
+	<-ctx.Done()
+
+	return nil
+}
+
+func (srv *RendezvousService) RouteQuery(ctx context.Context, query net.Query, caller net.SecureWriteCloser, hints net.Hints) (net.SecureWriteCloser, error) {
+	_, params := router.ParseQuery(query.Query())
+
+	targetID, err := id.ParsePublicKeyHex(params["target"])
+	if err != nil {
+		return net.Reject()
+	}
+
+	if targetID.IsEqual(query.Caller()) || targetID.IsEqual(srv.node.Identity()) {
+		return net.Reject()
+	}
+
+	var l = srv.directLink(targetID)
+	if l == nil {
+		return net.RouteNotFound(srv, errors.New("target not linked"))
+	}
+
+	return net.Accept(query, caller, func(conn net.SecureConn) {
+		if err := srv.introduce(conn, l); err != nil {
+			srv.log.Errorv(1, "error introducing %v to %v: %v", query.Caller(), targetID, err)
+		}
+	})
+}
+
+// introduce invites the target to a punching session with the caller and sends both sides each
+// other's addresses once they register with the reflector
+func (srv *RendezvousService) introduce(conn net.SecureConn, l net.Link) error {
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(srv.ctx, srv.config.Timeout)
+	defer cancel()
+
+	session, err := newSessionID()
+	if err != nil {
+		return err
+	}
+
+	srv.reflector.Expect(session)
+	defer srv.reflector.Forget(session)
+
+	err = cslq.Encode(conn, "v", proto.Session{ID: session[:], Port: srv.reflector.Port()})
+	if err != nil {
+		return err
+	}
+
+	var query = net.NewQuery(srv.node.Identity(), l.RemoteIdentity(), router.Query(punch.InviteServiceName, router.Params{
+		"session": session.String(),
+		"port":    strconv.Itoa(srv.reflector.Port()),
+		"peer":    conn.RemoteIdentity().PublicKeyHex(),
+	}))
+
+	peerConn, err := net.RouteWithHints(ctx, l, query, net.DefaultHints().SetSilent())
+	if err != nil {
+		return err
+	}
+	defer peerConn.Close()
+
+	initiator, responder, err := srv.reflector.Wait(ctx, session)
+	if err != nil {
+		return err
+	}
+
+	srv.log.Logv(1, "introducing %v at %v to %v at %v",
+		conn.RemoteIdentity(), initiator,
+		l.RemoteIdentity(), responder,
+	)
+
+	if err := cslq.Encode(conn, "v", proto.Peer{Address: responder.String()}); err != nil {
+		return err
+	}
+
+	return cslq.Encode(peerConn, "v", proto.Peer{Address: initiator.String()})
+}
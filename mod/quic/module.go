@@ -0,0 +1,33 @@
+package quic
+
+import (
+	"context"
+	"github.com/cryptopunkscc/astrald/net"
+	"github.com/cryptopunkscc/astrald/node/infra"
+	_net "net"
+)
+
+const ModuleName = "quic"
+
+type Module interface {
+	infra.Dialer
+	infra.Unpacker
+	infra.Parser
+	infra.EndpointLister
+	ListenPort() int
+
+	// DialPacketConn establishes a connection to addr over an existing packet connection, such as
+	// a UDP socket with a punched NAT path. The packet connection is closed with the connection or if
+	// dialing fails.
+	DialPacketConn(ctx context.Context, pconn _net.PacketConn, addr _net.Addr) (net.Conn, error)
+
+	// AcceptPacketConn accepts a single connection over an existing packet connection. The packet
+	// connection is closed with the connection or if accepting fails.
+	AcceptPacketConn(ctx context.Context, pconn _net.PacketConn) (net.Conn, error)
+}
+
+type Endpoint interface {
+	net.Endpoint
+	IP() _net.IP
+	Port() int
+}
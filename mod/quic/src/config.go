@@ -0,0 +1,18 @@
+package quic
+
+import "time"
+
+type Config struct {
+	DialTimeout     time.Duration
+	PublicEndpoints []string
+	ListenPort      int
+	KeepAlive       time.Duration // how often to send keep-alive packets (keeps NAT bindings open)
+	IdleTimeout     time.Duration // close the connection after this much time without network activity
+}
+
+var defaultConfig = Config{
+	DialTimeout: time.Minute,
+	ListenPort:  1791,
+	KeepAlive:   15 * time.Second,
+	IdleTimeout: time.Minute,
+}
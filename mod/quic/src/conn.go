@@ -0,0 +1,50 @@
+package quic
+
+import (
+	"github.com/cryptopunkscc/astrald/net"
+	"github.com/quic-go/quic-go"
+)
+
+var _ net.Conn = &Conn{}
+
+// Conn is a single bidirectional stream of a QUIC connection. The stream lives as long as the
+// connection, so closing the Conn closes the whole QUIC connection.
+type Conn struct {
+	quic.Stream
+	qconn          quic.Connection
+	outbound       bool
+	localEndpoint  Endpoint
+	remoteEndpoint Endpoint
+}
+
+func wrapQUICConn(qconn quic.Connection, stream quic.Stream, outbound bool) *Conn {
+	c := &Conn{
+		Stream:   stream,
+		qconn:    qconn,
+		outbound: outbound,
+	}
+
+	c.localEndpoint, _ = Parse(qconn.LocalAddr().String())
+	c.remoteEndpoint, _ = Parse(qconn.RemoteAddr().String())
+
+	return c
+}
+
+func (conn *Conn) Close() error {
+	conn.Stream.Close()
+	return conn.qconn.CloseWithError(0, "")
+}
+
+func (conn *Conn) LocalEndpoint() net.Endpoint {
+	return conn.localEndpoint
+}
+
+// RemoteEndpoint returns the address the connection was established with. If the peer migrates
+// to a new address, the connection survives, but the endpoint is not updated.
+func (conn *Conn) RemoteEndpoint() net.Endpoint {
+	return conn.remoteEndpoint
+}
+
+func (conn *Conn) Outbound() bool {
+	return conn.outbound
+}
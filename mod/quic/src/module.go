@@ -0,0 +1,41 @@
+package quic
+
+import (
+	"context"
+	"github.com/cryptopunkscc/astrald/log"
+	"github.com/cryptopunkscc/astrald/mod/quic"
+	"github.com/cryptopunkscc/astrald/node"
+	"github.com/cryptopunkscc/astrald/tasks"
+	_quic "github.com/quic-go/quic-go"
+)
+
+var _ quic.Module = &Module{}
+
+type Module struct {
+	config          Config
+	node            node.Node
+	log             *log.Logger
+	ctx             context.Context
+	publicEndpoints []Endpoint
+}
+
+func (mod *Module) Run(ctx context.Context) error {
+	mod.ctx = ctx
+
+	tasks.Group(NewServer(mod)).Run(ctx)
+
+	<-ctx.Done()
+
+	return nil
+}
+
+func (mod *Module) ListenPort() int {
+	return mod.config.ListenPort
+}
+
+func (mod *Module) quicConfig() *_quic.Config {
+	return &_quic.Config{
+		KeepAlivePeriod: mod.config.KeepAlive,
+		MaxIdleTimeout:  mod.config.IdleTimeout,
+	}
+}
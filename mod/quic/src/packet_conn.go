@@ -0,0 +1,83 @@
+package quic
+
+import (
+	"context"
+	"github.com/cryptopunkscc/astrald/net"
+	_quic "github.com/quic-go/quic-go"
+	_net "net"
+)
+
+// transportConn is a Conn that owns its QUIC transport and the packet connection underneath
+type transportConn struct {
+	*Conn
+	transport *_quic.Transport
+}
+
+func (conn *transportConn) Close() error {
+	err := conn.Conn.Close()
+	closeTransport(conn.transport)
+	return err
+}
+
+// closeTransport closes the transport along with its packet connection, which quic leaves open if
+// it didn't create it
+func closeTransport(transport *_quic.Transport) {
+	transport.Close()
+	transport.Conn.Close()
+}
+
+func (mod *Module) DialPacketConn(ctx context.Context, pconn _net.PacketConn, addr _net.Addr) (net.Conn, error) {
+	ctx, cancel := context.WithTimeout(ctx, mod.config.DialTimeout)
+	defer cancel()
+
+	var transport = &_quic.Transport{Conn: pconn}
+
+	qconn, err := transport.Dial(ctx, addr, newClientTLSConfig(), mod.quicConfig())
+	if err != nil {
+		closeTransport(transport)
+		return nil, err
+	}
+
+	stream, err := qconn.OpenStreamSync(ctx)
+	if err != nil {
+		qconn.CloseWithError(0, "")
+		closeTransport(transport)
+		return nil, err
+	}
+
+	return &transportConn{
+		Conn:      wrapQUICConn(qconn, stream, true),
+		transport: transport,
+	}, nil
+}
+
+func (mod *Module) AcceptPacketConn(ctx context.Context, pconn _net.PacketConn) (net.Conn, error) {
+	ctx, cancel := context.WithTimeout(ctx, mod.config.DialTimeout)
+	defer cancel()
+
+	tlsConfig, err := newServerTLSConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	var transport = &_quic.Transport{Conn: pconn}
+
+	listener, err := transport.Listen(tlsConfig, mod.quicConfig())
+	if err != nil {
+		closeTransport(transport)
+		return nil, err
+	}
+	// established connections outlive listeners of a transport
+	defer listener.Close()
+
+	conn, err := mod.accept(ctx, listener)
+	if err != nil {
+		closeTransport(transport)
+		return nil, err
+	}
+
+	return &transportConn{
+		Conn:      conn,
+		transport: transport,
+	}, nil
+}
@@ -0,0 +1,102 @@
+package quic
+
+import (
+	"context"
+	"github.com/cryptopunkscc/astrald/node/link"
+	_quic "github.com/quic-go/quic-go"
+	"strconv"
+)
+
+type Server struct {
+	*Module
+}
+
+func NewServer(module *Module) *Server {
+	return &Server{Module: module}
+}
+
+func (srv *Server) Run(ctx context.Context) error {
+	// start the listener
+	var addrStr = ":" + strconv.Itoa(srv.config.ListenPort)
+
+	listener, err := srv.listen(addrStr)
+	if err != nil {
+		srv.log.Errorv(0, "failed to start server: %v", err)
+		return err
+	}
+
+	endpoint, _ := Parse(listener.Addr().String())
+
+	srv.log.Info("started server at %v", endpoint)
+	defer srv.log.Info("stopped server at %v", endpoint)
+
+	go func() {
+		<-ctx.Done()
+		listener.Close()
+	}()
+
+	// accept connections
+	for {
+		qconn, err := listener.Accept(ctx)
+		if err != nil {
+			return err
+		}
+
+		// wait for the first stream in the connection's goroutine, so that a silent peer cannot block the listener
+		go func() {
+			conn, err := srv.acceptStream(ctx, qconn)
+			if err != nil {
+				return
+			}
+
+			l, err := link.Accept(ctx, conn, srv.node.Identity(), srv.node.Network().LinkConfig())
+			if err != nil {
+				srv.log.Errorv(1, "handshake failed from %v: %v", conn.RemoteEndpoint(), err)
+				return
+			}
+
+			err = srv.node.Network().AddLink(l)
+			if err != nil {
+				l.Close()
+			}
+		}()
+	}
+}
+
+func (mod *Module) listen(addr string) (*_quic.Listener, error) {
+	tlsConfig, err := newServerTLSConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	return _quic.ListenAddr(addr, tlsConfig, mod.quicConfig())
+}
+
+// acceptStream waits for the first stream of a connection
+func (mod *Module) acceptStream(ctx context.Context, qconn _quic.Connection) (*Conn, error) {
+	streamCtx, cancel := context.WithTimeout(ctx, mod.config.DialTimeout)
+	defer cancel()
+
+	stream, err := qconn.AcceptStream(streamCtx)
+	if err != nil {
+		qconn.CloseWithError(0, "")
+		return nil, err
+	}
+
+	return wrapQUICConn(qconn, stream, false), nil
+}
+
+// accept waits for the next connection and its first stream
+func (mod *Module) accept(ctx context.Context, listener *_quic.Listener) (*Conn, error) {
+	for {
+		qconn, err := listener.Accept(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		conn, err := mod.acceptStream(ctx, qconn)
+		if err == nil {
+			return conn, nil
+		}
+	}
+}
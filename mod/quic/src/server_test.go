@@ -0,0 +1,141 @@
+package quic
+
+import (
+	"bytes"
+	"context"
+	"github.com/cryptopunkscc/astrald/auth/id"
+	"github.com/cryptopunkscc/astrald/node/link"
+	"io"
+	_net "net"
+	"testing"
+	"time"
+)
+
+func TestLoopbackLink(t *testing.T) {
+	var ctx, cancel = context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var serverID, _ = id.GenerateIdentity()
+	var clientID, _ = id.GenerateIdentity()
+	var mod = &Module{config: defaultConfig}
+	var msg = []byte("hello over quic")
+
+	listener, err := mod.listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	var errCh = make(chan error, 1)
+	go func() {
+		conn, err := mod.accept(ctx, listener)
+		if err != nil {
+			errCh <- err
+			return
+		}
+
+		l, err := link.Accept(ctx, conn, serverID, link.DefaultConfig())
+		if err != nil {
+			errCh <- err
+			return
+		}
+		defer l.Close()
+
+		var buf = make([]byte, len(msg))
+		if _, err := io.ReadFull(l.Transport(), buf); err != nil {
+			errCh <- err
+			return
+		}
+		if !bytes.Equal(buf, msg) {
+			t.Errorf("received '%s', expected '%s'", buf, msg)
+		}
+		errCh <- nil
+	}()
+
+	endpoint, err := Parse(listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn, err := mod.Dial(ctx, endpoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := link.Open(ctx, conn, serverID, clientID, link.DefaultConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	if !l.RemoteIdentity().IsEqual(serverID) {
+		t.Fatalf("linked with %v, expected %v", l.RemoteIdentity(), serverID)
+	}
+
+	if _, err := l.Transport().Write(msg); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := <-errCh; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPacketConnLink(t *testing.T) {
+	var ctx, cancel = context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var serverID, _ = id.GenerateIdentity()
+	var clientID, _ = id.GenerateIdentity()
+	var mod = &Module{config: defaultConfig}
+
+	serverConn, err := _net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientConn, err := _net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var errCh = make(chan error, 1)
+	go func() {
+		conn, err := mod.AcceptPacketConn(ctx, serverConn)
+		if err != nil {
+			errCh <- err
+			return
+		}
+
+		l, err := link.Accept(ctx, conn, serverID, link.DefaultConfig())
+		if err != nil {
+			errCh <- err
+			return
+		}
+		defer l.Close()
+
+		if !l.RemoteIdentity().IsEqual(clientID) {
+			t.Errorf("accepted %v, expected %v", l.RemoteIdentity(), clientID)
+		}
+		errCh <- nil
+	}()
+
+	conn, err := mod.DialPacketConn(ctx, clientConn, serverConn.LocalAddr())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := link.Open(ctx, conn, serverID, clientID, link.DefaultConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := <-errCh; err != nil {
+		t.Fatal(err)
+	}
+
+	// closing the link releases the socket
+	l.Close()
+	if _, err := clientConn.WriteTo([]byte{0}, serverConn.LocalAddr()); err == nil {
+		t.Fatal("socket still open after closing the link")
+	}
+}
@@ -2,7 +2,6 @@ package relay
 
 import (
 	"bytes"
-	"crypto/ecdsa"
 	"crypto/sha256"
 	"errors"
 	"github.com/cryptopunkscc/astrald/auth/id"
@@ -69,11 +68,7 @@ func (cert *Cert) VerifyRelay() error {
 		return errors.New("relay signature missing")
 	case cert.RelayID.IsZero():
 		return errors.New("relay identity missing")
-	case !ecdsa.VerifyASN1(
-		cert.RelayID.PublicKey().ToECDSA(),
-		cert.Hash(),
-		cert.RelaySig,
-	):
+	case !cert.RelayID.Verify(cert.Hash(), cert.RelaySig):
 		return errors.New("relay signature invalid")
 	}
 
@@ -87,11 +82,7 @@ func (cert *Cert) VerifyTarget() error {
 		return errors.New("target signature missing")
 	case cert.TargetID.IsZero():
 		return errors.New("target identity missing")
-	case !ecdsa.VerifyASN1(
-		cert.TargetID.PublicKey().ToECDSA(),
-		cert.Hash(),
-		cert.TargetSig,
-	):
+	case !cert.TargetID.Verify(cert.Hash(), cert.TargetSig):
 		return errors.New("target signature invalid")
 	}
 
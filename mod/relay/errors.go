@@ -4,3 +4,4 @@ import "errors"
 
 var ErrCertAlreadyIndexed = errors.New("certificate already indexed")
 var ErrCertNotFound = errors.New("certificate not found")
+var ErrCertRevoked = errors.New("certificate revoked")
@@ -9,6 +9,7 @@ import (
 	"github.com/cryptopunkscc/astrald/lib/adc"
 	"github.com/cryptopunkscc/astrald/mod/objects"
 	"github.com/cryptopunkscc/astrald/mod/relay"
+	"github.com/cryptopunkscc/astrald/mod/revocations"
 	"github.com/cryptopunkscc/astrald/object"
 	"time"
 )
@@ -16,6 +17,7 @@ import (
 func (mod *Module) indexData(objectID object.ID) error {
 	// check if cert is already indexed
 	if mod.isCertIndexed(objectID) {
+		mod.objects.Hold(mod.node.Identity(), objectID)
 		return relay.ErrCertAlreadyIndexed
 	}
 
@@ -57,13 +59,23 @@ func (mod *Module) index(cert *relay.Cert) error {
 	cslq.Encode(w, "vv", adc.Header(relay.CertType), cert)
 	objectID := w.Resolve()
 
-	return mod.db.Create(&dbCert{
+	if mod.isRevoked(objectID, cert) {
+		return relay.ErrCertRevoked
+	}
+
+	err := mod.db.Create(&dbCert{
 		DataID:    objectID,
 		Direction: string(cert.Direction),
 		TargetID:  cert.TargetID,
 		RelayID:   cert.RelayID,
 		ExpiresAt: cert.ExpiresAt,
 	}).Error
+	if err != nil {
+		return err
+	}
+
+	// keep the certificate in storage while it's indexed
+	return mod.objects.Hold(mod.node.Identity(), objectID)
 }
 
 func (mod *Module) MakeCert(targetID id.Identity, relayID id.Identity, direction relay.Direction, duration time.Duration) (object.ID, error) {
@@ -236,14 +248,21 @@ func (mod *Module) makeCert(
 		ExpiresAt: time.Now().Add(duration),
 	}
 
+	var desc = fmt.Sprintf("relay certificate letting %s relay %s queries for %s until %s",
+		mod.node.Resolver().DisplayName(relayID),
+		direction,
+		mod.node.Resolver().DisplayName(targetID),
+		cert.ExpiresAt.Format(time.DateTime),
+	)
+
 	// sign with target identity
-	cert.TargetSig, err = mod.keys.Sign(cert.TargetID, cert.Hash())
+	cert.TargetSig, err = mod.keys.SignWithDescription(cert.TargetID, cert.Hash(), desc)
 	if err != nil {
 		return nil, fmt.Errorf("error signing certificate with target key: %w", err)
 	}
 
 	// sign with relay identity
-	cert.RelaySig, err = mod.keys.Sign(relayID, cert.Hash())
+	cert.RelaySig, err = mod.keys.SignWithDescription(relayID, cert.Hash(), desc)
 	if err != nil {
 		return nil, fmt.Errorf("error signing certificate with relay key: %w", err)
 	}
@@ -264,3 +283,23 @@ func (mod *Module) isCertIndexed(objectID object.ID) bool {
 	}
 	return c > 0
 }
+
+// isRevoked checks if the certificate was revoked by its target or relay
+func (mod *Module) isRevoked(objectID object.ID, cert *relay.Cert) bool {
+	if mod.revocations == nil {
+		return false
+	}
+	return mod.revocations.Revoked(objectID, cert.TargetID, cert.RelayID)
+}
+
+// removeRevoked removes a revoked certificate from the index
+func (mod *Module) removeRevoked(rev *revocations.Revocation) error {
+	tx := mod.db.
+		Where("data_id = ? and (target_id = ? or relay_id = ?)", rev.ObjectID, rev.SignerID, rev.SignerID).
+		Delete(&dbCert{})
+	if tx.Error != nil || tx.RowsAffected == 0 {
+		return tx.Error
+	}
+
+	return mod.objects.Release(mod.node.Identity(), rev.ObjectID)
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/cryptopunkscc/astrald/mod/keys"
 	"github.com/cryptopunkscc/astrald/mod/objects"
 	"github.com/cryptopunkscc/astrald/mod/relay"
+	"github.com/cryptopunkscc/astrald/mod/revocations"
 	"github.com/cryptopunkscc/astrald/node/modules"
 )
 
@@ -30,6 +31,9 @@ func (mod *Module) LoadDependencies() error {
 		return err
 	}
 
+	// load optional dependencies
+	mod.revocations, _ = modules.Load[revocations.Module](mod.node, revocations.ModuleName)
+
 	if adm, err := modules.Load[admin.Module](mod.node, admin.ModuleName); err == nil {
 		adm.AddCommand(relay.ModuleName, NewAdmin(mod))
 	}
@@ -8,11 +8,15 @@ import (
 	"github.com/cryptopunkscc/astrald/cslq"
 	"github.com/cryptopunkscc/astrald/lib/adc"
 	"github.com/cryptopunkscc/astrald/mod/relay"
+	"github.com/cryptopunkscc/astrald/object"
 )
 
 // IdentityMachine renders the final identity by applying certificates to the initial identity
 type IdentityMachine struct {
 	identity id.Identity
+
+	// Revoked, if set, is used to reject revoked certificates
+	Revoked func(objectID object.ID, cert *relay.Cert) bool
 }
 
 // NewIdentityMachine returns a new instance of an IdentityMachine with the provided identity as its initial state
@@ -45,6 +49,10 @@ func (m *IdentityMachine) Apply(certBytes []byte) error {
 			return fmt.Errorf("invalid certificate: %w", err)
 		}
 
+		if m.Revoked != nil && m.Revoked(object.Resolve(certBytes), &cert) {
+			return relay.ErrCertRevoked
+		}
+
 		m.identity = cert.TargetID
 
 		return nil
@@ -6,6 +6,7 @@ import (
 	"github.com/cryptopunkscc/astrald/mod/content"
 	"github.com/cryptopunkscc/astrald/mod/objects"
 	"github.com/cryptopunkscc/astrald/mod/relay"
+	"github.com/cryptopunkscc/astrald/mod/revocations"
 	"github.com/cryptopunkscc/astrald/node/events"
 )
 
@@ -16,6 +17,7 @@ type IndexerService struct {
 func (srv *IndexerService) Run(ctx context.Context) error {
 	go srv.watchNewCerts(ctx)
 	go srv.watchPurges(ctx)
+	go srv.watchRevocations(ctx)
 
 	<-ctx.Done()
 
@@ -45,3 +47,12 @@ func (srv *IndexerService) watchPurges(ctx context.Context) {
 		return nil
 	})
 }
+
+func (srv *IndexerService) watchRevocations(ctx context.Context) {
+	_ = events.Handle[revocations.EventRevoked](ctx, srv.node.Events(), func(event revocations.EventRevoked) error {
+		if err := srv.removeRevoked(event.Revocation); err != nil {
+			srv.log.Errorv(1, "error removing revoked cert %v: %v", event.Revocation.ObjectID, err)
+		}
+		return nil
+	})
+}
@@ -12,9 +12,11 @@ import (
 	"github.com/cryptopunkscc/astrald/mod/keys"
 	"github.com/cryptopunkscc/astrald/mod/objects"
 	"github.com/cryptopunkscc/astrald/mod/relay"
+	"github.com/cryptopunkscc/astrald/mod/revocations"
 	"github.com/cryptopunkscc/astrald/net"
 	"github.com/cryptopunkscc/astrald/node"
 	"github.com/cryptopunkscc/astrald/node/assets"
+	"github.com/cryptopunkscc/astrald/node/link"
 	"github.com/cryptopunkscc/astrald/node/router"
 	"github.com/cryptopunkscc/astrald/object"
 	"github.com/cryptopunkscc/astrald/streams"
@@ -37,6 +39,8 @@ type Module struct {
 	objects  objects.Module
 	content  content.Module
 	keys     keys.Module
+
+	revocations revocations.Module
 }
 
 func (mod *Module) Run(ctx context.Context) error {
@@ -46,6 +50,7 @@ func (mod *Module) Run(ctx context.Context) error {
 		&IndexerService{Module: mod},
 		&RelayService{Module: mod},
 		&RerouteService{Module: mod},
+		&ResumeService{Module: mod},
 	).Run(ctx)
 }
 
@@ -100,11 +105,20 @@ func (mod *Module) Reroute(nonce net.Nonce, router net.Router) error {
 	}
 
 	var errCode int
-	cslq.Decode(serviceConn, "c", &errCode)
+	if err := cslq.Decode(serviceConn, "c", &errCode); err != nil {
+		serviceConn.Close()
+		return err
+	}
 	if errCode != 0 {
+		serviceConn.Close()
 		return fmt.Errorf("error code %d", errCode)
 	}
 
+	// link sessions continue over the new conn on their own
+	if session, ok := net.FinalOutput(conn.Target()).(*link.Session); ok {
+		return session.Resume(serviceConn)
+	}
+
 	switcher, err := mod.insertSwitcherAfter(net.RootSource(conn.Caller()))
 	if err != nil {
 		return err
@@ -233,5 +247,8 @@ func (mod *Module) verifyIndex(objectID object.ID) error {
 	if err != nil {
 		mod.log.Errorv(2, "db: delete error: %v", err)
 	}
+
+	mod.objects.Release(mod.node.Identity(), objectID)
+
 	return nil
 }
@@ -9,20 +9,24 @@ import (
 	"github.com/cryptopunkscc/astrald/net"
 	"github.com/cryptopunkscc/astrald/node"
 	"github.com/cryptopunkscc/astrald/node/router"
+	"time"
 )
 
+// redirectTimeout is how long a redirect waits for its query
+const redirectTimeout = time.Minute
+
 // Redirect is a service that redirects a query to a different target
 type Redirect struct {
 	ServiceName string
 	Node        node.Node
 	Allow       id.Identity
 	Query       net.Query
+	cancel      context.CancelFunc
 }
 
 // NewRedirect creates a new redirection service on the node. Only `allow` can route to the service and the request
-// will be translated to `query`.
+// will be translated to `query`. The service is removed after it's used, when it times out or when ctx ends.
 func NewRedirect(ctx context.Context, query net.Query, allow id.Identity, node node.Node) (*Redirect, error) {
-	var err error
 	var r = &Redirect{
 		Node:  node,
 		Allow: allow,
@@ -33,9 +37,17 @@ func NewRedirect(ctx context.Context, query net.Query, allow id.Identity, node n
 	rand.Read(randBytes)
 	r.ServiceName = relay.ServiceName + "." + hex.EncodeToString(randBytes)
 
-	err = node.LocalRouter().AddRoute(r.ServiceName, r)
+	if err := node.LocalRouter().AddRoute(r.ServiceName, r); err != nil {
+		return nil, err
+	}
+
+	ctx, r.cancel = context.WithTimeout(ctx, redirectTimeout)
+	go func() {
+		<-ctx.Done()
+		node.LocalRouter().RemoveRoute(r.ServiceName)
+	}()
 
-	return r, err
+	return r, nil
 }
 
 func (r *Redirect) RouteQuery(ctx context.Context, query net.Query, proxyCaller net.SecureWriteCloser, hints net.Hints) (net.SecureWriteCloser, error) {
@@ -44,7 +56,7 @@ func (r *Redirect) RouteQuery(ctx context.Context, query net.Query, proxyCaller
 		return net.Reject()
 	}
 
-	defer r.Node.LocalRouter().RemoveRoute(r.ServiceName)
+	defer r.cancel()
 
 	finalQuery := r.Query
 
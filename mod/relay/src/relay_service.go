@@ -5,7 +5,6 @@ import (
 	"github.com/cryptopunkscc/astrald/mod/relay"
 	"github.com/cryptopunkscc/astrald/mod/relay/proto"
 	"github.com/cryptopunkscc/astrald/net"
-	"time"
 )
 
 var _ net.Router = &RelayService{}
@@ -39,6 +38,7 @@ func (srv *RelayService) serve(ctx context.Context, conn net.SecureConn) error {
 
 	var err error
 	var callerIM = NewIdentityMachine(conn.RemoteIdentity())
+	callerIM.Revoked = srv.isRevoked
 	var session = proto.New(conn)
 
 	// get query params
@@ -73,10 +73,10 @@ func (srv *RelayService) serve(ctx context.Context, conn net.SecureConn) error {
 	}
 
 	// create a proxy service
-	redirectCtx, _ := context.WithTimeout(ctx, time.Minute)
 	var realQuery = net.NewQueryNonce(callerIM.identity, params.Target, params.Query, net.Nonce(params.Nonce))
 
-	redirect, err := NewRedirect(redirectCtx, realQuery, conn.RemoteIdentity(), srv.node)
+	// the redirect outlives the relay query, so it's bound to the module instead
+	redirect, err := NewRedirect(srv.ctx, realQuery, conn.RemoteIdentity(), srv.node)
 	if err != nil {
 		session.EncodeErr(proto.ErrInternalError)
 		return err
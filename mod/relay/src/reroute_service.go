@@ -6,6 +6,7 @@ import (
 	"github.com/cryptopunkscc/astrald/cslq"
 	"github.com/cryptopunkscc/astrald/mod/relay"
 	"github.com/cryptopunkscc/astrald/net"
+	"github.com/cryptopunkscc/astrald/node/link"
 )
 
 type RerouteService struct {
@@ -43,9 +44,24 @@ func (srv *RerouteService) serve(client net.SecureConn) error {
 
 	conn := srv.findConnByNonce(net.Nonce(nonce))
 	if conn == nil {
+		cslq.Encode(client, "c", 1)
+		client.Close()
 		return errors.New("invalid nonce")
 	}
 
+	// link sessions continue over the new conn on their own
+	if session, ok := net.FinalOutput(conn.Caller()).(*link.Session); ok {
+		if !session.Identity().IsEqual(client.RemoteIdentity()) {
+			cslq.Encode(client, "c", 1)
+			client.Close()
+			return errors.New("identity mismatch")
+		}
+		if err := cslq.Encode(client, "c", 0); err != nil {
+			return err
+		}
+		return session.AcceptResume(client)
+	}
+
 	switcher, err := srv.insertSwitcherAfter(net.RootSource(conn.Target()))
 	if err != nil {
 		return err
@@ -0,0 +1,61 @@
+package relay
+
+import (
+	"context"
+	"github.com/cryptopunkscc/astrald/net"
+	"github.com/cryptopunkscc/astrald/node/link"
+	"github.com/cryptopunkscc/astrald/node/router"
+	"time"
+)
+
+const resumeRetryInterval = time.Second
+
+// ResumeService reroutes conns of link sessions that lost their link, so that the sessions continue over
+// new routes
+type ResumeService struct {
+	*Module
+}
+
+func (srv *ResumeService) Run(ctx context.Context) error {
+	for event := range srv.node.Events().Subscribe(ctx) {
+		switch event := event.(type) {
+		case router.EventConnAdded:
+			// sessions are resumed by the party that sent the query
+			if session, ok := net.FinalOutput(event.Conn.Target()).(*link.Session); ok {
+				go srv.watch(ctx, event.Conn, session)
+			}
+		}
+	}
+	return nil
+}
+
+func (srv *ResumeService) watch(ctx context.Context, conn *router.MonitoredConn, session *link.Session) {
+	var nonce = conn.Query().Nonce()
+
+	for {
+		select {
+		case <-session.Lost():
+		case <-session.Done():
+			return
+		case <-ctx.Done():
+			return
+		}
+
+		for session.Suspended() {
+			err := srv.Reroute(nonce, srv.node.Router())
+			if err == nil {
+				srv.log.Infov(1, "[%v] resumed the session with %v", nonce, session.Identity())
+				break
+			}
+			srv.log.Errorv(2, "[%v] error resuming the session: %v", nonce, err)
+
+			select {
+			case <-session.Done():
+				return
+			case <-ctx.Done():
+				return
+			case <-time.After(resumeRetryInterval):
+			}
+		}
+	}
+}
@@ -90,6 +90,7 @@ func (mod *Module) RouteVia(
 	}
 
 	var targetIM = NewIdentityMachine(relayID)
+	targetIM.Revoked = mod.isRevoked
 
 	// apply target certificate
 	if len(response.Cert) > 0 {
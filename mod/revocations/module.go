@@ -0,0 +1,35 @@
+package revocations
+
+import (
+	"errors"
+	"github.com/cryptopunkscc/astrald/auth/id"
+	"github.com/cryptopunkscc/astrald/object"
+)
+
+const (
+	ModuleName      = "revocations"
+	DBPrefix        = "revocations__"
+	SyncServiceName = "revocations.sync"
+
+	// SetName is the name of the set holding all known revocations
+	SetName = "revocations"
+)
+
+var ErrNotAParty = errors.New("signer is not a party of the revoked object")
+
+type Module interface {
+	// Revoke signs a revocation of the object with the signer's key, stores and publishes it
+	Revoke(signerID id.Identity, objectID object.ID) (*Revocation, error)
+	// Add verifies a revocation, stores and publishes it. The revoked object has to be available locally and
+	// the revocation has to be signed by one of its parties.
+	Add(rev *Revocation) error
+	// Revoked checks if the object was revoked by any of the signers. Returns true if the check fails.
+	Revoked(objectID object.ID, signers ...id.Identity) bool
+	// List returns all known revocations
+	List() ([]*Revocation, error)
+}
+
+// EventRevoked is emitted when a new revocation is added
+type EventRevoked struct {
+	Revocation *Revocation
+}
@@ -0,0 +1,75 @@
+package revocations
+
+import (
+	"crypto/sha256"
+	"errors"
+	"github.com/cryptopunkscc/astrald/auth/id"
+	"github.com/cryptopunkscc/astrald/cslq"
+	"github.com/cryptopunkscc/astrald/object"
+	"time"
+)
+
+// Revocation is a signed statement that an object (a certificate or a contract) should no longer
+// be trusted. Revocations only have effect if they're signed by one of the parties of the object.
+type Revocation struct {
+	ObjectID  object.ID
+	SignerID  id.Identity
+	RevokedAt time.Time
+	Signature []byte
+}
+
+func (*Revocation) ObjectType() string {
+	return "mod.revocations.revocation"
+}
+
+func (rev *Revocation) Hash() []byte {
+	var hash = sha256.New()
+	var err = cslq.Encode(hash,
+		"[c]cvvv",
+		rev.ObjectType(),
+		rev.ObjectID,
+		rev.SignerID,
+		cslq.Time(rev.RevokedAt),
+	)
+	if err != nil {
+		return nil
+	}
+	return hash.Sum(nil)
+}
+
+// Validate checks the signature of the revocation
+func (rev *Revocation) Validate() error {
+	switch {
+	case rev.ObjectID.IsZero():
+		return errors.New("object missing")
+	case rev.SignerID.IsZero():
+		return errors.New("signer identity missing")
+	case rev.Signature == nil:
+		return errors.New("signature missing")
+	case !rev.SignerID.Verify(rev.Hash(), rev.Signature):
+		return errors.New("signature invalid")
+	}
+
+	return nil
+}
+
+func (rev Revocation) MarshalCSLQ(enc *cslq.Encoder) error {
+	return enc.Encodef("vvv[c]c",
+		rev.ObjectID,
+		rev.SignerID,
+		cslq.Time(rev.RevokedAt),
+		rev.Signature,
+	)
+}
+
+func (rev *Revocation) UnmarshalCSLQ(dec *cslq.Decoder) error {
+	var revokedAt cslq.Time
+	err := dec.Decodef("vvv[c]c",
+		&rev.ObjectID,
+		&rev.SignerID,
+		&revokedAt,
+		&rev.Signature,
+	)
+	rev.RevokedAt = revokedAt.Time()
+	return err
+}
@@ -0,0 +1,158 @@
+package revocations
+
+import (
+	"errors"
+	"flag"
+	"github.com/cryptopunkscc/astrald/auth/id"
+	"github.com/cryptopunkscc/astrald/mod/admin"
+	"github.com/cryptopunkscc/astrald/mod/objects"
+	"github.com/cryptopunkscc/astrald/mod/revocations"
+	"github.com/cryptopunkscc/astrald/object"
+	"time"
+)
+
+type Admin struct {
+	mod  *Module
+	cmds map[string]func(admin.Terminal, []string) error
+}
+
+func NewAdmin(mod *Module) *Admin {
+	var adm = &Admin{mod: mod}
+	adm.cmds = map[string]func(admin.Terminal, []string) error{
+		"revoke": adm.revoke,
+		"list":   adm.list,
+		"sync":   adm.sync,
+		"help":   adm.help,
+	}
+
+	return adm
+}
+
+func (adm *Admin) Exec(term admin.Terminal, args []string) error {
+	if len(args) < 2 {
+		return adm.help(term, []string{})
+	}
+
+	cmd, args := args[1], args[2:]
+	if fn, found := adm.cmds[cmd]; found {
+		return fn(term, args)
+	}
+
+	return errors.New("unknown command")
+}
+
+// revoke revokes a relay certificate or a node contract with the key of one of its parties
+func (adm *Admin) revoke(term admin.Terminal, args []string) error {
+	flags := flag.NewFlagSet("revocations revoke", flag.ContinueOnError)
+	flags.SetOutput(term)
+	flags.Usage = func() {
+		term.Printf("Usage:\n\n  revocations revoke [options] <objectID>\n\nOptions:\n")
+		flags.PrintDefaults()
+	}
+	var signer = flags.String("i", "", "sign with this identity (defaults to the first party with a local key)")
+	err := flags.Parse(args)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	args = flags.Args()
+
+	if len(args) < 1 {
+		flags.Usage()
+		return nil
+	}
+
+	objectID, err := object.ParseID(args[0])
+	if err != nil {
+		return err
+	}
+
+	data, err := adm.mod.objects.Get(objectID, objects.DefaultOpenOpts())
+	if err != nil {
+		return err
+	}
+
+	signers, err := parties(data)
+	if err != nil {
+		return err
+	}
+
+	if *signer != "" {
+		signerID, err := adm.mod.node.Resolver().Resolve(*signer)
+		if err != nil {
+			return err
+		}
+
+		var isParty bool
+		for _, p := range signers {
+			isParty = isParty || p.IsEqual(signerID)
+		}
+		if !isParty {
+			return errors.New("signer is not a party of the object")
+		}
+		signers = []id.Identity{signerID}
+	}
+
+	var errs []error
+	for _, signerID := range signers {
+		rev, err := adm.mod.Revoke(signerID, objectID)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		term.Printf("%v revoked by %v\n", rev.ObjectID, rev.SignerID)
+		return nil
+	}
+
+	return errors.Join(errs...)
+}
+
+func (adm *Admin) list(term admin.Terminal, _ []string) error {
+	list, err := adm.mod.List()
+	if err != nil {
+		return err
+	}
+
+	var f = "%-20s %-20s %s\n"
+	term.Printf(f, admin.Header("Revoked at"), admin.Header("Signer"), admin.Header("Object"))
+	for _, rev := range list {
+		term.Printf(f,
+			rev.RevokedAt.Format(time.DateTime),
+			rev.SignerID,
+			rev.ObjectID,
+		)
+	}
+
+	return nil
+}
+
+func (adm *Admin) sync(term admin.Terminal, args []string) error {
+	if len(args) < 1 {
+		adm.mod.syncAll(adm.mod.ctx)
+		return nil
+	}
+
+	peer, err := adm.mod.node.Resolver().Resolve(args[0])
+	if err != nil {
+		return err
+	}
+
+	return adm.mod.syncPeer(adm.mod.ctx, peer)
+}
+
+func (adm *Admin) ShortDescription() string {
+	return "revocations of certificates and contracts"
+}
+
+func (adm *Admin) help(term admin.Terminal, _ []string) error {
+	term.Printf("usage: %s <command>\n\n", revocations.ModuleName)
+	term.Printf("commands:\n")
+	term.Printf("  revoke [-i signer] <objectID>   revoke a relay certificate or a node contract\n")
+	term.Printf("  list                            list known revocations\n")
+	term.Printf("  sync [peer]                     fetch revocations from linked peers now\n")
+	term.Printf("  help                            show help\n")
+	return nil
+}
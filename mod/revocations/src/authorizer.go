@@ -0,0 +1,33 @@
+package revocations
+
+import (
+	"github.com/cryptopunkscc/astrald/auth/id"
+	"github.com/cryptopunkscc/astrald/mod/objects"
+	"github.com/cryptopunkscc/astrald/mod/revocations"
+	"github.com/cryptopunkscc/astrald/node/authorizer"
+	"github.com/cryptopunkscc/astrald/object"
+)
+
+var _ authorizer.Authorizer = &Authorizer{}
+
+// Authorizer makes revocations publicly available, so that they can spread through the network
+type Authorizer struct {
+	mod *Module
+}
+
+func (auth *Authorizer) Authorize(identity id.Identity, action string, args ...any) bool {
+	if action != objects.ActionRead || len(args) == 0 {
+		return false
+	}
+
+	dataID, ok := args[0].(object.ID)
+	if !ok {
+		return false
+	}
+
+	return auth.mod.isIndexed(dataID)
+}
+
+func (auth *Authorizer) String() string {
+	return "mod." + revocations.ModuleName
+}
@@ -0,0 +1,26 @@
+package revocations
+
+import "time"
+
+type Config struct {
+	// SyncInterval sets how often revocations are fetched from linked peers
+	SyncInterval time.Duration `yaml:"sync_interval"`
+}
+
+var defaultConfig = Config{
+	SyncInterval: 10 * time.Minute,
+}
+
+// maxRevocationSize is the size limit of revocation objects fetched from peers
+const maxRevocationSize = 4096
+
+// limits of a single sync with a peer. Updates over the budget are fetched in the next sync.
+const (
+	maxSyncUpdates   = 100_000     // number of updates in a diff
+	syncItemBudget   = 256         // number of revocations fetched
+	syncSizeBudget   = 1024 * 1024 // total size of revocations fetched
+	maxRejectedCache = 100_000
+)
+
+// maxRevokedSize is the size limit of certificates and contracts checked for their parties
+const maxRevokedSize = 64 * 1024
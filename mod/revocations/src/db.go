@@ -0,0 +1,29 @@
+package revocations
+
+import (
+	"github.com/cryptopunkscc/astrald/auth/id"
+	"github.com/cryptopunkscc/astrald/mod/revocations"
+	"github.com/cryptopunkscc/astrald/object"
+	"time"
+)
+
+type dbRevocation struct {
+	DataID    object.ID   `gorm:"primaryKey"`
+	ObjectID  object.ID   `gorm:"index"` // the revoked object
+	SignerID  id.Identity `gorm:"index"`
+	RevokedAt time.Time
+	Signature []byte
+}
+
+func (dbRevocation) TableName() string {
+	return revocations.DBPrefix + "revocations"
+}
+
+func (row *dbRevocation) toRevocation() *revocations.Revocation {
+	return &revocations.Revocation{
+		ObjectID:  row.ObjectID,
+		SignerID:  row.SignerID,
+		RevokedAt: row.RevokedAt,
+		Signature: row.Signature,
+	}
+}
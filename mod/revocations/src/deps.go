@@ -0,0 +1,41 @@
+package revocations
+
+import (
+	"github.com/cryptopunkscc/astrald/cslq"
+	"github.com/cryptopunkscc/astrald/mod/admin"
+	"github.com/cryptopunkscc/astrald/mod/keys"
+	"github.com/cryptopunkscc/astrald/mod/objects"
+	"github.com/cryptopunkscc/astrald/mod/revocations"
+	"github.com/cryptopunkscc/astrald/mod/sets"
+	"github.com/cryptopunkscc/astrald/node/modules"
+)
+
+func (mod *Module) LoadDependencies() error {
+	var err error
+
+	// load required dependencies
+	mod.objects, err = modules.Load[objects.Module](mod.node, objects.ModuleName)
+	if err != nil {
+		return err
+	}
+
+	mod.sets, err = modules.Load[sets.Module](mod.node, sets.ModuleName)
+	if err != nil {
+		return err
+	}
+
+	// load optional dependencies
+	mod.keys, _ = modules.Load[keys.Module](mod.node, keys.ModuleName)
+
+	mod.objects.SetDecoder((&revocations.Revocation{}).ObjectType(), func(bytes []byte) (objects.Object, error) {
+		var rev revocations.Revocation
+		return &rev, cslq.Unmarshal(bytes, &rev)
+	})
+
+	// inject admin command
+	if adm, err := modules.Load[admin.Module](mod.node, admin.ModuleName); err == nil {
+		adm.AddCommand(revocations.ModuleName, NewAdmin(mod))
+	}
+
+	return nil
+}
@@ -0,0 +1,48 @@
+package revocations
+
+import (
+	"github.com/cryptopunkscc/astrald/log"
+	"github.com/cryptopunkscc/astrald/mod/revocations"
+	"github.com/cryptopunkscc/astrald/node/assets"
+	"github.com/cryptopunkscc/astrald/node/modules"
+	"github.com/cryptopunkscc/astrald/object"
+	"time"
+)
+
+type Loader struct{}
+
+func (Loader) Load(node modules.Node, assets assets.Assets, log *log.Logger) (modules.Module, error) {
+	var mod = &Module{
+		node:     node,
+		config:   defaultConfig,
+		log:      log,
+		lastSync: map[string]time.Time{},
+		rejected: map[object.ID]struct{}{},
+	}
+
+	_ = assets.LoadYAML(revocations.ModuleName, &mod.config)
+
+	if mod.config.SyncInterval <= 0 {
+		mod.config.SyncInterval = defaultConfig.SyncInterval
+	}
+
+	mod.db = assets.Database()
+
+	err := mod.db.AutoMigrate(&dbRevocation{})
+	if err != nil {
+		return nil, err
+	}
+
+	err = node.Auth().Add(&Authorizer{mod: mod})
+	if err != nil {
+		return nil, err
+	}
+
+	return mod, nil
+}
+
+func init() {
+	if err := modules.RegisterModule(revocations.ModuleName, Loader{}); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,189 @@
+package revocations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/cryptopunkscc/astrald/auth/id"
+	"github.com/cryptopunkscc/astrald/log"
+	"github.com/cryptopunkscc/astrald/mod/keys"
+	"github.com/cryptopunkscc/astrald/mod/objects"
+	"github.com/cryptopunkscc/astrald/mod/revocations"
+	"github.com/cryptopunkscc/astrald/mod/sets"
+	"github.com/cryptopunkscc/astrald/net"
+	"github.com/cryptopunkscc/astrald/node"
+	"github.com/cryptopunkscc/astrald/object"
+	"github.com/cryptopunkscc/astrald/tasks"
+	"gorm.io/gorm"
+	"sync"
+	"time"
+)
+
+var _ revocations.Module = &Module{}
+
+type Module struct {
+	config  Config
+	node    node.Node
+	log     *log.Logger
+	db      *gorm.DB
+	objects objects.Module
+	sets    sets.Module
+	keys    keys.Module
+	set     sets.Set
+	ctx     context.Context
+
+	mu       sync.Mutex
+	lastSync map[string]time.Time   // time of the last complete sync with each peer
+	rejected map[object.ID]struct{} // revocations that failed to sync
+}
+
+func (mod *Module) Run(ctx context.Context) error {
+	var err error
+
+	mod.ctx = ctx
+
+	mod.set, err = mod.sets.Open(revocations.SetName, true)
+	if err != nil {
+		return err
+	}
+
+	return tasks.Group(
+		&SyncService{Module: mod},
+		&Syncer{Module: mod},
+	).Run(ctx)
+}
+
+func (mod *Module) Revoke(signerID id.Identity, objectID object.ID) (*revocations.Revocation, error) {
+	if mod.keys == nil {
+		return nil, errors.New("keys module unavailable")
+	}
+
+	var rev = &revocations.Revocation{
+		ObjectID:  objectID,
+		SignerID:  signerID,
+		RevokedAt: time.Now(),
+	}
+
+	var err error
+	var desc = fmt.Sprintf("revocation of %v", objectID)
+
+	rev.Signature, err = mod.keys.SignWithDescription(signerID, rev.Hash(), desc)
+	if err != nil {
+		return nil, err
+	}
+
+	return rev, mod.Add(rev)
+}
+
+func (mod *Module) Add(rev *revocations.Revocation) error {
+	if err := rev.Validate(); err != nil {
+		return err
+	}
+
+	if err := mod.checkParty(rev); err != nil {
+		return err
+	}
+
+	dataID, err := mod.objects.Store(context.Background(), rev)
+	if err != nil {
+		return err
+	}
+
+	if mod.isIndexed(dataID) {
+		return nil
+	}
+
+	err = mod.db.Create(&dbRevocation{
+		DataID:    dataID,
+		ObjectID:  rev.ObjectID,
+		SignerID:  rev.SignerID,
+		RevokedAt: rev.RevokedAt,
+		Signature: rev.Signature,
+	}).Error
+	if err != nil {
+		return err
+	}
+
+	if mod.set != nil {
+		if err = mod.set.Add(dataID); err != nil {
+			mod.log.Errorv(1, "error adding %v to set: %v", dataID, err)
+		}
+	}
+
+	mod.log.Info("%v revoked by %v", rev.ObjectID, rev.SignerID)
+
+	mod.node.Events().Emit(revocations.EventRevoked{Revocation: rev})
+
+	return nil
+}
+
+func (mod *Module) Revoked(objectID object.ID, signers ...id.Identity) bool {
+	var signerIDs []id.Identity
+	err := mod.db.
+		Model(&dbRevocation{}).
+		Where("object_id = ?", objectID).
+		Select("signer_id").
+		Find(&signerIDs).Error
+	if err != nil {
+		// treat the object as revoked if we can't tell
+		mod.log.Error("db error: %v", err)
+		return true
+	}
+
+	for _, signerID := range signerIDs {
+		for _, s := range signers {
+			if s.IsEqual(signerID) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// checkParty checks if the revocation is signed by a party of the revoked object. Revocations of objects
+// that aren't available locally are refused, so that peers cannot fill the index with arbitrary revocations.
+func (mod *Module) checkParty(rev *revocations.Revocation) error {
+	if rev.ObjectID.Size > maxRevokedSize {
+		return objects.ErrObjectTooLarge
+	}
+
+	data, err := mod.objects.Get(rev.ObjectID, &objects.OpenOpts{Zone: net.ZoneDevice | net.ZoneVirtual})
+	if err != nil {
+		return err
+	}
+
+	list, err := parties(data)
+	if err != nil {
+		return err
+	}
+
+	for _, party := range list {
+		if party.IsEqual(rev.SignerID) {
+			return nil
+		}
+	}
+
+	return revocations.ErrNotAParty
+}
+
+func (mod *Module) List() ([]*revocations.Revocation, error) {
+	var rows []*dbRevocation
+	err := mod.db.Order("revoked_at").Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var list = make([]*revocations.Revocation, 0, len(rows))
+	for _, row := range rows {
+		list = append(list, row.toRevocation())
+	}
+
+	return list, nil
+}
+
+func (mod *Module) isIndexed(dataID object.ID) bool {
+	var count int64
+	mod.db.Model(&dbRevocation{}).Where("data_id = ?", dataID).Count(&count)
+	return count > 0
+}
@@ -0,0 +1,39 @@
+package revocations
+
+import (
+	"bytes"
+	"errors"
+	"github.com/cryptopunkscc/astrald/auth/id"
+	"github.com/cryptopunkscc/astrald/cslq"
+	"github.com/cryptopunkscc/astrald/lib/adc"
+	"github.com/cryptopunkscc/astrald/mod/relay"
+	"github.com/cryptopunkscc/astrald/mod/user"
+)
+
+// parties returns identities that can revoke the encoded certificate or contract
+func parties(data []byte) ([]id.Identity, error) {
+	var r = bytes.NewReader(data)
+
+	var dataType adc.Header
+	if err := cslq.Decode(r, "v", &dataType); err != nil {
+		return nil, err
+	}
+
+	switch dataType.String() {
+	case relay.CertType:
+		var cert relay.Cert
+		if err := cslq.Decode(r, "v", &cert); err != nil {
+			return nil, err
+		}
+		return []id.Identity{cert.TargetID, cert.RelayID}, nil
+
+	case (&user.NodeContract{}).ObjectType():
+		var contract user.NodeContract
+		if err := cslq.Decode(r, "v", &contract); err != nil {
+			return nil, err
+		}
+		return []id.Identity{contract.UserID, contract.NodeID}, nil
+	}
+
+	return nil, errors.New("object is not a certificate or a contract")
+}
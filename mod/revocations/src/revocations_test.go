@@ -0,0 +1,185 @@
+package revocations
+
+import (
+	"bytes"
+	"errors"
+	"github.com/cryptopunkscc/astrald/auth/id"
+	"github.com/cryptopunkscc/astrald/cslq"
+	"github.com/cryptopunkscc/astrald/lib/adc"
+	"github.com/cryptopunkscc/astrald/log"
+	"github.com/cryptopunkscc/astrald/mod/objects"
+	"github.com/cryptopunkscc/astrald/mod/revocations"
+	"github.com/cryptopunkscc/astrald/mod/user"
+	"github.com/cryptopunkscc/astrald/object"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestRevocation(t *testing.T) {
+	signerID, err := id.GenerateIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var rev = &revocations.Revocation{
+		ObjectID:  object.ID{Size: 1, Hash: [32]byte{1}},
+		SignerID:  signerID,
+		RevokedAt: time.Now(),
+	}
+	if rev.Validate() == nil {
+		t.Fatal("unsigned revocation is valid")
+	}
+
+	rev.Signature, err = signerID.Sign(rev.Hash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = rev.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf = &bytes.Buffer{}
+	if err = cslq.Encode(buf, "v", rev); err != nil {
+		t.Fatal(err)
+	}
+	var decoded revocations.Revocation
+	if err = cslq.Decode(buf, "v", &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if err = decoded.Validate(); err != nil {
+		t.Fatalf("decoded revocation invalid: %v", err)
+	}
+
+	decoded.ObjectID = object.ID{Size: 1, Hash: [32]byte{2}}
+	if decoded.Validate() == nil {
+		t.Fatal("revocation of a different object is valid")
+	}
+}
+
+func TestParties(t *testing.T) {
+	var userID, nodeID id.Identity
+	for _, i := range []*id.Identity{&userID, &nodeID} {
+		var err error
+		if *i, err = id.GenerateIdentity(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var contract = &user.NodeContract{
+		UserID:    userID,
+		NodeID:    nodeID,
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+	var buf = &bytes.Buffer{}
+	err := cslq.Encode(buf, "vv", adc.Header(contract.ObjectType()), contract)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := parties(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 || !list[0].IsEqual(userID) || !list[1].IsEqual(nodeID) {
+		t.Fatal("unexpected parties")
+	}
+
+	if _, err = parties([]byte("not an object")); err == nil {
+		t.Fatal("parties of invalid data")
+	}
+}
+
+func TestRevoked(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = db.AutoMigrate(&dbRevocation{}); err != nil {
+		t.Fatal(err)
+	}
+
+	var alice, bob id.Identity
+	for _, i := range []*id.Identity{&alice, &bob} {
+		if *i, err = id.GenerateIdentity(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var mod = &Module{db: db}
+	var objectID = object.ID{Size: 1, Hash: [32]byte{1}}
+
+	err = db.Create(&dbRevocation{
+		DataID:    object.ID{Size: 1, Hash: [32]byte{2}},
+		ObjectID:  objectID,
+		SignerID:  alice,
+		RevokedAt: time.Now(),
+	}).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !mod.Revoked(objectID, bob, alice) {
+		t.Fatal("object revoked by a party not reported")
+	}
+	if mod.Revoked(objectID, bob) {
+		t.Fatal("revocation by a non-party accepted")
+	}
+	if mod.Revoked(object.ID{Size: 1, Hash: [32]byte{3}}, alice) {
+		t.Fatal("unrelated object reported as revoked")
+	}
+
+	// fail closed when the index is unavailable
+	mod.log = log.NewLogger(log.NewLinePrinter(log.NewMonoOutput(io.Discard)))
+	if err = db.Migrator().DropTable(&dbRevocation{}); err != nil {
+		t.Fatal(err)
+	}
+	if !mod.Revoked(objectID, bob) {
+		t.Fatal("revocation check passed without the index")
+	}
+}
+
+// testObjects serves objects from memory
+type testObjects struct {
+	objects.Module
+	data map[object.ID][]byte
+}
+
+func (o *testObjects) Get(objectID object.ID, _ *objects.OpenOpts) ([]byte, error) {
+	data, found := o.data[objectID]
+	if !found {
+		return nil, objects.ErrNotFound
+	}
+	return data, nil
+}
+
+func TestCheckParty(t *testing.T) {
+	var userID, nodeID, otherID id.Identity
+	for _, i := range []*id.Identity{&userID, &nodeID, &otherID} {
+		var err error
+		if *i, err = id.GenerateIdentity(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var contract = &user.NodeContract{UserID: userID, NodeID: nodeID, ExpiresAt: time.Now().Add(time.Hour)}
+	var buf = &bytes.Buffer{}
+	if err := cslq.Encode(buf, "vv", adc.Header(contract.ObjectType()), contract); err != nil {
+		t.Fatal(err)
+	}
+	var contractID = object.Resolve(buf.Bytes())
+
+	var mod = &Module{objects: &testObjects{data: map[object.ID][]byte{contractID: buf.Bytes()}}}
+
+	if err := mod.checkParty(&revocations.Revocation{ObjectID: contractID, SignerID: nodeID}); err != nil {
+		t.Fatal(err)
+	}
+	if err := mod.checkParty(&revocations.Revocation{ObjectID: contractID, SignerID: otherID}); !errors.Is(err, revocations.ErrNotAParty) {
+		t.Fatalf("expected ErrNotAParty, got %v", err)
+	}
+	if err := mod.checkParty(&revocations.Revocation{ObjectID: object.ID{Size: 1, Hash: [32]byte{1}}, SignerID: nodeID}); err == nil {
+		t.Fatal("accepted a revocation of an unknown object")
+	}
+}
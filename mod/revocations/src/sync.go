@@ -0,0 +1,196 @@
+package revocations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/cryptopunkscc/astrald/auth/id"
+	"github.com/cryptopunkscc/astrald/lib/arl"
+	"github.com/cryptopunkscc/astrald/mod/objects"
+	"github.com/cryptopunkscc/astrald/mod/revocations"
+	"github.com/cryptopunkscc/astrald/mod/sets/sync"
+	"github.com/cryptopunkscc/astrald/net"
+	"github.com/cryptopunkscc/astrald/node/events"
+	"github.com/cryptopunkscc/astrald/node/network"
+	"github.com/cryptopunkscc/astrald/object"
+	"io"
+	"time"
+)
+
+// SyncService serves the set of known revocations to anyone
+type SyncService struct {
+	*Module
+}
+
+func (srv *SyncService) Run(ctx context.Context) error {
+	err := srv.node.LocalRouter().AddRoute(revocations.SyncServiceName, srv)
+	if err != nil {
+		return err
+	}
+	defer srv.node.LocalRouter().RemoveRoute(revocations.SyncServiceName)
+
+	<-ctx.Done()
+
+	return nil
+}
+
+func (srv *SyncService) RouteQuery(ctx context.Context, query net.Query, caller net.SecureWriteCloser, hints net.Hints) (net.SecureWriteCloser, error) {
+	return sync.NewProvider(srv.set).RouteQuery(ctx, query, caller, hints)
+}
+
+// Syncer fetches revocations from linked peers when they link and periodically afterwards
+type Syncer struct {
+	*Module
+}
+
+func (srv *Syncer) Run(ctx context.Context) error {
+	go events.Handle(ctx, srv.node.Events(), func(e network.EventLinkAdded) error {
+		go srv.syncPeer(ctx, e.Link.RemoteIdentity())
+		return nil
+	})
+
+	var ticker = time.NewTicker(srv.config.SyncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			srv.syncAll(ctx)
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+func (mod *Module) syncAll(ctx context.Context) {
+	var peers = map[string]id.Identity{}
+	for _, l := range mod.node.Network().Links().All() {
+		peers[l.RemoteIdentity().PublicKeyHex()] = l.RemoteIdentity()
+	}
+
+	for _, peer := range peers {
+		mod.syncPeer(ctx, peer)
+	}
+}
+
+// syncPeer fetches revocations added by the peer since the last sync
+func (mod *Module) syncPeer(ctx context.Context, peer id.Identity) error {
+	var key = peer.PublicKeyHex()
+
+	mod.mu.Lock()
+	var since = mod.lastSync[key]
+	mod.mu.Unlock()
+
+	var consumer = sync.NewConsumer(
+		arl.New(mod.node.Identity(), peer, revocations.SyncServiceName),
+		mod.node.Router(),
+	)
+	consumer.MaxUpdates = maxSyncUpdates
+
+	diff, err := consumer.Sync(ctx, since)
+	if errors.Is(err, sync.ErrResyncRequired) {
+		diff, err = consumer.Sync(ctx, time.Time{})
+	}
+	if err != nil {
+		mod.log.Errorv(2, "sync with %v: %v", peer, err)
+		return err
+	}
+
+	var added, fetched int
+	var size uint64
+	var complete = true
+	for _, update := range diff.Updates {
+		if !update.Present || mod.isIndexed(update.ObjectID) || mod.isRejected(update.ObjectID) {
+			continue
+		}
+
+		// leave the rest for the next sync
+		if fetched >= syncItemBudget || size+update.ObjectID.Size > syncSizeBudget {
+			complete = false
+			break
+		}
+		fetched++
+		size += update.ObjectID.Size
+
+		rev, err := mod.fetch(ctx, peer, update.ObjectID)
+		if err == nil {
+			err = mod.Add(rev)
+		}
+		if err != nil {
+			mod.log.Errorv(2, "sync %v from %v: %v", update.ObjectID, peer, err)
+			mod.reject(update.ObjectID)
+			continue
+		}
+		added++
+	}
+
+	if complete {
+		mod.mu.Lock()
+		mod.lastSync[key] = diff.Time
+		mod.mu.Unlock()
+	}
+
+	if added > 0 {
+		mod.log.Infov(1, "synced %v revocations from %v", added, peer)
+	}
+
+	return nil
+}
+
+// reject remembers a revocation that failed to sync, so that it doesn't use up the budget of later syncs
+func (mod *Module) reject(dataID object.ID) {
+	mod.mu.Lock()
+	defer mod.mu.Unlock()
+
+	if len(mod.rejected) >= maxRejectedCache {
+		clear(mod.rejected)
+	}
+	mod.rejected[dataID] = struct{}{}
+}
+
+func (mod *Module) isRejected(dataID object.ID) bool {
+	mod.mu.Lock()
+	defer mod.mu.Unlock()
+
+	_, found := mod.rejected[dataID]
+	return found
+}
+
+// fetch reads a revocation object directly from the peer
+func (mod *Module) fetch(ctx context.Context, peer id.Identity, objectID object.ID) (*revocations.Revocation, error) {
+	if objectID.Size > maxRevocationSize {
+		return nil, objects.ErrObjectTooLarge
+	}
+
+	consumer, err := mod.objects.Connect(mod.node.Identity(), peer)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := consumer.Open(ctx, objectID, objects.DefaultOpenOpts())
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	data, err := io.ReadAll(io.LimitReader(r, maxRevocationSize))
+	if err != nil {
+		return nil, err
+	}
+
+	if !object.Resolve(data).IsEqual(objectID) {
+		return nil, errors.New("object mismatch")
+	}
+
+	obj, err := mod.objects.Decode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	rev, ok := obj.(*revocations.Revocation)
+	if !ok {
+		return nil, fmt.Errorf("not a revocation: %s", obj.ObjectType())
+	}
+
+	return rev, nil
+}
@@ -13,6 +13,9 @@ import (
 type Consumer struct {
 	router net.Router
 	arl    *arl.ARL
+
+	// MaxUpdates limits the number of updates read in a single sync. Zero means no limit.
+	MaxUpdates int
 }
 
 func NewConsumer(arl *arl.ARL, router net.Router) *Consumer {
@@ -52,19 +55,11 @@ func (c *Consumer) Sync(ctx context.Context, since time.Time) (diff Diff, err er
 			diff.Time = time.Unix(0, timestamp)
 			return
 
-		case opAdd: // add
-			var objectID object.ID
-			err = cslq.Decode(conn, "v", &objectID)
-			if err != nil {
-				return
+		case opAdd, opRemove:
+			if c.MaxUpdates > 0 && len(diff.Updates) >= c.MaxUpdates {
+				return diff, ErrTooManyUpdates
 			}
 
-			diff.Updates = append(diff.Updates, Update{
-				ObjectID: objectID,
-				Present:  true,
-			})
-
-		case opRemove: // remove
 			var objectID object.ID
 			err = cslq.Decode(conn, "v", &objectID)
 			if err != nil {
@@ -73,7 +68,7 @@ func (c *Consumer) Sync(ctx context.Context, since time.Time) (diff Diff, err er
 
 			diff.Updates = append(diff.Updates, Update{
 				ObjectID: objectID,
-				Present:  false,
+				Present:  op == opAdd,
 			})
 
 		case opResync:
@@ -25,3 +25,4 @@ type Update struct {
 
 var ErrResyncRequired = errors.New("resync required")
 var ErrProtocolError = errors.New("protocol error")
+var ErrTooManyUpdates = errors.New("too many updates")
@@ -102,7 +102,12 @@ func (srv *InviteService) Invite(ctx context.Context, userID id.Identity, nodeID
 		ExpiresAt: time.Now().Add(relay.DefaultCertDuration),
 	}
 
-	cert.TargetSig, err = srv.keys.Sign(cert.TargetID, cert.Hash())
+	var desc = fmt.Sprintf("invitation letting %s join as a node of %s",
+		srv.node.Resolver().DisplayName(nodeID),
+		srv.node.Resolver().DisplayName(userID),
+	)
+
+	cert.TargetSig, err = srv.keys.SignWithDescription(cert.TargetID, cert.Hash(), desc)
 	if err != nil {
 		return fmt.Errorf("error signing invite certificate: %w", err)
 	}
@@ -70,7 +70,7 @@ func (d *SetupDialogue) createNewUser() error {
 	}
 
 	d.Say("Creating a new identity...")
-	identity, _, err := d.keys.CreateKey(alias)
+	identity, _, err := d.keys.DeriveKey(alias, "users/"+alias)
 	if err != nil {
 		return err
 	}
@@ -45,7 +45,7 @@ func (srv *Server) Run(ctx context.Context) error {
 		var conn = wrapTCPConn(rawConn, false)
 
 		go func() {
-			l, err := link.Accept(ctx, conn, srv.node.Identity())
+			l, err := link.Accept(ctx, conn, srv.node.Identity(), srv.node.Network().LinkConfig())
 			if err != nil {
 				srv.log.Errorv(1, "handshake failed from %v: %v", conn.RemoteEndpoint(), err)
 				return
@@ -0,0 +1,24 @@
+package tokens
+
+import (
+	"github.com/cryptopunkscc/astrald/auth/id"
+	"time"
+)
+
+const (
+	ModuleName = "tokens"
+
+	// QueryParam is the name of the query parameter carrying a token
+	QueryParam = "token"
+)
+
+type Module interface {
+	// Issue creates a root token signed by the issuer
+	Issue(issuerID id.Identity, audienceID id.Identity, actions []string, args []string, duration time.Duration) (*Token, error)
+	// Delegate creates a token that passes a subset of the parent's rights to a new audience
+	Delegate(parent *Token, audienceID id.Identity, actions []string, args []string, duration time.Duration) (*Token, error)
+	// Add verifies the token and grants its rights to its audience until it expires
+	Add(token *Token) error
+	// Tokens returns all tokens granting rights to the audience
+	Tokens(audienceID id.Identity) []*Token
+}
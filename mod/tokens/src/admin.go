@@ -0,0 +1,229 @@
+package tokens
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/cryptopunkscc/astrald/mod/admin"
+	"github.com/cryptopunkscc/astrald/mod/tokens"
+	"strings"
+	"time"
+)
+
+const defaultDuration = 24 * time.Hour
+
+type Admin struct {
+	mod  *Module
+	cmds map[string]func(admin.Terminal, []string) error
+}
+
+func NewAdmin(mod *Module) *Admin {
+	var adm = &Admin{mod: mod}
+	adm.cmds = map[string]func(admin.Terminal, []string) error{
+		"issue":    adm.issue,
+		"delegate": adm.delegate,
+		"add":      adm.add,
+		"list":     adm.list,
+		"show":     adm.show,
+		"help":     adm.help,
+	}
+
+	return adm
+}
+
+func (adm *Admin) Exec(term admin.Terminal, args []string) error {
+	if len(args) < 2 {
+		return adm.help(term, []string{})
+	}
+
+	cmd, args := args[1], args[2:]
+	if fn, found := adm.cmds[cmd]; found {
+		return fn(term, args)
+	}
+
+	return errors.New("unknown command")
+}
+
+func (adm *Admin) issue(term admin.Terminal, args []string) error {
+	flags := flag.NewFlagSet("tokens issue", flag.ContinueOnError)
+	flags.SetOutput(term)
+	flags.Usage = func() {
+		term.Printf("Usage:\n\n  tokens issue [options] <audience> <action>[,<action>...]\n\nOptions:\n")
+		flags.PrintDefaults()
+	}
+	var issuer = flags.String("i", "localnode", "issuer of the token")
+	var duration = flags.Duration("d", defaultDuration, "validity of the token")
+	var tokenArgs = flags.String("a", "", "comma-separated list of allowed arguments (e.g. object IDs)")
+	err := flags.Parse(args)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	args = flags.Args()
+
+	if len(args) < 2 {
+		flags.Usage()
+		return nil
+	}
+
+	issuerID, err := adm.mod.node.Resolver().Resolve(*issuer)
+	if err != nil {
+		return err
+	}
+
+	audienceID, err := adm.mod.node.Resolver().Resolve(args[0])
+	if err != nil {
+		return err
+	}
+
+	token, err := adm.mod.Issue(issuerID, audienceID, split(args[1]), split(*tokenArgs), *duration)
+	if err != nil {
+		return err
+	}
+
+	term.Printf("%s\n", token)
+
+	return nil
+}
+
+func (adm *Admin) delegate(term admin.Terminal, args []string) error {
+	flags := flag.NewFlagSet("tokens delegate", flag.ContinueOnError)
+	flags.SetOutput(term)
+	flags.Usage = func() {
+		term.Printf("Usage:\n\n  tokens delegate [options] <token> <audience> [<action>[,<action>...]]\n\nOptions:\n")
+		flags.PrintDefaults()
+	}
+	var duration = flags.Duration("d", 0, "validity of the token (defaults to the validity of the parent)")
+	var tokenArgs = flags.String("a", "", "comma-separated list of allowed arguments (defaults to the parent's)")
+	err := flags.Parse(args)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	args = flags.Args()
+
+	if len(args) < 2 {
+		flags.Usage()
+		return nil
+	}
+
+	parent, err := tokens.Parse(args[0])
+	if err != nil {
+		return err
+	}
+
+	audienceID, err := adm.mod.node.Resolver().Resolve(args[1])
+	if err != nil {
+		return err
+	}
+
+	var actions []string
+	if len(args) > 2 {
+		actions = split(args[2])
+	}
+
+	token, err := adm.mod.Delegate(parent, audienceID, actions, split(*tokenArgs), *duration)
+	if err != nil {
+		return err
+	}
+
+	term.Printf("%s\n", token)
+
+	return nil
+}
+
+func (adm *Admin) add(term admin.Terminal, args []string) error {
+	if len(args) < 1 {
+		return errors.New("usage: tokens add <token>")
+	}
+
+	token, err := tokens.Parse(args[0])
+	if err != nil {
+		return err
+	}
+
+	return adm.mod.Add(token)
+}
+
+func (adm *Admin) list(term admin.Terminal, _ []string) error {
+	adm.mod.prune()
+
+	adm.mod.mu.Lock()
+	var list []*tokens.Token
+	for _, l := range adm.mod.grants {
+		list = append(list, l...)
+	}
+	adm.mod.mu.Unlock()
+
+	var f = "%-20s %-20s %-30s %-20s %s\n"
+	term.Printf(f, admin.Header("Audience"), admin.Header("Root issuer"), admin.Header("Actions"), admin.Header("Args"), admin.Header("Expires"))
+	for _, token := range list {
+		term.Printf(f,
+			token.AudienceID,
+			token.Root().IssuerID,
+			strings.Join(token.Actions, ","),
+			strings.Join(token.Args, ","),
+			time.Until(token.ExpiresAt).Round(time.Second),
+		)
+	}
+
+	return nil
+}
+
+func (adm *Admin) show(term admin.Terminal, args []string) error {
+	if len(args) < 1 {
+		return errors.New("usage: tokens show <token>")
+	}
+
+	token, err := tokens.Parse(args[0])
+	if err != nil {
+		return err
+	}
+
+	for i, t := 0, token; t != nil; i, t = i+1, t.Parent {
+		term.Printf("%s\n", admin.Keyword(fmt.Sprintf("#%d", i)))
+		term.Printf("  Issuer:   %v\n", t.IssuerID)
+		term.Printf("  Audience: %v\n", t.AudienceID)
+		term.Printf("  Actions:  %s\n", strings.Join(t.Actions, ", "))
+		term.Printf("  Args:     %s\n", strings.Join(t.Args, ", "))
+		term.Printf("  Expires:  %v\n", t.ExpiresAt.Format(time.RFC3339))
+	}
+
+	if err := token.Validate(); err != nil {
+		term.Printf("invalid: %v\n", err)
+	} else if !adm.mod.isTrusted(token.Root().IssuerID) {
+		term.Printf("valid, but the root issuer is not trusted by this node\n")
+	} else {
+		term.Printf("valid\n")
+	}
+
+	return nil
+}
+
+func (adm *Admin) ShortDescription() string {
+	return "capability tokens"
+}
+
+func (adm *Admin) help(term admin.Terminal, _ []string) error {
+	term.Printf("usage: %s <command>\n\n", tokens.ModuleName)
+	term.Printf("commands:\n")
+	term.Printf("  issue [options] <audience> <actions>              issue a token\n")
+	term.Printf("  delegate [options] <token> <audience> [actions]   delegate a token to another identity\n")
+	term.Printf("  add <token>                                       accept a token\n")
+	term.Printf("  list                                              list accepted tokens\n")
+	term.Printf("  show <token>                                      show the delegation chain of a token\n")
+	term.Printf("  help                                              show help\n")
+	return nil
+}
+
+// split splits a comma-separated list
+func split(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, ",")
+}
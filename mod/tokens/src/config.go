@@ -0,0 +1,9 @@
+package tokens
+
+type Config struct {
+	// Issuers lists identities (by name or public key) trusted to issue root tokens in addition
+	// to the node and its user
+	Issuers []string `yaml:"issuers"`
+}
+
+var defaultConfig = Config{}
@@ -0,0 +1,38 @@
+package tokens
+
+import (
+	"github.com/cryptopunkscc/astrald/cslq"
+	"github.com/cryptopunkscc/astrald/mod/admin"
+	"github.com/cryptopunkscc/astrald/mod/keys"
+	"github.com/cryptopunkscc/astrald/mod/objects"
+	"github.com/cryptopunkscc/astrald/mod/tokens"
+	"github.com/cryptopunkscc/astrald/mod/user"
+	"github.com/cryptopunkscc/astrald/node/modules"
+)
+
+func (mod *Module) LoadDependencies() error {
+	var err error
+
+	// load required dependencies
+	mod.keys, err = modules.Load[keys.Module](mod.node, keys.ModuleName)
+	if err != nil {
+		return err
+	}
+
+	// load optional dependencies
+	mod.user, _ = modules.Load[user.Module](mod.node, user.ModuleName)
+
+	if objs, err := modules.Load[objects.Module](mod.node, objects.ModuleName); err == nil {
+		objs.SetDecoder((&tokens.Token{}).ObjectType(), func(bytes []byte) (objects.Object, error) {
+			var token tokens.Token
+			return &token, cslq.Unmarshal(bytes, &token)
+		})
+	}
+
+	// inject admin command
+	if adm, err := modules.Load[admin.Module](mod.node, admin.ModuleName); err == nil {
+		adm.AddCommand(tokens.ModuleName, NewAdmin(mod))
+	}
+
+	return nil
+}
@@ -0,0 +1,39 @@
+package tokens
+
+import (
+	"github.com/cryptopunkscc/astrald/log"
+	"github.com/cryptopunkscc/astrald/mod/tokens"
+	"github.com/cryptopunkscc/astrald/node/assets"
+	"github.com/cryptopunkscc/astrald/node/modules"
+)
+
+type Loader struct{}
+
+func (Loader) Load(node modules.Node, assets assets.Assets, log *log.Logger) (modules.Module, error) {
+	var mod = &Module{
+		node:   node,
+		config: defaultConfig,
+		log:    log,
+		grants: map[string][]*tokens.Token{},
+	}
+
+	_ = assets.LoadYAML(tokens.ModuleName, &mod.config)
+
+	err := node.Router().AddGuard(mod)
+	if err != nil {
+		return nil, err
+	}
+
+	err = node.Auth().Add(mod)
+	if err != nil {
+		return nil, err
+	}
+
+	return mod, nil
+}
+
+func init() {
+	if err := modules.RegisterModule(tokens.ModuleName, Loader{}); err != nil {
+		panic(err)
+	}
+}
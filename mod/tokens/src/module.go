@@ -0,0 +1,229 @@
+package tokens
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/cryptopunkscc/astrald/auth/id"
+	"github.com/cryptopunkscc/astrald/log"
+	"github.com/cryptopunkscc/astrald/mod/keys"
+	"github.com/cryptopunkscc/astrald/mod/tokens"
+	"github.com/cryptopunkscc/astrald/mod/user"
+	"github.com/cryptopunkscc/astrald/net"
+	"github.com/cryptopunkscc/astrald/node"
+	"github.com/cryptopunkscc/astrald/node/authorizer"
+	"github.com/cryptopunkscc/astrald/node/router"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+var _ tokens.Module = &Module{}
+var _ authorizer.Authorizer = &Module{}
+var _ router.Guard = &Module{}
+
+// pruneInterval sets how often expired tokens are removed
+const pruneInterval = time.Minute
+
+// maxAudienceTokens is the maximum number of tokens kept for a single audience
+const maxAudienceTokens = 64
+
+type Module struct {
+	config Config
+	node   node.Node
+	log    *log.Logger
+	keys   keys.Module
+	user   user.Module
+
+	mu     sync.Mutex
+	grants map[string][]*tokens.Token // valid tokens by audience
+}
+
+func (mod *Module) Run(ctx context.Context) error {
+	var ticker = time.NewTicker(pruneInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			mod.prune()
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// Authorize allows actions granted by tokens of the identity
+func (mod *Module) Authorize(identity id.Identity, action string, args ...any) bool {
+	for _, token := range mod.Tokens(identity) {
+		if token.Grants(action, args...) {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckQuery picks up tokens attached to queries to the node. Queries with invalid tokens are refused.
+func (mod *Module) CheckQuery(query net.Query, _ net.Hints) error {
+	if !query.Target().IsEqual(mod.node.Identity()) {
+		return nil
+	}
+
+	_, params := router.ParseQuery(query.Query())
+	s, found := params[tokens.QueryParam]
+	if !found {
+		return nil
+	}
+
+	token, err := tokens.Parse(s)
+	if err != nil {
+		return err
+	}
+
+	if !token.AudienceID.IsEqual(query.Caller()) {
+		return errors.New("token was issued for a different audience")
+	}
+
+	return mod.Add(token)
+}
+
+func (mod *Module) Issue(issuerID id.Identity, audienceID id.Identity, actions []string, args []string, duration time.Duration) (*tokens.Token, error) {
+	return mod.sign(&tokens.Token{
+		IssuerID:   issuerID,
+		AudienceID: audienceID,
+		Actions:    actions,
+		Args:       args,
+		ExpiresAt:  time.Now().Add(duration),
+	})
+}
+
+func (mod *Module) Delegate(parent *tokens.Token, audienceID id.Identity, actions []string, args []string, duration time.Duration) (*tokens.Token, error) {
+	if len(actions) == 0 {
+		actions = parent.Actions
+	}
+	if len(args) == 0 {
+		args = parent.Args
+	}
+
+	var expiresAt = time.Now().Add(duration)
+	if duration <= 0 || expiresAt.After(parent.ExpiresAt) {
+		expiresAt = parent.ExpiresAt
+	}
+
+	return mod.sign(&tokens.Token{
+		IssuerID:   parent.AudienceID,
+		AudienceID: audienceID,
+		Actions:    actions,
+		Args:       args,
+		ExpiresAt:  expiresAt,
+		Parent:     parent,
+	})
+}
+
+func (mod *Module) Add(token *tokens.Token) error {
+	if err := token.Validate(); err != nil {
+		return err
+	}
+
+	if !mod.isTrusted(token.Root().IssuerID) {
+		return errors.New("root issuer is not trusted")
+	}
+
+	var key = token.AudienceID.PublicKeyHex()
+	var rootHash = token.Root().Hash()
+
+	mod.mu.Lock()
+	defer mod.mu.Unlock()
+
+	// the audience can delegate its tokens to itself indefinitely, so keep only the latest token of every root
+	var list = slices.DeleteFunc(mod.grants[key], func(t *tokens.Token) bool {
+		return slices.Equal(t.Root().Hash(), rootHash)
+	})
+
+	// make room by dropping the tokens that expire first
+	if len(list) >= maxAudienceTokens {
+		slices.SortFunc(list, func(a, b *tokens.Token) int {
+			return b.ExpiresAt.Compare(a.ExpiresAt)
+		})
+		list = list[:maxAudienceTokens-1]
+	}
+
+	mod.grants[key] = append(list, token)
+
+	return nil
+}
+
+func (mod *Module) Tokens(audienceID id.Identity) []*tokens.Token {
+	mod.mu.Lock()
+	defer mod.mu.Unlock()
+
+	var now = time.Now()
+	var list []*tokens.Token
+	for _, token := range mod.grants[audienceID.PublicKeyHex()] {
+		if token.ExpiresAt.After(now) {
+			list = append(list, token)
+		}
+	}
+
+	return list
+}
+
+func (mod *Module) sign(token *tokens.Token) (*tokens.Token, error) {
+	var err error
+
+	var desc = fmt.Sprintf("token granting %s %s until %s",
+		mod.node.Resolver().DisplayName(token.AudienceID),
+		strings.Join(token.Actions, ", "),
+		token.ExpiresAt.Format(time.DateTime),
+	)
+
+	token.Signature, err = mod.keys.SignWithDescription(token.IssuerID, token.Hash(), desc)
+	if err != nil {
+		return nil, err
+	}
+
+	return token, token.Validate()
+}
+
+// isTrusted checks if the identity can issue root tokens
+func (mod *Module) isTrusted(identity id.Identity) bool {
+	if identity.IsEqual(mod.node.Identity()) {
+		return true
+	}
+
+	if mod.user != nil && identity.IsEqual(mod.user.UserID()) {
+		return true
+	}
+
+	for _, name := range mod.config.Issuers {
+		issuerID, err := mod.node.Resolver().Resolve(name)
+		if err == nil && issuerID.IsEqual(identity) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// prune removes expired tokens
+func (mod *Module) prune() {
+	mod.mu.Lock()
+	defer mod.mu.Unlock()
+
+	var now = time.Now()
+	for key, list := range mod.grants {
+		list = slices.DeleteFunc(list, func(token *tokens.Token) bool {
+			return !token.ExpiresAt.After(now)
+		})
+		if len(list) == 0 {
+			delete(mod.grants, key)
+		} else {
+			mod.grants[key] = list
+		}
+	}
+}
+
+func (mod *Module) String() string {
+	return "mod." + tokens.ModuleName
+}
@@ -0,0 +1,200 @@
+package tokens
+
+import (
+	"errors"
+	"github.com/cryptopunkscc/astrald/auth/id"
+	"github.com/cryptopunkscc/astrald/mod/keys"
+	"github.com/cryptopunkscc/astrald/mod/tokens"
+	"github.com/cryptopunkscc/astrald/node"
+	"github.com/cryptopunkscc/astrald/node/resolver"
+	"github.com/cryptopunkscc/astrald/object"
+	"slices"
+	"testing"
+	"time"
+)
+
+type testKeys struct {
+	keys.Module
+	identities []id.Identity
+}
+
+func (k *testKeys) Sign(identity id.Identity, hash []byte) ([]byte, error) {
+	for _, i := range k.identities {
+		if i.IsEqual(identity) {
+			return i.Sign(hash)
+		}
+	}
+	return nil, errors.New("key not found")
+}
+
+func (k *testKeys) SignWithDescription(identity id.Identity, hash []byte, _ string) ([]byte, error) {
+	return k.Sign(identity, hash)
+}
+
+type testNode struct {
+	node.Node
+	identity id.Identity
+}
+
+func (n *testNode) Identity() id.Identity {
+	return n.identity
+}
+
+func (n *testNode) Resolver() resolver.Resolver {
+	return testResolver{}
+}
+
+type testResolver struct {
+	resolver.Resolver
+}
+
+func (testResolver) DisplayName(identity id.Identity) string {
+	return identity.Fingerprint()
+}
+
+func TestTokens(t *testing.T) {
+	var nodeID, alice, bob, mallory id.Identity
+	for _, i := range []*id.Identity{&nodeID, &alice, &bob, &mallory} {
+		var err error
+		if *i, err = id.GenerateIdentity(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var mod = &Module{
+		node:   &testNode{identity: nodeID},
+		keys:   &testKeys{identities: []id.Identity{nodeID, alice, bob, mallory}},
+		grants: map[string][]*tokens.Token{},
+	}
+
+	var objectID = object.ID{Size: 1, Hash: [32]byte{1}}
+	var otherID = object.ID{Size: 1, Hash: [32]byte{2}}
+
+	root, err := mod.Issue(nodeID, alice, []string{"objects.*"}, []string{objectID.String()}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	delegated, err := mod.Delegate(root, bob, []string{"objects.read"}, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// tokens survive encoding
+	parsed, err := tokens.Parse(delegated.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := mod.Add(parsed); err != nil {
+		t.Fatal(err)
+	}
+
+	if !mod.Authorize(bob, "objects.read", objectID) {
+		t.Fatal("delegated action was not allowed")
+	}
+	if mod.Authorize(bob, "objects.read", otherID) {
+		t.Fatal("action on another object was allowed")
+	}
+	if mod.Authorize(bob, "objects.write", objectID) {
+		t.Fatal("action not delegated was allowed")
+	}
+	if mod.Authorize(alice, "objects.read", objectID) {
+		t.Fatal("issuer of the delegated token gained rights")
+	}
+
+	// delegation cannot escalate rights
+	if _, err := mod.Delegate(root, bob, []string{"mod.admin.access"}, nil, 0); err == nil {
+		t.Fatal("delegated an action not granted by the parent")
+	}
+	if _, err := mod.Delegate(root, bob, nil, []string{otherID.String()}, 0); err == nil {
+		t.Fatal("delegated an argument not granted by the parent")
+	}
+	if _, err := mod.Delegate(delegated, mallory, []string{"objects.*"}, nil, 0); err == nil {
+		t.Fatal("delegated a wider action pattern")
+	}
+
+	// tokens signed by someone else than the parent's audience are invalid
+	forged, err := mod.Delegate(root, mallory, nil, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged.IssuerID = mallory
+	forged.Signature, _ = mallory.Sign(forged.Hash())
+	if err := mod.Add(forged); err == nil {
+		t.Fatal("accepted a token issued by a stranger")
+	}
+
+	// root tokens must be issued by a trusted identity
+	untrusted, err := mod.Issue(mallory, bob, []string{"*"}, nil, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := mod.Add(untrusted); err == nil {
+		t.Fatal("accepted a token from an untrusted issuer")
+	}
+
+	// tampering breaks the signature
+	tampered, _ := tokens.Parse(delegated.String())
+	tampered.Parent.Args = nil
+	if err := tampered.Validate(); err == nil {
+		t.Fatal("accepted a tampered token")
+	}
+
+	// expired tokens are invalid
+	if _, err := mod.Issue(nodeID, alice, []string{"*"}, nil, -time.Second); err == nil {
+		t.Fatal("issued an expired token")
+	}
+}
+
+func TestAddLimits(t *testing.T) {
+	var nodeID, bob id.Identity
+	for _, i := range []*id.Identity{&nodeID, &bob} {
+		var err error
+		if *i, err = id.GenerateIdentity(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var mod = &Module{
+		node:   &testNode{identity: nodeID},
+		keys:   &testKeys{identities: []id.Identity{nodeID, bob}},
+		grants: map[string][]*tokens.Token{},
+	}
+
+	root, err := mod.Issue(nodeID, bob, []string{"objects.*"}, nil, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// tokens the audience delegates to itself replace each other
+	var token = root
+	for i := 0; i < 5; i++ {
+		if token, err = mod.Delegate(token, bob, []string{"objects.read"}, nil, time.Duration(59-i)*time.Minute); err != nil {
+			t.Fatal(err)
+		}
+		if err := mod.Add(token); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if list := mod.Tokens(bob); len(list) != 1 || list[0] != token {
+		t.Fatalf("expected the last token only, got %d tokens", len(list))
+	}
+
+	// the tokens that expire first make room for new ones
+	for i := 0; i < maxAudienceTokens; i++ {
+		root, err := mod.Issue(nodeID, bob, []string{"objects.read"}, nil, time.Duration(i+1)*time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := mod.Add(root); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var list = mod.Tokens(bob)
+	if len(list) != maxAudienceTokens {
+		t.Fatalf("expected %d tokens, got %d", maxAudienceTokens, len(list))
+	}
+	if slices.Contains(list, token) {
+		t.Fatal("the token that expires first was kept")
+	}
+}
@@ -0,0 +1,241 @@
+package tokens
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"github.com/cryptopunkscc/astrald/auth/id"
+	"github.com/cryptopunkscc/astrald/cslq"
+	"github.com/jxskiss/base62"
+	"slices"
+	"strings"
+	"time"
+)
+
+const tokenPrefix = "tok1"
+
+// MaxChainLength is the maximum number of tokens in a delegation chain
+const MaxChainLength = 8
+
+// Token grants its audience the right to perform actions on behalf of its issuer. A token
+// issued by an audience of another token (its parent) delegates a subset of the parent's rights.
+type Token struct {
+	IssuerID   id.Identity
+	AudienceID id.Identity
+	Actions    []string // action names or prefixes ending with ".*"
+	Args       []string // allowed first arguments of actions, empty allows any
+	ExpiresAt  time.Time
+	Parent     *Token // nil for root tokens
+	Signature  []byte
+}
+
+func (*Token) ObjectType() string {
+	return "mod.tokens.token"
+}
+
+func (token *Token) Hash() []byte {
+	var parentHash []byte
+	if token.Parent != nil {
+		parentHash = token.Parent.Hash()
+	}
+
+	var hash = sha256.New()
+	var err = cslq.Encode(hash,
+		"[c]cvv[c][c]c[c][c]cv[c]c",
+		token.ObjectType(),
+		token.IssuerID,
+		token.AudienceID,
+		token.Actions,
+		token.Args,
+		cslq.Time(token.ExpiresAt),
+		parentHash,
+	)
+	if err != nil {
+		return nil
+	}
+	return hash.Sum(nil)
+}
+
+// Root returns the first token of the delegation chain
+func (token *Token) Root() *Token {
+	var root = token
+	for root.Parent != nil {
+		root = root.Parent
+	}
+	return root
+}
+
+// Validate checks that the token and all its parents are signed, unexpired and that every token
+// of the chain only delegates rights of its parent
+func (token *Token) Validate() error {
+	var length int
+	for t := token; t != nil; t = t.Parent {
+		if length++; length > MaxChainLength {
+			return errors.New("delegation chain too long")
+		}
+
+		switch {
+		case t.IssuerID.IsZero():
+			return errors.New("issuer identity missing")
+		case t.AudienceID.IsZero():
+			return errors.New("audience identity missing")
+		case len(t.Actions) == 0:
+			return errors.New("no actions")
+		case t.ExpiresAt.Before(time.Now()):
+			return errors.New("token expired")
+		case t.Signature == nil:
+			return errors.New("signature missing")
+		case !t.IssuerID.Verify(t.Hash(), t.Signature):
+			return errors.New("signature invalid")
+		}
+
+		if t.Parent != nil {
+			if err := t.checkParent(); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (token *Token) checkParent() error {
+	var parent = token.Parent
+
+	if !token.IssuerID.IsEqual(parent.AudienceID) {
+		return errors.New("issuer is not the audience of the parent token")
+	}
+	if token.ExpiresAt.After(parent.ExpiresAt) {
+		return errors.New("token outlives its parent")
+	}
+	for _, action := range token.Actions {
+		if !slices.ContainsFunc(parent.Actions, func(pattern string) bool {
+			return coversAction(pattern, action)
+		}) {
+			return fmt.Errorf("action %s not granted by the parent token", action)
+		}
+	}
+	if len(parent.Args) > 0 {
+		if len(token.Args) == 0 {
+			return errors.New("token lifts argument restrictions of its parent")
+		}
+		for _, arg := range token.Args {
+			if !slices.Contains(parent.Args, arg) {
+				return fmt.Errorf("argument %s not granted by the parent token", arg)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Grants checks if the token allows its audience to perform the action. The token must be validated first.
+func (token *Token) Grants(action string, args ...any) bool {
+	if !slices.ContainsFunc(token.Actions, func(pattern string) bool {
+		return MatchAction(pattern, action)
+	}) {
+		return false
+	}
+
+	if len(token.Args) == 0 {
+		return true
+	}
+	if len(args) == 0 {
+		return false
+	}
+
+	return slices.Contains(token.Args, fmt.Sprint(args[0]))
+}
+
+// MatchAction matches an action against a pattern. Patterns are either exact action names,
+// prefixes ending with ".*" or "*" matching all actions.
+func MatchAction(pattern string, action string) bool {
+	if pattern == "*" {
+		return true
+	}
+	if prefix, found := strings.CutSuffix(pattern, ".*"); found {
+		return strings.HasPrefix(action, prefix+".")
+	}
+	return pattern == action
+}
+
+// coversAction checks if a pattern covers all actions matched by another pattern
+func coversAction(pattern string, sub string) bool {
+	if prefix, found := strings.CutSuffix(sub, ".*"); found {
+		return pattern == "*" || pattern == sub || MatchAction(pattern, prefix+".*")
+	}
+	if sub == "*" {
+		return pattern == "*"
+	}
+	return MatchAction(pattern, sub)
+}
+
+func (token Token) MarshalCSLQ(enc *cslq.Encoder) error {
+	var parent []byte
+	if token.Parent != nil {
+		var err error
+		if parent, err = cslq.Marshal(token.Parent); err != nil {
+			return err
+		}
+	}
+
+	return enc.Encodef("vv[c][c]c[c][c]cv[c]c[s]c",
+		token.IssuerID,
+		token.AudienceID,
+		token.Actions,
+		token.Args,
+		cslq.Time(token.ExpiresAt),
+		token.Signature,
+		parent,
+	)
+}
+
+func (token *Token) UnmarshalCSLQ(dec *cslq.Decoder) error {
+	var expiresAt cslq.Time
+	var parent []byte
+	err := dec.Decodef("vv[c][c]c[c][c]cv[c]c[s]c",
+		&token.IssuerID,
+		&token.AudienceID,
+		&token.Actions,
+		&token.Args,
+		&expiresAt,
+		&token.Signature,
+		&parent,
+	)
+	token.ExpiresAt = expiresAt.Time()
+	if err != nil {
+		return err
+	}
+
+	if len(parent) > 0 {
+		token.Parent = &Token{}
+		return cslq.Unmarshal(parent, token.Parent)
+	}
+
+	return nil
+}
+
+// String returns the text form of the token, which can be used as a query parameter
+func (token *Token) String() string {
+	var buf = &bytes.Buffer{}
+	if err := cslq.Encode(buf, "v", token); err != nil {
+		return "error"
+	}
+	return tokenPrefix + base62.EncodeToString(buf.Bytes())
+}
+
+// Parse parses the text form of a token
+func Parse(s string) (*Token, error) {
+	data, err := base62.DecodeString(strings.TrimPrefix(s, tokenPrefix))
+	if err != nil {
+		return nil, err
+	}
+
+	var token Token
+	if err := cslq.Unmarshal(data, &token); err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
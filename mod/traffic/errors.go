@@ -0,0 +1,5 @@
+package traffic
+
+import "errors"
+
+var ErrQuotaExceeded = errors.New("traffic quota exceeded")
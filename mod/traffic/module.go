@@ -0,0 +1,28 @@
+package traffic
+
+import (
+	"github.com/cryptopunkscc/astrald/auth/id"
+	"time"
+)
+
+const ModuleName = "traffic"
+const DBPrefix = "traffic__"
+
+type Module interface {
+	// Usage returns the traffic of the identity (as a caller or a target) since the given day
+	Usage(identity id.Identity, since time.Time) Usage
+	// Sessions returns the number of open sessions of the identity
+	Sessions(identity id.Identity) int
+}
+
+// Usage holds traffic statistics
+type Usage struct {
+	BytesIn  uint64 // bytes sent to the caller
+	BytesOut uint64 // bytes sent to the target
+	Sessions int    // number of sessions
+}
+
+// Total returns the number of bytes transferred in both directions
+func (u Usage) Total() uint64 {
+	return u.BytesIn + u.BytesOut
+}
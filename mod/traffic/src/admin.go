@@ -0,0 +1,177 @@
+package traffic
+
+import (
+	"errors"
+	"fmt"
+	"github.com/cryptopunkscc/astrald/log"
+	"github.com/cryptopunkscc/astrald/mod/admin"
+	"time"
+)
+
+type Admin struct {
+	mod  *Module
+	cmds map[string]func(admin.Terminal, []string) error
+}
+
+func NewAdmin(mod *Module) *Admin {
+	var adm = &Admin{mod: mod}
+	adm.cmds = map[string]func(admin.Terminal, []string) error{
+		"usage":    adm.usage,
+		"show":     adm.show,
+		"sessions": adm.sessions,
+		"help":     adm.help,
+	}
+
+	return adm
+}
+
+func (adm *Admin) Exec(term admin.Terminal, args []string) error {
+	if len(args) < 2 {
+		return adm.help(term, []string{})
+	}
+
+	cmd, args := args[1], args[2:]
+	if fn, found := adm.cmds[cmd]; found {
+		return fn(term, args)
+	}
+
+	return errors.New("unknown command")
+}
+
+// usage lists traffic between callers and targets
+func (adm *Admin) usage(term admin.Terminal, args []string) error {
+	var period = "today"
+	if len(args) > 0 {
+		period = args[0]
+	}
+
+	since, err := periodStart(period)
+	if err != nil {
+		return err
+	}
+
+	adm.mod.flushAll()
+
+	var rows []*dbUsage
+
+	err = adm.mod.db.Model(&dbUsage{}).
+		Select("caller_id, target_id, sum(bytes_in) as bytes_in, sum(bytes_out) as bytes_out, sum(sessions) as sessions").
+		Where("day >= ?", since.Format(dayFormat)).
+		Group("caller_id, target_id").
+		Order("sum(bytes_in) + sum(bytes_out) desc").
+		Find(&rows).Error
+	if err != nil {
+		return err
+	}
+
+	var f = "%-20s %-20s %8s %10s %10s\n"
+	term.Printf(f, admin.Header("Caller"), admin.Header("Target"), admin.Header("Sessions"), admin.Header("In"), admin.Header("Out"))
+	for _, row := range rows {
+		term.Printf(f,
+			row.CallerID,
+			row.TargetID,
+			fmt.Sprintf("%d", row.Sessions),
+			log.DataSize(row.BytesIn).HumanReadable(),
+			log.DataSize(row.BytesOut).HumanReadable(),
+		)
+	}
+
+	return nil
+}
+
+// show shows traffic and the quota of a single identity
+func (adm *Admin) show(term admin.Terminal, args []string) error {
+	if len(args) < 1 {
+		return errors.New("argument missing")
+	}
+
+	identity, err := adm.mod.node.Resolver().Resolve(args[0])
+	if err != nil {
+		return err
+	}
+
+	adm.mod.flushAll()
+
+	var quota = adm.mod.Quota(identity)
+
+	term.Printf("Identity: %v\n", identity)
+	term.Printf("Open sessions: %v%v\n", adm.mod.Sessions(identity), limit(uint64(quota.Sessions), false))
+	for _, p := range []struct {
+		name  string
+		limit uint64
+	}{{"today", quota.DailyBytes}, {"month", quota.MonthlyBytes}, {"all", 0}} {
+		since, _ := periodStart(p.name)
+		var usage = adm.mod.Usage(identity, since)
+		term.Printf("%-8s %v sessions, %v in, %v out%v\n",
+			admin.Keyword(p.name),
+			usage.Sessions,
+			log.DataSize(usage.BytesIn).HumanReadable(),
+			log.DataSize(usage.BytesOut).HumanReadable(),
+			limit(p.limit, true),
+		)
+	}
+
+	return nil
+}
+
+// sessions lists open sessions with traffic
+func (adm *Admin) sessions(term admin.Terminal, _ []string) error {
+	adm.mod.mu.Lock()
+	var list = make([]*session, 0, len(adm.mod.sessions))
+	for _, s := range adm.mod.sessions {
+		list = append(list, s)
+	}
+	adm.mod.mu.Unlock()
+
+	var f = "%6s %-20s %-20s %10s %10s\n"
+	term.Printf(f, admin.Header("ID"), admin.Header("Caller"), admin.Header("Target"), admin.Header("In"), admin.Header("Out"))
+	for _, s := range list {
+		term.Printf(f,
+			fmt.Sprintf("%d", s.conn.ID()),
+			s.callerID,
+			s.targetID,
+			log.DataSize(s.conn.BytesIn()).HumanReadable(),
+			log.DataSize(s.conn.BytesOut()).HumanReadable(),
+		)
+	}
+
+	return nil
+}
+
+func (adm *Admin) ShortDescription() string {
+	return "traffic accounting and quotas"
+}
+
+func (adm *Admin) help(term admin.Terminal, _ []string) error {
+	term.Printf("usage: %s <command>\n\n", "traffic")
+	term.Printf("commands:\n")
+	term.Printf("  usage [today|month|all]        show traffic between callers and targets\n")
+	term.Printf("  show <identity>                show traffic and quota of an identity\n")
+	term.Printf("  sessions                       list open sessions\n")
+	term.Printf("  help                           show help\n")
+	return nil
+}
+
+func periodStart(period string) (time.Time, error) {
+	var now = time.Now()
+	switch period {
+	case "today", "day":
+		return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()), nil
+	case "month":
+		return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location()), nil
+	case "all":
+		return time.Time{}, nil
+	}
+	return time.Time{}, errors.New("invalid period")
+}
+
+func limit(v uint64, bytes bool) string {
+	switch {
+	case v == 0:
+		return ""
+	case bytes:
+		return fmt.Sprintf(" (limit %v)", log.DataSize(v).HumanReadable())
+	default:
+		return fmt.Sprintf(" (limit %v)", v)
+	}
+}
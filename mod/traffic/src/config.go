@@ -0,0 +1,29 @@
+package traffic
+
+import "time"
+
+type Config struct {
+	// Default quota applies to all identities other than the node's
+	Default Quota `yaml:"default"`
+
+	// Quotas overrides the default quota for specific identities (by name or public key)
+	Quotas map[string]Quota `yaml:"quotas"`
+
+	// FlushInterval sets how often traffic of open sessions is saved to the database
+	FlushInterval time.Duration `yaml:"flush_interval"`
+}
+
+// Quota limits the traffic of an identity. Zero values mean no limit.
+type Quota struct {
+	DailyBytes   uint64 `yaml:"daily_bytes"`
+	MonthlyBytes uint64 `yaml:"monthly_bytes"`
+	Sessions     int    `yaml:"sessions"`
+}
+
+func (q Quota) IsZero() bool {
+	return q == Quota{}
+}
+
+var defaultConfig = Config{
+	FlushInterval: time.Minute,
+}
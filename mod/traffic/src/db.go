@@ -0,0 +1,22 @@
+package traffic
+
+import (
+	"github.com/cryptopunkscc/astrald/auth/id"
+	"github.com/cryptopunkscc/astrald/mod/traffic"
+)
+
+const dayFormat = "2006-01-02"
+
+// dbUsage holds daily traffic between a caller and a target
+type dbUsage struct {
+	Day      string      `gorm:"primaryKey"`
+	CallerID id.Identity `gorm:"primaryKey;index"`
+	TargetID id.Identity `gorm:"primaryKey;index"`
+	BytesIn  uint64
+	BytesOut uint64
+	Sessions int
+}
+
+func (dbUsage) TableName() string {
+	return traffic.DBPrefix + "usage"
+}
@@ -0,0 +1,50 @@
+package traffic
+
+import (
+	"github.com/cryptopunkscc/astrald/log"
+	"github.com/cryptopunkscc/astrald/mod/traffic"
+	"github.com/cryptopunkscc/astrald/net"
+	"github.com/cryptopunkscc/astrald/node/assets"
+	"github.com/cryptopunkscc/astrald/node/modules"
+	"github.com/cryptopunkscc/astrald/node/router"
+)
+
+type Loader struct{}
+
+func (Loader) Load(node modules.Node, assets assets.Assets, log *log.Logger) (modules.Module, error) {
+	var mod = &Module{
+		node:     node,
+		config:   defaultConfig,
+		log:      log,
+		sessions: map[*router.MonitoredConn]*session{},
+		reserved: map[net.Nonce]*reservation{},
+		quotas:   map[string]Quota{},
+		totals:   map[string]*savedTotals{},
+	}
+
+	_ = assets.LoadYAML(traffic.ModuleName, &mod.config)
+
+	if mod.config.FlushInterval <= 0 {
+		mod.config.FlushInterval = defaultConfig.FlushInterval
+	}
+
+	mod.db = assets.Database()
+
+	err := mod.db.AutoMigrate(&dbUsage{})
+	if err != nil {
+		return nil, err
+	}
+
+	err = node.Router().AddGuard(mod)
+	if err != nil {
+		return nil, err
+	}
+
+	return mod, nil
+}
+
+func init() {
+	if err := modules.RegisterModule(traffic.ModuleName, Loader{}); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,401 @@
+package traffic
+
+import (
+	"context"
+	"fmt"
+	"github.com/cryptopunkscc/astrald/auth/id"
+	"github.com/cryptopunkscc/astrald/log"
+	"github.com/cryptopunkscc/astrald/mod/traffic"
+	"github.com/cryptopunkscc/astrald/net"
+	"github.com/cryptopunkscc/astrald/node"
+	"github.com/cryptopunkscc/astrald/node/router"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"sync"
+	"time"
+)
+
+var _ traffic.Module = &Module{}
+var _ router.Guard = &Module{}
+
+// reservationTimeout is how long a session slot is reserved for a query in case the router doesn't report
+// what happened to it
+const reservationTimeout = time.Minute
+
+type Module struct {
+	config Config
+	node   node.Node
+	log    *log.Logger
+	db     *gorm.DB
+
+	mu       sync.Mutex
+	sessions map[*router.MonitoredConn]*session
+	reserved map[net.Nonce]*reservation
+
+	quotasMu sync.RWMutex
+	quotas   map[string]Quota
+
+	totalsMu sync.Mutex
+	totals   map[string]*savedTotals
+}
+
+// session is an open connection whose traffic is being accounted
+type session struct {
+	conn       *router.MonitoredConn
+	callerID   id.Identity
+	targetID   id.Identity
+	flushedIn  int
+	flushedOut int
+}
+
+// savedTotals holds the bytes of an identity saved to the database today and this month
+type savedTotals struct {
+	day     string
+	daily   uint64
+	monthly uint64
+}
+
+// reservation is a session slot taken by a query that is being routed
+type reservation struct {
+	callerID  id.Identity
+	targetID  id.Identity
+	expiresAt time.Time
+}
+
+func (mod *Module) Run(ctx context.Context) error {
+	mod.loadQuotas()
+
+	var events = mod.node.Events().Subscribe(ctx)
+	var ticker = time.NewTicker(mod.config.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				mod.flushAll()
+				return nil
+			}
+
+			switch event := event.(type) {
+			case router.EventConnAdded:
+				mod.addSession(event.Conn)
+			case router.EventConnRemoved:
+				mod.removeSession(event.Conn)
+			case router.EventQueryFailed:
+				mod.release(event.Query.Nonce())
+			}
+
+		case <-ticker.C:
+			mod.flushAll()
+			mod.pruneReservations()
+
+		case <-ctx.Done():
+			mod.flushAll()
+			return nil
+		}
+	}
+}
+
+// CheckQuery refuses queries of identities that exceeded their quota. Queries let through reserve a session
+// slot until they're routed.
+func (mod *Module) CheckQuery(query net.Query, _ net.Hints) error {
+	for _, identity := range []id.Identity{query.Caller(), query.Target()} {
+		if err := mod.checkQuota(identity); err != nil {
+			return err
+		}
+	}
+	return mod.reserve(query)
+}
+
+func (mod *Module) Usage(identity id.Identity, since time.Time) (usage traffic.Usage) {
+	usage = mod.savedUsage(identity, since)
+
+	// add traffic of open sessions that wasn't saved yet
+	var in, out = mod.unsaved(identity)
+	usage.BytesIn += in
+	usage.BytesOut += out
+
+	return
+}
+
+func (mod *Module) Sessions(identity id.Identity) (count int) {
+	mod.mu.Lock()
+	defer mod.mu.Unlock()
+
+	for _, s := range mod.sessions {
+		if s.callerID.IsEqual(identity) || s.targetID.IsEqual(identity) {
+			count++
+		}
+	}
+	return
+}
+
+// Quota returns the quota that applies to the identity
+func (mod *Module) Quota(identity id.Identity) Quota {
+	if identity.IsEqual(mod.node.Identity()) {
+		return Quota{}
+	}
+
+	mod.quotasMu.RLock()
+	defer mod.quotasMu.RUnlock()
+
+	if quota, found := mod.quotas[identity.PublicKeyHex()]; found {
+		return quota
+	}
+	return mod.config.Default
+}
+
+func (mod *Module) checkQuota(identity id.Identity) error {
+	if identity.IsZero() {
+		return nil
+	}
+
+	var quota = mod.Quota(identity)
+	if quota.IsZero() {
+		return nil
+	}
+
+	if quota.DailyBytes == 0 && quota.MonthlyBytes == 0 {
+		return nil
+	}
+
+	var daily, monthly = mod.savedTotals(identity)
+	var in, out = mod.unsaved(identity)
+
+	if quota.DailyBytes > 0 && daily+in+out >= quota.DailyBytes {
+		return fmt.Errorf("%w: %v used its daily traffic", traffic.ErrQuotaExceeded, identity)
+	}
+
+	if quota.MonthlyBytes > 0 && monthly+in+out >= quota.MonthlyBytes {
+		return fmt.Errorf("%w: %v used its monthly traffic", traffic.ErrQuotaExceeded, identity)
+	}
+
+	return nil
+}
+
+// savedTotals returns the bytes of the identity saved today and this month. Totals are loaded from the database
+// once a day and kept up to date by save.
+func (mod *Module) savedTotals(identity id.Identity) (daily uint64, monthly uint64) {
+	mod.totalsMu.Lock()
+	defer mod.totalsMu.Unlock()
+
+	var now = time.Now()
+	var key = identity.PublicKeyHex()
+
+	t, found := mod.totals[key]
+	if !found || t.day != now.Format(dayFormat) {
+		var today = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+		var month = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+
+		t = &savedTotals{day: now.Format(dayFormat)}
+		t.daily = mod.savedUsage(identity, today).Total()
+		t.monthly = mod.savedUsage(identity, month).Total()
+		mod.totals[key] = t
+	}
+
+	return t.daily, t.monthly
+}
+
+// savedUsage returns the usage of the identity saved to the database since the given day
+func (mod *Module) savedUsage(identity id.Identity, since time.Time) (usage traffic.Usage) {
+	mod.db.Model(&dbUsage{}).
+		Select("coalesce(sum(bytes_in), 0) as bytes_in, coalesce(sum(bytes_out), 0) as bytes_out, coalesce(sum(sessions), 0) as sessions").
+		Where("day >= ? and (caller_id = ? or target_id = ?)", since.Format(dayFormat), identity, identity).
+		Scan(&usage)
+	return
+}
+
+// unsaved returns the traffic of open sessions of the identity that wasn't saved yet
+func (mod *Module) unsaved(identity id.Identity) (in uint64, out uint64) {
+	mod.mu.Lock()
+	defer mod.mu.Unlock()
+
+	for _, s := range mod.sessions {
+		if s.callerID.IsEqual(identity) || s.targetID.IsEqual(identity) {
+			in += uint64(s.conn.BytesIn() - s.flushedIn)
+			out += uint64(s.conn.BytesOut() - s.flushedOut)
+		}
+	}
+	return
+}
+
+// reserve takes a session slot of the caller and the target of the query if both are within their quota
+func (mod *Module) reserve(query net.Query) error {
+	if query.Caller().IsZero() || query.Target().IsZero() {
+		return nil
+	}
+
+	mod.mu.Lock()
+	defer mod.mu.Unlock()
+
+	for _, identity := range []id.Identity{query.Caller(), query.Target()} {
+		var quota = mod.Quota(identity)
+		if quota.Sessions > 0 && mod.sessionCount(identity) >= quota.Sessions {
+			return fmt.Errorf("%w: %v has %v open sessions", traffic.ErrQuotaExceeded, identity, quota.Sessions)
+		}
+	}
+
+	mod.reserved[query.Nonce()] = &reservation{
+		callerID:  query.Caller(),
+		targetID:  query.Target(),
+		expiresAt: time.Now().Add(reservationTimeout),
+	}
+
+	return nil
+}
+
+// release frees the session slot reserved for a query
+func (mod *Module) release(nonce net.Nonce) {
+	mod.mu.Lock()
+	defer mod.mu.Unlock()
+
+	delete(mod.reserved, nonce)
+}
+
+func (mod *Module) pruneReservations() {
+	mod.mu.Lock()
+	defer mod.mu.Unlock()
+
+	var now = time.Now()
+	for nonce, r := range mod.reserved {
+		if now.After(r.expiresAt) {
+			delete(mod.reserved, nonce)
+		}
+	}
+}
+
+// sessionCount returns the number of open and reserved sessions of the identity. Requires mod.mu.
+func (mod *Module) sessionCount(identity id.Identity) (count int) {
+	for _, s := range mod.sessions {
+		if s.callerID.IsEqual(identity) || s.targetID.IsEqual(identity) {
+			count++
+		}
+	}
+	for _, r := range mod.reserved {
+		if r.callerID.IsEqual(identity) || r.targetID.IsEqual(identity) {
+			count++
+		}
+	}
+	return
+}
+
+func (mod *Module) loadQuotas() {
+	mod.quotasMu.Lock()
+	defer mod.quotasMu.Unlock()
+
+	for name, quota := range mod.config.Quotas {
+		identity, err := mod.node.Resolver().Resolve(name)
+		if err != nil {
+			mod.log.Error("quotas: error resolving %v: %v", name, err)
+			continue
+		}
+		mod.quotas[identity.PublicKeyHex()] = quota
+	}
+}
+
+func (mod *Module) addSession(conn *router.MonitoredConn) {
+	var query = conn.Query()
+	if query.Caller().IsZero() || query.Target().IsZero() {
+		mod.release(query.Nonce())
+		return
+	}
+
+	var s = &session{
+		conn:     conn,
+		callerID: query.Caller(),
+		targetID: query.Target(),
+	}
+
+	// the session takes over the slot reserved for its query
+	mod.mu.Lock()
+	delete(mod.reserved, query.Nonce())
+	mod.sessions[conn] = s
+	mod.mu.Unlock()
+
+	mod.save(s.callerID, s.targetID, 0, 0, 1)
+}
+
+func (mod *Module) removeSession(conn *router.MonitoredConn) {
+	mod.mu.Lock()
+	s, found := mod.sessions[conn]
+	delete(mod.sessions, conn)
+	mod.mu.Unlock()
+
+	if found {
+		mod.flush(s)
+	}
+}
+
+func (mod *Module) flushAll() {
+	mod.mu.Lock()
+	var list = make([]*session, 0, len(mod.sessions))
+	for _, s := range mod.sessions {
+		list = append(list, s)
+	}
+	mod.mu.Unlock()
+
+	for _, s := range list {
+		mod.flush(s)
+	}
+}
+
+// flush saves the traffic of the session since the last flush
+func (mod *Module) flush(s *session) {
+	mod.mu.Lock()
+	var in, out = s.conn.BytesIn(), s.conn.BytesOut()
+	var dIn, dOut = in - s.flushedIn, out - s.flushedOut
+	s.flushedIn, s.flushedOut = in, out
+	mod.mu.Unlock()
+
+	if dIn == 0 && dOut == 0 {
+		return
+	}
+
+	mod.save(s.callerID, s.targetID, uint64(dIn), uint64(dOut), 0)
+}
+
+// save adds traffic to today's usage of the caller-target pair
+func (mod *Module) save(callerID, targetID id.Identity, bytesIn, bytesOut uint64, sessions int) {
+	// keep cached totals in sync with the database
+	mod.totalsMu.Lock()
+	defer mod.totalsMu.Unlock()
+
+	var day = time.Now().Format(dayFormat)
+
+	err := mod.db.Clauses(clause.OnConflict{
+		DoUpdates: clause.Assignments(map[string]any{
+			"bytes_in":  gorm.Expr("bytes_in + ?", bytesIn),
+			"bytes_out": gorm.Expr("bytes_out + ?", bytesOut),
+			"sessions":  gorm.Expr("sessions + ?", sessions),
+		}),
+	}).Create(&dbUsage{
+		Day:      day,
+		CallerID: callerID,
+		TargetID: targetID,
+		BytesIn:  bytesIn,
+		BytesOut: bytesOut,
+		Sessions: sessions,
+	}).Error
+	if err != nil {
+		mod.log.Error("error saving usage: %v", err)
+		return
+	}
+
+	var keys = []string{callerID.PublicKeyHex()}
+	if !callerID.IsEqual(targetID) {
+		keys = append(keys, targetID.PublicKeyHex())
+	}
+
+	for _, key := range keys {
+		t, found := mod.totals[key]
+		switch {
+		case !found:
+		case t.day != day:
+			delete(mod.totals, key)
+		default:
+			t.daily += bytesIn + bytesOut
+			t.monthly += bytesIn + bytesOut
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package traffic
+
+import (
+	"errors"
+	"github.com/cryptopunkscc/astrald/auth/id"
+	"github.com/cryptopunkscc/astrald/mod/traffic"
+	"github.com/cryptopunkscc/astrald/net"
+	"github.com/cryptopunkscc/astrald/node"
+	"github.com/cryptopunkscc/astrald/node/router"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"testing"
+	"time"
+)
+
+func TestAccounting(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&dbUsage{}); err != nil {
+		t.Fatal(err)
+	}
+
+	nodeID, _ := id.GenerateIdentity()
+
+	var mod = &Module{
+		db:       db,
+		node:     &testNode{identity: nodeID},
+		sessions: map[*router.MonitoredConn]*session{},
+		reserved: map[net.Nonce]*reservation{},
+		totals:   map[string]*savedTotals{},
+	}
+
+	callerID, _ := id.GenerateIdentity()
+	targetID, _ := id.GenerateIdentity()
+	otherID, _ := id.GenerateIdentity()
+
+	var newConn = func() (*router.MonitoredConn, *router.MonitoredWriter, *router.MonitoredWriter) {
+		var caller = router.NewMonitoredWriter(net.NewSecurePipeWriter(discard{}, targetID))
+		var target = router.NewMonitoredWriter(net.NewSecurePipeWriter(discard{}, callerID))
+		var query = net.NewQuery(callerID, targetID, "test")
+		return router.NewMonitoredConn(caller, target, query, net.DefaultHints()), caller, target
+	}
+
+	var today = time.Now().Add(-time.Minute)
+
+	conn1, caller1, target1 := newConn()
+	mod.addSession(conn1)
+	caller1.Write(make([]byte, 1000))
+	target1.Write(make([]byte, 100))
+
+	// unsaved traffic of open sessions counts
+	var usage = mod.Usage(callerID, today)
+	if usage.BytesIn != 1000 || usage.BytesOut != 100 || usage.Sessions != 1 {
+		t.Fatalf("unexpected usage %+v", usage)
+	}
+
+	mod.flushAll()
+	caller1.Write(make([]byte, 500))
+
+	conn2, caller2, _ := newConn()
+	mod.addSession(conn2)
+	caller2.Write(make([]byte, 10))
+
+	if n := mod.Sessions(targetID); n != 2 {
+		t.Fatalf("expected 2 sessions, got %d", n)
+	}
+
+	mod.removeSession(conn1)
+	mod.removeSession(conn2)
+
+	if n := mod.Sessions(targetID); n != 0 {
+		t.Fatalf("expected no sessions, got %d", n)
+	}
+
+	usage = mod.Usage(targetID, today)
+	if usage.BytesIn != 1510 || usage.BytesOut != 100 || usage.Sessions != 2 {
+		t.Fatalf("unexpected usage %+v", usage)
+	}
+
+	if usage = mod.Usage(otherID, today); usage.Total() != 0 {
+		t.Fatalf("unexpected usage of an unrelated identity %+v", usage)
+	}
+
+	if usage = mod.Usage(callerID, time.Now().Add(48*time.Hour)); usage.Total() != 0 {
+		t.Fatalf("unexpected future usage %+v", usage)
+	}
+
+	// quotas count saved traffic and traffic saved after the totals were loaded
+	mod.config.Default = Quota{DailyBytes: 2000}
+	if err := mod.checkQuota(callerID); err != nil {
+		t.Fatal(err)
+	}
+
+	conn3, caller3, _ := newConn()
+	mod.addSession(conn3)
+	caller3.Write(make([]byte, 500))
+	mod.removeSession(conn3)
+
+	if err := mod.checkQuota(callerID); !errors.Is(err, traffic.ErrQuotaExceeded) {
+		t.Fatalf("expected ErrQuotaExceeded, got %v", err)
+	}
+	if err := mod.checkQuota(otherID); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReserve(t *testing.T) {
+	nodeID, _ := id.GenerateIdentity()
+	callerID, _ := id.GenerateIdentity()
+	targetID, _ := id.GenerateIdentity()
+
+	var mod = &Module{
+		node:     &testNode{identity: nodeID},
+		config:   Config{Default: Quota{Sessions: 1}},
+		sessions: map[*router.MonitoredConn]*session{},
+		reserved: map[net.Nonce]*reservation{},
+	}
+
+	var query = net.NewQuery(callerID, targetID, "test")
+	if err := mod.CheckQuery(query, net.DefaultHints()); err != nil {
+		t.Fatal(err)
+	}
+
+	// the slot is taken before the first query is routed
+	if err := mod.CheckQuery(net.NewQuery(callerID, targetID, "test"), net.DefaultHints()); !errors.Is(err, traffic.ErrQuotaExceeded) {
+		t.Fatalf("expected ErrQuotaExceeded, got %v", err)
+	}
+
+	// a failed query frees its slot
+	mod.release(query.Nonce())
+	if err := mod.CheckQuery(net.NewQuery(callerID, targetID, "test"), net.DefaultHints()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+type testNode struct {
+	node.Node
+	identity id.Identity
+}
+
+func (n *testNode) Identity() id.Identity {
+	return n.identity
+}
+
+type discard struct{}
+
+func (discard) Write(p []byte) (int, error) { return len(p), nil }
+func (discard) Close() error                { return nil }
@@ -1,7 +1,6 @@
 package user
 
 import (
-	"crypto/ecdsa"
 	"crypto/sha256"
 	"errors"
 	"github.com/cryptopunkscc/astrald/auth/id"
@@ -71,11 +70,7 @@ func (contract *NodeContract) VerifyUserSignature() error {
 		return errors.New("user identity missing")
 	case contract.UserSignature == nil:
 		return errors.New("user signature missing")
-	case !ecdsa.VerifyASN1(
-		contract.UserID.PublicKey().ToECDSA(),
-		contract.Hash(),
-		contract.UserSignature,
-	):
+	case !contract.UserID.Verify(contract.Hash(), contract.UserSignature):
 		return errors.New("user signature is invalid")
 	}
 
@@ -88,11 +83,7 @@ func (contract *NodeContract) VerifyNodeSignature() error {
 		return errors.New("node identity missing")
 	case contract.NodeSignature == nil:
 		return errors.New("node signature missing")
-	case !ecdsa.VerifyASN1(
-		contract.NodeID.PublicKey().ToECDSA(),
-		contract.Hash(),
-		contract.NodeSignature,
-	):
+	case !contract.NodeID.Verify(contract.Hash(), contract.NodeSignature):
 		return errors.New("node signature is invalid")
 	}
 
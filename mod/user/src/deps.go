@@ -10,6 +10,7 @@ import (
 	"github.com/cryptopunkscc/astrald/mod/keys"
 	"github.com/cryptopunkscc/astrald/mod/objects"
 	"github.com/cryptopunkscc/astrald/mod/relay"
+	"github.com/cryptopunkscc/astrald/mod/revocations"
 	"github.com/cryptopunkscc/astrald/mod/sets"
 	"github.com/cryptopunkscc/astrald/mod/shares"
 	"github.com/cryptopunkscc/astrald/mod/user"
@@ -51,6 +52,7 @@ func (mod *Module) LoadDependencies() error {
 	mod.keys, _ = modules.Load[keys.Module](mod.node, keys.ModuleName)
 	mod.admin, _ = modules.Load[admin.Module](mod.node, admin.ModuleName)
 	mod.apphost, _ = modules.Load[apphost.Module](mod.node, apphost.ModuleName)
+	mod.revocations, _ = modules.Load[revocations.Module](mod.node, revocations.ModuleName)
 
 	if mod.sdp != nil {
 		mod.sdp.AddServiceDiscoverer(mod)
@@ -2,6 +2,7 @@ package user
 
 import (
 	"context"
+	"errors"
 	"github.com/cryptopunkscc/astrald/auth/id"
 	"github.com/cryptopunkscc/astrald/log"
 	"github.com/cryptopunkscc/astrald/mod/admin"
@@ -12,12 +13,14 @@ import (
 	"github.com/cryptopunkscc/astrald/mod/keys"
 	"github.com/cryptopunkscc/astrald/mod/objects"
 	"github.com/cryptopunkscc/astrald/mod/relay"
+	"github.com/cryptopunkscc/astrald/mod/revocations"
 	"github.com/cryptopunkscc/astrald/mod/sets"
 	"github.com/cryptopunkscc/astrald/mod/shares"
 	"github.com/cryptopunkscc/astrald/mod/user"
 	"github.com/cryptopunkscc/astrald/net"
 	"github.com/cryptopunkscc/astrald/node"
 	"github.com/cryptopunkscc/astrald/node/assets"
+	"github.com/cryptopunkscc/astrald/node/events"
 	"github.com/cryptopunkscc/astrald/node/router"
 	"github.com/cryptopunkscc/astrald/object"
 	"gorm.io/gorm"
@@ -43,6 +46,8 @@ type Module struct {
 	sets    sets.Module
 	dir     dir.Module
 
+	revocations revocations.Module
+
 	userID         id.Identity
 	userCert       []byte
 	routes         *router.PrefixRouter
@@ -51,6 +56,7 @@ type Module struct {
 
 func (mod *Module) Run(ctx context.Context) error {
 	go mod.rescanContracts(ctx)
+	go mod.watchRevocations(ctx)
 
 	<-ctx.Done()
 
@@ -141,6 +147,13 @@ func (mod *Module) setCache(objectID object.ID, contract *user.NodeContract) err
 	if err := contract.Validate(); err != nil {
 		return err
 	}
+	if mod.isRevoked(objectID, contract) {
+		return errors.New("contract revoked")
+	}
+
+	// keep the contract in storage
+	mod.objects.Hold(mod.node.Identity(), objectID)
+
 	return mod.db.Create(&dbNodeContract{
 		ObjectID:  objectID,
 		UserID:    contract.UserID,
@@ -165,3 +178,30 @@ func (mod *Module) getCache(objectID object.ID) *user.NodeContract {
 		ExpiresAt: row.ExpiresAt,
 	}
 }
+
+// isRevoked checks if the contract was revoked by its user or node
+func (mod *Module) isRevoked(objectID object.ID, contract *user.NodeContract) bool {
+	if mod.revocations == nil {
+		return false
+	}
+	return mod.revocations.Revoked(objectID, contract.UserID, contract.NodeID)
+}
+
+// watchRevocations removes revoked contracts from the cache
+func (mod *Module) watchRevocations(ctx context.Context) {
+	events.Handle(ctx, mod.node.Events(), func(event revocations.EventRevoked) error {
+		var rev = event.Revocation
+
+		tx := mod.db.
+			Where("object_id = ? and (user_id = ? or node_id = ?)", rev.ObjectID, rev.SignerID, rev.SignerID).
+			Delete(&dbNodeContract{})
+		switch {
+		case tx.Error != nil:
+			mod.log.Errorv(1, "error removing revoked contract %v: %v", rev.ObjectID, tx.Error)
+		case tx.RowsAffected > 0:
+			mod.objects.Release(mod.node.Identity(), rev.ObjectID)
+		}
+
+		return nil
+	})
+}
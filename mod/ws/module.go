@@ -0,0 +1,31 @@
+package ws
+
+import (
+	"github.com/cryptopunkscc/astrald/net"
+	"github.com/cryptopunkscc/astrald/node/infra"
+)
+
+const ModuleName = "ws"
+
+// NetworkName is the network of plain WebSocket connections
+const NetworkName = "ws"
+
+// SecureNetworkName is the network of WebSocket connections over TLS
+const SecureNetworkName = "wss"
+
+type Module interface {
+	infra.Dialer
+	infra.Unpacker
+	infra.Parser
+	infra.EndpointLister
+	ListenPort() int
+}
+
+type Endpoint interface {
+	net.Endpoint
+	Host() string
+	Port() int
+	Path() string
+	Secure() bool
+	URL() string
+}
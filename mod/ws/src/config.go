@@ -0,0 +1,29 @@
+package ws
+
+import "time"
+
+type Config struct {
+	DialTimeout     time.Duration
+	PublicEndpoints []string // URLs, like wss://example.com/astral
+	ListenPort      int
+	Path            string // HTTP path of the server
+
+	// TLSCert and TLSKey are paths to PEM files. When both are set, the server accepts wss
+	// connections instead of ws.
+	TLSCert string
+	TLSKey  string
+
+	// TLSSkipVerify disables verification of server certificates. Links authenticate nodes on
+	// their own, so this only affects the privacy of the connection's metadata.
+	TLSSkipVerify bool
+
+	// Proxy is the URL of an HTTP proxy used for dialing. When empty, the proxy is taken from
+	// the HTTPS_PROXY, HTTP_PROXY and NO_PROXY environment variables.
+	Proxy string
+}
+
+var defaultConfig = Config{
+	DialTimeout: time.Minute,
+	ListenPort:  1792,
+	Path:        "/",
+}
@@ -0,0 +1,55 @@
+package ws
+
+import (
+	"github.com/cryptopunkscc/astrald/net"
+	"golang.org/x/net/websocket"
+	"net/netip"
+)
+
+var _ net.Conn = &Conn{}
+
+// Conn carries a link over binary WebSocket frames
+type Conn struct {
+	*websocket.Conn
+	outbound       bool
+	localEndpoint  Endpoint
+	remoteEndpoint Endpoint
+}
+
+func wrapWSConn(conn *websocket.Conn, localEndpoint Endpoint, remoteEndpoint Endpoint, outbound bool) *Conn {
+	conn.PayloadType = websocket.BinaryFrame
+
+	return &Conn{
+		Conn:           conn,
+		outbound:       outbound,
+		localEndpoint:  localEndpoint,
+		remoteEndpoint: remoteEndpoint,
+	}
+}
+
+func (conn *Conn) LocalEndpoint() net.Endpoint {
+	return conn.localEndpoint
+}
+
+func (conn *Conn) RemoteEndpoint() net.Endpoint {
+	return conn.remoteEndpoint
+}
+
+func (conn *Conn) Outbound() bool {
+	return conn.outbound
+}
+
+// endpointFromAddr returns the endpoint of a TCP address carrying WebSocket connections
+func endpointFromAddr(addr string, secure bool, path string) Endpoint {
+	var e = Endpoint{secure: secure, path: path}
+
+	ap, err := netip.ParseAddrPort(addr)
+	if err != nil {
+		return e
+	}
+
+	e.host = ap.Addr().Unmap().String()
+	e.port = ap.Port()
+
+	return e
+}
@@ -0,0 +1,93 @@
+package ws
+
+import (
+	"context"
+	"crypto/tls"
+	"github.com/cryptopunkscc/astrald/mod/ws"
+	"github.com/cryptopunkscc/astrald/net"
+	"github.com/cryptopunkscc/astrald/node/infra"
+	"golang.org/x/net/websocket"
+	_net "net"
+	"time"
+)
+
+func (mod *Module) Dial(ctx context.Context, endpoint net.Endpoint) (net.Conn, error) {
+	switch endpoint.Network() {
+	case ws.NetworkName, ws.SecureNetworkName:
+	default:
+		return nil, infra.ErrUnsupportedNetwork
+	}
+
+	e, ok := endpoint.(Endpoint)
+	if !ok {
+		var err error
+		if e, err = Unpack(endpoint.Network(), endpoint.Pack()); err != nil {
+			return nil, err
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, mod.config.DialTimeout)
+	defer cancel()
+
+	rawConn, err := mod.dialTCP(ctx, e)
+	if err != nil {
+		return nil, err
+	}
+
+	// abort the handshakes when the context ends
+	var stop = context.AfterFunc(ctx, func() {
+		rawConn.SetDeadline(time.Now())
+	})
+
+	wsConn, err := mod.handshake(rawConn, e)
+	if !stop() {
+		err = ctx.Err()
+	}
+	if err != nil {
+		rawConn.Close()
+		return nil, err
+	}
+
+	var local = endpointFromAddr(rawConn.LocalAddr().String(), e.secure, e.path)
+
+	return wrapWSConn(wsConn, local, e, true), nil
+}
+
+// handshake runs the TLS handshake, if needed, and the WebSocket handshake over a connection
+func (mod *Module) handshake(rawConn _net.Conn, e Endpoint) (*websocket.Conn, error) {
+	var conn = rawConn
+
+	if e.secure {
+		var tlsConn = tls.Client(rawConn, &tls.Config{
+			ServerName:         e.host,
+			InsecureSkipVerify: mod.config.TLSSkipVerify,
+		})
+		if err := tlsConn.Handshake(); err != nil {
+			return nil, err
+		}
+		conn = tlsConn
+	}
+
+	config, err := websocket.NewConfig(e.URL(), e.httpURL())
+	if err != nil {
+		return nil, err
+	}
+
+	return websocket.NewClient(config, conn)
+}
+
+// dialTCP opens a TCP connection to the endpoint, through a proxy if one is configured for it
+func (mod *Module) dialTCP(ctx context.Context, e Endpoint) (_net.Conn, error) {
+	proxyURL, err := mod.proxy(e)
+	if err != nil {
+		return nil, err
+	}
+
+	if proxyURL != nil {
+		return dialProxy(ctx, proxyURL, e.address())
+	}
+
+	var dialer _net.Dialer
+
+	return dialer.DialContext(ctx, "tcp", e.address())
+}
@@ -0,0 +1,80 @@
+package ws
+
+import (
+	"bytes"
+	"github.com/cryptopunkscc/astrald/cslq"
+	"github.com/cryptopunkscc/astrald/mod/ws"
+	_net "net"
+	"strconv"
+)
+
+var _ ws.Endpoint = Endpoint{}
+
+type Endpoint struct {
+	secure bool
+	host   string
+	port   uint16
+	path   string
+}
+
+func (e Endpoint) Pack() []byte {
+	var b = &bytes.Buffer{}
+
+	cslq.Encode(b, "[c]c s [c]c", e.host, e.port, e.path)
+
+	return b.Bytes()
+}
+
+// String returns the address of the endpoint - host, port and path, if it's not the root
+func (e Endpoint) String() string {
+	var s = e.address()
+	if e.path != "/" {
+		s += e.path
+	}
+	return s
+}
+
+func (e Endpoint) Network() string {
+	if e.secure {
+		return ws.SecureNetworkName
+	}
+	return ws.NetworkName
+}
+
+func (e Endpoint) Host() string {
+	return e.host
+}
+
+func (e Endpoint) Port() int {
+	return int(e.port)
+}
+
+func (e Endpoint) Path() string {
+	return e.path
+}
+
+func (e Endpoint) Secure() bool {
+	return e.secure
+}
+
+// URL returns the WebSocket URL of the endpoint
+func (e Endpoint) URL() string {
+	return e.Network() + "://" + e.address() + e.path
+}
+
+func (e Endpoint) IsZero() bool {
+	return e.host == ""
+}
+
+// address returns the host and port of the endpoint
+func (e Endpoint) address() string {
+	return _net.JoinHostPort(e.host, strconv.Itoa(int(e.port)))
+}
+
+// httpURL returns the HTTP URL of the endpoint, used for choosing a proxy
+func (e Endpoint) httpURL() string {
+	if e.secure {
+		return "https://" + e.address() + e.path
+	}
+	return "http://" + e.address() + e.path
+}
@@ -0,0 +1,91 @@
+package ws
+
+import (
+	"github.com/cryptopunkscc/astrald/log"
+	"github.com/cryptopunkscc/astrald/mod/ws"
+	"github.com/cryptopunkscc/astrald/node/assets"
+	"github.com/cryptopunkscc/astrald/node/modules"
+	"net/url"
+	"strconv"
+)
+
+type Loader struct{}
+
+func (Loader) Load(node modules.Node, assets assets.Assets, l *log.Logger) (modules.Module, error) {
+	mod := &Module{
+		node:   node,
+		log:    l,
+		config: defaultConfig,
+		proxy:  environmentProxy(),
+	}
+
+	_ = assets.LoadYAML(ws.ModuleName, &mod.config)
+
+	if mod.config.Path == "" {
+		mod.config.Path = defaultConfig.Path
+	}
+
+	if mod.config.Proxy != "" {
+		proxyURL, err := url.Parse(mod.config.Proxy)
+		if err != nil {
+			l.Error("error parsing proxy url \"%s\": %s", mod.config.Proxy, err)
+		} else {
+			mod.proxy = fixedProxy(proxyURL)
+		}
+	}
+
+	// Parse public endpoints
+	for _, pe := range mod.config.PublicEndpoints {
+		endpoint, err := ParseURL(pe)
+		if err != nil {
+			l.Error("error parsing public endpoint \"%s\": %s", pe, err)
+			continue
+		}
+
+		mod.publicEndpoints = append(mod.publicEndpoints, endpoint)
+	}
+
+	for _, network := range []string{ws.NetworkName, ws.SecureNetworkName} {
+		node.Infra().SetDialer(network, mod)
+		node.Infra().SetParser(network, mod)
+		node.Infra().SetUnpacker(network, mod)
+	}
+	node.Infra().AddEndpoints(mod)
+
+	l.Root().PushFormatFunc(func(v any) ([]log.Op, bool) {
+		ep, ok := v.(Endpoint)
+		if !ok {
+			return nil, false
+		}
+
+		var ops = []log.Op{
+			log.OpColor{Color: log.Cyan},
+			log.OpText{Text: ep.host},
+			log.OpReset{},
+			log.OpColor{Color: log.White},
+			log.OpText{Text: ":"},
+			log.OpReset{},
+			log.OpColor{Color: log.Cyan},
+			log.OpText{Text: strconv.Itoa(int(ep.port))},
+			log.OpReset{},
+		}
+
+		if ep.path != "/" {
+			ops = append(ops,
+				log.OpColor{Color: log.White},
+				log.OpText{Text: ep.path},
+				log.OpReset{},
+			)
+		}
+
+		return ops, true
+	})
+
+	return mod, nil
+}
+
+func init() {
+	if err := modules.RegisterModule(ws.ModuleName, Loader{}); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,34 @@
+package ws
+
+import (
+	"context"
+	"github.com/cryptopunkscc/astrald/log"
+	"github.com/cryptopunkscc/astrald/mod/ws"
+	"github.com/cryptopunkscc/astrald/node"
+	"github.com/cryptopunkscc/astrald/tasks"
+)
+
+var _ ws.Module = &Module{}
+
+type Module struct {
+	config          Config
+	node            node.Node
+	log             *log.Logger
+	ctx             context.Context
+	publicEndpoints []Endpoint
+	proxy           proxyFunc
+}
+
+func (mod *Module) Run(ctx context.Context) error {
+	mod.ctx = ctx
+
+	tasks.Group(NewServer(mod)).Run(ctx)
+
+	<-ctx.Done()
+
+	return nil
+}
+
+func (mod *Module) ListenPort() int {
+	return mod.config.ListenPort
+}
@@ -0,0 +1,61 @@
+package ws
+
+import (
+	"errors"
+	"github.com/cryptopunkscc/astrald/mod/ws"
+	"github.com/cryptopunkscc/astrald/net"
+	"github.com/cryptopunkscc/astrald/node/infra"
+	"net/url"
+	"strconv"
+)
+
+func (mod *Module) Parse(network string, address string) (net.Endpoint, error) {
+	switch network {
+	case ws.NetworkName, ws.SecureNetworkName:
+	default:
+		return nil, infra.ErrUnsupportedNetwork
+	}
+
+	return ParseURL(network + "://" + address)
+}
+
+// ParseURL parses a WebSocket URL, like wss://example.com/astral. The port defaults to the standard
+// port of the scheme.
+func ParseURL(s string) (endpoint Endpoint, err error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return
+	}
+
+	var port = 80
+	switch u.Scheme {
+	case ws.NetworkName:
+	case ws.SecureNetworkName:
+		endpoint.secure = true
+		port = 443
+	default:
+		return endpoint, errors.New("invalid scheme")
+	}
+
+	endpoint.host = u.Hostname()
+	if endpoint.host == "" {
+		return endpoint, errors.New("missing host")
+	}
+
+	if p := u.Port(); p != "" {
+		if port, err = strconv.Atoi(p); err != nil {
+			return
+		}
+		if (port < 0) || (port > 65535) {
+			return endpoint, errors.New("port out of range")
+		}
+	}
+	endpoint.port = uint16(port)
+
+	endpoint.path = u.Path
+	if endpoint.path == "" {
+		endpoint.path = "/"
+	}
+
+	return
+}
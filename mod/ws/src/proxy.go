@@ -0,0 +1,119 @@
+package ws
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"errors"
+	"golang.org/x/net/http/httpproxy"
+	_net "net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// proxyFunc returns the URL of the proxy to use for an endpoint or nil for a direct connection
+type proxyFunc func(e Endpoint) (*url.URL, error)
+
+// fixedProxy always uses the same proxy
+func fixedProxy(proxyURL *url.URL) proxyFunc {
+	return func(Endpoint) (*url.URL, error) {
+		return proxyURL, nil
+	}
+}
+
+// environmentProxy picks the proxy from the HTTPS_PROXY, HTTP_PROXY and NO_PROXY variables
+func environmentProxy() proxyFunc {
+	var fn = httpproxy.FromEnvironment().ProxyFunc()
+
+	return func(e Endpoint) (*url.URL, error) {
+		u, err := url.Parse(e.httpURL())
+		if err != nil {
+			return nil, err
+		}
+		return fn(u)
+	}
+}
+
+// dialProxy opens a tunnel to the address through an HTTP proxy using the CONNECT method
+func dialProxy(ctx context.Context, proxyURL *url.URL, address string) (_net.Conn, error) {
+	if proxyURL.Scheme != "http" {
+		return nil, errors.New("unsupported proxy scheme: " + proxyURL.Scheme)
+	}
+
+	var proxyAddr = proxyURL.Host
+	if proxyURL.Port() == "" {
+		proxyAddr = _net.JoinHostPort(proxyURL.Hostname(), "80")
+	}
+
+	var dialer _net.Dialer
+
+	conn, err := dialer.DialContext(ctx, "tcp", proxyAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	var stop = context.AfterFunc(ctx, func() {
+		conn.SetDeadline(time.Now())
+	})
+
+	r, err := connect(conn, proxyURL, address)
+	if !stop() {
+		err = ctx.Err()
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	// keep anything the proxy sent after its response
+	if r.Buffered() > 0 {
+		return &bufferedConn{Conn: conn, r: r}, nil
+	}
+
+	return conn, nil
+}
+
+// connect asks the proxy to open a tunnel to the address
+func connect(conn _net.Conn, proxyURL *url.URL, address string) (*bufio.Reader, error) {
+	var req = &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: address},
+		Host:   address,
+		Header: http.Header{},
+	}
+
+	if user := proxyURL.User; user != nil {
+		password, _ := user.Password()
+		var credentials = base64.StdEncoding.EncodeToString([]byte(user.Username() + ":" + password))
+		req.Header.Set("Proxy-Authorization", "Basic "+credentials)
+	}
+
+	if err := req.Write(conn); err != nil {
+		return nil, err
+	}
+
+	var r = bufio.NewReader(conn)
+
+	res, err := http.ReadResponse(r, req)
+	if err != nil {
+		return nil, err
+	}
+
+	// the body of a successful response is the tunnel, so it's left unread
+	if res.StatusCode != http.StatusOK {
+		return nil, errors.New("proxy refused to connect: " + res.Status)
+	}
+
+	return r, nil
+}
+
+// bufferedConn is a connection with some of its input already buffered
+type bufferedConn struct {
+	_net.Conn
+	r *bufio.Reader
+}
+
+func (conn *bufferedConn) Read(p []byte) (int, error) {
+	return conn.r.Read(p)
+}
@@ -0,0 +1,108 @@
+package ws
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"github.com/cryptopunkscc/astrald/node/link"
+	"golang.org/x/net/websocket"
+	_net "net"
+	"net/http"
+	"strconv"
+)
+
+type Server struct {
+	*Module
+}
+
+func NewServer(module *Module) *Server {
+	return &Server{Module: module}
+}
+
+func (srv *Server) Run(ctx context.Context) error {
+	// start the listener
+	var addrStr = ":" + strconv.Itoa(srv.config.ListenPort)
+
+	listener, err := _net.Listen("tcp", addrStr)
+	if err != nil {
+		srv.log.Errorv(0, "failed to start server: %v", err)
+		return err
+	}
+
+	var endpoint = endpointFromAddr(listener.Addr().String(), srv.secure(), srv.config.Path)
+
+	srv.log.Info("started server at %v", endpoint)
+	defer srv.log.Info("stopped server at %v", endpoint)
+
+	var mux = http.NewServeMux()
+	mux.Handle(srv.config.Path, srv.handler(func(conn *Conn) {
+		l, err := link.Accept(ctx, conn, srv.node.Identity(), srv.node.Network().LinkConfig())
+		if err != nil {
+			srv.log.Errorv(1, "handshake failed from %v: %v", conn.RemoteEndpoint(), err)
+			return
+		}
+
+		err = srv.node.Network().AddLink(l)
+		if err != nil {
+			l.Close()
+			return
+		}
+
+		<-l.Done()
+	}))
+
+	var server = newHTTPServer(mux)
+
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+
+	if srv.secure() {
+		err = server.ServeTLS(listener, srv.config.TLSCert, srv.config.TLSKey)
+	} else {
+		err = server.Serve(listener)
+	}
+
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+
+	return err
+}
+
+// secure returns true if the server accepts wss connections
+func (mod *Module) secure() bool {
+	return mod.config.TLSCert != "" && mod.config.TLSKey != ""
+}
+
+// handler returns an HTTP handler that upgrades requests to WebSocket connections and passes them
+// to fn. The connection is closed when fn returns.
+func (mod *Module) handler(fn func(conn *Conn)) http.Handler {
+	return websocket.Server{
+		// nodes don't send the Origin header browsers do
+		Handshake: func(*websocket.Config, *http.Request) error {
+			return nil
+		},
+		Handler: func(wsConn *websocket.Conn) {
+			var req = wsConn.Request()
+			var secure = req.TLS != nil
+
+			var local Endpoint
+			if addr, ok := req.Context().Value(http.LocalAddrContextKey).(_net.Addr); ok {
+				local = endpointFromAddr(addr.String(), secure, req.URL.Path)
+			}
+			var remote = endpointFromAddr(req.RemoteAddr, secure, req.URL.Path)
+
+			fn(wrapWSConn(wsConn, local, remote, false))
+		},
+	}
+}
+
+func newHTTPServer(handler http.Handler) *http.Server {
+	return &http.Server{
+		Handler: handler,
+		// WebSocket needs to take over the connection, which HTTP/2 doesn't allow
+		TLSNextProto: map[string]func(*http.Server, *tls.Conn, http.Handler){},
+	}
+}
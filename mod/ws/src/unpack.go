@@ -0,0 +1,30 @@
+package ws
+
+import (
+	"bytes"
+	"github.com/cryptopunkscc/astrald/cslq"
+	"github.com/cryptopunkscc/astrald/mod/ws"
+	"github.com/cryptopunkscc/astrald/net"
+	"github.com/cryptopunkscc/astrald/node/infra"
+)
+
+var _ infra.Unpacker = &Module{}
+
+func (mod *Module) Unpack(network string, data []byte) (net.Endpoint, error) {
+	switch network {
+	case ws.NetworkName, ws.SecureNetworkName:
+	default:
+		return nil, infra.ErrUnsupportedNetwork
+	}
+	return Unpack(network, data)
+}
+
+func Unpack(network string, buf []byte) (addr Endpoint, err error) {
+	var r = bytes.NewReader(buf)
+
+	addr.secure = network == ws.SecureNetworkName
+
+	err = cslq.Decode(r, "[c]c s [c]c", &addr.host, &addr.port, &addr.path)
+
+	return
+}
@@ -0,0 +1,183 @@
+package ws
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"github.com/cryptopunkscc/astrald/auth/id"
+	"github.com/cryptopunkscc/astrald/node/link"
+	"io"
+	_net "net"
+	"net/http"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	var tests = []struct {
+		url     string
+		network string
+		address string
+	}{
+		{"ws://127.0.0.1:1792", "ws", "127.0.0.1:1792"},
+		{"wss://example.com/astral", "wss", "example.com:443/astral"},
+		{"ws://[::1]/", "ws", "[::1]:80"},
+	}
+
+	for _, test := range tests {
+		e, err := ParseURL(test.url)
+		if err != nil {
+			t.Fatalf("%s: %v", test.url, err)
+		}
+		if e.Network() != test.network || e.String() != test.address {
+			t.Fatalf("%s: parsed as %s %s", test.url, e.Network(), e)
+		}
+
+		unpacked, err := Unpack(e.Network(), e.Pack())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if unpacked != e {
+			t.Fatalf("%s: unpacked as %v", test.url, unpacked.URL())
+		}
+	}
+
+	if _, err := ParseURL("http://example.com"); err == nil {
+		t.Fatal("parsed a non-websocket url")
+	}
+}
+
+func TestLoopbackLink(t *testing.T) {
+	var mod = &Module{config: defaultConfig, proxy: fixedProxy(nil)}
+
+	testLink(t, mod)
+}
+
+func TestProxyLink(t *testing.T) {
+	listener, err := _net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	var tunnels atomic.Int32
+	go serveProxy(listener, &tunnels)
+
+	var mod = &Module{
+		config: defaultConfig,
+		proxy:  fixedProxy(&url.URL{Scheme: "http", Host: listener.Addr().String(), User: url.UserPassword("user", "pass")}),
+	}
+
+	testLink(t, mod)
+
+	if tunnels.Load() != 1 {
+		t.Fatalf("expected 1 tunnel through the proxy, got %d", tunnels.Load())
+	}
+}
+
+// testLink establishes a link over a WebSocket connection dialed by mod
+func testLink(t *testing.T, mod *Module) {
+	var ctx, cancel = context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var serverID, _ = id.GenerateIdentity()
+	var clientID, _ = id.GenerateIdentity()
+	var msg = []byte("hello over websocket")
+
+	listener, err := _net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var errCh = make(chan error, 1)
+	var server = newHTTPServer(mod.handler(func(conn *Conn) {
+		l, err := link.Accept(ctx, conn, serverID, link.DefaultConfig())
+		if err != nil {
+			errCh <- err
+			return
+		}
+		defer l.Close()
+
+		var buf = make([]byte, len(msg))
+		if _, err := io.ReadFull(l.Transport(), buf); err != nil {
+			errCh <- err
+			return
+		}
+		if !bytes.Equal(buf, msg) {
+			t.Errorf("received '%s', expected '%s'", buf, msg)
+		}
+		errCh <- nil
+	}))
+	go server.Serve(listener)
+	defer server.Close()
+
+	endpoint, err := ParseURL("ws://" + listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn, err := mod.Dial(ctx, endpoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := link.Open(ctx, conn, serverID, clientID, link.DefaultConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	if _, err := l.Transport().Write(msg); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := <-errCh; err != nil {
+		t.Fatal(err)
+	}
+}
+
+// serveProxy runs a minimal HTTP CONNECT proxy that requires basic authentication
+func serveProxy(listener _net.Listener, tunnels *atomic.Int32) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+
+		go func(conn _net.Conn) {
+			defer conn.Close()
+
+			var r = bufio.NewReader(conn)
+			req, err := http.ReadRequest(r)
+			if err != nil {
+				return
+			}
+
+			if user, pass, ok := parseProxyAuth(req); req.Method != http.MethodConnect || !ok || user != "user" || pass != "pass" {
+				io.WriteString(conn, "HTTP/1.1 407 Proxy Authentication Required\r\n\r\n")
+				return
+			}
+
+			target, err := _net.Dial("tcp", req.Host)
+			if err != nil {
+				io.WriteString(conn, "HTTP/1.1 502 Bad Gateway\r\n\r\n")
+				return
+			}
+			defer target.Close()
+
+			tunnels.Add(1)
+			io.WriteString(conn, "HTTP/1.1 200 Connection established\r\n\r\n")
+
+			go io.Copy(target, r)
+			io.Copy(conn, target)
+		}(conn)
+	}
+}
+
+func parseProxyAuth(req *http.Request) (user, pass string, ok bool) {
+	// reuse the parser of the Authorization header
+	var r = &http.Request{Header: http.Header{"Authorization": req.Header["Proxy-Authorization"]}}
+	return r.BasicAuth()
+}
@@ -0,0 +1,182 @@
+package mux
+
+import (
+	"bytes"
+	"compress/flate"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// Compression is a method of compressing frame data
+type Compression int
+
+const (
+	CompressionNone Compression = iota
+	CompressionDeflate
+)
+
+// minCompressSize is the size below which frames are not worth compressing
+const minCompressSize = 64
+
+func (c Compression) String() string {
+	switch c {
+	case CompressionNone:
+		return "none"
+	case CompressionDeflate:
+		return "deflate"
+	}
+	return "unknown"
+}
+
+// Supported returns true if frames compressed with the method can be encoded and decoded
+func (c Compression) Supported() bool {
+	switch c {
+	case CompressionNone, CompressionDeflate:
+		return true
+	}
+	return false
+}
+
+// CompressionStats holds the number of bytes passed through a compressed stream
+type CompressionStats struct {
+	Data uint64 // bytes before compression
+	Wire uint64 // bytes sent or received over the transport
+}
+
+// Ratio returns the compression ratio or 0 if nothing was compressed yet
+func (stats CompressionStats) Ratio() float64 {
+	if stats.Wire == 0 {
+		return 0
+	}
+	return float64(stats.Data) / float64(stats.Wire)
+}
+
+// Add returns the sum of two stats
+func (stats CompressionStats) Add(other CompressionStats) CompressionStats {
+	return CompressionStats{
+		Data: stats.Data + other.Data,
+		Wire: stats.Wire + other.Wire,
+	}
+}
+
+type compressionCounter struct {
+	data atomic.Uint64
+	wire atomic.Uint64
+}
+
+func (c *compressionCounter) add(data int, wire int) {
+	c.data.Add(uint64(data))
+	c.wire.Add(uint64(wire))
+}
+
+func (c *compressionCounter) stats() CompressionStats {
+	return CompressionStats{Data: c.data.Load(), Wire: c.wire.Load()}
+}
+
+var flateWriters = sync.Pool{
+	New: func() any {
+		w, _ := flate.NewWriter(nil, flate.BestSpeed)
+		return w
+	},
+}
+
+var flateReaders = sync.Pool{
+	New: func() any {
+		return flate.NewReader(nil)
+	},
+}
+
+// encodeFrame encodes frame data for a stream using the provided compression. On compressed streams every
+// non-empty frame starts with a byte holding the method used for the rest of the frame, so that frames which
+// don't benefit from compression can be sent as they are.
+func encodeFrame(compression Compression, data []byte) ([]byte, error) {
+	if compression == CompressionNone || len(data) == 0 {
+		return data, nil
+	}
+
+	var buf = &bytes.Buffer{}
+	buf.Grow(len(data) + 1)
+
+	if len(data) >= minCompressSize {
+		buf.WriteByte(byte(compression))
+		if err := compress(buf, compression, data); err != nil {
+			return nil, err
+		}
+		if buf.Len() < len(data)+1 {
+			return buf.Bytes(), nil
+		}
+		buf.Reset()
+	}
+
+	if len(data)+1 > MaxFrameSize {
+		return nil, ErrFrameTooLarge
+	}
+
+	buf.WriteByte(byte(CompressionNone))
+	buf.Write(data)
+
+	return buf.Bytes(), nil
+}
+
+// decodeFrame decodes frame data received from a stream using the provided compression
+func decodeFrame(compression Compression, data []byte) ([]byte, error) {
+	if compression == CompressionNone || len(data) == 0 {
+		return data, nil
+	}
+
+	var method = Compression(data[0])
+
+	switch method {
+	case CompressionNone:
+		if len(data) == 1 {
+			return nil, ErrInvalidFrame
+		}
+		return data[1:], nil
+
+	case CompressionDeflate:
+		return decompress(method, data[1:])
+	}
+
+	return nil, ErrInvalidFrame
+}
+
+func compress(w io.Writer, compression Compression, data []byte) error {
+	switch compression {
+	case CompressionDeflate:
+		var fw = flateWriters.Get().(*flate.Writer)
+		defer flateWriters.Put(fw)
+
+		fw.Reset(w)
+		if _, err := fw.Write(data); err != nil {
+			return err
+		}
+		return fw.Close()
+	}
+
+	return ErrUnsupportedCompression
+}
+
+func decompress(compression Compression, data []byte) ([]byte, error) {
+	switch compression {
+	case CompressionDeflate:
+		var fr = flateReaders.Get().(io.ReadCloser)
+		defer flateReaders.Put(fr)
+
+		if err := fr.(flate.Resetter).Reset(bytes.NewReader(data), nil); err != nil {
+			return nil, err
+		}
+
+		// limit the output so that a malicious frame cannot inflate beyond the frame size
+		out, err := io.ReadAll(io.LimitReader(fr, MaxFrameSize+1))
+		switch {
+		case err != nil:
+			return nil, ErrInvalidFrame
+		case len(out) > MaxFrameSize, len(out) == 0:
+			return nil, ErrInvalidFrame
+		}
+		return out, nil
+	}
+
+	return nil, ErrUnsupportedCompression
+}
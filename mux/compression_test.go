@@ -0,0 +1,34 @@
+package mux
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+)
+
+func TestEncodeFrame(t *testing.T) {
+	// incompressible frames are sent as they are, which takes an extra byte
+	var data = make([]byte, MaxPayloadSize)
+	rand.Read(data)
+
+	frame, err := encodeFrame(CompressionDeflate, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(frame) > MaxFrameSize {
+		t.Fatalf("encoded frame has %d bytes", len(frame))
+	}
+
+	decoded, err := decodeFrame(CompressionDeflate, frame)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decoded, data) {
+		t.Fatal("decoded frame doesn't match")
+	}
+
+	var mux = NewFrameMux(&bytes.Buffer{}, nil)
+	if err := mux.Write(Frame{Data: make([]byte, MaxPayloadSize+1)}); err != ErrFrameTooLarge {
+		t.Fatalf("expected ErrFrameTooLarge, got %v", err)
+	}
+}
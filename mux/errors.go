@@ -9,3 +9,5 @@ var ErrPortNotInUse = errors.New("port not in use")
 var ErrAllPortsUsed = errors.New("all ports used")
 var ErrPortClosed = errors.New("port closed")
 var ErrCloseUnsupported = errors.New("transport does not support closing")
+var ErrInvalidFrame = errors.New("invalid frame")
+var ErrUnsupportedCompression = errors.New("unsupported compression")
@@ -10,17 +10,23 @@ import (
 type FrameMux struct {
 	mu             sync.Mutex
 	mux            *RawMux
+	sched          *Scheduler
 	portHandlers   map[int]HandlerFunc
 	defaultHandler HandlerFunc
 	logID          int
 	nextPort       int
+	inbound        atomic.Int32 // compression of received frames
+	decompressed   compressionCounter
 }
 
 var nextID atomic.Int64
 
 func NewFrameMux(transport io.ReadWriter, defaultHandler HandlerFunc) *FrameMux {
+	var raw = NewRawMux(transport)
+
 	return &FrameMux{
-		mux:            NewRawMux(transport),
+		mux:            raw,
+		sched:          NewScheduler(raw),
 		portHandlers:   make(map[int]HandlerFunc),
 		defaultHandler: defaultHandler,
 	}
@@ -36,11 +42,21 @@ func (mux *FrameMux) Run(ctx context.Context) error {
 	frame.Mux = mux
 
 	for {
-		frame.Port, frame.Data, err = mux.mux.Read()
+		var data []byte
+		frame.Port, data, err = mux.mux.Read()
 		if err != nil {
 			return err
 		}
 
+		var compression = Compression(mux.inbound.Load())
+		frame.Data, err = decodeFrame(compression, data)
+		if err != nil {
+			return err
+		}
+		if compression != CompressionNone {
+			mux.decompressed.add(len(frame.Data), len(data))
+		}
+
 		handler := mux.portHandler(frame.Port)
 		if handler != nil {
 			handler(frame)
@@ -120,13 +136,73 @@ func (mux *FrameMux) Unbind(port int) error {
 	return mux.unbind(port)
 }
 
+// Write queues a frame for writing and waits until it's written. When many ports write at the same time,
+// frames are interleaved according to the weights of their remote ports.
 func (mux *FrameMux) Write(frame Frame) error {
-	return mux.mux.Write(frame.Port, frame.Data)
+	return mux.sched.Write(frame.Port, frame.Data)
 }
 
 // Close sends an EOF frame to the specified remote port
 func (mux *FrameMux) Close(remotePort int) error {
-	return mux.mux.Write(remotePort, []byte{})
+	return mux.sched.Write(remotePort, []byte{})
+}
+
+// SetWeight sets the share of the transport that frames written to the remote port get when it's congested.
+// Weight of 0 restores DefaultWeight.
+func (mux *FrameMux) SetWeight(remotePort int, weight int) {
+	mux.sched.SetWeight(remotePort, weight)
+}
+
+// SwitchCompression writes a frame and compresses all frames written after it using the provided method.
+// The remote party has to switch its inbound compression when it handles the frame.
+func (mux *FrameMux) SwitchCompression(frame Frame, compression Compression) error {
+	return mux.sched.WriteSwitch(frame.Port, frame.Data, compression)
+}
+
+// WriteThen writes a frame and calls fn after the frame is written, before any other frame is written
+func (mux *FrameMux) WriteThen(frame Frame, fn func()) error {
+	return mux.sched.WriteThen(frame.Port, frame.Data, fn)
+}
+
+// Post queues a frame without waiting for it to be written. Frame handlers use it to reply without blocking
+// the read loop.
+func (mux *FrameMux) Post(frame Frame) error {
+	return mux.sched.Post(frame.Port, frame.Data)
+}
+
+// PostThen queues a frame like Post and calls fn after the frame is written, before any other frame is written
+func (mux *FrameMux) PostThen(frame Frame, fn func()) error {
+	return mux.sched.PostThen(frame.Port, frame.Data, fn)
+}
+
+// SetInboundCompression sets the compression of frames read after the current one. It should be called from
+// a frame handler, which runs before the next frame is read.
+func (mux *FrameMux) SetInboundCompression(compression Compression) error {
+	if !compression.Supported() {
+		return ErrUnsupportedCompression
+	}
+	mux.inbound.Store(int32(compression))
+	return nil
+}
+
+// Compression returns compression methods used for received and written frames
+func (mux *FrameMux) Compression() (inbound Compression, outbound Compression) {
+	return Compression(mux.inbound.Load()), mux.sched.Compression()
+}
+
+// CompressionStats returns the number of bytes received and written while compression was on
+func (mux *FrameMux) CompressionStats() (inbound CompressionStats, outbound CompressionStats) {
+	return mux.decompressed.stats(), mux.sched.CompressionStats()
+}
+
+// Scheduler returns the scheduler that orders frames written by the multiplexer
+func (mux *FrameMux) Scheduler() *Scheduler {
+	return mux.sched
+}
+
+// Weight returns the scheduling weight of a remote port
+func (mux *FrameMux) Weight(remotePort int) int {
+	return mux.sched.Weight(remotePort)
 }
 
 // Unbind unbinds any handler assigned to the specified port.
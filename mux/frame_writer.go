@@ -23,14 +23,14 @@ func (writer *FrameWriter) Write(p []byte) (n int, err error) {
 	left := p[:]
 
 	for len(left) > 0 {
-		chunkLen := MaxFrameSize
+		chunkLen := MaxPayloadSize
 		if chunkLen > len(left) {
 			chunkLen = len(left)
 		}
 
 		writer.mu.Lock()
 		if writer.err == nil {
-			err = writer.mux.mux.Write(writer.port, left[0:chunkLen])
+			err = writer.mux.Write(Frame{Port: writer.port, Data: left[0:chunkLen]})
 		} else {
 			err = writer.err
 		}
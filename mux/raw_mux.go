@@ -14,6 +14,10 @@ const MaxPorts = 1 << 16
 // so this cannot exceed (1<<16)-1.
 const MaxFrameSize = 1<<16 - 1
 
+// MaxPayloadSize - maximum size of data in a frame written to a FrameMux. Frames on compressed streams start
+// with a byte holding the compression method, so their data has to leave room for it.
+const MaxPayloadSize = MaxFrameSize - 1
+
 // frameFormat - cslq format of a data frame
 const frameFormat = "s[s]c"
 
@@ -49,19 +53,13 @@ func (mux *RawMux) Write(port int, frame []byte) (err error) {
 		return ErrFrameTooLarge
 	}
 
-	err = binary.Write(mux.transport, binary.BigEndian, uint16(port))
-	if err != nil {
-		return
-	}
+	// write the whole frame at once, so that the transport sees frames as single writes
+	var buf = make([]byte, 4, 4+len(frame))
+	binary.BigEndian.PutUint16(buf[0:], uint16(port))
+	binary.BigEndian.PutUint16(buf[2:], uint16(len(frame)))
+	buf = append(buf, frame...)
 
-	err = binary.Write(mux.transport, binary.BigEndian, uint16(len(frame)))
-	if err != nil {
-		return
-	}
-
-	if len(frame) > 0 {
-		_, err = mux.transport.Write(frame)
-	}
+	_, err = mux.transport.Write(buf)
 
 	return err
 }
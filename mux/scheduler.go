@@ -0,0 +1,292 @@
+package mux
+
+import (
+	"container/heap"
+	"sync"
+)
+
+// DefaultWeight is the scheduling weight of ports that have no weight set
+const DefaultWeight = 4
+
+// frameCost is the fixed cost of a frame added to its data length, so that empty frames also take their turn
+const frameCost = 4
+
+// costScale keeps finish tags integer while dividing costs by weights
+const costScale = 1 << 16
+
+// maxIdleTags is the number of finish tags kept before tags of idle ports are cleaned up
+const maxIdleTags = 256
+
+// Scheduler serializes writes of many ports to a single RawMux using weighted fair queuing. Every queued frame
+// gets a virtual start tag, which is the current virtual time or the finish tag of the port's previous frame,
+// whichever is later, and a finish tag that's larger than the start tag by the frame's size divided by the port's
+// weight. Frames are written in the order of their finish tags and the virtual time follows the start tag of the
+// frame being written. A port with twice the weight gets twice the share of the transport when it's congested,
+// while an idle transport is always used in full.
+//
+// The scheduler also compresses frames right before they're written, so that switching the compression takes
+// effect exactly after a chosen frame.
+type Scheduler struct {
+	raw       *RawMux
+	mu        sync.Mutex
+	queue     frameQueue
+	weights   map[int]int
+	finish    map[int]uint64 // finish tag of the last frame queued for a port
+	vtime     uint64         // start tag of the last frame written
+	maxFinish uint64         // the largest finish tag written so far
+	seq       uint64
+	flushing  bool
+	fifo      bool
+	err       error
+
+	compression Compression
+	compressed  compressionCounter
+}
+
+type queuedFrame struct {
+	port   int
+	data   []byte
+	start  uint64
+	finish uint64
+	seq    uint64
+	done   chan error
+
+	// compression to switch to after the frame is written
+	switchTo Compression
+	switches bool
+
+	// called after the frame is written, before any other frame
+	after func()
+}
+
+func NewScheduler(raw *RawMux) *Scheduler {
+	return &Scheduler{
+		raw:     raw,
+		weights: make(map[int]int),
+		finish:  make(map[int]uint64),
+	}
+}
+
+// Write queues a frame and waits until it's written to the transport. Frames written to the same port
+// are written in order.
+func (s *Scheduler) Write(port int, data []byte) error {
+	return s.write(&queuedFrame{port: port, data: data})
+}
+
+// WriteSwitch writes a frame like Write and switches compression of all frames written after it. The frame
+// itself is written using the previous compression.
+func (s *Scheduler) WriteSwitch(port int, data []byte, compression Compression) error {
+	if !compression.Supported() {
+		return ErrUnsupportedCompression
+	}
+
+	return s.write(&queuedFrame{port: port, data: data, switchTo: compression, switches: true})
+}
+
+// WriteThen writes a frame like Write and calls fn after the frame is written, but before any other frame is
+// written. It allows changes to the transport to take effect exactly after the frame. Fn must not write frames.
+func (s *Scheduler) WriteThen(port int, data []byte, fn func()) error {
+	return s.write(&queuedFrame{port: port, data: data, after: fn})
+}
+
+// Post queues a frame like Write, but returns without waiting for the frame to be written. It's meant for
+// control frames sent from the read loop, which must not block on the transport.
+func (s *Scheduler) Post(port int, data []byte) error {
+	return s.PostThen(port, data, nil)
+}
+
+// PostThen queues a frame like Post and calls fn after the frame is written, like WriteThen
+func (s *Scheduler) PostThen(port int, data []byte, fn func()) error {
+	_, err := s.enqueue(&queuedFrame{port: port, data: data, after: fn})
+	return err
+}
+
+// Compression returns the compression used for frames written to the transport
+func (s *Scheduler) Compression() Compression {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.compression
+}
+
+// CompressionStats returns the number of bytes written while compression was on
+func (s *Scheduler) CompressionStats() CompressionStats {
+	return s.compressed.stats()
+}
+
+func (s *Scheduler) write(frame *queuedFrame) error {
+	done, err := s.enqueue(frame)
+	if err != nil {
+		return err
+	}
+	return <-done
+}
+
+// enqueue queues a frame and returns a channel that receives the result of the write
+func (s *Scheduler) enqueue(frame *queuedFrame) (<-chan error, error) {
+	var port, data = frame.port, frame.data
+
+	if port < 0 || port > MaxPorts-1 {
+		return nil, ErrInvalidPort
+	}
+
+	if len(data) > MaxPayloadSize {
+		return nil, ErrFrameTooLarge
+	}
+
+	frame.done = make(chan error, 1)
+
+	s.mu.Lock()
+	if s.err != nil {
+		s.mu.Unlock()
+		return nil, s.err
+	}
+
+	frame.seq = s.seq
+	s.seq++
+	if !s.fifo {
+		if len(s.finish) > maxIdleTags {
+			s.forgetIdle()
+		}
+		frame.start = max(s.vtime, s.finish[port])
+		frame.finish = frame.start + uint64(len(data)+frameCost)*costScale/uint64(s.weight(port))
+		s.finish[port] = frame.finish
+	}
+
+	heap.Push(&s.queue, frame)
+
+	if !s.flushing {
+		s.flushing = true
+		go s.flush()
+	}
+	s.mu.Unlock()
+
+	return frame.done, nil
+}
+
+// SetWeight sets the scheduling weight of a port. Weight of 0 or less restores DefaultWeight.
+func (s *Scheduler) SetWeight(port int, weight int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if weight <= 0 {
+		delete(s.weights, port)
+		delete(s.finish, port)
+		return
+	}
+	s.weights[port] = weight
+}
+
+// SetFIFO makes the scheduler ignore weights and write frames in the order in which they were queued
+func (s *Scheduler) SetFIFO(fifo bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.fifo = fifo
+}
+
+// Weight returns the scheduling weight of a port
+func (s *Scheduler) Weight(port int) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.weight(port)
+}
+
+// Queued returns the number of frames waiting to be written
+func (s *Scheduler) Queued() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.queue.Len()
+}
+
+func (s *Scheduler) weight(port int) int {
+	if w, found := s.weights[port]; found {
+		return w
+	}
+	return DefaultWeight
+}
+
+// flush writes queued frames until the queue is empty
+func (s *Scheduler) flush() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for s.queue.Len() > 0 {
+		var frame = heap.Pop(&s.queue).(*queuedFrame)
+
+		s.vtime = frame.start
+		s.maxFinish = max(s.maxFinish, frame.finish)
+
+		var compression = s.compression
+
+		s.mu.Unlock()
+		data, err := encodeFrame(compression, frame.data)
+		if err != nil {
+			// the frame cannot be encoded, but the transport is fine
+			s.mu.Lock()
+			frame.done <- err
+			continue
+		}
+		err = s.raw.Write(frame.port, data)
+		if err == nil && compression != CompressionNone {
+			s.compressed.add(len(frame.data), len(data))
+		}
+		s.mu.Lock()
+
+		if err == nil && frame.switches {
+			s.compression = frame.switchTo
+		}
+		if err == nil && frame.after != nil {
+			frame.after()
+		}
+
+		frame.done <- err
+
+		if err != nil {
+			s.err = err
+			for s.queue.Len() > 0 {
+				heap.Pop(&s.queue).(*queuedFrame).done <- err
+			}
+		}
+	}
+
+	// the transport is idle, so ports that write next start from a clean slate
+	s.vtime = s.maxFinish
+	s.flushing = false
+}
+
+// forgetIdle removes finish tags that are behind the virtual time, as they don't affect scheduling anymore
+func (s *Scheduler) forgetIdle() {
+	for port, finish := range s.finish {
+		if finish <= s.vtime {
+			delete(s.finish, port)
+		}
+	}
+}
+
+// frameQueue is a min-heap of frames ordered by their finish tags
+type frameQueue []*queuedFrame
+
+func (q frameQueue) Len() int { return len(q) }
+
+func (q frameQueue) Less(i, j int) bool {
+	if q[i].finish == q[j].finish {
+		return q[i].seq < q[j].seq
+	}
+	return q[i].finish < q[j].finish
+}
+
+func (q frameQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *frameQueue) Push(x any) { *q = append(*q, x.(*queuedFrame)) }
+
+func (q *frameQueue) Pop() any {
+	old := *q
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = nil
+	*q = old[:n-1]
+	return item
+}
@@ -0,0 +1,139 @@
+package mux
+
+import (
+	"bytes"
+	"testing"
+)
+
+// pausedScheduler returns a scheduler that queues frames without writing them until flushed
+func pausedScheduler() (*Scheduler, *bytes.Buffer) {
+	var buf = &bytes.Buffer{}
+	var s = NewScheduler(NewRawMux(buf))
+	s.flushing = true
+	return s, buf
+}
+
+// written flushes the queue and returns the frames in the order they were written to the transport
+func written(t *testing.T, s *Scheduler, buf *bytes.Buffer) (ports []int, frames [][]byte) {
+	t.Helper()
+
+	s.flush()
+
+	var raw = NewRawMux(buf)
+	for buf.Len() > 0 {
+		port, frame, err := raw.Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		ports = append(ports, port)
+		frames = append(frames, frame)
+	}
+	return
+}
+
+func post(t *testing.T, s *Scheduler, port int, data []byte) {
+	t.Helper()
+
+	if err := s.Post(port, data); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSchedulerWeights(t *testing.T) {
+	var s, buf = pausedScheduler()
+	s.SetWeight(1, 2*DefaultWeight)
+
+	for i := 0; i < 30; i++ {
+		post(t, s, 1, make([]byte, 100))
+		post(t, s, 2, make([]byte, 100))
+	}
+
+	ports, _ := written(t, s, buf)
+	if len(ports) != 60 {
+		t.Fatalf("written %d frames, expected 60", len(ports))
+	}
+
+	// while both ports are backlogged, the port with twice the weight gets twice the share
+	var count = map[int]int{}
+	for _, port := range ports[:30] {
+		count[port]++
+	}
+	if count[1] < 19 || count[1] > 21 {
+		t.Fatalf("port 1 got %d of the first 30 frames, expected 20", count[1])
+	}
+}
+
+func TestSchedulerFrameCost(t *testing.T) {
+	var s, buf = pausedScheduler()
+
+	// a port that sends a short frame doesn't wait behind another port's backlog of large frames
+	for i := 0; i < 10; i++ {
+		post(t, s, 1, make([]byte, 1000))
+	}
+	post(t, s, 2, make([]byte, 10))
+
+	ports, _ := written(t, s, buf)
+	if ports[0] != 2 {
+		t.Fatalf("first frame written to port %d, expected 2", ports[0])
+	}
+}
+
+func TestSchedulerPortOrder(t *testing.T) {
+	var s, buf = pausedScheduler()
+	s.SetWeight(2, 1)
+
+	for i := 0; i < 20; i++ {
+		post(t, s, 1, []byte{byte(i)})
+		post(t, s, 2, make([]byte, 10*i))
+	}
+
+	ports, frames := written(t, s, buf)
+
+	// frames of a single port are written in order regardless of their cost
+	var next = map[int]int{}
+	for i, port := range ports {
+		var n int
+		switch port {
+		case 1:
+			n = int(frames[i][0])
+		case 2:
+			n = len(frames[i]) / 10
+		}
+		if n != next[port] {
+			t.Fatalf("port %d wrote frame %d, expected %d", port, n, next[port])
+		}
+		next[port]++
+	}
+}
+
+func TestSchedulerFIFO(t *testing.T) {
+	var s, buf = pausedScheduler()
+	s.SetWeight(2, 16*DefaultWeight)
+	s.SetFIFO(true)
+
+	for i := 0; i < 10; i++ {
+		post(t, s, 1, make([]byte, 100))
+		post(t, s, 2, make([]byte, 100))
+	}
+
+	ports, _ := written(t, s, buf)
+	for i, port := range ports {
+		if port != i%2+1 {
+			t.Fatalf("frame %d written to port %d, expected %d", i, port, i%2+1)
+		}
+	}
+}
+
+func TestSchedulerSetWeight(t *testing.T) {
+	var s = NewScheduler(NewRawMux(&bytes.Buffer{}))
+
+	s.SetWeight(1, 10)
+	if w := s.Weight(1); w != 10 {
+		t.Fatalf("weight is %d, expected 10", w)
+	}
+
+	s.SetWeight(1, 0)
+	if w := s.Weight(1); w != DefaultWeight {
+		t.Fatalf("weight is %d, expected %d", w, DefaultWeight)
+	}
+}
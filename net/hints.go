@@ -1,12 +1,14 @@
 package net
 
 type Hints struct {
-	Origin  string // Origin denotes the where the query originated (OriginLocal or OriginNetwork)
-	Silent  bool   // Silent tells the router to not log the query
-	Reroute bool   // Reroute allows a nonce to reenter the router event though it's already en route
-	Update  bool   // Update tells the monitored router to update query details for the nonce when rerouting
-	Extra   map[string]any
-	_       struct{}
+	Origin   string   // Origin denotes the where the query originated (OriginLocal or OriginNetwork)
+	Silent   bool     // Silent tells the router to not log the query
+	Reroute  bool     // Reroute allows a nonce to reenter the router event though it's already en route
+	Update   bool     // Update tells the monitored router to update query details for the nonce when rerouting
+	Priority Priority // Priority sets the scheduling priority of the session on links
+	Resume   bool     // Resume lets links resume the session over a new route if they are lost
+	Extra    map[string]any
+	_        struct{}
 }
 
 func DefaultHints() Hints {
@@ -37,6 +39,18 @@ func (hints Hints) SetUpdate() Hints {
 	return clone
 }
 
+func (hints Hints) SetResume() Hints {
+	var clone = hints.clone()
+	clone.Resume = true
+	return clone
+}
+
+func (hints Hints) WithPriority(priority Priority) Hints {
+	var clone = hints.clone()
+	clone.Priority = priority
+	return clone
+}
+
 func (hints Hints) WithValue(key string, val any) Hints {
 	var clone = hints.clone()
 	if clone.Extra == nil {
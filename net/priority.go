@@ -0,0 +1,35 @@
+package net
+
+import "fmt"
+
+// Priority controls the share of link bandwidth a session gets when the link is congested
+type Priority int
+
+const (
+	PriorityBulk        = Priority(-1) // large transfers that should yield to other sessions
+	PriorityNormal      = Priority(0)
+	PriorityInteractive = Priority(1) // latency-sensitive sessions, like consoles and app control channels
+)
+
+func (p Priority) String() string {
+	switch {
+	case p < PriorityNormal:
+		return "bulk"
+	case p > PriorityNormal:
+		return "interactive"
+	}
+	return "normal"
+}
+
+// ParsePriority parses the name of a priority
+func ParsePriority(s string) (Priority, error) {
+	switch s {
+	case "bulk":
+		return PriorityBulk, nil
+	case "normal":
+		return PriorityNormal, nil
+	case "interactive":
+		return PriorityInteractive, nil
+	}
+	return PriorityNormal, fmt.Errorf("invalid priority: %s", s)
+}
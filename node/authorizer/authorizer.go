@@ -11,3 +11,8 @@ type AuthSet interface {
 type Authorizer interface {
 	Authorize(id id.Identity, action string, args ...any) bool
 }
+
+// Denier is an Authorizer that can also veto actions allowed by other authorizers
+type Denier interface {
+	Deny(id id.Identity, action string, args ...any) bool
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/cryptopunkscc/astrald/auth/id"
 	"github.com/cryptopunkscc/astrald/log"
+	"github.com/cryptopunkscc/astrald/node/events"
 	"github.com/cryptopunkscc/astrald/sig"
 	"reflect"
 	"strings"
@@ -14,31 +15,33 @@ var _ AuthSet = &CoreAuthorizer{}
 type CoreAuthorizer struct {
 	authorizers sig.Set[Authorizer]
 	log         *log.Logger
+	events      *events.Queue
 }
 
-func NewCoreAuthorizer(log *log.Logger) (*CoreAuthorizer, error) {
-	return &CoreAuthorizer{log: log}, nil
+func NewCoreAuthorizer(log *log.Logger, events *events.Queue) (*CoreAuthorizer, error) {
+	return &CoreAuthorizer{log: log, events: events}, nil
 }
 
 func (auth *CoreAuthorizer) Authorize(identity id.Identity, action string, args ...any) bool {
-	for _, a := range auth.authorizers.Clone() {
-		if a.Authorize(identity, action, args...) {
-			name := reflect.TypeOf(a).String()
-			if s, ok := a.(fmt.Stringer); ok {
-				name = s.String()
-			}
+	var authorizers = auth.authorizers.Clone()
 
-			var fmt = "allowed %v to %v" + strings.Repeat(" %v", len(args)) + " by %v"
-			var vals = []any{identity, action}
-			vals = append(vals, args...)
-			vals = append(vals, name)
+	// explicit denials take precedence
+	for _, a := range authorizers {
+		if d, ok := a.(Denier); ok && d.Deny(identity, action, args...) {
+			auth.decided(false, a, identity, action, args)
+			return false
+		}
+	}
 
-			auth.log.Infov(2, fmt, vals...)
+	for _, a := range authorizers {
+		if a.Authorize(identity, action, args...) {
+			auth.decided(true, a, identity, action, args)
 			return true
 		}
 	}
 
 	auth.log.Logv(2, "denied %v to %v", identity, action)
+	auth.emit(EventDecision{Identity: identity, Action: action, Args: args})
 
 	return false
 }
@@ -50,3 +53,39 @@ func (auth *CoreAuthorizer) Add(authorizer Authorizer) error {
 func (auth *CoreAuthorizer) Remove(authorizer Authorizer) error {
 	return auth.authorizers.Remove(authorizer)
 }
+
+// decided logs and emits a decision made by an authorizer
+func (auth *CoreAuthorizer) decided(allowed bool, a Authorizer, identity id.Identity, action string, args []any) {
+	var decision = "denied"
+	if allowed {
+		decision = "allowed"
+	}
+
+	var fmt = decision + " %v to %v" + strings.Repeat(" %v", len(args)) + " by %v"
+	var vals = []any{identity, action}
+	vals = append(vals, args...)
+	vals = append(vals, Name(a))
+
+	auth.log.Infov(2, fmt, vals...)
+	auth.emit(EventDecision{
+		Identity:   identity,
+		Action:     action,
+		Args:       args,
+		Allowed:    allowed,
+		Authorizer: Name(a),
+	})
+}
+
+func (auth *CoreAuthorizer) emit(e EventDecision) {
+	if auth.events != nil {
+		auth.events.Emit(e)
+	}
+}
+
+// Name returns the display name of an authorizer
+func Name(a Authorizer) string {
+	if s, ok := a.(fmt.Stringer); ok {
+		return s.String()
+	}
+	return reflect.TypeOf(a).String()
+}
@@ -0,0 +1,33 @@
+package authorizer
+
+import (
+	"fmt"
+	"github.com/cryptopunkscc/astrald/auth/id"
+	"strings"
+)
+
+// EventDecision is emitted for every authorization decision
+type EventDecision struct {
+	Identity   id.Identity
+	Action     string
+	Args       []any
+	Allowed    bool
+	Authorizer string // name of the authorizer that made the decision, empty if nothing allowed the action
+}
+
+func (e EventDecision) String() string {
+	var decision = "denied"
+	if e.Allowed {
+		decision = "allowed"
+	}
+
+	var s = fmt.Sprintf("%s %v to %s", decision, e.Identity, e.Action)
+	if len(e.Args) > 0 {
+		s += " " + strings.TrimSuffix(strings.TrimPrefix(fmt.Sprint(e.Args), "["), "]")
+	}
+	if e.Authorizer != "" {
+		s += " by " + e.Authorizer
+	}
+
+	return s
+}
@@ -1,11 +1,31 @@
 package node
 
+import "time"
+
 const configName = "node"
 
 type Config struct {
 	Identity      string   `yaml:"identity"`
 	Modules       []string `yaml:"modules"`
 	LogRouteTrace bool     `yaml:"log_route_trace"`
+
+	// MaxRemotePriority is the highest priority granted to queries from peers (bulk, normal or interactive)
+	MaxRemotePriority string `yaml:"max_remote_priority"`
+
+	// DisableCompression stops links from offering compression to peers
+	DisableCompression bool `yaml:"disable_compression"`
+
+	// ResumeGracePeriod is how long sessions of lost links wait to be resumed (default 30s)
+	ResumeGracePeriod time.Duration `yaml:"resume_grace_period"`
+
+	// ResumeWindow is the number of unacknowledged bytes each resumable session keeps for replay (default 1MB)
+	ResumeWindow int `yaml:"resume_window"`
+
+	// DisableResume stops links from running resumable sessions
+	DisableResume bool `yaml:"disable_resume"`
+
+	// ObfuscatedOnly drops incoming connections that aren't obfuscated
+	ObfuscatedOnly bool `yaml:"obfuscated_only"`
 }
 
 var defaultConfig = Config{}
@@ -4,10 +4,12 @@ import (
 	"errors"
 	"fmt"
 	"github.com/cryptopunkscc/astrald/auth/id"
+	"github.com/cryptopunkscc/astrald/net"
 	"github.com/cryptopunkscc/astrald/node/assets"
 	"github.com/cryptopunkscc/astrald/node/authorizer"
 	"github.com/cryptopunkscc/astrald/node/events"
 	"github.com/cryptopunkscc/astrald/node/infra"
+	"github.com/cryptopunkscc/astrald/node/link"
 	"github.com/cryptopunkscc/astrald/node/modules"
 	"github.com/cryptopunkscc/astrald/node/network"
 	"github.com/cryptopunkscc/astrald/node/resolver"
@@ -84,8 +86,13 @@ func NewCoreNode(nodeID id.Identity, res resources.Resources) (*CoreNode, error)
 		}
 	}
 
+	linkConfig, err := node.linkConfig()
+	if err != nil {
+		return nil, fmt.Errorf("error loading config: %w", err)
+	}
+
 	// authorizer
-	node.auth, err = authorizer.NewCoreAuthorizer(node.log.Tag("auth"))
+	node.auth, err = authorizer.NewCoreAuthorizer(node.log.Tag("auth"), &node.events)
 	if err != nil {
 		return nil, fmt.Errorf("error setting up authorizer: %w", err)
 	}
@@ -112,7 +119,7 @@ func NewCoreNode(nodeID id.Identity, res resources.Resources) (*CoreNode, error)
 	node.resolver = resolver.NewCoreResolver(node)
 
 	// network
-	node.network, err = network.NewCoreNetwork(node, &node.events, node.log)
+	node.network, err = network.NewCoreNetwork(node, linkConfig, &node.events, node.log)
 	if err != nil {
 		return nil, fmt.Errorf("error setting up peer manager: %w", err)
 	}
@@ -156,6 +163,38 @@ func (node *CoreNode) checkNodeAlias() error {
 	return node.tracker.SetAlias(node.identity, alias)
 }
 
+// linkConfig returns the config of links made from the node config
+func (node *CoreNode) linkConfig() (config link.Config, err error) {
+	config = link.DefaultConfig()
+
+	if node.config.MaxRemotePriority != "" {
+		config.MaxRemotePriority, err = net.ParsePriority(node.config.MaxRemotePriority)
+		if err != nil {
+			return
+		}
+	}
+
+	if node.config.DisableCompression {
+		config.Compression = nil
+	}
+
+	if node.config.ResumeGracePeriod > 0 {
+		config.ResumeGracePeriod = node.config.ResumeGracePeriod
+	}
+
+	if node.config.ResumeWindow > 0 {
+		config.ResumeWindow = node.config.ResumeWindow
+	}
+
+	if node.config.DisableResume {
+		config.ResumeGracePeriod = 0
+	}
+
+	config.ObfuscatedOnly = node.config.ObfuscatedOnly
+
+	return
+}
+
 func (node *CoreNode) Conns() *router.ConnSet {
 	return node.router.Conns()
 }
@@ -2,10 +2,15 @@ package infra
 
 import (
 	"context"
+	"github.com/cryptopunkscc/astrald/auth/obfs"
 	"github.com/cryptopunkscc/astrald/net"
 )
 
 func (infra *CoreInfra) Dial(ctx context.Context, addr net.Endpoint) (net.Conn, error) {
+	if _, ok := obfs.SplitNetwork(addr.Network()); ok {
+		return infra.dialObfs(ctx, addr)
+	}
+
 	infra.mu.RLock()
 	defer infra.mu.RUnlock()
 
@@ -26,3 +31,28 @@ func (infra *CoreInfra) SetDialer(network string, dialer Dialer) {
 		infra.dialers[network] = dialer
 	}
 }
+
+// dialObfs dials the underlying endpoint of an obfuscated endpoint and wraps the connection
+func (infra *CoreInfra) dialObfs(ctx context.Context, addr net.Endpoint) (net.Conn, error) {
+	e, ok := addr.(*obfs.Endpoint)
+	if !ok {
+		unpacked, err := infra.Unpack(addr.Network(), addr.Pack())
+		if err != nil {
+			return nil, err
+		}
+		e = unpacked.(*obfs.Endpoint)
+	}
+
+	conn, err := infra.Dial(ctx, e.Unwrap())
+	if err != nil {
+		return nil, err
+	}
+
+	oconn, err := obfs.Open(ctx, conn, e.Key())
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return oconn, nil
+}
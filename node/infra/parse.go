@@ -1,10 +1,25 @@
 package infra
 
 import (
+	"github.com/cryptopunkscc/astrald/auth/obfs"
 	"github.com/cryptopunkscc/astrald/net"
 )
 
 func (infra *CoreInfra) Parse(network string, address string) (net.Endpoint, error) {
+	if network, ok := obfs.SplitNetwork(network); ok {
+		key, address, err := obfs.SplitAddress(address)
+		if err != nil {
+			return nil, err
+		}
+
+		e, err := infra.Parse(network, address)
+		if err != nil {
+			return nil, err
+		}
+
+		return obfs.NewEndpoint(e, key), nil
+	}
+
 	infra.mu.RLock()
 	defer infra.mu.RUnlock()
 
@@ -1,10 +1,25 @@
 package infra
 
 import (
+	"github.com/cryptopunkscc/astrald/auth/obfs"
 	"github.com/cryptopunkscc/astrald/net"
 )
 
 func (infra *CoreInfra) Unpack(network string, data []byte) (net.Endpoint, error) {
+	if network, ok := obfs.SplitNetwork(network); ok {
+		key, data, err := obfs.SplitPacked(data)
+		if err != nil {
+			return nil, err
+		}
+
+		e, err := infra.Unpack(network, data)
+		if err != nil {
+			return nil, err
+		}
+
+		return obfs.NewEndpoint(e, key), nil
+	}
+
 	infra.mu.RLock()
 	defer infra.mu.RUnlock()
 
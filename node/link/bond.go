@@ -0,0 +1,647 @@
+package link
+
+import (
+	"bytes"
+	"errors"
+	"github.com/cryptopunkscc/astrald/auth/id"
+	"github.com/cryptopunkscc/astrald/cslq"
+	"github.com/cryptopunkscc/astrald/net"
+	"io"
+	"math/rand"
+	"slices"
+	"sync"
+	"time"
+)
+
+var _ net.SecureConn = &Bond{}
+
+const (
+	bondWindow         = 4 * 1024 * 1024 // max number of bytes written but not acknowledged by the remote party
+	bondSegmentWindow  = 4096            // max number of segments written but not acknowledged by the remote party
+	bondPathWindow     = 512 * 1024      // number of bytes in flight above which other paths are used as well
+	bondMaxSegmentSize = 1 << 17
+	bondAckThreshold   = 64 * 1024
+	bondAckDelay       = 10 * time.Millisecond
+	bondPingInterval   = 5 * time.Second
+	bondPathTimeout    = 30 * time.Second
+)
+
+// segment types
+const (
+	segData = iota
+	segAck
+	segPing
+	segPong
+	segFin
+)
+
+var ErrNoPaths = errors.New("no paths left")
+var ErrBondClosed = errors.New("bond closed")
+
+// Bond is a SecureConn that carries a single stream over many paths to the same remote party. Written data is
+// split into numbered segments which are sent over the path with the lowest latency, and spill over to other
+// paths when it's busy. Segments stay buffered until the remote party acknowledges them, so when a path fails,
+// segments that were sent over it are sent again over the remaining paths and the stream continues.
+type Bond struct {
+	id             uint64
+	localIdentity  id.Identity
+	remoteIdentity id.Identity
+	outbound       bool
+
+	mu    sync.Mutex
+	cond  *sync.Cond
+	paths []*BondPath
+	err   error
+
+	// sending
+	sendSeq      uint64
+	unacked      []*bondSegment
+	unackedBytes int
+
+	// receiving
+	recvSeq     uint64 // sequence number of the next segment to be queued
+	reorder     map[uint64][]byte
+	recvQueue   [][]byte
+	recvBytes   int // bytes in recvQueue and reorder
+	consumed    int // bytes read since the last ack
+	ackTimer    *time.Timer
+	onClose     func()
+	closeSignal chan struct{}
+}
+
+type bondSegment struct {
+	seq  uint64
+	data []byte
+	path *BondPath
+}
+
+// BondPath is a single connection used by a Bond
+type BondPath struct {
+	conn     net.SecureConn
+	bond     *Bond
+	wmu      sync.Mutex
+	inflight int // guarded by bond's mutex
+
+	pingMu    sync.Mutex
+	pingNonce uint32
+	pingAt    time.Time
+	pongAt    time.Time
+	latency   time.Duration
+	addedAt   time.Time
+}
+
+// NewBond returns a new Bond with the provided ID between two identities. Paths need to be added before
+// the bond can carry any data.
+func NewBond(bondID uint64, localIdentity id.Identity, remoteIdentity id.Identity, outbound bool) *Bond {
+	bond := &Bond{
+		id:             bondID,
+		localIdentity:  localIdentity,
+		remoteIdentity: remoteIdentity,
+		outbound:       outbound,
+		reorder:        map[uint64][]byte{},
+		closeSignal:    make(chan struct{}),
+	}
+	bond.cond = sync.NewCond(&bond.mu)
+	return bond
+}
+
+// ID returns the identifier of the bond shared by both parties
+func (bond *Bond) ID() uint64 {
+	return bond.id
+}
+
+// AddPath adds a new path to the bond. The conn has to be authenticated with the bond's identities.
+func (bond *Bond) AddPath(conn net.SecureConn) error {
+	if !conn.RemoteIdentity().IsEqual(bond.remoteIdentity) || !conn.LocalIdentity().IsEqual(bond.localIdentity) {
+		return ErrIdentityMismatch
+	}
+
+	var path = &BondPath{
+		conn:    conn,
+		bond:    bond,
+		latency: -1,
+		addedAt: time.Now(),
+	}
+
+	bond.mu.Lock()
+	if bond.err != nil {
+		bond.mu.Unlock()
+		return bond.err
+	}
+	bond.paths = append(bond.paths, path)
+	bond.cond.Broadcast()
+
+	// segments that had no path to go through
+	var resend []*bondSegment
+	for _, seg := range bond.unacked {
+		if seg.path == nil {
+			resend = append(resend, seg)
+		}
+	}
+	bond.mu.Unlock()
+
+	go path.readLoop()
+	go path.pingLoop()
+
+	for _, seg := range resend {
+		bond.send(seg)
+	}
+
+	return nil
+}
+
+// Paths returns all active paths of the bond
+func (bond *Bond) Paths() []*BondPath {
+	bond.mu.Lock()
+	defer bond.mu.Unlock()
+
+	return slices.Clone(bond.paths)
+}
+
+// Read reads data received from the remote party in the order in which it was written
+func (bond *Bond) Read(p []byte) (n int, err error) {
+	bond.mu.Lock()
+	defer bond.mu.Unlock()
+
+	for len(bond.recvQueue) == 0 {
+		if bond.err != nil {
+			return 0, io.EOF
+		}
+		bond.cond.Wait()
+	}
+
+	for len(p) > 0 && len(bond.recvQueue) > 0 {
+		var c = copy(p, bond.recvQueue[0])
+		bond.recvBytes -= c
+		n += c
+		p = p[c:]
+		if c == len(bond.recvQueue[0]) {
+			bond.recvQueue = bond.recvQueue[1:]
+		} else {
+			bond.recvQueue[0] = bond.recvQueue[0][c:]
+		}
+	}
+
+	bond.consumed += n
+	switch {
+	case bond.consumed >= bondAckThreshold:
+		go bond.sendAck()
+	case bond.ackTimer == nil:
+		bond.ackTimer = time.AfterFunc(bondAckDelay, bond.sendAck)
+	}
+
+	return n, nil
+}
+
+// Write writes data to the bond. It blocks while a segment would exceed the window of data waiting to be
+// acknowledged, which is also the amount of data the remote party buffers.
+func (bond *Bond) Write(p []byte) (n int, err error) {
+	for len(p) > 0 {
+		var size = min(len(p), bondMaxSegmentSize)
+
+		bond.mu.Lock()
+		for bond.err == nil && (bond.unackedBytes+size > bondWindow || len(bond.unacked) >= bondSegmentWindow) {
+			bond.cond.Wait()
+		}
+		if bond.err != nil {
+			bond.mu.Unlock()
+			return n, bond.err
+		}
+
+		var seg = &bondSegment{
+			seq:  bond.sendSeq,
+			data: bytes.Clone(p[:size]),
+		}
+		bond.sendSeq++
+		bond.unacked = append(bond.unacked, seg)
+		bond.unackedBytes += size
+		bond.mu.Unlock()
+
+		bond.send(seg)
+
+		n += size
+		p = p[size:]
+	}
+
+	return n, nil
+}
+
+// Close closes the bond and all its paths
+func (bond *Bond) Close() error {
+	return bond.closeWithError(ErrBondClosed, true)
+}
+
+// Done returns a channel that will be closed when the bond closes
+func (bond *Bond) Done() <-chan struct{} {
+	return bond.closeSignal
+}
+
+// Err returns the reason why the bond was closed or nil if it's open
+func (bond *Bond) Err() error {
+	bond.mu.Lock()
+	defer bond.mu.Unlock()
+
+	return bond.err
+}
+
+// Outbound returns true if the local party created the bond
+func (bond *Bond) Outbound() bool {
+	return bond.outbound
+}
+
+// LocalEndpoint returns the local endpoint of the preferred path
+func (bond *Bond) LocalEndpoint() net.Endpoint {
+	if path := bond.bestPath(); path != nil {
+		return path.conn.LocalEndpoint()
+	}
+	return nil
+}
+
+// RemoteEndpoint returns the remote endpoint of the preferred path
+func (bond *Bond) RemoteEndpoint() net.Endpoint {
+	if path := bond.bestPath(); path != nil {
+		return path.conn.RemoteEndpoint()
+	}
+	return nil
+}
+
+func (bond *Bond) LocalIdentity() id.Identity {
+	return bond.localIdentity
+}
+
+func (bond *Bond) RemoteIdentity() id.Identity {
+	return bond.remoteIdentity
+}
+
+func (bond *Bond) bestPath() *BondPath {
+	bond.mu.Lock()
+	defer bond.mu.Unlock()
+
+	return bond.pickPath(false)
+}
+
+// pickPath returns the path with the lowest latency. If spill is true, paths that have too much data
+// in flight are skipped unless all of them do.
+func (bond *Bond) pickPath(spill bool) *BondPath {
+	var paths = slices.Clone(bond.paths)
+
+	slices.SortStableFunc(paths, func(a, b *BondPath) int {
+		return cmpLatency(a.Latency(), b.Latency())
+	})
+
+	if len(paths) == 0 {
+		return nil
+	}
+
+	if spill {
+		for _, path := range paths {
+			if path.inflight < bondPathWindow {
+				return path
+			}
+		}
+		return slices.MinFunc(paths, func(a, b *BondPath) int {
+			return a.inflight - b.inflight
+		})
+	}
+
+	return paths[0]
+}
+
+// send sends a segment over the best path. If the path fails, the segment will be sent again by dropPath.
+func (bond *Bond) send(seg *bondSegment) {
+	bond.mu.Lock()
+	var path = bond.pickPath(true)
+	if path == nil {
+		bond.mu.Unlock()
+		return
+	}
+	seg.path = path
+	path.inflight += len(seg.data)
+	bond.mu.Unlock()
+
+	var buf = &bytes.Buffer{}
+	buf.Grow(len(seg.data) + 13)
+	cslq.Encode(buf, "cql", segData, seg.seq, uint32(len(seg.data)))
+	buf.Write(seg.data)
+
+	if err := path.write(buf.Bytes()); err != nil {
+		bond.dropPath(path, err)
+	}
+}
+
+func (bond *Bond) sendAck() {
+	bond.mu.Lock()
+	if bond.ackTimer != nil {
+		bond.ackTimer.Stop()
+		bond.ackTimer = nil
+	}
+	bond.consumed = 0
+	var ack = bond.recvSeq - uint64(len(bond.recvQueue))
+	var path = bond.pickPath(false)
+	bond.mu.Unlock()
+
+	if path == nil {
+		return
+	}
+
+	var buf = &bytes.Buffer{}
+	cslq.Encode(buf, "cq", segAck, ack)
+
+	if err := path.write(buf.Bytes()); err != nil {
+		bond.dropPath(path, err)
+	}
+}
+
+// handleData queues a received segment. Returns an error if the remote party sent more than its window allows.
+func (bond *Bond) handleData(seq uint64, data []byte) error {
+	bond.mu.Lock()
+	defer bond.mu.Unlock()
+
+	switch {
+	case seq < bond.recvSeq:
+		// the segment was sent again after a path failure, make sure the remote party knows we have it
+		if bond.ackTimer == nil {
+			bond.ackTimer = time.AfterFunc(bondAckDelay, bond.sendAck)
+		}
+		return nil
+
+	case seq-(bond.recvSeq-uint64(len(bond.recvQueue))) >= bondSegmentWindow:
+		return ErrProtocolError
+
+	case bond.recvBytes+len(data) > bondWindow:
+		return ErrProtocolError
+
+	case seq > bond.recvSeq:
+		if _, found := bond.reorder[seq]; !found {
+			bond.reorder[seq] = data
+			bond.recvBytes += len(data)
+		}
+		return nil
+	}
+
+	bond.recvQueue = append(bond.recvQueue, data)
+	bond.recvBytes += len(data)
+	bond.recvSeq++
+
+	for {
+		next, found := bond.reorder[bond.recvSeq]
+		if !found {
+			break
+		}
+		delete(bond.reorder, bond.recvSeq)
+		bond.recvQueue = append(bond.recvQueue, next)
+		bond.recvSeq++
+	}
+
+	bond.cond.Broadcast()
+
+	return nil
+}
+
+func (bond *Bond) handleAck(ack uint64) {
+	bond.mu.Lock()
+	defer bond.mu.Unlock()
+
+	for len(bond.unacked) > 0 && bond.unacked[0].seq < ack {
+		var seg = bond.unacked[0]
+		bond.unacked[0] = nil
+		bond.unacked = bond.unacked[1:]
+		bond.unackedBytes -= len(seg.data)
+		if seg.path != nil {
+			seg.path.inflight -= len(seg.data)
+		}
+	}
+
+	bond.cond.Broadcast()
+}
+
+// dropPath removes a failed path from the bond and sends segments that weren't acknowledged over other paths
+func (bond *Bond) dropPath(path *BondPath, err error) {
+	bond.mu.Lock()
+	var idx = slices.Index(bond.paths, path)
+	if idx == -1 {
+		bond.mu.Unlock()
+		return
+	}
+	bond.paths = slices.Delete(bond.paths, idx, idx+1)
+	path.conn.Close()
+
+	if len(bond.paths) == 0 {
+		bond.mu.Unlock()
+		bond.closeWithError(ErrNoPaths, false)
+		return
+	}
+
+	var resend []*bondSegment
+	for _, seg := range bond.unacked {
+		if seg.path == path {
+			seg.path = nil
+			resend = append(resend, seg)
+		}
+	}
+	bond.mu.Unlock()
+
+	for _, seg := range resend {
+		bond.send(seg)
+	}
+}
+
+func (bond *Bond) closeWithError(err error, notify bool) error {
+	bond.mu.Lock()
+	if bond.err != nil {
+		bond.mu.Unlock()
+		return nil
+	}
+	bond.err = err
+	if bond.ackTimer != nil {
+		bond.ackTimer.Stop()
+	}
+	var paths = bond.paths
+	bond.paths = nil
+	bond.cond.Broadcast()
+	close(bond.closeSignal)
+	bond.mu.Unlock()
+
+	for _, path := range paths {
+		if notify {
+			path.write([]byte{segFin})
+		}
+		path.conn.Close()
+	}
+
+	if bond.onClose != nil {
+		bond.onClose()
+	}
+
+	return nil
+}
+
+// Conn returns the connection used by the path
+func (path *BondPath) Conn() net.SecureConn {
+	return path.conn
+}
+
+// Latency returns the last measured roundtrip time of the path or -1 if it's not known yet
+func (path *BondPath) Latency() time.Duration {
+	path.pingMu.Lock()
+	defer path.pingMu.Unlock()
+
+	return path.latency
+}
+
+// AddedAt returns the time when the path was added to the bond
+func (path *BondPath) AddedAt() time.Time {
+	return path.addedAt
+}
+
+func (path *BondPath) write(p []byte) error {
+	path.wmu.Lock()
+	defer path.wmu.Unlock()
+
+	_, err := path.conn.Write(p)
+	return err
+}
+
+func (path *BondPath) readLoop() {
+	var err = path.read()
+	if errors.Is(err, io.EOF) {
+		err = ErrLinkClosedByPeer
+	}
+	path.bond.dropPath(path, err)
+}
+
+func (path *BondPath) read() error {
+	var r = path.conn
+
+	for {
+		var segType int
+		if err := cslq.Decode(r, "c", &segType); err != nil {
+			return err
+		}
+
+		switch segType {
+		case segData:
+			var seq uint64
+			var size uint32
+			if err := cslq.Decode(r, "ql", &seq, &size); err != nil {
+				return err
+			}
+			if size == 0 || size > bondMaxSegmentSize {
+				return ErrProtocolError
+			}
+			var data = make([]byte, size)
+			if _, err := io.ReadFull(r, data); err != nil {
+				return err
+			}
+			if err := path.bond.handleData(seq, data); err != nil {
+				path.bond.closeWithError(err, true)
+				return err
+			}
+
+		case segAck:
+			var ack uint64
+			if err := cslq.Decode(r, "q", &ack); err != nil {
+				return err
+			}
+			path.bond.handleAck(ack)
+
+		case segPing:
+			var nonce uint32
+			if err := cslq.Decode(r, "l", &nonce); err != nil {
+				return err
+			}
+			go path.pong(nonce)
+
+		case segPong:
+			var nonce uint32
+			if err := cslq.Decode(r, "l", &nonce); err != nil {
+				return err
+			}
+			path.handlePong(nonce)
+
+		case segFin:
+			path.bond.closeWithError(ErrLinkClosedByPeer, false)
+			return ErrLinkClosedByPeer
+
+		default:
+			return ErrProtocolError
+		}
+	}
+}
+
+// pingLoop measures the latency of the path and drops it when it stops responding
+func (path *BondPath) pingLoop() {
+	for {
+		path.ping()
+
+		select {
+		case <-time.After(bondPingInterval):
+		case <-path.bond.Done():
+			return
+		}
+
+		path.pingMu.Lock()
+		var silent = path.pongAt.Before(path.pingAt) && time.Since(path.pingAt) > bondPathTimeout
+		path.pingMu.Unlock()
+
+		if silent {
+			path.bond.dropPath(path, ErrPingTimeout)
+			return
+		}
+	}
+}
+
+func (path *BondPath) ping() {
+	path.pingMu.Lock()
+	// keep the first unanswered ping, so that a silent path times out
+	if path.pongAt.Before(path.pingAt) {
+		path.pingMu.Unlock()
+		return
+	}
+	path.pingNonce = rand.Uint32()
+	path.pingAt = time.Now()
+	var nonce = path.pingNonce
+	path.pingMu.Unlock()
+
+	var buf = &bytes.Buffer{}
+	cslq.Encode(buf, "cl", segPing, nonce)
+
+	if err := path.write(buf.Bytes()); err != nil {
+		path.bond.dropPath(path, err)
+	}
+}
+
+func (path *BondPath) pong(nonce uint32) {
+	var buf = &bytes.Buffer{}
+	cslq.Encode(buf, "cl", segPong, nonce)
+
+	if err := path.write(buf.Bytes()); err != nil {
+		path.bond.dropPath(path, err)
+	}
+}
+
+func (path *BondPath) handlePong(nonce uint32) {
+	path.pingMu.Lock()
+	defer path.pingMu.Unlock()
+
+	if nonce != path.pingNonce || !path.pongAt.Before(path.pingAt) {
+		return
+	}
+	path.pongAt = time.Now()
+	path.latency = path.pongAt.Sub(path.pingAt)
+}
+
+// cmpLatency compares latencies treating unknown latency as the highest
+func cmpLatency(a, b time.Duration) int {
+	switch {
+	case a == b:
+		return 0
+	case a < 0:
+		return 1
+	case b < 0:
+		return -1
+	case a < b:
+		return -1
+	}
+	return 1
+}
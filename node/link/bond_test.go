@@ -0,0 +1,213 @@
+package link
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"github.com/cryptopunkscc/astrald/auth/id"
+	"github.com/cryptopunkscc/astrald/net"
+	"github.com/cryptopunkscc/astrald/streams"
+	"io"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTestBonds returns two ends of a bond connected by the provided number of paths
+func newTestBonds(t *testing.T, paths int) (left *Bond, right *Bond, leftPaths []io.Closer) {
+	var leftID, _ = id.GenerateIdentity()
+	var rightID, _ = id.GenerateIdentity()
+
+	left = NewBond(1, leftID, rightID, true)
+	right = NewBond(1, rightID, leftID, false)
+
+	for i := 0; i < paths; i++ {
+		var leftConn, rightConn = streams.Pipe()
+		if err := left.AddPath(NewSecureConn(leftID, rightID, leftConn)); err != nil {
+			t.Fatal(err)
+		}
+		if err := right.AddPath(NewSecureConn(rightID, leftID, rightConn)); err != nil {
+			t.Fatal(err)
+		}
+		leftPaths = append(leftPaths, leftConn)
+	}
+
+	return
+}
+
+func TestBondPathFailure(t *testing.T) {
+	var left, right, paths = newTestBonds(t, 3)
+	defer left.Close()
+
+	var data = make([]byte, 8*1024*1024)
+	rand.Read(data)
+
+	var wg sync.WaitGroup
+	var received = &bytes.Buffer{}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		io.CopyN(received, right, int64(len(data)))
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < len(data); i += 32 * 1024 {
+			if _, err := left.Write(data[i : i+32*1024]); err != nil {
+				t.Error(err)
+				return
+			}
+			// fail two of the paths halfway through
+			if i == len(data)/2 {
+				paths[0].Close()
+				paths[2].Close()
+			}
+		}
+	}()
+
+	wg.Wait()
+
+	if !bytes.Equal(received.Bytes(), data) {
+		t.Fatalf("received %d bytes that don't match the %d bytes sent", received.Len(), len(data))
+	}
+
+	if n := len(left.Paths()); n != 1 {
+		t.Fatalf("left has %d paths, expected 1", n)
+	}
+
+	if err := left.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-right.Done():
+	case <-time.After(time.Second):
+		t.Fatal("right side did not close")
+	}
+}
+
+func TestBondedLink(t *testing.T) {
+	var ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+
+	var leftBond, rightBond, paths = newTestBonds(t, 2)
+	var payload = make([]byte, 2*1024*1024)
+	rand.Read(payload)
+
+	var left = NewBondedLink(leftBond, DefaultConfig())
+	var right = NewBondedLink(rightBond, DefaultConfig())
+	left.SetUplink(payloadRouter{payload})
+	right.SetUplink(payloadRouter{payload})
+
+	go left.Run(ctx)
+	go right.Run(ctx)
+
+	conn, err := net.Route(ctx, left, net.NewQuery(left.LocalIdentity(), left.RemoteIdentity(), "payload"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf = make([]byte, len(payload)/4)
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		t.Fatal(err)
+	}
+
+	// the session has to survive the loss of a path
+	paths[0].Close()
+
+	rest, err := io.ReadAll(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(append(buf, rest...), payload) {
+		t.Fatalf("received payload doesn't match")
+	}
+
+	select {
+	case <-left.Done():
+		t.Fatalf("link closed: %v", left.Err())
+	default:
+	}
+}
+
+func TestBondReceiveWindow(t *testing.T) {
+	var bond = NewBond(1, id.Identity{}, id.Identity{}, false)
+
+	// segments beyond the window are refused
+	if err := bond.handleData(bondSegmentWindow, []byte{1}); err == nil {
+		t.Fatal("accepted a segment beyond the window")
+	}
+	if err := bond.handleData(bondSegmentWindow-1, []byte{1}); err != nil {
+		t.Fatal(err)
+	}
+
+	// buffered segments cannot exceed the window
+	var segment = make([]byte, bondMaxSegmentSize)
+	for seq := uint64(1); seq < bondWindow/bondMaxSegmentSize; seq++ {
+		if err := bond.handleData(seq, segment); err != nil {
+			t.Fatal(seq, err)
+		}
+	}
+	if err := bond.handleData(bondWindow/bondMaxSegmentSize, segment); err == nil {
+		t.Fatal("buffered more than the window")
+	}
+
+	// reading frees the window
+	if err := bond.handleData(0, []byte{1}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.ReadFull(bond, make([]byte, 1+bondMaxSegmentSize)); err != nil {
+		t.Fatal(err)
+	}
+	if err := bond.handleData(bondWindow/bondMaxSegmentSize, segment); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBondSendWindow(t *testing.T) {
+	var bond = NewBond(1, id.Identity{}, id.Identity{}, true)
+
+	var write = func(size int) <-chan struct{} {
+		var done = make(chan struct{})
+		go func() {
+			bond.Write(make([]byte, size))
+			close(done)
+		}()
+		return done
+	}
+
+	var expect = func(done <-chan struct{}, blocked bool) {
+		t.Helper()
+		select {
+		case <-done:
+			if blocked {
+				t.Fatal("write exceeded the window")
+			}
+		case <-time.After(100 * time.Millisecond):
+			if !blocked {
+				t.Fatal("write blocked within the window")
+			}
+		}
+	}
+
+	// writes that fill the window exactly don't wait
+	expect(write(bondWindow-bondMaxSegmentSize), false)
+	expect(write(bondMaxSegmentSize), false)
+
+	var done = write(1)
+	expect(done, true)
+
+	// an ack of the first segment frees its space
+	bond.handleAck(1)
+	expect(done, false)
+
+	// a segment that exceeds the window by one byte waits as well
+	done = write(bondMaxSegmentSize)
+	expect(done, true)
+
+	bond.handleAck(2)
+	expect(done, false)
+}
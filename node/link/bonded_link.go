@@ -0,0 +1,194 @@
+package link
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/cryptopunkscc/astrald/auth"
+	"github.com/cryptopunkscc/astrald/auth/id"
+	"github.com/cryptopunkscc/astrald/cslq"
+	"github.com/cryptopunkscc/astrald/net"
+	"math/rand"
+	"sync"
+)
+
+var _ net.Link = &BondedLink{}
+
+// BondedLink is a link that runs over a Bond, so that it can use many transports to the same node at once and
+// keep its sessions when some of them fail.
+type BondedLink struct {
+	*CoreLink
+	bond *Bond
+}
+
+// bonds holds bonded links by their IDs, so that new paths can join them
+var bonds = struct {
+	sync.Mutex
+	m map[uint64]*BondedLink
+}{m: map[uint64]*BondedLink{}}
+
+// NewBondedLink returns a new link that runs over the provided bond
+func NewBondedLink(bond *Bond, config Config) *BondedLink {
+	var l = &BondedLink{
+		CoreLink: NewCoreLink(bond, config),
+		bond:     bond,
+	}
+
+	bond.onClose = func() {
+		bonds.Lock()
+		delete(bonds.m, bond.ID())
+		bonds.Unlock()
+
+		// the bond may be closed by the link itself, which holds its lock while closing the transport
+		go l.CloseWithError(bond.Err())
+	}
+
+	return l
+}
+
+// OpenBond negotiates a new bonded link over the provided conn as the active party. More paths can be added
+// with AddPath.
+func OpenBond(
+	ctx context.Context,
+	conn net.Conn,
+	remoteID id.Identity,
+	localID id.Identity,
+	config Config,
+) (*BondedLink, error) {
+	var bondID = rand.Uint64()
+
+	secureConn, err := openBondPath(ctx, conn, remoteID, localID, bondID)
+	if err != nil {
+		return nil, err
+	}
+
+	var bond = NewBond(bondID, localID, remoteID, true)
+	var l = NewBondedLink(bond, config)
+
+	bonds.Lock()
+	bonds.m[bondID] = l
+	bonds.Unlock()
+
+	if err := bond.AddPath(secureConn); err != nil {
+		secureConn.Close()
+		return nil, err
+	}
+
+	return l, nil
+}
+
+// AddPath negotiates a new path over the provided conn and adds it to the link
+func (l *BondedLink) AddPath(ctx context.Context, conn net.Conn) error {
+	secureConn, err := openBondPath(ctx, conn, l.RemoteIdentity(), l.LocalIdentity(), l.bond.ID())
+	if err != nil {
+		return err
+	}
+
+	if err := l.bond.AddPath(secureConn); err != nil {
+		secureConn.Close()
+		return err
+	}
+
+	return nil
+}
+
+// Bond returns the bond used as the link's transport
+func (l *BondedLink) Bond() *Bond {
+	return l.bond
+}
+
+// Paths returns the paths currently used by the link
+func (l *BondedLink) Paths() []*BondPath {
+	return l.bond.Paths()
+}
+
+// openBondPath performs the handshake over the conn and requests it to be used as a path of the bond
+func openBondPath(
+	ctx context.Context,
+	conn net.Conn,
+	remoteID id.Identity,
+	localID id.Identity,
+	bondID uint64,
+) (secureConn net.SecureConn, err error) {
+	defer func() {
+		if err != nil {
+			conn.Close()
+		}
+	}()
+
+	secureConn, err = auth.HandshakeOutbound(ctx, conn, remoteID, localID)
+	if err != nil {
+		return nil, fmt.Errorf("outbound handshake: %w", err)
+	}
+
+	var linkFeatures []string
+
+	err = cslq.Decode(secureConn, featureListFormat, &linkFeatures)
+	if err != nil {
+		return nil, fmt.Errorf("read features: %w", err)
+	}
+
+	var bondFound bool
+	for _, f := range linkFeatures {
+		if f == featureBond {
+			bondFound = true
+		}
+	}
+	if !bondFound {
+		return nil, errors.New("remote party does not support bonding")
+	}
+
+	err = cslq.Encode(secureConn, "[c]cq", featureBond, bondID)
+	if err != nil {
+		return nil, fmt.Errorf("write bond: %w", err)
+	}
+
+	var errCode int
+	err = cslq.Decode(secureConn, "c", &errCode)
+	if err != nil {
+		return nil, fmt.Errorf("read bond response: %w", err)
+	}
+	if errCode != 0 {
+		return nil, errors.New("bond negotiation error")
+	}
+
+	return secureConn, nil
+}
+
+// acceptBondPath reads the bond ID requested by the remote party and adds the conn to the bond. If the bond
+// doesn't exist, a new bonded link is created.
+func acceptBondPath(secureConn net.SecureConn, config Config) (*BondedLink, error) {
+	var bondID uint64
+	if err := cslq.Decode(secureConn, "q", &bondID); err != nil {
+		return nil, err
+	}
+
+	bonds.Lock()
+	l, found := bonds.m[bondID]
+	if !found {
+		l = NewBondedLink(NewBond(bondID, secureConn.LocalIdentity(), secureConn.RemoteIdentity(), false), config)
+		bonds.m[bondID] = l
+	}
+	bonds.Unlock()
+
+	if !l.RemoteIdentity().IsEqual(secureConn.RemoteIdentity()) || !l.LocalIdentity().IsEqual(secureConn.LocalIdentity()) {
+		cslq.Encode(secureConn, "c", 1)
+		return nil, ErrIdentityMismatch
+	}
+
+	cslq.Encode(secureConn, "c", 0)
+
+	if err := l.bond.AddPath(secureConn); err != nil {
+		// don't leave a bond without paths behind
+		if !found {
+			bonds.Lock()
+			if bonds.m[bondID] == l {
+				delete(bonds.m, bondID)
+			}
+			bonds.Unlock()
+		}
+		return nil, err
+	}
+
+	return l, nil
+}
@@ -0,0 +1,43 @@
+package link
+
+import (
+	"github.com/cryptopunkscc/astrald/mux"
+	"github.com/cryptopunkscc/astrald/net"
+	"time"
+)
+
+// Config holds the settings of a link
+type Config struct {
+	// Compression lists compression methods that the link offers to its peer in the order of preference.
+	// The link doesn't compress anything if the list is empty.
+	Compression []mux.Compression
+
+	// MaxRemotePriority is the highest priority that queries from the peer can get. Priorities announced by
+	// the peer are clamped to it, so that it cannot take the link bandwidth reserved for local interactive
+	// sessions.
+	MaxRemotePriority net.Priority
+
+	// ResumeGracePeriod is how long sessions of a lost link wait to be resumed over a new route before they
+	// close. Sessions are not resumable if it's zero. Only sessions of queries routed with the Resume hint are
+	// resumable.
+	ResumeGracePeriod time.Duration
+
+	// ResumeWindow is the number of bytes a resumable session can send before the remote party confirms that
+	// it received them. The session keeps these bytes to send them again when it's resumed, so the window
+	// limits the memory used by each session.
+	ResumeWindow int
+
+	// ObfuscatedOnly makes Accept drop connections that don't start with an obfuscation handshake, so
+	// that probes of the node's endpoints get no response. Peers have to dial obfuscated endpoints.
+	ObfuscatedOnly bool
+}
+
+// DefaultConfig returns the config used by links unless the node provides its own
+func DefaultConfig() Config {
+	return Config{
+		Compression:       []mux.Compression{mux.CompressionDeflate},
+		MaxRemotePriority: net.PriorityInteractive,
+		ResumeGracePeriod: 30 * time.Second,
+		ResumeWindow:      1024 * 1024,
+	}
+}
@@ -7,19 +7,27 @@ import (
 	"github.com/cryptopunkscc/astrald/debug"
 	"github.com/cryptopunkscc/astrald/mux"
 	"github.com/cryptopunkscc/astrald/net"
-	"io"
 	"math/rand"
+	"slices"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
 const pingTimeout = 15 * time.Second
 const maxConcurrentPings = 10
 
+// maxQueuedPongs is the number of responses to pings that can wait for the transport. Pings above it are dropped.
+const maxQueuedPongs = maxConcurrentPings + 1
+
 type Control struct {
 	*CoreLink
 	notify map[int][]chan struct{}
 	pings  map[int]chan struct{}
+	pingMu sync.Mutex
 	nonce  int
+	rekey  rekeyControl
+	pongs  atomic.Int32 // pongs queued in the scheduler
 }
 
 func NewControl(link *CoreLink) *Control {
@@ -31,8 +39,30 @@ func NewControl(link *CoreLink) *Control {
 }
 
 func (c *Control) Run(ctx context.Context) error {
-	<-ctx.Done()
-	return nil
+	var _, rekeyable = c.transport.(Rekeyer)
+
+	if len(c.config.Compression) > 0 || rekeyable {
+		go c.offerFeatures()
+	}
+
+	if !rekeyable {
+		<-ctx.Done()
+		return nil
+	}
+
+	var ticker = time.NewTicker(rekeyCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if c.rekeyDue() {
+				c.Rekey()
+			}
+		case <-ctx.Done():
+			return nil
+		}
+	}
 }
 
 func (c *Control) handleMux(event mux.Event) {
@@ -41,7 +71,9 @@ func (c *Control) handleMux(event mux.Event) {
 		c.handleFrame(event)
 
 	case mux.Unbind:
-		c.CloseWithError(io.EOF)
+		// a peer closing on purpose sends an empty control frame first (see handleFrame),
+		// so an unbind without one means the transport was lost and a session may resume
+		c.CloseWithError(ErrLinkLost)
 	}
 }
 
@@ -49,6 +81,7 @@ func (c *Control) handleFrame(frame mux.Frame) {
 	c.Touch()
 
 	if frame.IsEmpty() {
+		c.CloseWithError(ErrLinkClosedByPeer)
 		c.Unbind(frame.Port)
 		return
 	}
@@ -56,7 +89,30 @@ func (c *Control) handleFrame(frame mux.Frame) {
 	var r = bytes.NewReader(frame.Data[1:])
 	switch frame.Data[0] {
 	case codePing:
-		cslq.Invoke(r, c.handlePing)
+		cslq.Invoke(r, func(msg Ping) error {
+			if err := c.handlePing(msg); err != nil {
+				return err
+			}
+			offer, err := decodeCompressionOffer(r)
+			if err != nil {
+				return nil
+			}
+			if err := c.handleCompressionOffer(offer); err != nil {
+				return err
+			}
+			if offer, err := decodeRekeyOffer(r); err == nil {
+				return c.handleRekeyOffer(offer)
+			}
+			return nil
+		})
+	case codeCompress:
+		cslq.Invoke(r, c.handleCompress)
+	case codeRekey:
+		cslq.Invoke(r, c.handleRekey)
+	case codeRekeyAck:
+		cslq.Invoke(r, c.handleRekeyAck)
+	case codeRekeyDone:
+		c.handleRekeyDone()
 	case codePong:
 		cslq.Invoke(r, c.handlePong)
 	case codeGrowBuffer:
@@ -64,7 +120,11 @@ func (c *Control) handleFrame(frame mux.Frame) {
 	case codeReset:
 		cslq.Invoke(r, c.handleReset)
 	case codeQuery:
-		cslq.Invoke(r, c.handleQuery)
+		cslq.Invoke(r, func(msg Query) error {
+			msg.Priority = c.remotePriority(decodePriority(r))
+			msg.Flags = decodeFlags(r)
+			return c.handleQuery(msg)
+		})
 	default:
 		c.CloseWithError(ErrProtocolError)
 	}
@@ -73,16 +133,28 @@ func (c *Control) handleFrame(frame mux.Frame) {
 // Ping sends a ping request and waits for the response. Returns roundtrip time or an error.
 // Errors: ErrTooManyPings, ErrPingTimeout.
 func (c *Control) Ping() (time.Duration, error) {
+	return c.ping()
+}
+
+// ping sends a ping request with optional messages appended to it and waits for the response
+func (c *Control) ping(ext ...any) (time.Duration, error) {
+	c.pingMu.Lock()
 	if len(c.pings) > maxConcurrentPings {
+		c.pingMu.Unlock()
 		return 0, ErrTooManyPings
 	}
 
 	var nonce = rand.Int() & 0x7fffffff
 	var pingFrame = &bytes.Buffer{}
 	cslq.Encode(pingFrame, "cv", codePing, Ping{Nonce: nonce})
+	for _, e := range ext {
+		cslq.Encode(pingFrame, "v", e)
+	}
 
 	var ch = make(chan struct{})
 	c.pings[nonce] = ch
+	c.pingMu.Unlock()
+
 	var pingAt = time.Now()
 
 	c.mux.Write(mux.Frame{Data: pingFrame.Bytes()})
@@ -91,33 +163,92 @@ func (c *Control) Ping() (time.Duration, error) {
 	case <-ch:
 		return time.Since(pingAt), nil
 	case <-time.After(pingTimeout):
+		c.pingMu.Lock()
+		delete(c.pings, nonce)
+		c.pingMu.Unlock()
 		return 0, ErrPingTimeout
 	}
 }
 
-// handlePing is called when a Ping message is received
+// handlePing is called when a Ping message is received. The response is queued without waiting for it to be
+// written, so that the link doesn't stop reading frames while the transport is busy. Otherwise parties pinging
+// each other at the same time could block each other.
 func (c *Control) handlePing(msg Ping) error {
-	return c.Pong(msg.Nonce)
-}
+	// a peer flooding the link with pings doesn't get more responses than it can have pings in flight
+	if c.pongs.Add(1) > maxQueuedPongs {
+		c.pongs.Add(-1)
+		return nil
+	}
 
-// Pong sends a Pong message with the provided nonce
-func (c *Control) Pong(nonce int) error {
 	var buf = &bytes.Buffer{}
-	cslq.Encode(buf, "cv", codePong, Pong{Nonce: nonce})
-	return c.mux.Write(mux.Frame{Data: buf.Bytes()})
+	cslq.Encode(buf, "cv", codePong, Pong{Nonce: msg.Nonce})
+	return c.mux.PostThen(mux.Frame{Data: buf.Bytes()}, func() {
+		c.pongs.Add(-1)
+	})
 }
 
 // handlePong is called when a Pong message is received
 func (c *Control) handlePong(msg Pong) error {
+	c.pingMu.Lock()
 	ping, found := c.pings[msg.Nonce]
+	delete(c.pings, msg.Nonce)
+	c.pingMu.Unlock()
+
 	if !found {
 		return c.CloseWithError(ErrInvalidNonce)
 	}
-	delete(c.pings, msg.Nonce)
 	close(ping)
 	return nil
 }
 
+// offerFeatures sends a ping with the list of compression methods the link can decode and, if the transport
+// supports it, an offer to replace its keys
+func (c *Control) offerFeatures() {
+	var offer CompressionOffer
+	for _, m := range c.config.Compression {
+		offer.Methods = append(offer.Methods, int(m))
+	}
+
+	if _, ok := c.transport.(Rekeyer); ok {
+		c.ping(offer, RekeyOffer{Version: rekeyVersion})
+		return
+	}
+
+	c.ping(offer)
+}
+
+// handleCompressionOffer starts compressing frames sent to the remote party if it offered a method that
+// the link supports
+func (c *Control) handleCompressionOffer(offer CompressionOffer) error {
+	if _, out := c.mux.Compression(); out != mux.CompressionNone {
+		return nil
+	}
+
+	for _, method := range c.config.Compression {
+		if method == mux.CompressionNone || !slices.Contains(offer.Methods, int(method)) {
+			continue
+		}
+
+		var buf = &bytes.Buffer{}
+		cslq.Encode(buf, "cv", codeCompress, Compress{Method: int(method)})
+		go c.mux.SwitchCompression(mux.Frame{Data: buf.Bytes()}, method)
+		return nil
+	}
+
+	return nil
+}
+
+// handleCompress is called when a Compress message is received. Frames that follow it are compressed.
+func (c *Control) handleCompress(msg Compress) error {
+	var method = mux.Compression(msg.Method)
+
+	if method != mux.CompressionNone && !slices.Contains(c.config.Compression, method) {
+		return c.CloseWithError(ErrProtocolError)
+	}
+
+	return c.mux.SetInboundCompression(method)
+}
+
 // GrowBuffer sends a GrowBuffer message to indicate that there is more space in port's receive buffer
 func (c *Control) GrowBuffer(port int, size int) error {
 	var buf = &bytes.Buffer{}
@@ -131,6 +262,9 @@ func (c *Control) GrowBuffer(port int, size int) error {
 func (c *Control) handleGrowBuffer(msg GrowBuffer) error {
 	c.remoteBuffers.grow(msg.Port, msg.Size)
 
+	// the remote party grows the buffer after it flushes data to the output
+	c.remoteBuffers.ack(msg.Port, msg.Size)
+
 	return nil
 }
 
@@ -140,16 +274,18 @@ func (c *Control) Reset(port int) error {
 	cslq.Encode(buf, "cv", codeReset, Reset{
 		Port: port,
 	})
-	return c.mux.Write(mux.Frame{Data: buf.Bytes()})
+	// resets are sent when ports unbind, which happens in the read loop
+	return c.mux.Post(mux.Frame{Data: buf.Bytes()})
 }
 
 func (c *Control) handleReset(msg Reset) error {
 	c.remoteBuffers.reset(msg.Port)
+	c.mux.SetWeight(msg.Port, 0)
 	return nil
 }
 
 // Query sends a Query messsage to the remote party
-func (c *Control) Query(nonce uint64, query string, localPort int) error {
+func (c *Control) Query(nonce uint64, query string, localPort int, priority net.Priority, flags int) error {
 	var buf = &bytes.Buffer{}
 	cslq.Encode(buf, "cv", codeQuery, Query{
 		Query:  query,
@@ -157,6 +293,8 @@ func (c *Control) Query(nonce uint64, query string, localPort int) error {
 		Buffer: portBufferSize,
 		Nonce:  nonce,
 	})
+	encodePriority(buf, priority)
+	encodeFlags(buf, flags)
 	return c.mux.Write(mux.Frame{Data: buf.Bytes()})
 }
 
@@ -177,27 +315,58 @@ func (c *Control) executeQuery(msg Query) error {
 	var query = net.NewQueryNonce(c.RemoteIdentity(), c.LocalIdentity(), msg.Query, net.Nonce(msg.Nonce))
 
 	var caller = NewPortWriter(c.CoreLink, msg.Port)
+	caller.SetPriority(msg.Priority)
 
 	// lock the port writer so that the target cannot write to it before we get a chance to send the query response
 	caller.Lock()
 	defer caller.Unlock()
 
+	var hints = net.DefaultHints().WithOrigin(net.OriginNetwork).WithPriority(msg.Priority)
+
+	// if both parties support it, run the session so that it can be resumed if the link is lost
+	var session *Session
+	var input *sessionInput
+	var routeCaller net.SecureWriteCloser = caller
+	if msg.Flags&flagResumable != 0 && c.resumable() {
+		session = newSession(query.Nonce(), c.LocalIdentity(), c.RemoteIdentity(), c.config)
+		input = session.attachPort(caller)
+		routeCaller = session
+	}
+
 	// route the query upstream
-	target, err := c.uplink.RouteQuery(c.ctx, query, caller, net.DefaultHints().WithOrigin(net.OriginNetwork))
+	target, err := c.uplink.RouteQuery(c.ctx, query, routeCaller, hints)
 	if err != nil {
+		c.mux.SetWeight(msg.Port, 0)
 		return c.WriteResponse(msg.Port, &Response{Error: errRejected})
 	}
 
 	c.remoteBuffers.grow(msg.Port, msg.Buffer)
 
+	var output = target
+	var flags int
+	if session != nil {
+		session.output = target
+		output = input
+		flags |= flagResumable
+	}
+
 	// asign a local port to the target
-	binding, err := c.BindAny(target)
+	binding, err := c.BindAny(output)
 	if err != nil {
 		target.Close()
 		return c.WriteResponse(msg.Port, &Response{Error: errUnexpected})
 	}
 
-	return c.WriteResponse(msg.Port, &Response{Port: int(binding.port.Load()), Buffer: portBufferSize})
+	if session != nil {
+		input.binding = binding
+	}
+
+	return c.WriteResponse(msg.Port, &Response{Port: int(binding.port.Load()), Buffer: portBufferSize, Flags: flags})
+}
+
+// resumable returns true if sessions of the link should be resumed when the link is lost
+func (c *Control) resumable() bool {
+	return c.config.ResumeGracePeriod > 0
 }
 
 func (c *Control) WriteResponse(port int, r *Response) error {
@@ -206,6 +375,9 @@ func (c *Control) WriteResponse(port int, r *Response) error {
 	if err := cslq.Encode(buf, "v", r); err != nil {
 		return err
 	}
+	if r.Flags != 0 {
+		encodeFlags(buf, r.Flags)
+	}
 
 	return c.mux.Write(mux.Frame{
 		Port: port,
@@ -17,6 +17,15 @@ var DefaultMuxHandler = func(event mux.Event) {}
 
 const portBufferSize = 4 * 1024 * 1024
 const controlPort = 0
+const closeTimeout = time.Second
+
+// scheduling weights of link ports
+const (
+	weightBulk        = 1
+	weightNormal      = mux.DefaultWeight
+	weightInteractive = 16
+	weightControl     = 64
+)
 
 type CoreLink struct {
 	sig.Activity
@@ -30,12 +39,14 @@ type CoreLink struct {
 	mu            sync.Mutex
 	err           error
 	health        *health
+	config        Config
 	running       chan struct{}
 }
 
-func NewCoreLink(transport net.SecureConn) *CoreLink {
+func NewCoreLink(transport net.SecureConn, config Config) *CoreLink {
 	link := &CoreLink{
 		transport: transport,
+		config:    config,
 		running:   make(chan struct{}),
 	}
 
@@ -46,6 +57,7 @@ func NewCoreLink(transport net.SecureConn) *CoreLink {
 	if err := link.mux.Bind(controlPort, link.control.handleMux); err != nil {
 		panic(err)
 	}
+	link.mux.SetWeight(controlPort, weightControl)
 
 	return link
 }
@@ -99,8 +111,28 @@ func (link *CoreLink) Unbind(port int) error {
 	return link.mux.Unbind(port)
 }
 
-// Close closes the link.
+// Close tells the remote party that the link is closing and closes the link.
 func (link *CoreLink) Close() error {
+	select {
+	case <-link.running:
+		if link.Err() != nil {
+			break
+		}
+
+		// closing the control port closes the link on the remote side with ErrLinkClosedByPeer
+		var sent = make(chan struct{})
+		go func() {
+			defer close(sent)
+			link.mux.Close(controlPort)
+		}()
+
+		select {
+		case <-sent:
+		case <-time.After(closeTimeout):
+		}
+	default:
+	}
+
 	return link.CloseWithError(ErrLinkClosed)
 }
 
@@ -145,6 +177,29 @@ func (link *CoreLink) Latency() time.Duration {
 	return link.health.Latency()
 }
 
+// SetCompression sets compression methods that the link offers to the remote party. It has to be called before
+// the link is run.
+func (link *CoreLink) SetCompression(methods ...mux.Compression) {
+	link.config.Compression = methods
+}
+
+// Compression returns compression methods used for frames received from and sent to the remote party
+func (link *CoreLink) Compression() (inbound mux.Compression, outbound mux.Compression) {
+	return link.mux.Compression()
+}
+
+// CompressionRatio returns the ratio of data size to the size of compressed frames in both directions, or 0
+// if the link doesn't use compression.
+func (link *CoreLink) CompressionRatio() float64 {
+	in, out := link.mux.CompressionStats()
+	return in.Add(out).Ratio()
+}
+
+// Rekey replaces the keys of the link's transport
+func (link *CoreLink) Rekey() error {
+	return link.control.Rekey()
+}
+
 // Done returns a channel that will be closed when the link closes
 func (link *CoreLink) Done() <-chan struct{} {
 	<-link.running
@@ -156,28 +211,30 @@ func (link *CoreLink) Err() error {
 	return link.err
 }
 
+// write reserves space in the remote buffer of the port and queues the frame in the multiplexer. Writes to
+// different ports don't block each other, the multiplexer's scheduler decides the order in which they're sent.
 func (link *CoreLink) write(port int, frame []byte) error {
-	link.mu.Lock()
-	defer link.mu.Unlock()
-
-	bufferSize, open := link.remoteBuffers.size(port)
-	if !open {
-		return ErrRemoteBufferOverflow
-	}
-
-	if len(frame) > bufferSize {
-		return ErrRemoteBufferOverflow
+	if err := link.remoteBuffers.take(port, len(frame)); err != nil {
+		return err
 	}
 
-	err := link.mux.Write(mux.Frame{
+	return link.mux.Write(mux.Frame{
 		Port: port,
 		Data: frame,
 	})
-	if err != nil {
-		return err
-	}
+}
 
-	link.remoteBuffers.grow(port, -len(frame))
+// remotePriority clamps a priority announced by the peer to the supported range
+func (link *CoreLink) remotePriority(priority net.Priority) net.Priority {
+	return max(net.PriorityBulk, min(priority, link.config.MaxRemotePriority, net.PriorityInteractive))
+}
 
-	return nil
+func priorityWeight(priority net.Priority) int {
+	switch {
+	case priority < net.PriorityNormal:
+		return weightBulk
+	case priority > net.PriorityNormal:
+		return weightInteractive
+	}
+	return weightNormal
 }
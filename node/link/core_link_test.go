@@ -5,6 +5,8 @@ import (
 	"context"
 	"errors"
 	"github.com/cryptopunkscc/astrald/auth/id"
+	"github.com/cryptopunkscc/astrald/auth/obfs"
+	"github.com/cryptopunkscc/astrald/mux"
 	"github.com/cryptopunkscc/astrald/net"
 	"github.com/cryptopunkscc/astrald/streams"
 	"io"
@@ -60,7 +62,7 @@ func (l *TestRouter) RouteQuery(ctx context.Context, query net.Query, caller net
 	return net.Accept(query, caller, func(conn net.SecureConn) {
 		_, err := conn.Write([]byte(msg))
 		if err != nil {
-			l.t.Fatal(err)
+			l.t.Error(err)
 		}
 		conn.Close()
 	})
@@ -72,8 +74,8 @@ func TestCoreLink(t *testing.T) {
 	var id2, _ = id.GenerateIdentity()
 
 	var id1conn, id2conn = streams.Pipe()
-	var id1link = NewCoreLink(NewSecureConn(id1, id2, id1conn))
-	var id2link = NewCoreLink(NewSecureConn(id2, id1, id2conn))
+	var id1link = NewCoreLink(NewSecureConn(id1, id2, id1conn), DefaultConfig())
+	var id2link = NewCoreLink(NewSecureConn(id2, id1, id2conn), DefaultConfig())
 	var wg sync.WaitGroup
 
 	id1link.SetUplink(&TestRouter{t})
@@ -97,18 +99,18 @@ func TestCoreLink(t *testing.T) {
 		defer cancel()
 
 		if _, err := id1link.control.Ping(); err != nil {
-			t.Fatal(err)
+			t.Error(err)
 			return
 		}
 
 		if _, err := id2link.control.Ping(); err != nil {
-			t.Fatal(err)
+			t.Error(err)
 			return
 		}
 
 		conn, err := net.Route(ctx, id2link, net.NewQuery(id2, id1, "testing"))
 		if err != nil {
-			t.Fatal(err)
+			t.Error(err)
 			return
 		}
 
@@ -117,7 +119,8 @@ func TestCoreLink(t *testing.T) {
 		io.Copy(buf, conn)
 
 		if bytes.Compare(buf.Bytes(), []byte(msg)) != 0 {
-			t.Fatalf("received '%s', expected '%s'", string(buf.Bytes()), msg)
+			t.Errorf("received '%s', expected '%s'", string(buf.Bytes()), msg)
+			return
 		}
 
 		id2link.Close()
@@ -128,6 +131,10 @@ func TestCoreLink(t *testing.T) {
 
 	wg.Wait()
 
+	if t.Failed() {
+		return
+	}
+
 	if !errors.Is(id1link.Err(), ErrLinkClosedByPeer) {
 		t.Fatalf("link1 closed with %s, expected %s", id1link.Err(), ErrLinkClosedByPeer)
 	}
@@ -149,9 +156,9 @@ func TestOpenAccept(t *testing.T) {
 
 		conn := &FakeConn{ReadWriteCloser: left}
 
-		link, err := Accept(ctx, conn, leftID)
+		link, err := Accept(ctx, conn, leftID, DefaultConfig())
 		if err != nil {
-			t.Fatal(err)
+			t.Error(err)
 			return
 		}
 		link.Close()
@@ -162,9 +169,9 @@ func TestOpenAccept(t *testing.T) {
 
 		conn := &FakeConn{ReadWriteCloser: right}
 
-		link, err := Open(ctx, conn, leftID, rightID)
+		link, err := Open(ctx, conn, leftID, rightID, DefaultConfig())
 		if err != nil {
-			t.Fatal(err)
+			t.Error(err)
 			return
 		}
 		link.Close()
@@ -172,3 +179,307 @@ func TestOpenAccept(t *testing.T) {
 
 	wg.Wait()
 }
+
+type payloadRouter struct {
+	payload []byte
+}
+
+func (r payloadRouter) RouteQuery(ctx context.Context, query net.Query, caller net.SecureWriteCloser, hints net.Hints) (net.SecureWriteCloser, error) {
+	return net.Accept(query, caller, func(conn net.SecureConn) {
+		conn.Write(r.payload)
+		conn.Close()
+	})
+}
+
+func TestObfuscatedOpenAccept(t *testing.T) {
+	var wg sync.WaitGroup
+	var left, right = streams.Pipe()
+	var leftID, _ = id.GenerateIdentity()
+	var rightID, _ = id.GenerateIdentity()
+	var ctx = context.Background()
+
+	key, err := obfs.NodeKey(leftID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+
+		conn := &FakeConn{ReadWriteCloser: left}
+
+		link, err := Accept(ctx, conn, leftID, DefaultConfig())
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		link.Close()
+	}()
+
+	go func() {
+		defer wg.Done()
+
+		conn, err := obfs.Open(ctx, &FakeConn{ReadWriteCloser: right, outbound: true}, key)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		link, err := Open(ctx, conn, leftID, rightID, DefaultConfig())
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		link.Close()
+	}()
+
+	wg.Wait()
+}
+
+func TestCompression(t *testing.T) {
+	var tests = []struct {
+		name       string
+		left       []mux.Compression
+		right      []mux.Compression
+		compressed bool
+	}{
+		{"both", []mux.Compression{mux.CompressionDeflate}, []mux.Compression{mux.CompressionDeflate}, true},
+		{"left only", []mux.Compression{mux.CompressionDeflate}, nil, false},
+		{"right only", nil, []mux.Compression{mux.CompressionDeflate}, false},
+	}
+
+	var payload = bytes.Repeat([]byte(`{"type":"mod.objects.descriptor","size":1024}`+"\n"), 2000)
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var ctx, cancel = context.WithCancel(context.Background())
+			defer cancel()
+
+			var leftID, _ = id.GenerateIdentity()
+			var rightID, _ = id.GenerateIdentity()
+			var leftConn, rightConn = streams.Pipe()
+
+			var left = NewCoreLink(NewSecureConn(leftID, rightID, leftConn), DefaultConfig())
+			var right = NewCoreLink(NewSecureConn(rightID, leftID, rightConn), DefaultConfig())
+			left.SetCompression(test.left...)
+			right.SetCompression(test.right...)
+			left.SetUplink(payloadRouter{payload})
+			right.SetUplink(payloadRouter{payload})
+
+			go left.Run(ctx)
+			go right.Run(ctx)
+
+			// wait for the negotiation to finish
+			var want = mux.CompressionNone
+			if test.compressed {
+				want = mux.CompressionDeflate
+			}
+			var deadline = time.Now().Add(time.Second)
+			for {
+				if _, err := left.Ping(); err != nil {
+					t.Fatal(err)
+				}
+				if _, err := right.Ping(); err != nil {
+					t.Fatal(err)
+				}
+				lin, lout := left.Compression()
+				rin, rout := right.Compression()
+				if lin == want && lout == want && rin == want && rout == want {
+					break
+				}
+				if !test.compressed || time.Now().After(deadline) {
+					t.Fatalf("compression left %s/%s right %s/%s, expected %s", lin, lout, rin, rout, want)
+				}
+			}
+
+			for _, l := range []*CoreLink{left, right} {
+				conn, err := net.Route(ctx, l, net.NewQuery(l.LocalIdentity(), l.RemoteIdentity(), "payload"))
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				data, err := io.ReadAll(conn)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(data, payload) {
+					t.Fatalf("received %d bytes, expected %d", len(data), len(payload))
+				}
+			}
+
+			var ratio = left.CompressionRatio()
+			switch {
+			case test.compressed && ratio <= 1:
+				t.Fatalf("compression ratio %.2f, expected more than 1", ratio)
+			case !test.compressed && ratio != 0:
+				t.Fatalf("compression ratio %.2f, expected 0", ratio)
+			}
+		})
+	}
+}
+
+// throttledConn limits the write throughput of a connection to simulate a congested link
+type throttledConn struct {
+	io.ReadWriteCloser
+	bytesPerSecond int
+	debt           time.Duration
+}
+
+func (c *throttledConn) Write(p []byte) (int, error) {
+	c.debt += time.Duration(len(p)) * time.Second / time.Duration(c.bytesPerSecond)
+	if c.debt >= time.Millisecond {
+		time.Sleep(c.debt)
+		c.debt = 0
+	}
+	return c.ReadWriteCloser.Write(p)
+}
+
+// benchRouter echoes data back on "echo" queries and discards everything else
+type benchRouter struct{}
+
+func (benchRouter) RouteQuery(ctx context.Context, query net.Query, caller net.SecureWriteCloser, hints net.Hints) (net.SecureWriteCloser, error) {
+	return net.Accept(query, caller, func(conn net.SecureConn) {
+		defer conn.Close()
+
+		if query.Query() == "echo" {
+			io.Copy(conn, conn)
+		} else {
+			io.Copy(io.Discard, conn)
+		}
+	})
+}
+
+// BenchmarkCongestedLink measures the round trip time of small writes of an interactive session while
+// bulk sessions saturate the link.
+func BenchmarkCongestedLink(b *testing.B) {
+	b.Run("fifo", func(b *testing.B) {
+		benchmarkCongestedLink(b, true, net.PriorityNormal, net.PriorityNormal)
+	})
+	b.Run("fair", func(b *testing.B) {
+		benchmarkCongestedLink(b, false, net.PriorityNormal, net.PriorityNormal)
+	})
+	b.Run("prioritized", func(b *testing.B) {
+		benchmarkCongestedLink(b, false, net.PriorityBulk, net.PriorityInteractive)
+	})
+}
+
+func benchmarkCongestedLink(b *testing.B, fifo bool, bulkPriority, interactivePriority net.Priority) {
+	const bulkSessions = 4
+	const bulkChunkSize = 64 * 1024
+	const echoSize = 64
+
+	var ctx, cancel = context.WithCancel(context.Background())
+	var localID, _ = id.GenerateIdentity()
+	var remoteID, _ = id.GenerateIdentity()
+	var localConn, remoteConn = streams.Pipe()
+	var wg sync.WaitGroup
+
+	var localLink = NewCoreLink(NewSecureConn(localID, remoteID, &throttledConn{
+		ReadWriteCloser: localConn,
+		bytesPerSecond:  8 * 1024 * 1024,
+	}), DefaultConfig())
+	var remoteLink = NewCoreLink(NewSecureConn(remoteID, localID, remoteConn), DefaultConfig())
+
+	localLink.mux.Scheduler().SetFIFO(fifo)
+	localLink.SetUplink(benchRouter{})
+	remoteLink.SetUplink(benchRouter{})
+
+	for _, l := range []*CoreLink{localLink, remoteLink} {
+		l := l
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			l.Run(ctx)
+		}()
+	}
+
+	defer func() {
+		cancel()
+		localLink.Close()
+		wg.Wait()
+	}()
+
+	var bulkHints = net.DefaultHints().WithPriority(bulkPriority)
+	for i := 0; i < bulkSessions; i++ {
+		conn, err := net.RouteWithHints(ctx, localLink, net.NewQuery(localID, remoteID, "sink"), bulkHints)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer conn.Close()
+
+			var chunk = make([]byte, bulkChunkSize)
+			for {
+				if _, err := conn.Write(chunk); err != nil {
+					return
+				}
+			}
+		}()
+	}
+
+	var echoHints = net.DefaultHints().WithPriority(interactivePriority)
+	echo, err := net.RouteWithHints(ctx, localLink, net.NewQuery(localID, remoteID, "echo"), echoHints)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer echo.Close()
+
+	var out = make([]byte, echoSize)
+	var in = make([]byte, echoSize)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := echo.Write(out); err != nil {
+			b.Fatal(err)
+		}
+		if _, err := io.ReadFull(echo, in); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.StopTimer()
+}
+
+func TestRemotePriority(t *testing.T) {
+	var link = &CoreLink{config: DefaultConfig()}
+
+	for announced, expected := range map[net.Priority]net.Priority{
+		-100:                    net.PriorityBulk,
+		net.PriorityBulk:        net.PriorityBulk,
+		net.PriorityInteractive: net.PriorityInteractive,
+		100:                     net.PriorityInteractive,
+	} {
+		if p := link.remotePriority(announced); p != expected {
+			t.Fatalf("priority %d clamped to %v, expected %v", announced, p, expected)
+		}
+	}
+
+	link.config.MaxRemotePriority = net.PriorityNormal
+	if p := link.remotePriority(net.PriorityInteractive); p != net.PriorityNormal {
+		t.Fatalf("priority not limited by config, got %v", p)
+	}
+}
+
+func TestPingFlood(t *testing.T) {
+	var leftID, _ = id.GenerateIdentity()
+	var rightID, _ = id.GenerateIdentity()
+
+	// nothing reads from the other end, so pongs stay queued
+	var conn, peer = streams.Pipe()
+	defer peer.Close()
+
+	var link = NewCoreLink(NewSecureConn(leftID, rightID, conn), DefaultConfig())
+
+	for i := 0; i < 100; i++ {
+		if err := link.control.handlePing(Ping{Nonce: i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if n := link.control.pongs.Load(); n != maxQueuedPongs {
+		t.Fatalf("%d pongs queued, expected %d", n, maxQueuedPongs)
+	}
+}
@@ -1,10 +1,19 @@
 package link
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"io"
+)
 
 var ErrProtocolError = errors.New("protocol error")
 var ErrLinkClosed = errors.New("link closed")
 var ErrLinkClosedByPeer = errors.New("link closed by peer")
+
+// ErrLinkLost is set when the control port goes away without a close frame,
+// which means the transport ended. It wraps io.EOF, which used to be reported
+// in this case, so errors.Is(err, io.EOF) checks keep working.
+var ErrLinkLost = fmt.Errorf("link lost: %w", io.EOF)
 var ErrRemoteBufferOverflow = errors.New("remote buffer overflow")
 var ErrPortBufferOverflow = errors.New("port buffer overflow")
 var ErrPortBufferEmpty = errors.New("port buffer empty")
@@ -12,3 +21,8 @@ var ErrPortClosed = errors.New("port closed")
 var ErrPingTimeout = errors.New("ping timeout")
 var ErrTooManyPings = errors.New("too many pings in progress")
 var ErrInvalidNonce = errors.New("invalid ping nonce")
+var ErrIdentityMismatch = errors.New("identity mismatch")
+var ErrSessionExpired = errors.New("session expired")
+var ErrResumeFailed = errors.New("resume failed")
+var ErrRekeyUnsupported = errors.New("rekey unsupported")
+var ErrRekeyTimeout = errors.New("rekey timeout")
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"github.com/cryptopunkscc/astrald/auth"
 	"github.com/cryptopunkscc/astrald/auth/id"
+	"github.com/cryptopunkscc/astrald/auth/obfs"
 	"github.com/cryptopunkscc/astrald/cslq"
 	"github.com/cryptopunkscc/astrald/net"
 	"github.com/cryptopunkscc/astrald/node/infra"
@@ -18,11 +19,13 @@ import (
 const DefaultWorkerCount = 8
 const DefaultTimeout = time.Minute
 const featureMux = "mux"
+const featureBond = "bond"
 const featureListFormat = "[s][c]c"
 
 type Opts struct {
 	Endpoints []net.Endpoint
 	Workers   int
+	Bond      bool // link over a bond and add all reachable endpoints as its paths
 }
 
 type Node interface {
@@ -37,6 +40,7 @@ func Open(
 	conn net.Conn,
 	remoteID id.Identity,
 	localID id.Identity,
+	config Config,
 ) (link *CoreLink, err error) {
 	defer func() {
 		if err != nil {
@@ -77,27 +81,42 @@ func Open(
 		return nil, errors.New("link feature negotation error")
 	}
 
-	return NewCoreLink(secureConn), nil
+	return NewCoreLink(secureConn, config), nil
 }
 
-// Accept negotiaties a link over the provided conn as the passive party
+// Accept negotiaties a link over the provided conn as the passive party. If the remote party adds the conn
+// to an existing bonded link, that link is returned.
 func Accept(
 	ctx context.Context,
 	conn net.Conn,
 	localID id.Identity,
-) (link *CoreLink, err error) {
+	config Config,
+) (link net.Link, err error) {
 	defer func() {
 		if err != nil {
 			conn.Close()
 		}
 	}()
 
+	// obfuscated connections are unwrapped before the handshake
+	if key, err := obfs.NodeKey(localID); err == nil {
+		var accept = obfs.Accept
+		if config.ObfuscatedOnly {
+			accept = obfs.AcceptObfuscated
+		}
+		if conn, err = accept(ctx, conn, key); err != nil {
+			return nil, err
+		}
+	} else if config.ObfuscatedOnly {
+		return nil, err
+	}
+
 	secureConn, err := auth.HandshakeInbound(ctx, conn, localID)
 	if err != nil {
 		return
 	}
 
-	var linkFeatures = []string{featureMux}
+	var linkFeatures = []string{featureMux, featureBond}
 
 	err = cslq.Encode(secureConn, featureListFormat, linkFeatures)
 	if err != nil {
@@ -114,7 +133,15 @@ func Accept(
 		switch feature {
 		case featureMux:
 			cslq.Encode(secureConn, "c", 0)
-			return NewCoreLink(secureConn), nil
+			return NewCoreLink(secureConn, config), nil
+
+		case featureBond:
+			var bonded *BondedLink
+			bonded, err = acceptBondPath(secureConn, config)
+			if err != nil {
+				return nil, err
+			}
+			return bonded, nil
 
 		default:
 			cslq.Encode(secureConn, "c", 1)
@@ -130,13 +157,15 @@ func Accept(
 // MakeLink tries to establish a new link from the node to the provided identity. It does not automatically add
 // the new link to node's network. By default, it will try to reach the node using all endpoints from node's tracker.
 // This can be overriden in opts. MakeLink will spawn DefaultWorkerCount concurrent workers, unless overriden by
-// opts.
+// opts. If opts.Bond is set, the link is a BondedLink and endpoints reached after the first one are added to it as
+// paths in the background. The new link uses the provided config.
 func MakeLink(
 	ctx context.Context,
 	node Node,
 	remoteIdentity id.Identity,
+	config Config,
 	opts Opts,
-) (*CoreLink, error) {
+) (net.Link, error) {
 	var localIdentity = node.Identity()
 
 	if localIdentity.PrivateKey() == nil {
@@ -164,14 +193,48 @@ func MakeLink(
 
 	var wg sync.WaitGroup
 	var linked atomic.Bool
-	var res = make(chan *CoreLink)
+	var res = make(chan net.Link)
 	var workers = opts.Workers
 	if workers == 0 {
 		workers = DefaultWorkerCount
 	}
 
-	workerCtx, cancelWorkers := context.WithTimeout(ctx, DefaultTimeout)
-	defer cancelWorkers()
+	var workerCtx context.Context
+	var cancelWorkers context.CancelFunc
+	var open = func(conn net.Conn) (net.Link, error) {
+		return Open(workerCtx, conn, remoteIdentity, localIdentity, config)
+	}
+
+	if opts.Bond {
+		// paths keep being added after MakeLink returns, until the caller's context ends
+		workerCtx, cancelWorkers = context.WithTimeout(ctx, DefaultTimeout)
+		go func() {
+			wg.Wait()
+			cancelWorkers()
+		}()
+
+		var mu sync.Mutex
+		var bonded *BondedLink
+		open = func(conn net.Conn) (net.Link, error) {
+			mu.Lock()
+			if bonded == nil {
+				defer mu.Unlock()
+
+				l, err := OpenBond(workerCtx, conn, remoteIdentity, localIdentity, config)
+				if err != nil {
+					return nil, err
+				}
+				bonded = l
+				return l, nil
+			}
+			mu.Unlock()
+
+			return bonded, bonded.AddPath(workerCtx, conn)
+		}
+	} else {
+		workerCtx, cancelWorkers = context.WithTimeout(ctx, DefaultTimeout)
+		defer cancelWorkers()
+	}
 
 	wg.Add(workers)
 	for i := 0; i < workers; i++ {
@@ -196,18 +259,24 @@ func MakeLink(
 					break
 				}
 
-				link, err := Open(workerCtx, conn, remoteIdentity, localIdentity)
+				link, err := open(conn)
 				if err != nil {
 					break
 				}
 
 				if !linked.CompareAndSwap(false, true) {
-					link.Close()
-					return
+					if !opts.Bond {
+						link.Close()
+						return
+					}
+					// the conn was added as a path, try the next endpoint
+					continue
 				}
 
 				res <- link
-				return
+				if !opts.Bond {
+					return
+				}
 			}
 		}()
 	}
@@ -1,11 +1,21 @@
 package link
 
+import (
+	"github.com/cryptopunkscc/astrald/cslq"
+	"github.com/cryptopunkscc/astrald/net"
+	"io"
+)
+
 const (
 	codeQuery = iota
 	codeGrowBuffer
 	codeReset
 	codePing
 	codePong
+	codeCompress
+	codeRekey
+	codeRekeyAck
+	codeRekeyDone
 )
 
 const (
@@ -36,15 +46,91 @@ type Pong struct {
 	Nonce int `cslq:"l"`
 }
 
+// CompressionOffer lists compression methods that a party can decode. It's sent after the first Ping on a link,
+// so that peers which don't support compression ignore it.
+type CompressionOffer struct {
+	Methods []int `cslq:"[c]c"`
+}
+
+// Compress tells the remote party that all frames sent after this message are compressed using the method.
+// It's only sent to parties that offered the method.
+type Compress struct {
+	Method int `cslq:"c"`
+}
+
+func decodeCompressionOffer(r io.Reader) (offer CompressionOffer, err error) {
+	err = cslq.Decode(r, "v", &offer)
+	return
+}
+
+// RekeyOffer tells the remote party that the link can replace the keys of its transport. It's sent after
+// the CompressionOffer.
+type RekeyOffer struct {
+	Version int `cslq:"c"`
+}
+
+func decodeRekeyOffer(r io.Reader) (offer RekeyOffer, err error) {
+	err = cslq.Decode(r, "v", &offer)
+	return
+}
+
+// Rekey starts replacing the keys of the transport. It carries the ephemeral key of the initiating party.
+type Rekey struct {
+	Key []byte `cslq:"[c]c"`
+}
+
+// RekeyAck carries the ephemeral key of the responding party. Frames sent after it use the new key.
+type RekeyAck struct {
+	Key []byte `cslq:"[c]c"`
+}
+
 type Query struct {
-	Query  string `cslq:"[c]c"`
-	Port   int    `cslq:"s"`
-	Buffer int    `cslq:"l"`
-	Nonce  uint64 `cslq:"q"`
+	Query    string       `cslq:"[c]c"`
+	Port     int          `cslq:"s"`
+	Buffer   int          `cslq:"l"`
+	Nonce    uint64       `cslq:"q"`
+	Priority net.Priority `cslq:"skip"`
+	Flags    int          `cslq:"skip"`
+}
+
+// session flags sent after the priority of a Query and after a Response
+const (
+	flagResumable = 1 << iota // the party supports resuming the session over a new route
+)
+
+// The priority of a query is sent as a single signed byte after the Query message. Peers that don't support
+// priorities ignore the trailing byte of the frame, and queries from such peers get the normal priority.
+
+func encodePriority(w io.Writer, priority net.Priority) error {
+	return cslq.Encode(w, "c", uint8(int8(priority)))
+}
+
+func decodePriority(r io.Reader) net.Priority {
+	var b uint8
+	if err := cslq.Decode(r, "c", &b); err != nil {
+		return net.PriorityNormal
+	}
+	return net.Priority(int8(b))
+}
+
+// Flags of a query follow its priority and flags of a response follow the response. Peers that don't support
+// flags ignore them, and messages from such peers have no flags set.
+
+func encodeFlags(w io.Writer, flags int) error {
+	return cslq.Encode(w, "c", flags)
+}
+
+func decodeFlags(r io.Reader) int {
+	var flags int
+	if err := cslq.Decode(r, "c", &flags); err != nil {
+		return 0
+	}
+	return flags
 }
 
 type Response struct {
 	Error  int `cslq:"c"`
 	Port   int `cslq:"s"`
 	Buffer int `cslq:"l"`
+	Flags  int `cslq:"skip"`
 }
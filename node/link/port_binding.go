@@ -12,6 +12,10 @@ type PortBinding struct {
 	async *streams.AsyncWriter
 	link  *CoreLink
 	port  atomic.Int32
+
+	// remoteEOF is set when the remote party closed the port, as opposed to the port being unbound because
+	// the link closed
+	remoteEOF atomic.Bool
 }
 
 func NewPortBinding(output net.SecureWriteCloser, link *CoreLink) *PortBinding {
@@ -75,6 +79,7 @@ func (binding *PortBinding) handleFrame(frame mux.Frame) {
 
 	// check EOF
 	if frame.IsEmpty() {
+		binding.remoteEOF.Store(true)
 		frame.Mux.Unbind(frame.Port)
 		return
 	}
@@ -21,6 +21,7 @@ type PortWriter struct {
 	port         int
 	err          error
 	maxFrameSize int
+	priority     net.Priority
 }
 
 func NewPortWriter(link *CoreLink, port int) *PortWriter {
@@ -102,7 +103,7 @@ func (w *PortWriter) MaxFrameSize() int {
 }
 
 func (w *PortWriter) SetMaxFrameSize(maxFrameSize int) {
-	w.maxFrameSize = maxFrameSize
+	w.maxFrameSize = min(maxFrameSize, mux.MaxPayloadSize)
 }
 
 func (w *PortWriter) Close() error {
@@ -115,9 +116,23 @@ func (w *PortWriter) Close() error {
 
 	w.err = ErrPortClosed
 
+	defer w.link.mux.SetWeight(w.port, 0)
+
 	return w.link.mux.Write(mux.Frame{Port: w.port})
 }
 
+// Priority returns the scheduling priority of the port
+func (w *PortWriter) Priority() net.Priority {
+	return w.priority
+}
+
+// SetPriority sets the scheduling priority of the port. Frames of higher priority ports get sent ahead of
+// frames of lower priority ports when the link is congested.
+func (w *PortWriter) SetPriority(priority net.Priority) {
+	w.priority = priority
+	w.link.mux.SetWeight(w.port, priorityWeight(priority))
+}
+
 func (w *PortWriter) Transport() net.SecureConn {
 	return w.link.Transport()
 }
@@ -0,0 +1,221 @@
+package link
+
+import (
+	"bytes"
+	"github.com/cryptopunkscc/astrald/cslq"
+	"github.com/cryptopunkscc/astrald/mux"
+	"sync"
+	"time"
+)
+
+// Rekeyer is implemented by transports whose keys can be replaced while they're in use
+type Rekeyer interface {
+	StartRekey() ([]byte, error)
+	AcceptRekey(remoteKey []byte) ([]byte, error)
+	FinishRekey(remoteKey []byte) error
+	SwitchSendKey() error
+	SwitchRecvKey() error
+	KeyUsage() (bytes uint64, since time.Time)
+}
+
+// RekeyInterval is the age of transport keys after which links replace them. Zero disables time-based rekeying.
+var RekeyInterval = time.Hour
+
+// RekeyBytes is the number of bytes sent and received with the same transport keys after which links replace
+// them. Zero disables byte-based rekeying.
+var RekeyBytes uint64 = 1 << 30
+
+const rekeyCheckInterval = 10 * time.Second
+const rekeyTimeout = 15 * time.Second
+const rekeyVersion = 1
+
+// rekeyControl holds the state of rekeying of the link's transport
+type rekeyControl struct {
+	mu      sync.Mutex
+	remote  bool          // the remote party supports rekeying
+	pending []byte        // our ephemeral key if we started a rekey
+	done    chan struct{} // closed when the current rekey finishes
+}
+
+// Rekey replaces the keys of the link's transport and waits until both parties use the new keys. If both parties
+// start a rekey at the same time, only one of them proceeds.
+// Errors: ErrRekeyUnsupported, ErrRekeyTimeout
+func (c *Control) Rekey() error {
+	rk, ok := c.transport.(Rekeyer)
+	if !ok {
+		return ErrRekeyUnsupported
+	}
+
+	c.rekey.mu.Lock()
+	if !c.rekey.remote {
+		c.rekey.mu.Unlock()
+		return ErrRekeyUnsupported
+	}
+
+	if c.rekey.done == nil {
+		key, err := rk.StartRekey()
+		if err != nil {
+			c.rekey.mu.Unlock()
+			return err
+		}
+		c.rekey.pending = key
+		c.rekey.done = make(chan struct{})
+
+		var buf = &bytes.Buffer{}
+		cslq.Encode(buf, "cv", codeRekey, Rekey{Key: key})
+		if err := c.mux.Write(mux.Frame{Data: buf.Bytes()}); err != nil {
+			c.rekey.pending = nil
+			c.rekey.done = nil
+			c.rekey.mu.Unlock()
+			return err
+		}
+	}
+	var done = c.rekey.done
+	c.rekey.mu.Unlock()
+
+	select {
+	case <-done:
+		return nil
+	case <-c.ctx.Done():
+		return ErrLinkClosed
+	case <-time.After(rekeyTimeout):
+		c.rekey.mu.Lock()
+		if c.rekey.done == done {
+			c.rekey.pending = nil
+			c.rekey.done = nil
+		}
+		c.rekey.mu.Unlock()
+		return ErrRekeyTimeout
+	}
+}
+
+// rekeyDue returns true if the keys of the transport should be replaced
+func (c *Control) rekeyDue() bool {
+	rk, ok := c.transport.(Rekeyer)
+	if !ok {
+		return false
+	}
+
+	c.rekey.mu.Lock()
+	var remote = c.rekey.remote
+	c.rekey.mu.Unlock()
+	if !remote {
+		return false
+	}
+
+	used, since := rk.KeyUsage()
+	switch {
+	case RekeyInterval > 0 && time.Since(since) >= RekeyInterval:
+		return true
+	case RekeyBytes > 0 && used >= RekeyBytes:
+		return true
+	}
+	return false
+}
+
+func (c *Control) handleRekeyOffer(offer RekeyOffer) error {
+	if _, ok := c.transport.(Rekeyer); !ok || offer.Version < rekeyVersion {
+		return nil
+	}
+
+	c.rekey.mu.Lock()
+	c.rekey.remote = true
+	c.rekey.mu.Unlock()
+
+	return nil
+}
+
+// handleRekey is called when the remote party starts a rekey. We reply with our ephemeral key and switch
+// the send key right after the reply.
+func (c *Control) handleRekey(msg Rekey) error {
+	rk, ok := c.transport.(Rekeyer)
+	if !ok {
+		return c.CloseWithError(ErrProtocolError)
+	}
+
+	c.rekey.mu.Lock()
+	// if both parties started a rekey, the one with the greater key proceeds
+	if c.rekey.pending != nil && bytes.Compare(c.rekey.pending, msg.Key) > 0 {
+		c.rekey.mu.Unlock()
+		return nil
+	}
+	c.rekey.pending = nil
+	if c.rekey.done == nil {
+		c.rekey.done = make(chan struct{})
+	}
+	c.rekey.mu.Unlock()
+
+	key, err := rk.AcceptRekey(msg.Key)
+	if err != nil {
+		return c.CloseWithError(ErrProtocolError)
+	}
+
+	var buf = &bytes.Buffer{}
+	cslq.Encode(buf, "cv", codeRekeyAck, RekeyAck{Key: key})
+	go c.mux.WriteThen(mux.Frame{Data: buf.Bytes()}, func() {
+		if err := rk.SwitchSendKey(); err != nil {
+			c.CloseWithError(err)
+		}
+	})
+
+	return nil
+}
+
+// handleRekeyAck is called when the remote party accepted our rekey. Frames that follow the message use the new
+// key, and so will our frames after RekeyDone.
+func (c *Control) handleRekeyAck(msg RekeyAck) error {
+	rk, ok := c.transport.(Rekeyer)
+	if !ok {
+		return c.CloseWithError(ErrProtocolError)
+	}
+
+	c.rekey.mu.Lock()
+	var pending = c.rekey.pending
+	c.rekey.mu.Unlock()
+	if pending == nil {
+		return c.CloseWithError(ErrProtocolError)
+	}
+
+	if err := rk.FinishRekey(msg.Key); err != nil {
+		return c.CloseWithError(ErrProtocolError)
+	}
+	if err := rk.SwitchRecvKey(); err != nil {
+		return c.CloseWithError(err)
+	}
+
+	go c.mux.WriteThen(mux.Frame{Data: []byte{codeRekeyDone}}, func() {
+		if err := rk.SwitchSendKey(); err != nil {
+			c.CloseWithError(err)
+			return
+		}
+		c.finishRekey()
+	})
+
+	return nil
+}
+
+// handleRekeyDone is called when the remote party switched to the new key
+func (c *Control) handleRekeyDone() error {
+	rk, ok := c.transport.(Rekeyer)
+	if !ok {
+		return c.CloseWithError(ErrProtocolError)
+	}
+
+	if err := rk.SwitchRecvKey(); err != nil {
+		return c.CloseWithError(err)
+	}
+
+	c.finishRekey()
+	return nil
+}
+
+func (c *Control) finishRekey() {
+	c.rekey.mu.Lock()
+	defer c.rekey.mu.Unlock()
+
+	c.rekey.pending = nil
+	if c.rekey.done != nil {
+		close(c.rekey.done)
+		c.rekey.done = nil
+	}
+}
@@ -0,0 +1,134 @@
+package link
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"github.com/cryptopunkscc/astrald/auth"
+	"github.com/cryptopunkscc/astrald/auth/id"
+	"github.com/cryptopunkscc/astrald/net"
+	"github.com/cryptopunkscc/astrald/streams"
+	"io"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRekey(t *testing.T) {
+	const sessions = 4
+	const payloadSize = 1024 * 1024
+	const rekeys = 8
+
+	var ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+
+	var leftID, _ = id.GenerateIdentity()
+	var rightID, _ = id.GenerateIdentity()
+	var leftConn, rightConn = streams.Pipe()
+	var left, right *CoreLink
+	var wg sync.WaitGroup
+
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		l, err := Accept(ctx, &FakeConn{ReadWriteCloser: rightConn}, rightID, DefaultConfig())
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		right = l.(*CoreLink)
+	}()
+	go func() {
+		defer wg.Done()
+		var err error
+		left, err = Open(ctx, &FakeConn{ReadWriteCloser: leftConn, outbound: true}, rightID, leftID, DefaultConfig())
+		if err != nil {
+			t.Error(err)
+		}
+	}()
+	wg.Wait()
+	if t.Failed() {
+		return
+	}
+
+	left.SetUplink(benchRouter{})
+	right.SetUplink(benchRouter{})
+	go left.Run(ctx)
+	go right.Run(ctx)
+	defer left.Close()
+
+	// wait for both links to learn that the other side supports rekeying
+	var deadline = time.Now().Add(time.Second)
+	for _, l := range []*CoreLink{left, right} {
+		for {
+			err := l.Rekey()
+			if err == nil {
+				break
+			}
+			if !errors.Is(err, ErrRekeyUnsupported) || time.Now().After(deadline) {
+				t.Fatal(err)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	// send data both ways while both sides keep replacing keys
+	for _, l := range []*CoreLink{left, right} {
+		for i := 0; i < sessions; i++ {
+			l := l
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				conn, err := net.Route(ctx, l, net.NewQuery(l.LocalIdentity(), l.RemoteIdentity(), "echo"))
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				defer conn.Close()
+
+				var payload = make([]byte, payloadSize)
+				rand.Read(payload)
+
+				go func() {
+					conn.Write(payload)
+				}()
+
+				var echo = make([]byte, payloadSize)
+				if _, err := io.ReadFull(conn, echo); err != nil {
+					t.Error(err)
+					return
+				}
+				if !bytes.Equal(payload, echo) {
+					t.Error("echoed data does not match")
+				}
+			}()
+		}
+
+		l := l
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < rekeys; i++ {
+				if err := l.Rekey(); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if t.Failed() {
+		return
+	}
+
+	for _, l := range []*CoreLink{left, right} {
+		if n := l.Transport().(*auth.NoiseConn).Rekeys(); n == 0 {
+			t.Fatalf("transport was not rekeyed")
+		}
+		if _, err := l.Ping(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -7,12 +7,14 @@ import (
 type remoteBuffers struct {
 	link  *CoreLink
 	sizes map[int]int
+	acks  map[int]func(int)
 	cond  *sync.Cond
 }
 
 func newRemoteBuffers(link *CoreLink) *remoteBuffers {
 	return &remoteBuffers{
 		sizes: map[int]int{},
+		acks:  map[int]func(int){},
 		cond:  sync.NewCond(&sync.Mutex{}),
 		link:  link,
 	}
@@ -31,6 +33,7 @@ func (buffers *remoteBuffers) reset(port int) {
 	defer buffers.cond.L.Unlock()
 
 	delete(buffers.sizes, port)
+	delete(buffers.acks, port)
 	buffers.cond.Broadcast()
 }
 
@@ -42,6 +45,41 @@ func (buffers *remoteBuffers) grow(port int, size int) {
 	buffers.cond.Broadcast()
 }
 
+// onAck sets the function called with the number of bytes the remote party flushed from port's buffer
+func (buffers *remoteBuffers) onAck(port int, fn func(int)) {
+	buffers.cond.L.Lock()
+	defer buffers.cond.L.Unlock()
+
+	buffers.acks[port] = fn
+}
+
+// ack passes the number of bytes the remote party flushed from port's buffer to the port's ack function
+func (buffers *remoteBuffers) ack(port int, size int) {
+	buffers.cond.L.Lock()
+	var fn = buffers.acks[port]
+	buffers.cond.L.Unlock()
+
+	if fn != nil {
+		fn(size)
+	}
+}
+
+// take reserves size bytes in port's buffer.
+// Errors: ErrRemoteBufferOverflow
+func (buffers *remoteBuffers) take(port int, size int) error {
+	buffers.cond.L.Lock()
+	defer buffers.cond.L.Unlock()
+
+	s, open := buffers.sizes[port]
+	if !open || s < size {
+		return ErrRemoteBufferOverflow
+	}
+
+	buffers.sizes[port] = s - size
+
+	return nil
+}
+
 // wait waits for port's buffer to be at least size bytes and returns nil. If the link closes while wait is waiting,
 // it will return the error with which the link was closed.
 func (buffers *remoteBuffers) wait(port int, size int) error {
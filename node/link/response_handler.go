@@ -27,6 +27,9 @@ func (h *ResponseHandler) HandleFrame(frame mux.Frame) {
 	var res Response
 	var r = bytes.NewReader(frame.Data)
 	var err = cslq.Decode(r, "v", &res)
+	if err == nil {
+		res.Flags = decodeFlags(r)
+	}
 
 	h.Func(res, err)
 }
@@ -29,6 +29,11 @@ func (link *CoreLink) RouteQuery(ctx context.Context, query net.Query, caller ne
 		return net.RouteNotFound(link, err)
 	}
 
+	var flags int
+	if hints.Resume && link.control.resumable() {
+		flags |= flagResumable
+	}
+
 	// set up response handler
 	var done = make(chan struct{})
 	responseHandler.Func = func(res Response, herr error) {
@@ -48,21 +53,40 @@ func (link *CoreLink) RouteQuery(ctx context.Context, query net.Query, caller ne
 			return
 		}
 
+		// prepare the target
+		var w = NewPortWriter(link, res.Port)
+		w.SetPriority(hints.Priority)
+		target = w
+
+		// if the remote party accepted a resumable session, run it between the caller and the port
+		var output = caller
+		var session *Session
+		var input *sessionInput
+		if res.Flags&flagResumable != 0 && flags&flagResumable != 0 {
+			session = newSession(query.Nonce(), link.LocalIdentity(), link.RemoteIdentity(), link.config)
+			session.output = caller
+			input = session.attachPort(w)
+			output = input
+			target = session
+		}
+
 		// rebind the port to the caller
-		_, err = link.Bind(localPort, caller)
+		var binding *PortBinding
+		binding, err = link.Bind(localPort, output)
 		if err != nil {
+			target = nil
 			return
 		}
+		if input != nil {
+			input.binding = binding
+		}
 
 		// grow the remote buffer for the port
 		link.remoteBuffers.grow(res.Port, res.Buffer)
-
-		// prepare the target
-		target = NewPortWriter(link, res.Port)
 	}
 
 	// send the query to the remote peer
-	if err := link.control.Query(uint64(query.Nonce()), query.Query(), localPort); err != nil {
+	if err := link.control.Query(uint64(query.Nonce()), query.Query(), localPort, hints.Priority, flags); err != nil {
 		link.CloseWithError(err)
 		return net.RouteNotFound(link, err)
 	}
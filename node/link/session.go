@@ -0,0 +1,874 @@
+package link
+
+import (
+	"encoding/binary"
+	"errors"
+	"github.com/cryptopunkscc/astrald/auth/id"
+	"github.com/cryptopunkscc/astrald/cslq"
+	"github.com/cryptopunkscc/astrald/net"
+	"github.com/cryptopunkscc/astrald/streams"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var _ net.SecureWriteCloser = &Session{}
+
+const (
+	sessionChunkSize    = 64 * 1024
+	sessionAckThreshold = 64 * 1024
+
+	// the window has to fit a chunk on top of the data the remote party receives before it acknowledges it
+	minResumeWindow = sessionChunkSize + sessionAckThreshold
+)
+
+// codes of frames sent over resumed sessions
+const (
+	sessionData  = 'd'
+	sessionFin   = 'f'
+	sessionAck   = 'a'
+	sessionAbort = 'x'
+)
+
+// Session is a resumable session of a query routed over a link. It counts bytes in both directions and keeps
+// the bytes it sent until they're received, so that when the link is lost, both parties can continue the session
+// over a new route from exactly the byte at which the old one stopped. The party that sent the query is
+// responsible for resuming the session - it reroutes the query's conn (see relay.Module.Reroute), which hands
+// the new route to Resume on its side and to AcceptResume on the side of the remote party.
+//
+// A session can only be resumed while the router still holds its conn, so once both parties closed it, it
+// cannot be resumed anymore. The remote party waiting for data lost with the link ends the session after
+// the grace period.
+type Session struct {
+	*net.SourceField
+	nonce    net.Nonce
+	localID  id.Identity
+	remoteID id.Identity
+	grace    time.Duration
+	window   int // the size of the buffer for data received from the remote party
+
+	inMu sync.Mutex // serializes delivery of received data
+	mu   sync.Mutex
+	cond *sync.Cond
+
+	gen       int
+	transport sessionTransport // nil while the session is suspended
+	syncing   bool             // true while lost data is resent after a resume
+	framed    bool
+	expiry    *time.Timer
+	lost      chan struct{} // closed while the session is suspended
+
+	sent       uint64
+	acked      uint64
+	sendWindow int    // the number of bytes that can be sent before they're acknowledged
+	history    []byte // bytes sent but not acknowledged yet, ending at sent
+
+	received uint64
+	unacked  int
+	output   net.SecureWriteCloser
+
+	localEOF  bool
+	remoteEOF bool
+	complete  bool
+	err       error
+	resumes   int
+	done      chan struct{}
+}
+
+// sessionTransport carries the data of a session
+type sessionTransport interface {
+	write(p []byte) error
+	finish() error
+	// lost returns true if the error means that the transport is gone and the session should be resumed
+	lost(err error) bool
+	// closeErr returns the error with which the transport was closed on purpose or nil
+	closeErr() error
+	// detach stops the session from using the transport
+	detach()
+	// abort tells the remote party that the session was aborted
+	abort()
+}
+
+func newSession(nonce net.Nonce, localID id.Identity, remoteID id.Identity, config Config) *Session {
+	var s = &Session{
+		SourceField: net.NewSourceField(nil),
+		nonce:       nonce,
+		localID:     localID,
+		remoteID:    remoteID,
+		grace:       config.ResumeGracePeriod,
+		window:      max(config.ResumeWindow, minResumeWindow),
+		sendWindow:  max(config.ResumeWindow, minResumeWindow),
+		lost:        make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+	s.cond = sync.NewCond(&s.mu)
+	return s
+}
+
+// Write writes data to the session. If the session is suspended or the remote party did not acknowledge
+// a full window of data yet, Write waits.
+func (s *Session) Write(p []byte) (n int, err error) {
+	for len(p) > 0 {
+		var chunk = p
+		if len(chunk) > sessionChunkSize {
+			chunk = chunk[:sessionChunkSize]
+		}
+
+		if err = s.writeChunk(chunk); err != nil {
+			return
+		}
+
+		n += len(chunk)
+		p = p[len(chunk):]
+	}
+	return
+}
+
+func (s *Session) writeChunk(p []byte) error {
+	s.mu.Lock()
+	for {
+		if s.err != nil {
+			s.mu.Unlock()
+			return s.err
+		}
+		if s.localEOF {
+			s.mu.Unlock()
+			return ErrPortClosed
+		}
+		if s.transport != nil && !s.syncing && int(s.sent-s.acked)+len(p) <= s.sendWindow {
+			break
+		}
+		s.cond.Wait()
+	}
+
+	var gen, t = s.gen, s.transport
+
+	// record the data before writing it, so that if the transport fails midway, the data is resent on resume
+	s.history = append(s.history, p...)
+	s.sent += uint64(len(p))
+	s.mu.Unlock()
+
+	if err := t.write(p); err != nil {
+		return s.transportError(gen, t, err)
+	}
+	return nil
+}
+
+// Close closes the sending direction of the session
+func (s *Session) Close() error {
+	s.mu.Lock()
+	if s.err != nil || s.localEOF {
+		s.mu.Unlock()
+		return nil
+	}
+	s.localEOF = true
+
+	// a suspended session will send the EOF when it's resumed
+	var gen, t = s.gen, s.transport
+	if t == nil || s.syncing {
+		s.mu.Unlock()
+		return nil
+	}
+	s.mu.Unlock()
+
+	if err := t.finish(); err != nil {
+		s.transportError(gen, t, err)
+	}
+
+	s.checkDone()
+	return nil
+}
+
+// Identity returns the identity of the remote party
+func (s *Session) Identity() id.Identity {
+	return s.remoteID
+}
+
+// Nonce returns the nonce of the session's query
+func (s *Session) Nonce() net.Nonce {
+	return s.nonce
+}
+
+// Resumes returns the number of times the session was resumed
+func (s *Session) Resumes() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.resumes
+}
+
+// Suspended returns true if the session lost its transport and waits to be resumed
+func (s *Session) Suspended() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.err == nil && s.transport == nil
+}
+
+// Lost returns a channel that will be closed when the session loses its transport. Once the session is
+// resumed, Lost returns a new channel.
+func (s *Session) Lost() <-chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.lost
+}
+
+// Done returns a channel that will be closed when the session ends
+func (s *Session) Done() <-chan struct{} {
+	return s.done
+}
+
+// Err returns the error that ended the session
+func (s *Session) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.err
+}
+
+// attachPort sets up the session to run over a link port. It returns the writer to which the port has to be bound.
+func (s *Session) attachPort(w *PortWriter) *sessionInput {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var input = &sessionInput{SourceField: net.NewSourceField(nil), session: s, gen: s.gen}
+	s.transport = &portTransport{w: w, input: input}
+
+	var gen = s.gen
+	w.link.remoteBuffers.onAck(w.port, func(n int) {
+		s.handlePortAck(gen, n)
+	})
+
+	// the port may not report the loss while its buffers are full, so watch the link itself
+	var t = s.transport
+	go func() {
+		select {
+		case <-w.link.Done():
+			s.transportError(gen, t, ErrLinkLost)
+		case <-s.done:
+		}
+	}()
+
+	return input
+}
+
+// deliver writes data received over the transport of the generation to the output
+func (s *Session) deliver(gen int, p []byte) error {
+	s.inMu.Lock()
+	defer s.inMu.Unlock()
+
+	// count the data before writing it, so that a resume doesn't wait for an output that may itself wait
+	// for the session
+	s.mu.Lock()
+	var ok = gen == s.gen && s.err == nil && !s.remoteEOF
+	var out = s.output
+	if ok {
+		s.received += uint64(len(p))
+	}
+	s.mu.Unlock()
+
+	// data from a stale transport will be sent again after resume
+	if !ok {
+		return nil
+	}
+
+	if _, err := out.Write(p); err != nil {
+		s.end(err)
+		return err
+	}
+
+	// acknowledge the data once it's written, so that the remote party doesn't overflow our buffer
+	s.mu.Lock()
+	var ack, t = uint64(0), s.transport
+	if s.framed && gen == s.gen {
+		s.unacked += len(p)
+		if s.unacked >= sessionAckThreshold {
+			s.unacked = 0
+			ack = s.received
+		}
+	}
+	s.mu.Unlock()
+
+	if ack > 0 && t != nil {
+		if ft, ok := t.(*framedTransport); ok {
+			ft.ack(ack)
+		}
+	}
+
+	return nil
+}
+
+// inputEOF is called when the remote party closes its sending direction
+func (s *Session) inputEOF(gen int) {
+	s.inMu.Lock()
+	defer s.inMu.Unlock()
+
+	s.mu.Lock()
+	if gen != s.gen || s.err != nil || s.remoteEOF {
+		s.mu.Unlock()
+		return
+	}
+	s.remoteEOF = true
+	var out = s.output
+	s.mu.Unlock()
+
+	out.Close()
+	s.checkDone()
+}
+
+// handleAck is called when the remote party confirms that it received data up to the provided count
+func (s *Session) handleAck(gen int, received uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if gen != s.gen || received <= s.acked || received > s.sent {
+		return
+	}
+	s.acked = received
+	s.trimHistory()
+	s.cond.Broadcast()
+}
+
+// handlePortAck is called when the remote party flushed n more bytes received over the port transport
+func (s *Session) handlePortAck(gen int, n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if gen != s.gen {
+		return
+	}
+	s.acked = min(s.acked+uint64(n), s.sent)
+	s.trimHistory()
+	s.cond.Broadcast()
+}
+
+// trimHistory drops bytes that will never have to be sent again
+func (s *Session) trimHistory() {
+	var start = s.sent - uint64(len(s.history))
+	if s.acked > start {
+		s.history = s.history[s.acked-start:]
+	}
+}
+
+// transportError handles an error returned by the transport of the generation and returns the error
+// that should be returned to the writer
+func (s *Session) transportError(gen int, t sessionTransport, err error) error {
+	// sessions of links closed on purpose will not be resumed
+	if cerr := t.closeErr(); cerr != nil {
+		s.end(cerr)
+		return cerr
+	}
+
+	if !t.lost(err) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		if gen != s.gen {
+			return nil
+		}
+		return err
+	}
+
+	s.suspend(gen)
+	return nil
+}
+
+// suspend detaches the session from its transport and waits for the session to be resumed
+func (s *Session) suspend(gen int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if gen != s.gen || s.err != nil || (s.localEOF && s.remoteEOF) {
+		return
+	}
+
+	if s.transport != nil {
+		if err := s.transport.closeErr(); err != nil {
+			go s.end(err)
+			return
+		}
+		go s.transport.detach()
+	}
+	s.transport = nil
+	s.syncing = false
+	s.gen++
+
+	if s.grace <= 0 {
+		go s.end(ErrSessionExpired)
+		return
+	}
+
+	if s.expiry == nil {
+		s.expiry = time.AfterFunc(s.grace, func() {
+			s.end(ErrSessionExpired)
+		})
+	}
+
+	select {
+	case <-s.lost:
+	default:
+		close(s.lost)
+	}
+
+	s.cond.Broadcast()
+}
+
+// Resume continues the session over conn, which the remote party passes to AcceptResume of its side of
+// the session. Data that the remote party did not receive over the previous transport is sent again. An active
+// session is moved to the new transport.
+func (s *Session) Resume(conn net.SecureConn) error {
+	gen, err := s.detach(conn)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+
+	var peerReceived, peerWindow uint64
+	err = cslq.Encode(conn, "qq", s.receivedCount(), s.window)
+	if err == nil {
+		err = cslq.Decode(conn, "qq", &peerReceived, &peerWindow)
+	}
+	if err != nil {
+		conn.Close()
+		s.suspend(gen)
+		return err
+	}
+
+	return s.attach(conn, peerReceived, int(min(peerWindow, uint64(s.window))))
+}
+
+// AcceptResume continues the session over conn, which the remote party passes to Resume of its side of
+// the session.
+func (s *Session) AcceptResume(conn net.SecureConn) error {
+	gen, err := s.detach(conn)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+
+	var peerReceived, peerWindow uint64
+	err = cslq.Decode(conn, "qq", &peerReceived, &peerWindow)
+	if err == nil {
+		err = cslq.Encode(conn, "qq", s.receivedCount(), s.window)
+	}
+	if err != nil {
+		conn.Close()
+		s.suspend(gen)
+		return err
+	}
+
+	return s.attach(conn, peerReceived, int(min(peerWindow, uint64(s.window))))
+}
+
+// detach stops the session from using its transport before it's moved to conn and returns the generation
+// of the detached session
+func (s *Session) detach(conn net.SecureConn) (int, error) {
+	if !conn.LocalIdentity().IsEqual(s.localID) || !conn.RemoteIdentity().IsEqual(s.remoteID) {
+		return 0, ErrIdentityMismatch
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err != nil {
+		return 0, s.err
+	}
+	if s.complete {
+		return 0, ErrPortClosed
+	}
+
+	// stop delivering data from the old transport, so that the received count doesn't change
+	if s.transport != nil {
+		go s.transport.detach()
+		s.transport = nil
+		s.syncing = false
+		s.gen++
+	}
+
+	return s.gen, nil
+}
+
+// receivedCount returns the number of bytes delivered to the output. It must only be called after the session
+// was detached from its transport.
+func (s *Session) receivedCount() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.received
+}
+
+// attach continues the session over conn by resending everything the remote party did not receive. No more
+// than the window of data is sent before the remote party acknowledges it.
+func (s *Session) attach(conn net.SecureConn, peerReceived uint64, window int) error {
+	s.mu.Lock()
+
+	if s.err != nil {
+		s.mu.Unlock()
+		conn.Close()
+		return s.err
+	}
+
+	var start = s.sent - uint64(len(s.history))
+	if peerReceived < start || peerReceived > s.sent || window < minResumeWindow {
+		s.mu.Unlock()
+		conn.Close()
+		s.end(ErrResumeFailed)
+		return ErrResumeFailed
+	}
+
+	if s.transport != nil {
+		go s.transport.detach()
+	}
+
+	var t = newFramedTransport(conn)
+	s.gen++
+	var gen = s.gen
+	s.transport = t
+	s.syncing = true
+	s.framed = true
+	s.acked = peerReceived
+	s.sendWindow = window
+	s.unacked = 0
+	s.resumes++
+	if s.expiry != nil {
+		s.expiry.Stop()
+		s.expiry = nil
+	}
+	select {
+	case <-s.lost:
+		s.lost = make(chan struct{})
+	default:
+	}
+	s.trimHistory()
+
+	var lost = s.history
+	var fin = s.localEOF
+	s.mu.Unlock()
+
+	go s.readFrames(gen, t)
+
+	var err = t.write(lost)
+	if err == nil && fin {
+		err = t.finish()
+	}
+
+	s.mu.Lock()
+	if s.gen == gen {
+		s.syncing = false
+		s.cond.Broadcast()
+	}
+	s.mu.Unlock()
+
+	if err != nil {
+		s.suspend(gen)
+		return err
+	}
+
+	s.checkDone()
+	return nil
+}
+
+// readFrames reads frames of a resumed session until the transport fails
+func (s *Session) readFrames(gen int, t *framedTransport) {
+	var hdr [5]byte
+
+	// deliver data from a separate goroutine, so that acks are read even while the output blocks
+	var input = &frameInput{session: s, gen: gen}
+	var async = streams.NewAsyncWriter(input, s.window)
+	defer async.Close()
+
+	var suspend = func() {
+		// the remote party releases the transport once it got our EOF, so deliver the rest of its data first
+		if input.fin.Load() {
+			async.Close()
+			<-async.Done()
+		}
+		s.suspend(gen)
+	}
+
+	for {
+		if _, err := io.ReadFull(t.conn, hdr[:1]); err != nil {
+			suspend()
+			return
+		}
+
+		switch hdr[0] {
+		case sessionData:
+			if _, err := io.ReadFull(t.conn, hdr[1:5]); err != nil {
+				suspend()
+				return
+			}
+			var size = binary.BigEndian.Uint32(hdr[1:5])
+			if size == 0 || size > sessionChunkSize {
+				s.end(ErrProtocolError)
+				return
+			}
+			var buf = make([]byte, size)
+			if _, err := io.ReadFull(t.conn, buf); err != nil {
+				suspend()
+				return
+			}
+			// the remote party never sends more than the window before we acknowledge it
+			if _, err := async.Write(buf); err != nil {
+				s.end(ErrProtocolError)
+				return
+			}
+
+		case sessionFin:
+			input.fin.Store(true)
+			async.Close()
+
+		case sessionAck:
+			var received uint64
+			if err := cslq.Decode(t.conn, "q", &received); err != nil {
+				suspend()
+				return
+			}
+			s.handleAck(gen, received)
+
+		case sessionAbort:
+			s.end(ErrPortClosed)
+			return
+
+		default:
+			s.end(ErrProtocolError)
+			return
+		}
+	}
+}
+
+// checkDone releases the transport once both parties closed their sending directions
+func (s *Session) checkDone() {
+	s.mu.Lock()
+	if !s.localEOF || !s.remoteEOF || s.syncing || s.transport == nil || s.complete {
+		s.mu.Unlock()
+		return
+	}
+	s.complete = true
+	var t, framed = s.transport, s.framed
+	s.mu.Unlock()
+
+	if framed {
+		t.detach()
+	}
+}
+
+// end ends the session with an error
+func (s *Session) end(err error) {
+	s.mu.Lock()
+	if s.err != nil {
+		s.mu.Unlock()
+		return
+	}
+	s.err = err
+	var t = s.transport
+	s.transport = nil
+	s.gen++
+	if s.expiry != nil {
+		s.expiry.Stop()
+	}
+	var closeOutput = !s.remoteEOF && s.output != nil
+	s.cond.Broadcast()
+	s.mu.Unlock()
+
+	if t != nil {
+		t.abort()
+	}
+	if closeOutput {
+		s.output.Close()
+	}
+
+	close(s.done)
+}
+
+// sessionInput passes data received from a link port to the session
+type sessionInput struct {
+	*net.SourceField
+	session *Session
+	gen     int
+	binding *PortBinding
+}
+
+func (in *sessionInput) Write(p []byte) (int, error) {
+	if err := in.session.deliver(in.gen, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (in *sessionInput) Close() error {
+	if in.binding != nil && in.binding.remoteEOF.Load() {
+		in.session.inputEOF(in.gen)
+	} else {
+		in.session.suspend(in.gen)
+	}
+	return nil
+}
+
+func (in *sessionInput) Identity() id.Identity {
+	return in.session.localID
+}
+
+// portTransport runs a session over a pair of link ports
+type portTransport struct {
+	w     *PortWriter
+	input *sessionInput
+}
+
+func (t *portTransport) write(p []byte) error {
+	_, err := t.w.Write(p)
+	return err
+}
+
+func (t *portTransport) finish() error {
+	return t.w.Close()
+}
+
+func (t *portTransport) lost(err error) bool {
+	// the port is closed when the remote party stops reading, other errors come from the link
+	return !errors.Is(err, ErrPortClosed) || t.w.link.Err() != nil
+}
+
+func (t *portTransport) closeErr() error {
+	switch err := t.w.link.Err(); {
+	case errors.Is(err, ErrLinkClosed), errors.Is(err, ErrLinkClosedByPeer):
+		return err
+	}
+	return nil
+}
+
+func (t *portTransport) detach() {
+	// unblock a writer waiting for the remote buffer
+	t.w.link.remoteBuffers.reset(t.w.port)
+	t.abort()
+}
+
+func (t *portTransport) abort() {
+	t.w.Close()
+	if t.input.binding != nil {
+		if port := t.input.binding.Port(); port != 0 {
+			t.w.link.Unbind(port)
+		}
+	}
+}
+
+// frameInput passes data received over a framed transport to the session
+type frameInput struct {
+	session *Session
+	gen     int
+	fin     atomic.Bool
+}
+
+func (in *frameInput) Write(p []byte) (int, error) {
+	if err := in.session.deliver(in.gen, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (in *frameInput) Close() error {
+	if in.fin.Load() {
+		in.session.inputEOF(in.gen)
+	}
+	return nil
+}
+
+// framedTransport runs a resumed session over a conn
+type framedTransport struct {
+	conn   net.SecureConn
+	mu     sync.Mutex
+	acks   chan uint64
+	closed chan struct{}
+	once   sync.Once
+}
+
+func newFramedTransport(conn net.SecureConn) *framedTransport {
+	var t = &framedTransport{
+		conn:   conn,
+		acks:   make(chan uint64, 1),
+		closed: make(chan struct{}),
+	}
+	go t.sendAcks()
+	return t
+}
+
+func (t *framedTransport) write(p []byte) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var hdr [5]byte
+	hdr[0] = sessionData
+	for len(p) > 0 {
+		var n = min(len(p), sessionChunkSize)
+		binary.BigEndian.PutUint32(hdr[1:], uint32(n))
+		if _, err := t.conn.Write(hdr[:]); err != nil {
+			return err
+		}
+		if _, err := t.conn.Write(p[:n]); err != nil {
+			return err
+		}
+		p = p[n:]
+	}
+	return nil
+}
+
+func (t *framedTransport) finish() error {
+	return t.writeCode(sessionFin)
+}
+
+// ack queues an acknowledgment of received data. Acks are sent from a separate goroutine, so that reading
+// never waits for writing, and only the most recent one is sent.
+func (t *framedTransport) ack(received uint64) {
+	select {
+	case <-t.acks:
+	default:
+	}
+	select {
+	case t.acks <- received:
+	default:
+	}
+}
+
+func (t *framedTransport) sendAcks() {
+	for {
+		select {
+		case received := <-t.acks:
+			t.mu.Lock()
+			err := cslq.Encode(t.conn, "cq", sessionAck, received)
+			t.mu.Unlock()
+			if err != nil {
+				return
+			}
+		case <-t.closed:
+			return
+		}
+	}
+}
+
+func (t *framedTransport) lost(error) bool {
+	return true
+}
+
+func (t *framedTransport) closeErr() error {
+	return nil
+}
+
+func (t *framedTransport) detach() {
+	t.once.Do(func() { close(t.closed) })
+	t.conn.Close()
+}
+
+func (t *framedTransport) abort() {
+	t.writeCode(sessionAbort)
+	t.detach()
+}
+
+func (t *framedTransport) writeCode(code byte) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	_, err := t.conn.Write([]byte{code})
+	return err
+}
@@ -0,0 +1,283 @@
+package link
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"github.com/cryptopunkscc/astrald/auth/id"
+	"github.com/cryptopunkscc/astrald/net"
+	"github.com/cryptopunkscc/astrald/streams"
+	"io"
+	"sync"
+	"testing"
+	"time"
+)
+
+// echoRouter echoes back everything it receives
+type echoRouter struct{}
+
+func (echoRouter) RouteQuery(ctx context.Context, query net.Query, caller net.SecureWriteCloser, hints net.Hints) (net.SecureWriteCloser, error) {
+	return net.Accept(query, caller, func(conn net.SecureConn) {
+		io.Copy(conn, conn)
+		conn.Close()
+	})
+}
+
+// resumeRouter echoes back everything it receives and resumes the most recent echo session over "resume"
+// queries, as the reroute service of the relay does
+type resumeRouter struct {
+	sync.Mutex
+	session *Session
+}
+
+func (r *resumeRouter) RouteQuery(ctx context.Context, query net.Query, caller net.SecureWriteCloser, hints net.Hints) (net.SecureWriteCloser, error) {
+	if query.Query() != "resume" {
+		if s, ok := caller.(*Session); ok {
+			r.Lock()
+			r.session = s
+			r.Unlock()
+		}
+		return echoRouter{}.RouteQuery(ctx, query, caller, hints)
+	}
+
+	r.Lock()
+	var s = r.session
+	r.Unlock()
+
+	return net.Accept(query, caller, func(conn net.SecureConn) {
+		s.AcceptResume(conn)
+	})
+}
+
+// targetRecorder routes queries over a link and records the last target
+type targetRecorder struct {
+	*CoreLink
+	target net.SecureWriteCloser
+}
+
+func (r *targetRecorder) RouteQuery(ctx context.Context, query net.Query, caller net.SecureWriteCloser, hints net.Hints) (target net.SecureWriteCloser, err error) {
+	target, err = r.CoreLink.RouteQuery(ctx, query, caller, hints)
+	r.target = target
+	return
+}
+
+// linkRouter routes queries over the most recent link
+type linkRouter struct {
+	sync.Mutex
+	link *CoreLink
+}
+
+func (r *linkRouter) RouteQuery(ctx context.Context, query net.Query, caller net.SecureWriteCloser, hints net.Hints) (net.SecureWriteCloser, error) {
+	r.Lock()
+	var l = r.link
+	r.Unlock()
+
+	return l.RouteQuery(ctx, query, caller, hints)
+}
+
+func (r *linkRouter) setLink(l *CoreLink) {
+	r.Lock()
+	defer r.Unlock()
+	r.link = l
+}
+
+func TestSessionResume(t *testing.T) {
+	var ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+
+	var leftID, _ = id.GenerateIdentity()
+	var rightID, _ = id.GenerateIdentity()
+	var router = &linkRouter{}
+	var remote = &resumeRouter{}
+
+	var newLinks = func() (*CoreLink, io.Closer) {
+		var leftConn, rightConn = streams.Pipe()
+		var left = NewCoreLink(NewSecureConn(leftID, rightID, leftConn), DefaultConfig())
+		var right = NewCoreLink(NewSecureConn(rightID, leftID, rightConn), DefaultConfig())
+		left.SetUplink(router)
+		right.SetUplink(remote)
+		go left.Run(ctx)
+		go right.Run(ctx)
+		router.setLink(left)
+		return left, leftConn
+	}
+
+	var left, transport = newLinks()
+	var recorder = &targetRecorder{CoreLink: left}
+
+	conn, err := net.RouteWithHints(ctx, recorder, net.NewQuery(leftID, rightID, "echo"), net.DefaultHints().SetResume())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	session, ok := recorder.target.(*Session)
+	if !ok {
+		t.Fatalf("query was routed without a session")
+	}
+
+	var data = make([]byte, 8*1024*1024)
+	rand.Read(data)
+
+	go func() {
+		for i := 0; i < len(data); i += 32 * 1024 {
+			if _, err := conn.Write(data[i : i+32*1024]); err != nil {
+				t.Error(err)
+				return
+			}
+		}
+		conn.Close()
+	}()
+
+	var buf = make([]byte, len(data)/3)
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		t.Fatal(err)
+	}
+
+	var done = make(chan []byte)
+	go func() {
+		rest, _ := io.ReadAll(conn)
+		done <- rest
+	}()
+
+	// lose the link in the middle of the session and resume it over a new one
+	transport.Close()
+
+	select {
+	case <-session.Lost():
+	case <-time.After(5 * time.Second):
+		t.Fatal("session was not suspended")
+	}
+
+	newLink, _ := newLinks()
+	resumeConn, err := net.Route(ctx, newLink, net.NewQuery(leftID, rightID, "resume"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := session.Resume(resumeConn); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case rest := <-done:
+		if !bytes.Equal(append(buf, rest...), data) {
+			t.Fatalf("received %d bytes that don't match the %d bytes sent", len(buf)+len(rest), len(data))
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("session was not resumed")
+	}
+
+	if session.Resumes() != 1 {
+		t.Fatalf("session was resumed %d times", session.Resumes())
+	}
+}
+
+func TestSessionWindow(t *testing.T) {
+	var config = DefaultConfig()
+	config.ResumeWindow = minResumeWindow
+
+	var s = newSession(1, id.Identity{}, id.Identity{}, config)
+	s.transport = nopTransport{}
+
+	// a full window is sent without waiting
+	if _, err := s.Write(make([]byte, minResumeWindow)); err != nil {
+		t.Fatal(err)
+	}
+
+	var done = make(chan struct{})
+	go func() {
+		s.Write([]byte{0})
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("write exceeded the window")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	s.handlePortAck(s.gen, 1)
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("write was not resumed after an ack")
+	}
+
+	if len(s.history) != minResumeWindow {
+		t.Fatalf("history holds %d bytes", len(s.history))
+	}
+}
+
+func TestSessionPortAck(t *testing.T) {
+	var s = newSession(1, id.Identity{}, id.Identity{}, DefaultConfig())
+	s.history = make([]byte, 1000)
+	s.sent = 1000
+
+	// bytes flushed by the remote party will never have to be sent again
+	s.handlePortAck(s.gen, 600)
+	if s.acked != 600 || len(s.history) != 400 {
+		t.Fatalf("acked %d, history %d", s.acked, len(s.history))
+	}
+
+	// acks of a previous transport are ignored
+	s.handlePortAck(s.gen-1, 100)
+	if s.acked != 600 {
+		t.Fatalf("stale ack was applied")
+	}
+
+	s.handlePortAck(s.gen, 1000)
+	if s.acked != s.sent || len(s.history) != 0 {
+		t.Fatalf("acked %d, history %d", s.acked, len(s.history))
+	}
+}
+
+func TestSessionLinkClose(t *testing.T) {
+	var ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+
+	var leftID, _ = id.GenerateIdentity()
+	var rightID, _ = id.GenerateIdentity()
+
+	var leftConn, rightConn = streams.Pipe()
+	var left = NewCoreLink(NewSecureConn(leftID, rightID, leftConn), DefaultConfig())
+	var right = NewCoreLink(NewSecureConn(rightID, leftID, rightConn), DefaultConfig())
+	left.SetUplink(&linkRouter{link: left})
+	right.SetUplink(echoRouter{})
+	go left.Run(ctx)
+	go right.Run(ctx)
+
+	conn, err := net.RouteWithHints(ctx, left, net.NewQuery(leftID, rightID, "echo"), net.DefaultHints().SetResume())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// sessions of a link closed on purpose end right away instead of waiting to be resumed
+	left.Close()
+
+	var done = make(chan struct{})
+	go func() {
+		io.ReadAll(conn)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("session was not closed")
+	}
+
+	if !errors.Is(right.Err(), ErrLinkClosedByPeer) {
+		t.Fatalf("remote link closed with %v", right.Err())
+	}
+}
+
+// nopTransport discards everything written to a session
+type nopTransport struct{}
+
+func (nopTransport) write([]byte) error { return nil }
+func (nopTransport) finish() error      { return nil }
+func (nopTransport) lost(error) bool    { return true }
+func (nopTransport) closeErr() error    { return nil }
+func (nopTransport) detach()            {}
+func (nopTransport) abort()             {}
@@ -22,15 +22,17 @@ type CoreNetwork struct {
 	links   *LinkSet
 	events  events.Queue
 	log     *log.Logger
+	config  link.Config
 	running atomic.Bool
 	mu      sync.Mutex
 }
 
-func NewCoreNetwork(node Node, eventParent *events.Queue, log *log.Logger) (*CoreNetwork, error) {
+func NewCoreNetwork(node Node, config link.Config, eventParent *events.Queue, log *log.Logger) (*CoreNetwork, error) {
 	m := &CoreNetwork{
-		node:  node,
-		log:   log.Tag(logTag),
-		links: NewLinkSet(),
+		node:   node,
+		log:    log.Tag(logTag),
+		links:  NewLinkSet(),
+		config: config,
 	}
 
 	m.events.SetParent(eventParent)
@@ -81,9 +83,18 @@ func (n *CoreNetwork) AddLink(l net.Link) error {
 		return ErrIdentityMismatch
 	}
 
-	if corelink, ok := l.(*link.CoreLink); ok {
-		corelink.SetUplink(n.node.Router())
-		defer corelink.Check()
+	switch l := l.(type) {
+	case *link.CoreLink:
+		l.SetUplink(n.node.Router())
+		defer l.Check()
+
+	case *link.BondedLink:
+		// new paths of a bonded link return the same link, which is already running
+		if n.links.Contains(l) {
+			return nil
+		}
+		l.SetUplink(n.node.Router())
+		defer l.Check()
 	}
 
 	active, err := n.links.Add(l)
@@ -115,6 +126,11 @@ func (n *CoreNetwork) Events() *events.Queue {
 	return &n.events
 }
 
+// LinkConfig returns the config of new links
+func (n *CoreNetwork) LinkConfig() link.Config {
+	return n.config
+}
+
 func (n *CoreNetwork) Links() *LinkSet {
 	return n.links
 }
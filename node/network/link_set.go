@@ -81,6 +81,15 @@ func (set *LinkSet) find(id int) (*ActiveLink, error) {
 	return nil, ErrLinkNotFound
 }
 
+// Contains returns true if the link is in the set
+func (set *LinkSet) Contains(l net.Link) bool {
+	set.mu.Lock()
+	defer set.mu.Unlock()
+
+	_, found := set.index[l]
+	return found
+}
+
 // Remove removes a link from the set.
 // Errors: ErrLinkNotFound
 func (set *LinkSet) Remove(id int) error {
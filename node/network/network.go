@@ -3,10 +3,13 @@ package network
 import (
 	"github.com/cryptopunkscc/astrald/net"
 	"github.com/cryptopunkscc/astrald/node/events"
+	"github.com/cryptopunkscc/astrald/node/link"
 )
 
 type Network interface {
 	AddLink(net.Link) error
 	Links() *LinkSet
 	Events() *events.Queue
+	// LinkConfig returns the config of new links
+	LinkConfig() link.Config
 }
@@ -8,9 +8,11 @@ import (
 	"github.com/cryptopunkscc/astrald/log"
 	"github.com/cryptopunkscc/astrald/net"
 	"github.com/cryptopunkscc/astrald/node/events"
+	"github.com/cryptopunkscc/astrald/sig"
 	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -23,10 +25,21 @@ type CoreRouter struct {
 	events        events.Queue
 	conns         *ConnSet
 	routes        []Route
+	guards        sig.Set[Guard]
 	mu            sync.RWMutex
 	logRouteTrace bool
 	enroute       map[string]struct{}
 	enrouteMu     sync.Mutex
+	routed        atomic.Uint64
+	failed        atomic.Uint64
+	refused       atomic.Uint64
+}
+
+// Stats holds the counters of queries routed by the router
+type Stats struct {
+	Routed  uint64 // queries routed successfully
+	Failed  uint64 // queries that could not be routed
+	Refused uint64 // queries refused by guards
 }
 
 func NewCoreRouter(log *log.Logger, eventParent *events.Queue) *CoreRouter {
@@ -64,6 +77,24 @@ func (r *CoreRouter) RouteQuery(ctx context.Context, query net.Query, caller net
 
 	var startedAt = time.Now()
 
+	// rerouted queries belong to connections that were already let through
+	if !hints.Reroute {
+		if err = r.checkGuards(query, hints); err != nil {
+			r.refused.Add(1)
+			r.events.Emit(EventQueryFailed{Query: query})
+			if !silent {
+				r.log.Infov(0, "[%v] %v -> %v:%v refused: %v",
+					query.Nonce(),
+					query.Caller(),
+					query.Target(),
+					query.Query(),
+					err,
+				)
+			}
+			return nil, err
+		}
+	}
+
 	if hints.Reroute {
 		hints.Reroute = false
 		var conn = r.conns.FindByNonce(query.Nonce())
@@ -86,6 +117,12 @@ func (r *CoreRouter) RouteQuery(ctx context.Context, query net.Query, caller net
 
 	var d = time.Since(startedAt).Round(1 * time.Microsecond)
 
+	if err != nil {
+		r.failed.Add(1)
+	} else {
+		r.routed.Add(1)
+	}
+
 	// log routing results
 	if !silent {
 		if err != nil {
@@ -133,6 +170,7 @@ func (r *CoreRouter) routeMonitored(ctx context.Context, query net.Query, caller
 	target, err = r.routeQuery(ctx, query, callerMonitor, hints)
 	if err != nil {
 		r.conns.Remove(conn)
+		r.events.Emit(EventQueryFailed{Query: query})
 		return nil, err
 	}
 
@@ -230,6 +268,33 @@ func (r *CoreRouter) RemoveRoute(caller id.Identity, target id.Identity, router
 	return errors.New("route not found")
 }
 
+// AddGuard adds a guard that can refuse queries before they're routed
+func (r *CoreRouter) AddGuard(guard Guard) error {
+	return r.guards.Add(guard)
+}
+
+func (r *CoreRouter) RemoveGuard(guard Guard) error {
+	return r.guards.Remove(guard)
+}
+
+func (r *CoreRouter) checkGuards(query net.Query, hints net.Hints) error {
+	for _, guard := range r.guards.Clone() {
+		if err := guard.CheckQuery(query, hints); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Stats returns the counters of routed queries
+func (r *CoreRouter) Stats() Stats {
+	return Stats{
+		Routed:  r.routed.Load(),
+		Failed:  r.failed.Load(),
+		Refused: r.refused.Load(),
+	}
+}
+
 func (r *CoreRouter) Conns() *ConnSet {
 	return r.conns
 }
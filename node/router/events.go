@@ -1,5 +1,7 @@
 package router
 
+import "github.com/cryptopunkscc/astrald/net"
+
 type EventConnAdded struct {
 	Conn *MonitoredConn
 }
@@ -7,3 +9,8 @@ type EventConnAdded struct {
 type EventConnRemoved struct {
 	Conn *MonitoredConn
 }
+
+// EventQueryFailed is emitted when a new query is refused by a guard or fails to route
+type EventQueryFailed struct {
+	Query net.Query
+}
@@ -0,0 +1,9 @@
+package router
+
+import "github.com/cryptopunkscc/astrald/net"
+
+// Guard can refuse a query before it's routed
+type Guard interface {
+	// CheckQuery returns a non-nil error if the query should not be routed
+	CheckQuery(query net.Query, hints net.Hints) error
+}
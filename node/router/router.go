@@ -12,4 +12,6 @@ type Router interface {
 	AddRoute(caller id.Identity, target id.Identity, router net.Router, priority int) error
 	RemoveRoute(caller id.Identity, target id.Identity, router net.Router) error
 	Routes() []Route
+	AddGuard(guard Guard) error
+	RemoveGuard(guard Guard) error
 }